@@ -0,0 +1,214 @@
+package client
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// AdaptiveRetryer implements the "adaptive" retry mode. In addition to the
+// retry quota and backoff of the StandardRetryer, the AdaptiveRetryer limits
+// the rate at which request attempts are sent with a client side token
+// bucket.
+//
+// The rate limiter is only enabled once a request is throttled by the
+// service. When throttled, the allowed send rate is reduced, and then
+// increased following a CUBIC curve as requests succeed. Request attempts
+// wait for the rate limiter before being signed and sent, so all requests
+// made with the same AdaptiveRetryer value share the send rate.
+//
+// Use NewAdaptiveRetryer to create an AdaptiveRetryer. The retryer can be set
+// on the aws.Config.Retryer, or enabled with the aws.Config.RetryMode option
+// or the shared config file's retry_mode setting.
+type AdaptiveRetryer struct {
+	*StandardRetryer
+
+	limiterOnce sync.Once
+	limiter     *clientRateLimiter
+}
+
+// NewAdaptiveRetryer returns an AdaptiveRetryer which will retry requests at
+// most maxRetries times.
+func NewAdaptiveRetryer(maxRetries int) *AdaptiveRetryer {
+	return &AdaptiveRetryer{
+		StandardRetryer: NewStandardRetryer(maxRetries),
+	}
+}
+
+func (a *AdaptiveRetryer) rateLimiter() *clientRateLimiter {
+	a.limiterOnce.Do(func() {
+		a.limiter = newClientRateLimiter(time.Now)
+	})
+	return a.limiter
+}
+
+// attachRequestHandlers adds the handlers applying the retry quota and
+// client side rate limiting to the request.
+func (a *AdaptiveRetryer) attachRequestHandlers(r *request.Request) {
+	a.StandardRetryer.attachRequestHandlers(r)
+
+	r.Handlers.Sign.PushFrontNamed(request.NamedHandler{
+		Name: "core.ClientRateLimitHandler",
+		Fn: func(r *request.Request) {
+			delay := a.rateLimiter().acquire(1)
+			if delay <= 0 {
+				return
+			}
+			if err := aws.SleepWithContext(r.Context(), delay); err != nil {
+				r.Error = awserr.New(request.CanceledErrorCode,
+					"request context canceled", err)
+			}
+		},
+	})
+	r.Handlers.CompleteAttempt.PushBackNamed(request.NamedHandler{
+		Name: "core.ClientRateUpdateHandler",
+		Fn: func(r *request.Request) {
+			a.rateLimiter().updateSendingRate(r.Error != nil && r.IsErrorThrottle())
+		},
+	})
+}
+
+const (
+	rateLimiterMinFillRate     = 0.5
+	rateLimiterMinCapacity     = 1
+	rateLimiterSmooth          = 0.8
+	rateLimiterBeta            = 0.7
+	rateLimiterScaleConstant   = 0.4
+	rateLimiterTimeBucketScale = 2
+)
+
+// clientRateLimiter is a token bucket which limits the rate request attempts
+// are sent. The bucket's fill rate is adjusted based on the measured send
+// rate and throttling responses, using the CUBIC congestion control
+// algorithm. All times are measured in seconds.
+type clientRateLimiter struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	enabled         bool
+	fillRate        float64
+	maxCapacity     float64
+	currentCapacity float64
+	lastTimestamp   float64
+
+	measuredTxRate   float64
+	lastTxRateBucket float64
+	requestCount     int64
+
+	lastMaxRate      float64
+	lastThrottleTime float64
+	timeWindow       float64
+}
+
+func newClientRateLimiter(now func() time.Time) *clientRateLimiter {
+	l := &clientRateLimiter{now: now}
+	t := l.seconds()
+	l.lastTxRateBucket = math.Floor(t)
+	l.lastThrottleTime = t
+	return l
+}
+
+func (l *clientRateLimiter) seconds() float64 {
+	return float64(l.now().UnixNano()) / float64(time.Second)
+}
+
+// acquire removes the amount of tokens from the bucket, and returns how long
+// the caller must wait before sending the request attempt. The tokens are
+// reserved immediately, so concurrent callers wait in turn.
+func (l *clientRateLimiter) acquire(amount float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.enabled {
+		return 0
+	}
+
+	l.refill()
+
+	var delay time.Duration
+	if amount > l.currentCapacity {
+		delay = time.Duration((amount - l.currentCapacity) / l.fillRate * float64(time.Second))
+	}
+	l.currentCapacity -= amount
+
+	return delay
+}
+
+// updateSendingRate updates the measured send rate and the fill rate of the
+// bucket based on if the request attempt was throttled.
+func (l *clientRateLimiter) updateSendingRate(throttled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.updateMeasuredRate()
+
+	var calculatedRate float64
+	if throttled {
+		rateToUse := l.measuredTxRate
+		if l.enabled {
+			rateToUse = math.Min(l.measuredTxRate, l.fillRate)
+		}
+
+		l.lastMaxRate = rateToUse
+		l.calculateTimeWindow()
+		l.lastThrottleTime = l.seconds()
+		calculatedRate = l.cubicThrottle(rateToUse)
+		l.enabled = true
+	} else {
+		l.calculateTimeWindow()
+		calculatedRate = l.cubicSuccess(l.seconds())
+	}
+
+	newRate := math.Min(calculatedRate, 2*l.measuredTxRate)
+	l.updateRate(newRate)
+}
+
+func (l *clientRateLimiter) refill() {
+	t := l.seconds()
+	if l.lastTimestamp == 0 {
+		l.lastTimestamp = t
+		return
+	}
+
+	fillAmount := (t - l.lastTimestamp) * l.fillRate
+	l.currentCapacity = math.Min(l.maxCapacity, l.currentCapacity+fillAmount)
+	l.lastTimestamp = t
+}
+
+func (l *clientRateLimiter) updateRate(newRate float64) {
+	l.refill()
+	l.fillRate = math.Max(newRate, rateLimiterMinFillRate)
+	l.maxCapacity = math.Max(newRate, rateLimiterMinCapacity)
+	l.currentCapacity = math.Min(l.currentCapacity, l.maxCapacity)
+}
+
+func (l *clientRateLimiter) updateMeasuredRate() {
+	t := l.seconds()
+	timeBucket := math.Floor(t*rateLimiterTimeBucketScale) / rateLimiterTimeBucketScale
+	l.requestCount++
+
+	if timeBucket > l.lastTxRateBucket {
+		currentRate := float64(l.requestCount) / (timeBucket - l.lastTxRateBucket)
+		l.measuredTxRate = (currentRate * rateLimiterSmooth) +
+			(l.measuredTxRate * (1 - rateLimiterSmooth))
+		l.requestCount = 0
+		l.lastTxRateBucket = timeBucket
+	}
+}
+
+func (l *clientRateLimiter) calculateTimeWindow() {
+	l.timeWindow = math.Cbrt((l.lastMaxRate * (1 - rateLimiterBeta)) / rateLimiterScaleConstant)
+}
+
+func (l *clientRateLimiter) cubicSuccess(t float64) float64 {
+	dt := t - l.lastThrottleTime
+	return rateLimiterScaleConstant*math.Pow(dt-l.timeWindow, 3) + l.lastMaxRate
+}
+
+func (l *clientRateLimiter) cubicThrottle(rateToUse float64) float64 {
+	return rateToUse * rateLimiterBeta
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+type mockClock struct {
+	t time.Time
+}
+
+func (c *mockClock) now() time.Time { return c.t }
+
+func (c *mockClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestClientRateLimiter_DisabledUntilThrottled(t *testing.T) {
+	clock := &mockClock{t: time.Unix(1000, 0)}
+	l := newClientRateLimiter(clock.now)
+
+	for i := 0; i < 100; i++ {
+		if d := l.acquire(1); d != 0 {
+			t.Fatalf("%d, expect no delay while disabled, got %v", i, d)
+		}
+	}
+
+	l.updateSendingRate(true)
+	if !l.enabled {
+		t.Fatalf("expect rate limiter to be enabled after throttle")
+	}
+}
+
+func TestClientRateLimiter_ThrottleReducesRate(t *testing.T) {
+	clock := &mockClock{t: time.Unix(1000, 0)}
+	l := newClientRateLimiter(clock.now)
+
+	// Send 10 requests a second for a few seconds to measure the send rate.
+	for i := 0; i < 40; i++ {
+		clock.advance(100 * time.Millisecond)
+		l.updateSendingRate(false)
+	}
+	measured := l.measuredTxRate
+	if measured < 5 || measured > 15 {
+		t.Fatalf("expect measured rate near 10, got %v", measured)
+	}
+
+	l.updateSendingRate(true)
+	if e, a := l.measuredTxRate*rateLimiterBeta, l.fillRate; math.Abs(e-a) > 0.001 {
+		t.Errorf("expect fill rate %v, got %v", e, a)
+	}
+
+	// Drain the bucket, and expect the next acquire to be delayed.
+	for l.currentCapacity >= 1 {
+		l.acquire(1)
+	}
+	if d := l.acquire(1); d <= 0 {
+		t.Errorf("expect delay after bucket is drained, got %v", d)
+	}
+
+	// Rate recovers towards the previous max rate after successes.
+	throttledRate := l.fillRate
+	for i := 0; i < 50; i++ {
+		clock.advance(100 * time.Millisecond)
+		l.updateSendingRate(false)
+	}
+	if l.fillRate <= throttledRate {
+		t.Errorf("expect fill rate to increase from %v, got %v", throttledRate, l.fillRate)
+	}
+}
+
+func TestClientRateLimiter_MinFillRate(t *testing.T) {
+	clock := &mockClock{t: time.Unix(1000, 0)}
+	l := newClientRateLimiter(clock.now)
+
+	for i := 0; i < 10; i++ {
+		l.updateSendingRate(true)
+	}
+	if e, a := rateLimiterMinFillRate, l.fillRate; a < e {
+		t.Errorf("expect fill rate no less than %v, got %v", e, a)
+	}
+	if e, a := float64(rateLimiterMinCapacity), l.maxCapacity; a < e {
+		t.Errorf("expect max capacity no less than %v, got %v", e, a)
+	}
+}
+
+func TestAdaptiveRetryer_Throttle(t *testing.T) {
+	c, attempts := newRetryTestClient(aws.Config{RetryMode: aws.RetryModeAdaptive, MaxRetries: aws.Int(1)},
+		respondStatus(429),
+		respondStatus(200),
+	)
+	retryer := c.Retryer.(*AdaptiveRetryer)
+
+	req := c.NewRequest(&request.Operation{Name: "Operation"}, nil, nil)
+	if err := req.Send(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 2, *attempts; e != a {
+		t.Errorf("expect %v attempts, got %v", e, a)
+	}
+	if !retryer.rateLimiter().enabled {
+		t.Errorf("expect rate limiter to be enabled after throttle")
+	}
+	if e, a := DefaultRetryQuotaCapacity, retryer.retryQuota().available(); e != a {
+		t.Errorf("expect %v tokens available, got %v", e, a)
+	}
+}
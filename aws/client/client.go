@@ -66,7 +66,21 @@ func New(cfg aws.Config, info metadata.ClientInfo, handlers request.Handlers, op
 		if cfg.MaxRetries == nil || maxRetries == aws.UseServiceDefaultRetries {
 			maxRetries = 3
 		}
-		svc.Retryer = DefaultRetryer{NumMaxRetries: maxRetries}
+
+		switch cfg.RetryMode {
+		case aws.RetryModeStandard:
+			svc.Retryer = NewStandardRetryer(maxRetries)
+		case aws.RetryModeAdaptive:
+			svc.Retryer = NewAdaptiveRetryer(maxRetries)
+		default:
+			svc.Retryer = DefaultRetryer{NumMaxRetries: maxRetries}
+		}
+
+		if _, ok := svc.Retryer.(requestHandlerRetryer); ok {
+			// Set the retryer on the client's config so service clients with
+			// custom retryers do not override the retry mode.
+			svc.Config.Retryer = svc.Retryer
+		}
 	}
 
 	svc.AddDebugHandlers()
@@ -81,7 +95,18 @@ func New(cfg aws.Config, info metadata.ClientInfo, handlers request.Handlers, op
 // NewRequest returns a new Request pointer for the service API
 // operation and parameters.
 func (c *Client) NewRequest(operation *request.Operation, params interface{}, data interface{}) *request.Request {
-	return request.New(c.Config, c.ClientInfo, c.Handlers, c.Retryer, operation, params, data)
+	r := request.New(c.Config, c.ClientInfo, c.Handlers, c.Retryer, operation, params, data)
+	if retryer, ok := c.Retryer.(requestHandlerRetryer); ok {
+		retryer.attachRequestHandlers(r)
+	}
+
+	return r
+}
+
+// requestHandlerRetryer is implemented by retryers which need to track the
+// state of each request, e.g. the StandardRetryer's retry quota.
+type requestHandlerRetryer interface {
+	attachRequestHandlers(*request.Request)
 }
 
 // AddDebugHandlers injects debug logging handlers into the service to log request
@@ -0,0 +1,246 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/internal/sdkrand"
+)
+
+const (
+	// DefaultRetryQuotaCapacity is the number of tokens the retry quota of a
+	// StandardRetryer starts with.
+	DefaultRetryQuotaCapacity = 500
+
+	// DefaultRetryCost is the number of tokens removed from the retry quota
+	// for each retried request attempt.
+	DefaultRetryCost = 5
+
+	// DefaultRetryTimeoutCost is the number of tokens removed from the retry
+	// quota for each request attempt retried due to a timeout.
+	DefaultRetryTimeoutCost = 10
+
+	// DefaultNoRetryIncrement is the number of tokens added back to the retry
+	// quota when a request succeeds without being retried.
+	DefaultNoRetryIncrement = 1
+
+	// DefaultStandardMaxBackoff is the maximum delay a StandardRetryer will
+	// wait before retrying a request.
+	DefaultStandardMaxBackoff = 20 * time.Second
+)
+
+// StandardRetryer implements the "standard" retry mode. Requests are retried
+// using exponential backoff with full jitter, bounded by a retry quota shared
+// by all requests made with the retryer.
+//
+// Each retried attempt removes tokens from the retry quota. Retrying an
+// attempt which timed out costs more than other errors. Successful requests
+// refund the tokens used by their retries, or add a small increment if no
+// retry was needed. When the quota is exhausted requests fail without being
+// retried until enough requests succeed to refill it. This prevents a client
+// from amplifying load on a service which is already failing.
+//
+// Use NewStandardRetryer to create a StandardRetryer. The retryer can be set
+// on the aws.Config.Retryer, or enabled with the aws.Config.RetryMode option
+// or the shared config file's retry_mode setting.
+//
+//   sess := session.Must(session.NewSession(
+//       request.WithRetryer(aws.NewConfig(), client.NewStandardRetryer(2)),
+//   ))
+type StandardRetryer struct {
+	// NumMaxRetries is the maximum number of times a request will be
+	// retried.
+	NumMaxRetries int
+
+	// MaxBackoff is the maximum delay before retrying a request. Defaults to
+	// DefaultStandardMaxBackoff if zero.
+	MaxBackoff time.Duration
+
+	quotaOnce sync.Once
+	quota     *retryQuota
+}
+
+// NewStandardRetryer returns a StandardRetryer which will retry requests at
+// most maxRetries times.
+func NewStandardRetryer(maxRetries int) *StandardRetryer {
+	return &StandardRetryer{
+		NumMaxRetries: maxRetries,
+	}
+}
+
+// retryQuota returns the retry quota of the retryer, creating it if needed.
+func (s *StandardRetryer) retryQuota() *retryQuota {
+	s.quotaOnce.Do(func() {
+		s.quota = newRetryQuota(DefaultRetryQuotaCapacity)
+	})
+	return s.quota
+}
+
+// MaxRetries returns the number of maximum retries the retryer will use to
+// make an individual API request.
+func (s *StandardRetryer) MaxRetries() int {
+	return s.NumMaxRetries
+}
+
+// RetryRules returns the delay duration before retrying the request. The
+// delay is a random duration between zero and the exponential backoff for
+// the request's attempt, capped at MaxBackoff.
+func (s *StandardRetryer) RetryRules(r *request.Request) time.Duration {
+	maxBackoff := s.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = DefaultStandardMaxBackoff
+	}
+
+	backoff := maxBackoff
+	if r.RetryCount < 32 {
+		if b := time.Duration(1<<uint(r.RetryCount)) * time.Second; b < maxBackoff {
+			backoff = b
+		}
+	}
+	delay := time.Duration(sdkrand.SeededRand.Float64() * float64(backoff))
+
+	if r.IsErrorThrottle() {
+		if retryAfter, ok := getRetryAfterDelay(r); ok && retryAfter > delay {
+			delay = retryAfter
+		}
+	}
+
+	return delay
+}
+
+// ShouldRetry returns true if the request should be retried. The retry quota
+// is not considered by ShouldRetry, it is applied after the request's
+// retry state has been determined.
+func (s *StandardRetryer) ShouldRetry(r *request.Request) bool {
+	// If one of the other handlers already set the retry state
+	// we don't want to override it based on the service's state
+	if r.Retryable != nil {
+		return *r.Retryable
+	}
+
+	if r.HTTPResponse.StatusCode >= 500 && r.HTTPResponse.StatusCode != 501 {
+		return true
+	}
+
+	return r.IsErrorRetryable() || r.IsErrorThrottle()
+}
+
+// attachRequestHandlers adds the handlers applying the retry quota to the
+// request.
+func (s *StandardRetryer) attachRequestHandlers(r *request.Request) {
+	var lastCost int
+
+	r.Handlers.Retry.PushBackNamed(request.NamedHandler{
+		Name: "core.RetryQuotaHandler",
+		Fn: func(r *request.Request) {
+			lastCost = s.acquireRetryQuota(r)
+		},
+	})
+	r.Handlers.Complete.PushBackNamed(request.NamedHandler{
+		Name: "core.RetryQuotaReleaseHandler",
+		Fn: func(r *request.Request) {
+			if r.Error != nil {
+				return
+			}
+			if lastCost == 0 {
+				lastCost = DefaultNoRetryIncrement
+			}
+			s.retryQuota().release(lastCost)
+		},
+	})
+}
+
+// acquireRetryQuota determines if the request will be retried, and removes
+// the cost of the retry from the quota. If the quota does not have enough
+// tokens the request will not be retried. Returns the number of tokens
+// removed from the quota.
+func (s *StandardRetryer) acquireRetryQuota(r *request.Request) int {
+	if r.Retryable == nil || aws.BoolValue(r.Config.EnforceShouldRetryCheck) {
+		r.Retryable = aws.Bool(r.ShouldRetry(r))
+	}
+	if !r.WillRetry() {
+		return 0
+	}
+
+	cost := DefaultRetryCost
+	if isErrTimeout(r.Error) {
+		cost = DefaultRetryTimeoutCost
+	}
+	if !s.retryQuota().acquire(cost) {
+		r.Retryable = aws.Bool(false)
+		return 0
+	}
+
+	return cost
+}
+
+type timeoutError interface {
+	Timeout() bool
+}
+
+// isErrTimeout returns if the error, or one of its nested errors, is a
+// timeout error.
+func isErrTimeout(err error) bool {
+	for err != nil {
+		switch e := err.(type) {
+		case awserr.Error:
+			switch e.Code() {
+			case request.ErrCodeResponseTimeout, "RequestTimeout", "RequestTimeoutException":
+				return true
+			}
+			err = e.OrigErr()
+		case timeoutError:
+			return e.Timeout()
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+// retryQuota is a token bucket limiting the number of retries that can be
+// made.
+type retryQuota struct {
+	mu       sync.Mutex
+	capacity int
+	tokens   int
+}
+
+func newRetryQuota(capacity int) *retryQuota {
+	return &retryQuota{capacity: capacity, tokens: capacity}
+}
+
+// acquire removes the amount of tokens from the quota. Returns false without
+// modifying the quota if the quota does not have enough tokens.
+func (q *retryQuota) acquire(amount int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if amount > q.tokens {
+		return false
+	}
+	q.tokens -= amount
+	return true
+}
+
+// release adds the amount of tokens back to the quota, up to its capacity.
+func (q *retryQuota) release(amount int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.tokens += amount
+	if q.tokens > q.capacity {
+		q.tokens = q.capacity
+	}
+}
+
+func (q *retryQuota) available() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.tokens
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/corehandlers"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+type mockTimeoutError struct{}
+
+func (mockTimeoutError) Error() string { return "i/o timeout" }
+func (mockTimeoutError) Timeout() bool { return true }
+
+// newRetryTestClient returns a client which responds to requests with the
+// responses provided, in order. The last response is repeated for any
+// additional attempts.
+func newRetryTestClient(cfg aws.Config, responses ...func(*request.Request)) (*Client, *int) {
+	var attempts int
+
+	var handlers request.Handlers
+	handlers.Send.PushBack(func(r *request.Request) {
+		i := attempts
+		if i >= len(responses) {
+			i = len(responses) - 1
+		}
+		attempts++
+		responses[i](r)
+	})
+	handlers.ValidateResponse.PushBackNamed(corehandlers.ValidateResponseHandler)
+	handlers.AfterRetry.PushBackNamed(corehandlers.AfterRetryHandler)
+
+	cfg.SleepDelay = func(time.Duration) {}
+	c := New(cfg, metadata.ClientInfo{}, handlers)
+
+	return c, &attempts
+}
+
+func respondStatus(status int) func(*request.Request) {
+	return func(r *request.Request) {
+		r.HTTPResponse = &http.Response{
+			StatusCode: status,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}
+	}
+}
+
+func respondTimeout(r *request.Request) {
+	r.HTTPResponse = &http.Response{
+		StatusCode: 0,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}
+	r.Error = awserr.New(request.ErrCodeRequestError, "send request failed", mockTimeoutError{})
+}
+
+func TestNewClient_RetryMode(t *testing.T) {
+	cases := map[aws.RetryMode]interface{}{
+		"":                    DefaultRetryer{},
+		aws.RetryModeLegacy:   DefaultRetryer{},
+		aws.RetryModeStandard: &StandardRetryer{},
+		aws.RetryModeAdaptive: &AdaptiveRetryer{},
+	}
+
+	for mode, expect := range cases {
+		c := New(aws.Config{RetryMode: mode, MaxRetries: aws.Int(5)}, metadata.ClientInfo{}, request.Handlers{})
+
+		switch expect.(type) {
+		case DefaultRetryer:
+			if _, ok := c.Retryer.(DefaultRetryer); !ok {
+				t.Errorf("%q, expect DefaultRetryer, got %T", mode, c.Retryer)
+			}
+			if c.Config.Retryer != nil {
+				t.Errorf("%q, expect config retryer not to be set, got %T", mode, c.Config.Retryer)
+			}
+		case *StandardRetryer:
+			if _, ok := c.Retryer.(*StandardRetryer); !ok {
+				t.Errorf("%q, expect StandardRetryer, got %T", mode, c.Retryer)
+			}
+			if c.Config.Retryer != c.Retryer {
+				t.Errorf("%q, expect config retryer to be set", mode)
+			}
+		case *AdaptiveRetryer:
+			if _, ok := c.Retryer.(*AdaptiveRetryer); !ok {
+				t.Errorf("%q, expect AdaptiveRetryer, got %T", mode, c.Retryer)
+			}
+		}
+		if e, a := 5, c.MaxRetries(); e != a {
+			t.Errorf("%q, expect %v max retries, got %v", mode, e, a)
+		}
+	}
+}
+
+func TestStandardRetryer_RetryQuota(t *testing.T) {
+	c, attempts := newRetryTestClient(aws.Config{RetryMode: aws.RetryModeStandard, MaxRetries: aws.Int(2)},
+		respondStatus(500),
+		respondStatus(500),
+		respondStatus(200),
+	)
+	retryer := c.Retryer.(*StandardRetryer)
+
+	req := c.NewRequest(&request.Operation{Name: "Operation"}, nil, nil)
+	if err := req.Send(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 3, *attempts; e != a {
+		t.Errorf("expect %v attempts, got %v", e, a)
+	}
+	// Only the cost of the last retry is refunded.
+	if e, a := DefaultRetryQuotaCapacity-DefaultRetryCost, retryer.retryQuota().available(); e != a {
+		t.Errorf("expect %v tokens available, got %v", e, a)
+	}
+}
+
+func TestStandardRetryer_RetryQuotaExhausted(t *testing.T) {
+	c, attempts := newRetryTestClient(aws.Config{RetryMode: aws.RetryModeStandard, MaxRetries: aws.Int(3)},
+		respondTimeout,
+	)
+	retryer := c.Retryer.(*StandardRetryer)
+	retryer.retryQuota().acquire(DefaultRetryQuotaCapacity - DefaultRetryTimeoutCost)
+
+	req := c.NewRequest(&request.Operation{Name: "Operation"}, nil, nil)
+	if err := req.Send(); err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	// Only enough tokens for a single timeout retry.
+	if e, a := 2, *attempts; e != a {
+		t.Errorf("expect %v attempts, got %v", e, a)
+	}
+	if e, a := 0, retryer.retryQuota().available(); e != a {
+		t.Errorf("expect %v tokens available, got %v", e, a)
+	}
+}
+
+func TestStandardRetryer_NoRetryIncrement(t *testing.T) {
+	c, _ := newRetryTestClient(aws.Config{RetryMode: aws.RetryModeStandard},
+		respondStatus(200),
+	)
+	retryer := c.Retryer.(*StandardRetryer)
+	retryer.retryQuota().acquire(10)
+
+	req := c.NewRequest(&request.Operation{Name: "Operation"}, nil, nil)
+	if err := req.Send(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := DefaultRetryQuotaCapacity-10+DefaultNoRetryIncrement, retryer.retryQuota().available(); e != a {
+		t.Errorf("expect %v tokens available, got %v", e, a)
+	}
+}
+
+func TestStandardRetryer_RetryRules(t *testing.T) {
+	retryer := NewStandardRetryer(10)
+
+	for i := 0; i < 10; i++ {
+		r := &request.Request{
+			RetryCount:   i,
+			HTTPResponse: &http.Response{StatusCode: 500},
+		}
+		max := time.Duration(1<<uint(i)) * time.Second
+		if max > DefaultStandardMaxBackoff {
+			max = DefaultStandardMaxBackoff
+		}
+		if d := retryer.RetryRules(r); d < 0 || d > max {
+			t.Errorf("%d, expect delay between 0 and %v, got %v", i, max, d)
+		}
+	}
+
+	r := &request.Request{
+		HTTPResponse: &http.Response{
+			StatusCode: 429,
+			Header:     http.Header{"Retry-After": []string{"30"}},
+		},
+	}
+	if e, a := 30*time.Second, retryer.RetryRules(r); e != a {
+		t.Errorf("expect %v retry after delay, got %v", e, a)
+	}
+}
+
+func TestIsErrTimeout(t *testing.T) {
+	cases := []struct {
+		Err    error
+		Expect bool
+	}{
+		{Err: nil},
+		{Err: awserr.New("SomeError", "message", nil)},
+		{Err: awserr.New(request.ErrCodeResponseTimeout, "message", nil), Expect: true},
+		{Err: awserr.New(request.ErrCodeRequestError, "message", mockTimeoutError{}), Expect: true},
+		{Err: awserr.New(request.ErrCodeSerialization, "message",
+			awserr.New(request.ErrCodeRequestError, "message", mockTimeoutError{})), Expect: true},
+	}
+
+	for i, c := range cases {
+		if e, a := c.Expect, isErrTimeout(c.Err); e != a {
+			t.Errorf("%d, expect %v timeout, got %v", i, e, a)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package aws
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -17,6 +18,36 @@ const UseServiceDefaultRetries = -1
 // interface.
 type RequestRetryer interface{}
 
+// RetryMode is the mode the SDK's default retryer will use to retry failed
+// requests.
+type RetryMode string
+
+const (
+	// RetryModeLegacy uses the client.DefaultRetryer, and any service specific
+	// customizations of it. This is the default retry mode.
+	RetryModeLegacy RetryMode = "legacy"
+
+	// RetryModeStandard uses the client.StandardRetryer, which limits retries
+	// with a retry quota token bucket.
+	RetryModeStandard RetryMode = "standard"
+
+	// RetryModeAdaptive uses the client.AdaptiveRetryer, which in addition to
+	// the standard retry mode's retry quota limits the rate requests are sent
+	// when the service throttles requests.
+	RetryModeAdaptive RetryMode = "adaptive"
+)
+
+// ParseRetryMode returns the RetryMode for the string value. Returns false
+// if the value is not a known retry mode. The value is case insensitive.
+func ParseRetryMode(v string) (RetryMode, bool) {
+	switch m := RetryMode(strings.ToLower(v)); m {
+	case RetryModeLegacy, RetryModeStandard, RetryModeAdaptive:
+		return m, true
+	default:
+		return "", false
+	}
+}
+
 // A Config provides service configuration for service clients. By default,
 // all clients will use the defaults.DefaultConfig structure.
 //
@@ -107,6 +138,24 @@ type Config struct {
 	//
 	Retryer RequestRetryer
 
+	// RetryMode selects the retryer the service client will use when the
+	// Retryer field is not set. Defaults to RetryModeLegacy, the
+	// client.DefaultRetryer.
+	//
+	// MaxRetries is used as the maximum number of retries for all retry
+	// modes.
+	RetryMode RetryMode
+
+	// STSRegionalEndpoint selects whether STS service clients use the
+	// regional STS endpoint of the configured region, or the legacy global
+	// endpoint, sts.amazonaws.com, for regions that have used it. Defaults
+	// to the legacy endpoints.
+	//
+	//   sess := session.Must(session.NewSession(&aws.Config{
+	//       STSRegionalEndpoint: endpoints.RegionalSTSEndpoint,
+	//   }))
+	STSRegionalEndpoint endpoints.STSRegionalEndpoint
+
 	// Disables semantic parameter validation, which validates input for
 	// missing required fields and/or other semantic request input errors.
 	DisableParamValidation *bool
@@ -321,6 +370,20 @@ func (c *Config) WithMaxRetries(max int) *Config {
 	return c
 }
 
+// WithRetryMode sets a config RetryMode value returning a Config pointer
+// for chaining.
+func (c *Config) WithRetryMode(mode RetryMode) *Config {
+	c.RetryMode = mode
+	return c
+}
+
+// WithSTSRegionalEndpoint sets a config STSRegionalEndpoint value returning
+// a Config pointer for chaining.
+func (c *Config) WithSTSRegionalEndpoint(sre endpoints.STSRegionalEndpoint) *Config {
+	c.STSRegionalEndpoint = sre
+	return c
+}
+
 // WithDisableParamValidation sets a config DisableParamValidation value
 // returning a Config pointer for chaining.
 func (c *Config) WithDisableParamValidation(disable bool) *Config {
@@ -469,6 +532,14 @@ func mergeInConfig(dst *Config, other *Config) {
 		dst.Retryer = other.Retryer
 	}
 
+	if len(other.RetryMode) != 0 {
+		dst.RetryMode = other.RetryMode
+	}
+
+	if other.STSRegionalEndpoint != endpoints.UnsetSTSEndpoint {
+		dst.STSRegionalEndpoint = other.STSRegionalEndpoint
+	}
+
 	if other.DisableParamValidation != nil {
 		dst.DisableParamValidation = other.DisableParamValidation
 	}
@@ -70,9 +70,25 @@ func NewChainCredentials(providers []Provider) *Credentials {
 // If a provider is found it will be cached and any calls to IsExpired()
 // will return the expired state of the cached provider.
 func (c *ChainProvider) Retrieve() (Value, error) {
+	return c.RetrieveWithContext(backgroundContext())
+}
+
+// RetrieveWithContext returns the credentials value or error if no provider
+// returned without error. The Context is passed to the providers which
+// implement ProviderWithContext.
+//
+// If a provider is found it will be cached and any calls to IsExpired()
+// will return the expired state of the cached provider.
+func (c *ChainProvider) RetrieveWithContext(ctx Context) (Value, error) {
 	var errs []error
 	for _, p := range c.Providers {
-		creds, err := p.Retrieve()
+		var creds Value
+		var err error
+		if pc, ok := p.(ProviderWithContext); ok {
+			creds, err = pc.RetrieveWithContext(ctx)
+		} else {
+			creds, err = p.Retrieve()
+		}
 		if err == nil {
 			c.curr = p
 			return creds, nil
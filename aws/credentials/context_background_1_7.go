@@ -0,0 +1,19 @@
+// +build go1.7
+
+package credentials
+
+import "context"
+
+// errCanceled is the error returned by the Context of a credentials retrieval
+// after all of its callers stopped waiting.
+var errCanceled = context.Canceled
+
+// backgroundContext returns a context that will never be canceled, has no
+// values, and no deadline. This context is used by the SDK to provide
+// backwards compatibility with non-context API operations and functionality.
+//
+// This is a copy of aws.BackgroundContext, which the credentials package
+// cannot import.
+func backgroundContext() Context {
+	return context.Background()
+}
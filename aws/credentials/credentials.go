@@ -31,6 +31,23 @@
 //     credsValue, err := creds.Get()
 //     // New credentials will be retrieved instead of from cache.
 //
+// Example of retrieving the credentials with a Context. Get returns early if
+// the Context is canceled, and the Context is passed to Providers which
+// implement the ProviderWithContext interface.
+//
+//     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//     defer cancel()
+//
+//     credsValue, err := creds.GetWithContext(ctx)
+//
+// Example of refreshing the credentials in the background, before they
+// expire. Callers of Get continue to get the cached credentials while the new
+// credentials are retrieved, instead of waiting on the Provider. The Provider
+// must implement the Expirer interface.
+//
+//     creds := credentials.NewCredentials(&ec2rolecreds.EC2RoleProvider{})
+//     creds.EnableBackgroundRefresh(5 * time.Minute)
+//
 //
 // Custom Provider
 //
@@ -106,6 +123,17 @@ type Provider interface {
 	IsExpired() bool
 }
 
+// ProviderWithContext is a Provider that can retrieve credentials with a
+// Context. Credentials.GetWithContext passes its Context to the Provider's
+// RetrieveWithContext, so the retrieval can be canceled.
+type ProviderWithContext interface {
+	Provider
+
+	// RetrieveWithContext returns nil if it successfully retrieved the
+	// value. Error is returned if the value were not obtainable, or empty.
+	RetrieveWithContext(Context) (Value, error)
+}
+
 // An Expirer is an interface that Providers can implement to expose the expiration
 // time, if known.  If the Provider cannot accurately provide this info,
 // it should not implement this interface.
@@ -196,6 +224,9 @@ func (e *Expiry) ExpiresAt() time.Time {
 // The first Credentials.Get() will always call Provider.Retrieve() to get the
 // first instance of the credentials Value. All calls to Get() after that
 // will return the cached credentials Value until IsExpired() returns true.
+//
+// Concurrent calls to Get() which find the credentials expired share a single
+// call to the Provider's Retrieve().
 type Credentials struct {
 	creds        Value
 	forceRefresh bool
@@ -203,6 +234,22 @@ type Credentials struct {
 	m sync.RWMutex
 
 	provider Provider
+
+	// The in flight retrieval of the credentials, shared by all callers
+	// waiting on the credentials. Nil if the credentials are not being
+	// retrieved.
+	retrieval *retrieval
+
+	// The Provider is not safe to use concurrently with its Retrieve, so
+	// while the credentials are being retrieved, the credentials expire at
+	// the provider's expiration from before the retrieval started. Zero if
+	// the credentials were already expired.
+	retrievalExpiresAt time.Time
+
+	// Background refresh of the credentials, see EnableBackgroundRefresh.
+	refreshEnabled bool
+	refreshWindow  time.Duration
+	refreshTimer   *time.Timer
 }
 
 // NewCredentials returns a pointer to a new Credentials with the provider set.
@@ -223,6 +270,25 @@ func NewCredentials(provider Provider) *Credentials {
 // If Credentials.Expire() was called the credentials Value will be force
 // expired, and the next call to Get() will cause them to be refreshed.
 func (c *Credentials) Get() (Value, error) {
+	return c.GetWithContext(backgroundContext())
+}
+
+// GetWithContext returns the credentials value, or error if the credentials
+// Value failed to be retrieved. Will return early if the passed in context is
+// canceled.
+//
+// Will return the cached credentials Value if it has not expired. If the
+// credentials Value has expired the Provider's Retrieve() will be called
+// to refresh the credentials, or RetrieveWithContext if the Provider
+// implements ProviderWithContext.
+//
+// Concurrent callers share a single retrieval of the credentials. The context
+// passed to the Provider is only canceled once all of the callers waiting on
+// the retrieval have stopped waiting, because their contexts were canceled.
+//
+// If Credentials.Expire() was called the credentials Value will be force
+// expired, and the next call to Get() will cause them to be refreshed.
+func (c *Credentials) GetWithContext(ctx Context) (Value, error) {
 	// Check the cached credentials first with just the read lock.
 	c.m.RLock()
 	if !c.isExpired() {
@@ -235,18 +301,230 @@ func (c *Credentials) Get() (Value, error) {
 	// Credentials are expired need to retrieve the credentials taking the full
 	// lock.
 	c.m.Lock()
-	defer c.m.Unlock()
+	for {
+		if !c.isExpired() {
+			creds := c.creds
+			c.m.Unlock()
+			return creds, nil
+		}
+
+		r := c.retrieval
+		if r == nil || !r.canceled {
+			break
+		}
 
-	if c.isExpired() {
-		creds, err := c.provider.Retrieve()
+		// The in flight retrieval was canceled by all of its callers. Wait
+		// for the Provider to return before retrieving the credentials again.
+		c.m.Unlock()
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return Value{}, newCanceledError(ctx)
+		}
+		c.m.Lock()
+	}
+
+	r := c.retrieve(ctx, time.Time{})
+	r.waiters++
+	c.m.Unlock()
+
+	select {
+	case <-r.done:
+		return r.creds, r.err
+	case <-ctx.Done():
+		c.m.Lock()
+		r.waiters--
+		if r.waiters == 0 && !r.canceled {
+			r.canceled = true
+			close(r.cancel)
+		}
+		c.m.Unlock()
+		return Value{}, newCanceledError(ctx)
+	}
+}
+
+// retrieve starts retrieving the credentials from the provider, or returns
+// the retrieval in flight. The credentials are considered expired at
+// expiresAt while they are retrieved. Must be called with the lock held.
+func (c *Credentials) retrieve(ctx Context, expiresAt time.Time) *retrieval {
+	if c.retrieval != nil {
+		return c.retrieval
+	}
+
+	r := &retrieval{
+		done:   make(chan struct{}),
+		cancel: make(chan struct{}),
+	}
+	r.ctx = retrievalContext{Context: ctx, done: r.cancel}
+
+	c.retrieval = r
+	c.retrievalExpiresAt = expiresAt
+
+	go func() {
+		var creds Value
+		var err error
+		if p, ok := c.provider.(ProviderWithContext); ok {
+			creds, err = p.RetrieveWithContext(r.ctx)
+		} else {
+			creds, err = c.provider.Retrieve()
+		}
 		if err != nil {
-			return Value{}, err
+			creds = Value{}
 		}
-		c.creds = creds
-		c.forceRefresh = false
+
+		c.m.Lock()
+		defer c.m.Unlock()
+
+		if err == nil {
+			c.creds = creds
+			c.forceRefresh = false
+		}
+		c.retrieval = nil
+		c.retrievalExpiresAt = time.Time{}
+
+		r.creds, r.err = creds, err
+		close(r.done)
+
+		c.scheduleRefresh()
+	}()
+
+	return r
+}
+
+// A retrieval is a call to the Provider to retrieve the credentials, shared
+// by the callers waiting on it.
+type retrieval struct {
+	ctx Context
+
+	// Closed once the Provider returned, with creds and err set.
+	done  chan struct{}
+	creds Value
+	err   error
+
+	// Closed once all callers stopped waiting on the retrieval, canceling
+	// the context passed to the Provider. Guarded by the Credentials lock.
+	cancel   chan struct{}
+	canceled bool
+	waiters  int
+}
+
+// retrievalContext is the Context of a retrieval. Its values are those of the
+// Context which started the retrieval, but it is only canceled once all of the
+// callers waiting on the retrieval stopped waiting.
+type retrievalContext struct {
+	Context
+	done chan struct{}
+}
+
+func (retrievalContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (c retrievalContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c retrievalContext) Err() error {
+	select {
+	case <-c.done:
+		return errCanceled
+	default:
+		return nil
+	}
+}
+
+func newCanceledError(ctx Context) error {
+	return awserr.New("RequestCanceled", "request context canceled", ctx.Err())
+}
+
+// minRefreshDelay is the minimum delay of a background refresh. The
+// credentials are not refreshed in the background if they expire sooner.
+var minRefreshDelay = time.Second
+
+// EnableBackgroundRefresh enables retrieving new credentials in the
+// background before the cached credentials expire, so callers of Get do not
+// wait on the Provider. Callers continue to get the cached credentials while
+// new credentials are retrieved.
+//
+// The Provider must implement the Expirer interface. The credentials are
+// refreshed the window duration before the Provider's ExpiresAt, or half way
+// to ExpiresAt if the credentials expire sooner. If the background refresh
+// fails it is retried until the credentials expire, at which point Get will
+// retrieve the credentials.
+//
+// The first credentials are retrieved by Get, not in the background.
+func (c *Credentials) EnableBackgroundRefresh(window time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.refreshEnabled = true
+	c.refreshWindow = window
+	c.scheduleRefresh()
+}
+
+// DisableBackgroundRefresh disables retrieving new credentials in the
+// background. A background refresh in flight is not stopped.
+func (c *Credentials) DisableBackgroundRefresh() {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.refreshEnabled = false
+	c.stopRefresh()
+}
+
+// scheduleRefresh schedules the background refresh of the credentials, if
+// enabled. Must be called with the lock held.
+func (c *Credentials) scheduleRefresh() {
+	c.stopRefresh()
+
+	if !c.refreshEnabled || c.forceRefresh || c.retrieval != nil {
+		return
+	}
+	expirer, ok := c.provider.(Expirer)
+	if !ok {
+		return
+	}
+
+	remaining := expirer.ExpiresAt().Sub(time.Now())
+	delay := remaining - c.refreshWindow
+	if delay < remaining/2 {
+		delay = remaining / 2
+	}
+	if delay < minRefreshDelay {
+		return
 	}
 
-	return c.creds, nil
+	c.refreshTimer = time.AfterFunc(delay, c.refresh)
+}
+
+// stopRefresh stops the scheduled background refresh. Must be called with
+// the lock held.
+func (c *Credentials) stopRefresh() {
+	if c.refreshTimer != nil {
+		c.refreshTimer.Stop()
+		c.refreshTimer = nil
+	}
+}
+
+// refresh starts retrieving the credentials in the background. Callers of
+// Get continue to get the cached credentials until the provider's expiration.
+func (c *Credentials) refresh() {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.refreshTimer = nil
+	if !c.refreshEnabled || c.retrieval != nil {
+		return
+	}
+
+	var expiresAt time.Time
+	if !c.isExpired() {
+		expiresAt = c.provider.(Expirer).ExpiresAt()
+	}
+
+	// The background refresh is never canceled, as it has no caller which
+	// stops waiting.
+	c.retrieve(backgroundContext(), expiresAt).waiters++
 }
 
 // Expire expires the credentials and forces them to be retrieved on the
@@ -275,7 +553,13 @@ func (c *Credentials) IsExpired() bool {
 
 // isExpired helper method wrapping the definition of expired credentials.
 func (c *Credentials) isExpired() bool {
-	return c.forceRefresh || c.provider.IsExpired()
+	if c.forceRefresh {
+		return true
+	}
+	if c.retrieval != nil {
+		return !time.Now().Before(c.retrievalExpiresAt)
+	}
+	return c.provider.IsExpired()
 }
 
 // ExpiresAt provides access to the functionality of the Expirer interface of
@@ -295,5 +579,8 @@ func (c *Credentials) ExpiresAt() (time.Time, error) {
 		// set expiration time to the distant past
 		return time.Time{}, nil
 	}
+	if c.retrieval != nil {
+		return c.retrievalExpiresAt, nil
+	}
 	return expirer.ExpiresAt(), nil
 }
@@ -0,0 +1,260 @@
+// +build go1.7
+
+package credentials
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+type contextProvider struct {
+	Expiry
+	retrieve func(ctx Context) (Value, error)
+}
+
+func (p *contextProvider) Retrieve() (Value, error) {
+	return p.RetrieveWithContext(backgroundContext())
+}
+
+func (p *contextProvider) RetrieveWithContext(ctx Context) (Value, error) {
+	return p.retrieve(ctx)
+}
+
+func TestCredentialsGetWithContext_Values(t *testing.T) {
+	type key struct{}
+
+	p := &contextProvider{}
+	p.retrieve = func(ctx Context) (Value, error) {
+		return Value{AccessKeyID: ctx.Value(key{}).(string), SecretAccessKey: "secret"}, nil
+	}
+	c := NewCredentials(p)
+
+	creds, err := c.GetWithContext(context.WithValue(context.Background(), key{}, "AKID"))
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "AKID", creds.AccessKeyID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestCredentialsGetWithContext_Canceled(t *testing.T) {
+	canceled := make(chan struct{})
+
+	p := &contextProvider{}
+	p.retrieve = func(ctx Context) (Value, error) {
+		<-ctx.Done()
+		if e, a := context.Canceled, ctx.Err(); e != a {
+			t.Errorf("expect %v, got %v", e, a)
+		}
+		close(canceled)
+		return Value{}, ctx.Err()
+	}
+	c := NewCredentials(p)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := c.GetWithContext(ctx)
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := "RequestCanceled", err.(awserr.Error).Code(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatalf("expect provider context to be canceled")
+	}
+}
+
+func TestCredentialsGetWithContext_SharedRetrieval(t *testing.T) {
+	var retrieves int32
+	release := make(chan struct{})
+
+	p := &contextProvider{}
+	p.retrieve = func(ctx Context) (Value, error) {
+		n := atomic.AddInt32(&retrieves, 1)
+		<-release
+		p.SetExpiration(time.Now().Add(time.Hour), 0)
+		return Value{AccessKeyID: "AKID" + strconv.Itoa(int(n)), SecretAccessKey: "secret"}, nil
+	}
+	c := NewCredentials(p)
+
+	var wg sync.WaitGroup
+	results := make([]Value, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			creds, err := c.Get()
+			if err != nil {
+				t.Errorf("expect no error, got %v", err)
+			}
+			results[i] = creds
+		}(i)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if e, a := int32(1), atomic.LoadInt32(&retrieves); e != a {
+		t.Errorf("expect %v retrieves, got %v", e, a)
+	}
+	for i, creds := range results {
+		if e, a := "AKID1", creds.AccessKeyID; e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+	}
+}
+
+func TestCredentialsGetWithContext_WaiterCanceled(t *testing.T) {
+	release := make(chan struct{})
+
+	p := &contextProvider{}
+	p.retrieve = func(ctx Context) (Value, error) {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			t.Errorf("expect provider context not to be canceled")
+		}
+		p.SetExpiration(time.Now().Add(time.Hour), 0)
+		return Value{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+	}
+	c := NewCredentials(p)
+
+	done := make(chan Value)
+	go func() {
+		creds, err := c.Get()
+		if err != nil {
+			t.Errorf("expect no error, got %v", err)
+		}
+		done <- creds
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetWithContext(ctx); err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	close(release)
+	if e, a := "AKID", (<-done).AccessKeyID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestCredentialsGetWithContext_RetryCanceled(t *testing.T) {
+	var retrieves int32
+
+	p := &contextProvider{}
+	p.retrieve = func(ctx Context) (Value, error) {
+		if atomic.AddInt32(&retrieves, 1) == 1 {
+			<-ctx.Done()
+			return Value{}, ctx.Err()
+		}
+		p.SetExpiration(time.Now().Add(time.Hour), 0)
+		return Value{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+	}
+	c := NewCredentials(p)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetWithContext(ctx); err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	creds, err := c.Get()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "AKID", creds.AccessKeyID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := int32(2), atomic.LoadInt32(&retrieves); e != a {
+		t.Errorf("expect %v retrieves, got %v", e, a)
+	}
+}
+
+func TestCredentialsBackgroundRefresh(t *testing.T) {
+	origMinRefreshDelay := minRefreshDelay
+	defer func() { minRefreshDelay = origMinRefreshDelay }()
+	minRefreshDelay = time.Millisecond
+
+	var retrieves int32
+	refreshing := make(chan struct{})
+	release := make(chan struct{})
+
+	p := &contextProvider{}
+	p.retrieve = func(ctx Context) (Value, error) {
+		n := atomic.AddInt32(&retrieves, 1)
+		if n == 2 {
+			close(refreshing)
+			<-release
+		}
+		p.SetExpiration(time.Now().Add(200*time.Millisecond), 0)
+		return Value{AccessKeyID: "AKID" + strconv.Itoa(int(n)), SecretAccessKey: "secret"}, nil
+	}
+	c := NewCredentials(p)
+	c.EnableBackgroundRefresh(150 * time.Millisecond)
+	defer c.DisableBackgroundRefresh()
+
+	creds, err := c.Get()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "AKID1", creds.AccessKeyID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	select {
+	case <-refreshing:
+	case <-time.After(time.Second):
+		t.Fatalf("expect credentials to be refreshed in the background")
+	}
+
+	// The cached credentials are returned while they are being refreshed.
+	creds, err = c.Get()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "AKID1", creds.AccessKeyID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	close(release)
+	for i := 0; i < 100; i++ {
+		if creds, _ = c.Get(); creds.AccessKeyID != "AKID1" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if e, a := "AKID2", creds.AccessKeyID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestCredentialsBackgroundRefresh_NotExpirer(t *testing.T) {
+	stub := &stubProvider{creds: Value{AccessKeyID: "AKID", SecretAccessKey: "secret"}}
+	c := NewCredentials(stub)
+	c.EnableBackgroundRefresh(time.Minute)
+
+	if _, err := c.Get(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	c.m.RLock()
+	defer c.m.RUnlock()
+	if c.refreshTimer != nil {
+		t.Errorf("expect no background refresh for a provider without expiration")
+	}
+}
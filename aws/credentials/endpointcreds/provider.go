@@ -27,10 +27,24 @@
 //        "code": "ErrorCode",
 //        "message": "Helpful error message."
 //    }
+//
+// The error document is returned as an awserr.RequestFailure with the
+// document's code and message, and the response's HTTP status code. The
+// RequestFailure is the original error of the "CredentialsEndpointError"
+// returned by Retrieve.
+//
+//    creds, err := provider.Retrieve()
+//    if aerr, ok := err.(awserr.Error); ok {
+//        if reqErr, ok := aerr.OrigErr().(awserr.RequestFailure); ok {
+//            fmt.Println(reqErr.StatusCode(), reqErr.Code(), reqErr.Message())
+//        }
+//    }
 package endpointcreds
 
 import (
 	"encoding/json"
+	"io/ioutil"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -70,6 +84,12 @@ type Provider struct {
 	// Optional authorization token value if set will be used as the value of
 	// the Authorization header of the endpoint credential request.
 	AuthorizationToken string
+
+	// Optional path to a file containing the authorization token. The file
+	// is read on every retrieval, so a token which is rotated in the file is
+	// used by the next retrieval. If set, the file's token is used instead of
+	// AuthorizationToken.
+	AuthorizationTokenFile string
 }
 
 // NewProviderClient returns a credentials Provider for retrieving AWS credentials
@@ -116,7 +136,20 @@ func (p *Provider) IsExpired() bool {
 // Retrieve will attempt to request the credentials from the endpoint the Provider
 // was configured for. And error will be returned if the retrieval fails.
 func (p *Provider) Retrieve() (credentials.Value, error) {
-	resp, err := p.getCredentials()
+	return p.RetrieveWithContext(aws.BackgroundContext())
+}
+
+// RetrieveWithContext will attempt to request the credentials from the endpoint
+// the Provider was configured for. And error will be returned if the retrieval
+// fails.
+func (p *Provider) RetrieveWithContext(ctx credentials.Context) (credentials.Value, error) {
+	authToken, err := p.authorizationToken()
+	if err != nil {
+		return credentials.Value{ProviderName: ProviderName},
+			awserr.New("CredentialsEndpointError", "failed to load authorization token", err)
+	}
+
+	resp, err := p.getCredentials(ctx, authToken)
 	if err != nil {
 		return credentials.Value{ProviderName: ProviderName},
 			awserr.New("CredentialsEndpointError", "failed to load credentials", err)
@@ -148,7 +181,30 @@ type errorOutput struct {
 	Message string `json:"message"`
 }
 
-func (p *Provider) getCredentials() (*getCredentialsOutput, error) {
+// authorizationToken returns the token the credentials are requested with,
+// reading the token file if one is set.
+func (p *Provider) authorizationToken() (string, error) {
+	if len(p.AuthorizationTokenFile) == 0 {
+		return p.AuthorizationToken, nil
+	}
+
+	b, err := ioutil.ReadFile(p.AuthorizationTokenFile)
+	if err != nil {
+		return "", err
+	}
+
+	// The token may be written with a trailing newline, but must not
+	// otherwise contain one, as it is sent as a header value.
+	token := strings.TrimSpace(string(b))
+	if strings.ContainsAny(token, "\r\n") {
+		return "", awserr.New("InvalidAuthorizationToken",
+			"authorization token file contains an invalid newline sequence", nil)
+	}
+
+	return token, nil
+}
+
+func (p *Provider) getCredentials(ctx aws.Context, authToken string) (*getCredentialsOutput, error) {
 	op := &request.Operation{
 		Name:       "GetCredentials",
 		HTTPMethod: "GET",
@@ -156,8 +212,9 @@ func (p *Provider) getCredentials() (*getCredentialsOutput, error) {
 
 	out := &getCredentialsOutput{}
 	req := p.Client.NewRequest(op, nil, out)
+	req.SetContext(ctx)
 	req.HTTPRequest.Header.Set("Accept", "application/json")
-	if authToken := p.AuthorizationToken; len(authToken) != 0 {
+	if len(authToken) != 0 {
 		req.HTTPRequest.Header.Set("Authorization", authToken)
 	}
 
@@ -199,5 +256,9 @@ func unmarshalError(r *request.Request) {
 
 	// Response body format is not consistent between metadata endpoints.
 	// Grab the error message as a string and include that as the source error
-	r.Error = awserr.New(errOut.Code, errOut.Message, nil)
+	r.Error = awserr.NewRequestFailure(
+		awserr.New(errOut.Code, errOut.Message, nil),
+		r.HTTPResponse.StatusCode,
+		r.RequestID,
+	)
 }
@@ -3,8 +3,11 @@ package endpointcreds_test
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -133,11 +136,14 @@ func TestFailedRetrieveCredentials(t *testing.T) {
 		t.Errorf("expect %v, got %v", e, a)
 	}
 
-	aerr = aerr.OrigErr().(awserr.Error)
-	if e, a := "Error", aerr.Code(); e != a {
+	reqErr := aerr.OrigErr().(awserr.RequestFailure)
+	if e, a := "Error", reqErr.Code(); e != a {
 		t.Errorf("expect %v, got %v", e, a)
 	}
-	if e, a := "Message", aerr.Message(); e != a {
+	if e, a := "Message", reqErr.Message(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := 400, reqErr.StatusCode(); e != a {
 		t.Errorf("expect %v, got %v", e, a)
 	}
 
@@ -216,3 +222,97 @@ func TestAuthorizationToken(t *testing.T) {
 		t.Errorf("expect expired, wasn't")
 	}
 }
+
+func TestAuthorizationTokenFile(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "aws-sdk-go-endpointcreds")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+
+	var expectAuthToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e, a := expectAuthToken, r.Header.Get("Authorization"); e != a {
+			t.Errorf("expect %v, got %v", e, a)
+		}
+
+		encoder := json.NewEncoder(w)
+		err := encoder.Encode(map[string]interface{}{
+			"AccessKeyID":     "AKID",
+			"SecretAccessKey": "SECRET",
+			"Token":           "TOKEN",
+			"Expiration":      time.Now().Add(1 * time.Hour),
+		})
+
+		if err != nil {
+			fmt.Println("failed to write out creds", err)
+		}
+	}))
+	defer server.Close()
+
+	client := endpointcreds.NewProviderClient(*unit.Session.Config,
+		unit.Session.Handlers,
+		server.URL,
+		func(p *endpointcreds.Provider) {
+			p.AuthorizationToken = "Basic ignored"
+			p.AuthorizationTokenFile = tokenFile
+		},
+	)
+
+	// The token file is read on every retrieval, so rotated tokens are used.
+	for _, token := range []string{"Basic abc123", "Basic def456"} {
+		if err := ioutil.WriteFile(tokenFile, []byte(token+"\n"), 0600); err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		expectAuthToken = token
+
+		if _, err := client.Retrieve(); err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+	}
+}
+
+func TestAuthorizationTokenFile_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "aws-sdk-go-endpointcreds")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	invalidFile := filepath.Join(dir, "invalid")
+	if err := ioutil.WriteFile(invalidFile, []byte("Basic abc\r\nX-Injected: 123"), 0600); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	cases := map[string]string{
+		"missing": filepath.Join(dir, "missing"),
+		"newline": invalidFile,
+	}
+
+	for name, tokenFile := range cases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("%s, expect no request to be made", name)
+		}))
+
+		client := endpointcreds.NewProviderClient(*unit.Session.Config,
+			unit.Session.Handlers,
+			server.URL,
+			func(p *endpointcreds.Provider) {
+				p.AuthorizationTokenFile = tokenFile
+			},
+		)
+
+		creds, err := client.Retrieve()
+		server.Close()
+		if err == nil {
+			t.Fatalf("%s, expect error, got none", name)
+		}
+		if e, a := "CredentialsEndpointError", err.(awserr.Error).Code(); e != a {
+			t.Errorf("%s, expect %v, got %v", name, e, a)
+		}
+		if e, a := endpointcreds.ProviderName, creds.ProviderName; e != a {
+			t.Errorf("%s, expect %v, got %v", name, e, a)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package ssocreds
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/internal/shareddefaults"
+	"github.com/aws/aws-sdk-go/service/sso"
+	"github.com/aws/aws-sdk-go/service/sso/ssoiface"
+)
+
+const (
+	// ErrCodeSSOProviderInvalidToken is the code type that is returned if
+	// loaded token has expired or is otherwise invalid. To refresh when the
+	// AWS SSO token has expired, run "aws sso login" with the AWS CLI.
+	ErrCodeSSOProviderInvalidToken = "SSOProviderInvalidToken"
+
+	invalidTokenMessage = "the SSO session has expired or is invalid"
+
+	// ProviderName is the name of the provider used to specify the source
+	// of credentials.
+	ProviderName = "SSOProvider"
+)
+
+// nowTime is used to return the current time. This can be used to easily
+// test and compare test values.
+var nowTime = time.Now
+
+// Provider is an AWS credential provider that retrieves temporary AWS
+// credentials by exchanging an SSO login token.
+type Provider struct {
+	credentials.Expiry
+
+	// The Client which is configured for the AWS Region where the AWS SSO
+	// user portal is located.
+	Client ssoiface.SSOAPI
+
+	// The AWS account that is assigned to the user.
+	AccountID string
+
+	// The role name that is assigned to the user.
+	RoleName string
+
+	// The URL that points to the organization's AWS Single Sign-On (AWS SSO)
+	// user portal.
+	StartURL string
+
+	// The path to the cached SSO access token. Defaults to the token file in
+	// ~/.aws/sso/cache for the StartURL, see StandardCachedTokenFilepath.
+	CachedTokenFilepath string
+
+	// ExpiryWindow will allow the credentials to trigger refreshing prior to
+	// the credentials actually expiring. This is beneficial so race conditions
+	// with expiring credentials do not cause request to fail unexpectedly
+	// due to ExpiredTokenException exceptions.
+	ExpiryWindow time.Duration
+}
+
+// NewCredentials returns a new AWS Single Sign-On (AWS SSO) credential
+// provider. The ConfigProvider is expected to be configured for the AWS
+// Region the AWS SSO user portal is located in.
+func NewCredentials(c client.ConfigProvider, accountID, roleName, startURL string, optFns ...func(provider *Provider)) *credentials.Credentials {
+	return NewCredentialsWithClient(sso.New(c), accountID, roleName, startURL, optFns...)
+}
+
+// NewCredentialsWithClient returns a new AWS Single Sign-On (AWS SSO)
+// credential provider. The provided client is expected to be configured for
+// the AWS Region the AWS SSO user portal is located in.
+func NewCredentialsWithClient(client ssoiface.SSOAPI, accountID, roleName, startURL string, optFns ...func(provider *Provider)) *credentials.Credentials {
+	p := &Provider{
+		Client:    client,
+		AccountID: accountID,
+		RoleName:  roleName,
+		StartURL:  startURL,
+	}
+
+	for _, fn := range optFns {
+		fn(p)
+	}
+
+	return credentials.NewCredentials(p)
+}
+
+// Retrieve retrieves temporary AWS credentials from the configured Amazon
+// Single Sign-On (AWS SSO) user portal by exchanging the accessToken present
+// in ~/.aws/sso/cache.
+func (p *Provider) Retrieve() (credentials.Value, error) {
+	return p.RetrieveWithContext(aws.BackgroundContext())
+}
+
+// RetrieveWithContext retrieves temporary AWS credentials from the configured
+// Amazon Single Sign-On (AWS SSO) user portal by exchanging the accessToken
+// present in ~/.aws/sso/cache.
+func (p *Provider) RetrieveWithContext(ctx credentials.Context) (credentials.Value, error) {
+	filename := p.CachedTokenFilepath
+	if len(filename) == 0 {
+		var err error
+		if filename, err = StandardCachedTokenFilepath(p.StartURL); err != nil {
+			return credentials.Value{}, err
+		}
+	}
+
+	tokenFile, err := loadTokenFile(filename)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	output, err := p.Client.GetRoleCredentialsWithContext(ctx, &sso.GetRoleCredentialsInput{
+		AccessToken: &tokenFile.AccessToken,
+		AccountId:   &p.AccountID,
+		RoleName:    &p.RoleName,
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == sso.ErrCodeUnauthorizedException {
+			return credentials.Value{}, awserr.New(ErrCodeSSOProviderInvalidToken, invalidTokenMessage, err)
+		}
+		return credentials.Value{}, err
+	}
+
+	expireTime := time.Unix(0, aws.Int64Value(output.RoleCredentials.Expiration)*int64(time.Millisecond)).UTC()
+	p.SetExpiration(expireTime, p.ExpiryWindow)
+
+	return credentials.Value{
+		AccessKeyID:     aws.StringValue(output.RoleCredentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(output.RoleCredentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(output.RoleCredentials.SessionToken),
+		ProviderName:    ProviderName,
+	}, nil
+}
+
+// StandardCachedTokenFilepath returns the filepath for the cached SSO token
+// file, or error if unable get derive the path. Key that will be used to
+// compute a SHA1 value that is hex encoded.
+//
+// Derives the filepath using the Key as:
+//
+//     ~/.aws/sso/cache/<sha1-hex-encoded-key>.json
+func StandardCachedTokenFilepath(key string) (string, error) {
+	homeDir := shareddefaults.UserHomeDir()
+	if len(homeDir) == 0 {
+		return "", fmt.Errorf("unable to get USER's home directory for cached token")
+	}
+	hash := sha1.New()
+	if _, err := hash.Write([]byte(key)); err != nil {
+		return "", err
+	}
+
+	cacheFilename := strings.ToLower(hex.EncodeToString(hash.Sum(nil))) + ".json"
+
+	return filepath.Join(homeDir, ".aws", "sso", "cache", cacheFilename), nil
+}
+
+type rfc3339 time.Time
+
+func (r *rfc3339) UnmarshalJSON(bytes []byte) error {
+	var value string
+
+	if err := json.Unmarshal(bytes, &value); err != nil {
+		return err
+	}
+
+	// The AWS CLI has written the expiration in both RFC 3339, and with a
+	// literal UTC suffix.
+	parse, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if parse, err = time.Parse("2006-01-02T15:04:05UTC", value); err != nil {
+			return fmt.Errorf("expected RFC3339 timestamp: %v", err)
+		}
+	}
+
+	*r = rfc3339(parse)
+
+	return nil
+}
+
+type token struct {
+	AccessToken string  `json:"accessToken"`
+	ExpiresAt   rfc3339 `json:"expiresAt"`
+	Region      string  `json:"region,omitempty"`
+	StartURL    string  `json:"startUrl,omitempty"`
+}
+
+func (t token) Expired() bool {
+	return nowTime().After(time.Time(t.ExpiresAt))
+}
+
+func loadTokenFile(filename string) (t token, err error) {
+	fileBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return token{}, awserr.New(ErrCodeSSOProviderInvalidToken, invalidTokenMessage, err)
+	}
+
+	if err := json.Unmarshal(fileBytes, &t); err != nil {
+		return token{}, awserr.New(ErrCodeSSOProviderInvalidToken, invalidTokenMessage, err)
+	}
+
+	if len(t.AccessToken) == 0 {
+		return token{}, awserr.New(ErrCodeSSOProviderInvalidToken, "cached SSO token must contain accessToken and expiresAt fields", nil)
+	}
+
+	if t.Expired() {
+		return token{}, awserr.New(ErrCodeSSOProviderInvalidToken, invalidTokenMessage, nil)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,227 @@
+// +build go1.7
+
+package ssocreds
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/internal/sdktesting"
+	"github.com/aws/aws-sdk-go/service/sso"
+	"github.com/aws/aws-sdk-go/service/sso/ssoiface"
+)
+
+type mockClient struct {
+	ssoiface.SSOAPI
+
+	t *testing.T
+
+	Output *sso.GetRoleCredentialsOutput
+	Err    error
+
+	ExpectedAccountID   string
+	ExpectedAccessToken string
+	ExpectedRoleName    string
+}
+
+func (m mockClient) GetRoleCredentialsWithContext(ctx aws.Context, params *sso.GetRoleCredentialsInput, _ ...request.Option) (*sso.GetRoleCredentialsOutput, error) {
+	if len(m.ExpectedAccountID) > 0 {
+		if e, a := m.ExpectedAccountID, aws.StringValue(params.AccountId); e != a {
+			m.t.Errorf("expect %v, got %v", e, a)
+		}
+	}
+
+	if len(m.ExpectedAccessToken) > 0 {
+		if e, a := m.ExpectedAccessToken, aws.StringValue(params.AccessToken); e != a {
+			m.t.Errorf("expect %v, got %v", e, a)
+		}
+	}
+
+	if len(m.ExpectedRoleName) > 0 {
+		if e, a := m.ExpectedRoleName, aws.StringValue(params.RoleName); e != a {
+			m.t.Errorf("expect %v, got %v", e, a)
+		}
+	}
+
+	if m.Err != nil {
+		return nil, m.Err
+	}
+
+	return m.Output, nil
+}
+
+func swapNowTime(referenceTime time.Time) func() {
+	oldNowTime := nowTime
+	nowTime = func() time.Time {
+		return referenceTime
+	}
+	return func() {
+		nowTime = oldNowTime
+	}
+}
+
+func TestStandardCachedTokenFilepath(t *testing.T) {
+	restoreEnv := sdktesting.StashEnv()
+	defer restoreEnv()
+	if runtime.GOOS == "windows" {
+		os.Setenv("USERPROFILE", "/home/user")
+	} else {
+		os.Setenv("HOME", "/home/user")
+	}
+
+	filename, err := StandardCachedTokenFilepath("https://my-sso-portal.awsapps.com/start")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expect := filepath.Join("/home/user", ".aws", "sso", "cache",
+		"c7aaaf71fcc8777ae2475525ed049d39fe16c484.json")
+	if e, a := expect, filename; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	restoreTime := swapNowTime(time.Date(2021, 01, 19, 19, 50, 0, 0, time.UTC))
+	defer restoreTime()
+
+	cases := map[string]struct {
+		Client              mockClient
+		AccountID           string
+		RoleName            string
+		StartURL            string
+		TokenFile           string
+		ExpectedErr         string
+		ExpectedCredentials credentials.Value
+		ExpectedExpire      time.Time
+	}{
+		"missing required parameter values": {
+			StartURL:    "https://invalid-required",
+			TokenFile:   "testdata/missing_token.json",
+			ExpectedErr: ErrCodeSSOProviderInvalidToken,
+		},
+		"valid required parameter values": {
+			Client: mockClient{
+				ExpectedAccountID:   "012345678901",
+				ExpectedRoleName:    "TestRole",
+				ExpectedAccessToken: "dGhpcyBpcyBub3QgYSByZWFsIHZhbHVl",
+				Output: &sso.GetRoleCredentialsOutput{
+					RoleCredentials: &sso.RoleCredentials{
+						AccessKeyId:     aws.String("AccessKey"),
+						Expiration:      aws.Int64(1611177743123),
+						SecretAccessKey: aws.String("SecretKey"),
+						SessionToken:    aws.String("SessionToken"),
+					},
+				},
+			},
+			AccountID: "012345678901",
+			RoleName:  "TestRole",
+			StartURL:  "https://valid-required-only",
+			TokenFile: "testdata/valid_token.json",
+			ExpectedCredentials: credentials.Value{
+				AccessKeyID:     "AccessKey",
+				SecretAccessKey: "SecretKey",
+				SessionToken:    "SessionToken",
+				ProviderName:    ProviderName,
+			},
+			ExpectedExpire: time.Date(2021, 01, 20, 21, 22, 23, 0.123e9, time.UTC),
+		},
+		"expiration with UTC suffix": {
+			Client: mockClient{
+				Output: &sso.GetRoleCredentialsOutput{
+					RoleCredentials: &sso.RoleCredentials{
+						AccessKeyId:     aws.String("AccessKey"),
+						Expiration:      aws.Int64(1611177743123),
+						SecretAccessKey: aws.String("SecretKey"),
+						SessionToken:    aws.String("SessionToken"),
+					},
+				},
+			},
+			TokenFile: "testdata/valid_token_utc_suffix.json",
+			ExpectedCredentials: credentials.Value{
+				AccessKeyID:     "AccessKey",
+				SecretAccessKey: "SecretKey",
+				SessionToken:    "SessionToken",
+				ProviderName:    ProviderName,
+			},
+			ExpectedExpire: time.Date(2021, 01, 20, 21, 22, 23, 0.123e9, time.UTC),
+		},
+		"expired access token": {
+			StartURL:    "https://expired",
+			TokenFile:   "testdata/expired_token.json",
+			ExpectedErr: "the SSO session has expired or is invalid",
+		},
+		"invalid token file": {
+			TokenFile:   "testdata/invalid_json.json",
+			ExpectedErr: ErrCodeSSOProviderInvalidToken,
+		},
+		"missing access token": {
+			TokenFile:   "testdata/missing_access_token.json",
+			ExpectedErr: "must contain accessToken and expiresAt fields",
+		},
+		"unauthorized access token": {
+			Client: mockClient{
+				Err: awserr.New(sso.ErrCodeUnauthorizedException, "unauthorized", nil),
+			},
+			TokenFile:   "testdata/valid_token.json",
+			ExpectedErr: ErrCodeSSOProviderInvalidToken,
+		},
+		"api error": {
+			Client: mockClient{
+				Err: fmt.Errorf("api error"),
+			},
+			TokenFile:   "testdata/valid_token.json",
+			ExpectedErr: "api error",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			tt.Client.t = t
+
+			provider := &Provider{
+				Client:              tt.Client,
+				AccountID:           tt.AccountID,
+				RoleName:            tt.RoleName,
+				StartURL:            tt.StartURL,
+				CachedTokenFilepath: tt.TokenFile,
+			}
+
+			provider.Expiry.CurrentTime = nowTime
+
+			credentials, err := provider.Retrieve()
+			if len(tt.ExpectedErr) != 0 {
+				if err == nil {
+					t.Fatalf("expect %v error, got none", tt.ExpectedErr)
+				}
+				if e, a := tt.ExpectedErr, err.Error(); !strings.Contains(a, e) {
+					t.Fatalf("expect %v error, got %v", e, a)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			if e, a := tt.ExpectedCredentials, credentials; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+
+			if !tt.ExpectedExpire.IsZero() {
+				if e, a := tt.ExpectedExpire, provider.ExpiresAt(); !e.Equal(a) {
+					t.Errorf("expect %v, got %v", e, a)
+				}
+			}
+		})
+	}
+}
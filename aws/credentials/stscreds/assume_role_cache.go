@@ -0,0 +1,272 @@
+package stscreds
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/internal/shareddefaults"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// DefaultCacheDir returns the directory the AWS CLI caches assumed role
+// credentials in, ~/.aws/cli/cache. Setting the AssumeRoleProvider's CacheDir
+// to this directory shares the assumed role's credentials with the AWS CLI.
+func DefaultCacheDir() string {
+	return filepath.Join(shareddefaults.UserHomeDir(), ".aws", "cli", "cache")
+}
+
+// cachedCredentials is the format of the AWS CLI's cached AssumeRole
+// responses.
+type cachedCredentials struct {
+	Credentials struct {
+		AccessKeyId     string
+		SecretAccessKey string
+		SessionToken    string
+		Expiration      cacheTime
+	}
+	AssumedRoleUser *struct {
+		AssumedRoleId string
+		Arn           string
+	} `json:",omitempty"`
+}
+
+// cacheTime is the expiration of cached credentials. The AWS CLI has written
+// the expiration both in RFC 3339, and with a literal UTC suffix.
+type cacheTime time.Time
+
+func (t cacheTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).UTC().Format(time.RFC3339))
+}
+
+func (t *cacheTime) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	parsed, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		if parsed, err = time.Parse("2006-01-02T15:04:05UTC", v); err != nil {
+			return err
+		}
+	}
+	*t = cacheTime(parsed)
+	return nil
+}
+
+// cacheKey returns the key the assumed role's credentials are cached by, the
+// SHA1 hash of the AssumeRole parameters which identify the credentials. The
+// key is computed the same way as the AWS CLI's, so the processes share the
+// cached credentials.
+func (p *AssumeRoleProvider) cacheKey() string {
+	args := map[string]interface{}{
+		"RoleArn": p.RoleARN,
+	}
+	if !p.sessionNameGenerated {
+		args["RoleSessionName"] = p.RoleSessionName
+	}
+	if p.SerialNumber != nil {
+		args["SerialNumber"] = *p.SerialNumber
+	}
+	if p.Duration != 0 {
+		args["DurationSeconds"] = json.Number(fmt.Sprintf("%d", int64(p.Duration/time.Second)))
+	}
+	if p.ExternalID != nil {
+		args["ExternalId"] = *p.ExternalID
+	}
+	if p.Policy != nil {
+		// The policy's keys are sorted, so equivalent policies have the
+		// same key.
+		var policy interface{}
+		d := json.NewDecoder(strings.NewReader(*p.Policy))
+		d.UseNumber()
+		if err := d.Decode(&policy); err == nil {
+			args["Policy"] = policy
+		} else {
+			args["Policy"] = *p.Policy
+		}
+	}
+
+	var buf bytes.Buffer
+	writeCacheKeyJSON(&buf, args)
+
+	hash := sha1.Sum(buf.Bytes())
+	return hex.EncodeToString(hash[:])
+}
+
+// writeCacheKeyJSON writes the value as JSON the way Python's json.dumps does
+// with sorted keys, which the AWS CLI hashes for the cache key.
+func writeCacheKeyJSON(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeCacheKeyJSON(buf, k)
+			buf.WriteString(": ")
+			writeCacheKeyJSON(buf, v[k])
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeCacheKeyJSON(buf, e)
+		}
+		buf.WriteByte(']')
+	case string:
+		writeCacheKeyString(buf, v)
+	case json.Number:
+		buf.WriteString(string(v))
+	case bool:
+		if v {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case nil:
+		buf.WriteString("null")
+	}
+}
+
+// writeCacheKeyString writes the string as JSON, escaping non-ASCII
+// characters as Python's json.dumps does.
+func writeCacheKeyString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			buf.WriteString(`\"`)
+		case r == '\\':
+			buf.WriteString(`\\`)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r == '\b':
+			buf.WriteString(`\b`)
+		case r == '\f':
+			buf.WriteString(`\f`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(buf, `\u%04x`, r)
+		case r > 0x7f && r <= 0xffff:
+			fmt.Fprintf(buf, `\u%04x`, r)
+		case r > 0xffff:
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(buf, `\u%04x\u%04x`, r1, r2)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// loadCachedCredentials returns the credentials cached in the file, if they
+// are not expired. Credentials which cannot be read are ignored, and the role
+// is assumed instead.
+func (p *AssumeRoleProvider) loadCachedCredentials(filename string) (credentials.Value, bool) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return credentials.Value{}, false
+	}
+
+	var cached cachedCredentials
+	if err := json.Unmarshal(b, &cached); err != nil {
+		return credentials.Value{}, false
+	}
+
+	creds := credentials.Value{
+		AccessKeyID:     cached.Credentials.AccessKeyId,
+		SecretAccessKey: cached.Credentials.SecretAccessKey,
+		SessionToken:    cached.Credentials.SessionToken,
+		ProviderName:    ProviderName,
+	}
+	if !creds.HasKeys() {
+		return credentials.Value{}, false
+	}
+
+	expiration := time.Time(cached.Credentials.Expiration)
+	window := p.ExpiryWindow
+	if window < 0 {
+		window = 0
+	}
+	if !expiration.Add(-window).After(now()) {
+		return credentials.Value{}, false
+	}
+
+	p.SetExpiration(expiration, p.ExpiryWindow)
+	return creds, true
+}
+
+// storeCachedCredentials writes the AssumeRole response to the cache file.
+// The file is only readable by the user, and is replaced atomically so other
+// processes never read partially written credentials.
+func storeCachedCredentials(filename string, output *sts.AssumeRoleOutput) error {
+	var cached cachedCredentials
+	cached.Credentials.AccessKeyId = aws.StringValue(output.Credentials.AccessKeyId)
+	cached.Credentials.SecretAccessKey = aws.StringValue(output.Credentials.SecretAccessKey)
+	cached.Credentials.SessionToken = aws.StringValue(output.Credentials.SessionToken)
+	cached.Credentials.Expiration = cacheTime(aws.TimeValue(output.Credentials.Expiration))
+	if u := output.AssumedRoleUser; u != nil {
+		cached.AssumedRoleUser = &struct {
+			AssumedRoleId string
+			Arn           string
+		}{
+			AssumedRoleId: aws.StringValue(u.AssumedRoleId),
+			Arn:           aws.StringValue(u.Arn),
+		}
+	}
+
+	b, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// TempFile creates the file only readable and writable by the user.
+	f, err := ioutil.TempFile(dir, filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(b); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
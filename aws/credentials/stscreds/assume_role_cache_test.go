@@ -0,0 +1,173 @@
+package stscreds
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+func TestAssumeRoleProvider_CacheKey(t *testing.T) {
+	cases := []struct {
+		provider *AssumeRoleProvider
+		expect   string
+	}{
+		{
+			provider: &AssumeRoleProvider{
+				RoleARN:         "arn:aws:iam::123456789012:role/foo",
+				RoleSessionName: "sess",
+				SerialNumber:    aws.String("arn:aws:iam::123456789012:mfa/user"),
+				Duration:        time.Hour,
+			},
+			expect: "9ba58a0f73d88c1d27d212cb09949212bbca82a9",
+		},
+		{
+			provider: &AssumeRoleProvider{
+				RoleARN:              "arn:aws:iam::123456789012:role/foo",
+				RoleSessionName:      "1571346000000000000",
+				ExternalID:           aws.String("ext-é"),
+				Policy:               aws.String(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:*","Resource":"*","N":1}]}`),
+				sessionNameGenerated: true,
+			},
+			expect: "11d5e0183f41291663c2466ea82a5cedf3db5953",
+		},
+	}
+
+	for i, c := range cases {
+		if e, a := c.expect, c.provider.cacheKey(); e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+	}
+}
+
+func TestAssumeRoleProvider_Cache(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "aws-sdk-go-stscreds-cache")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var assumed, prompted int
+	newProvider := func() *AssumeRoleProvider {
+		return &AssumeRoleProvider{
+			Client: &stubSTS{TestInput: func(*sts.AssumeRoleInput) {
+				assumed++
+			}},
+			RoleARN:         "roleARN",
+			RoleSessionName: "sessionName",
+			Duration:        time.Hour,
+			SerialNumber:    aws.String("0123456789"),
+			TokenProvider: func() (string, error) {
+				prompted++
+				return "tokencode", nil
+			},
+			CacheDir: filepath.Join(dir, "cache"),
+		}
+	}
+
+	p := newProvider()
+	creds, err := p.Retrieve()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "roleARN", creds.AccessKeyID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	filename := filepath.Join(dir, "cache", p.cacheKey()+".json")
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("expect credentials to be cached, got %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		if e, a := os.FileMode(0600), info.Mode().Perm(); e != a {
+			t.Errorf("expect %v file mode, got %v", e, a)
+		}
+	}
+
+	// A new provider, as in another process, uses the cached credentials
+	// without assuming the role or prompting for a MFA token code.
+	p = newProvider()
+	creds, err = p.Retrieve()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "roleARN", creds.AccessKeyID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "assumedSessionToken", creds.SessionToken; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := 1, assumed; e != a {
+		t.Errorf("expect %v AssumeRole calls, got %v", e, a)
+	}
+	if e, a := 1, prompted; e != a {
+		t.Errorf("expect %v token prompts, got %v", e, a)
+	}
+	if p.IsExpired() {
+		t.Errorf("expect cached credentials not to be expired")
+	}
+}
+
+func TestAssumeRoleProvider_CacheExpired(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "aws-sdk-go-stscreds-cache")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var assumed int
+	p := &AssumeRoleProvider{
+		Client: &stubSTS{TestInput: func(*sts.AssumeRoleInput) {
+			assumed++
+		}},
+		RoleARN:         "roleARN",
+		RoleSessionName: "sessionName",
+		ExpiryWindow:    5 * time.Minute,
+		CacheDir:        dir,
+	}
+
+	// Cached in the AWS CLI's format, expiring within the expiry window.
+	cached := map[string]interface{}{
+		"Credentials": map[string]interface{}{
+			"AccessKeyId":     "cachedAKID",
+			"SecretAccessKey": "cachedSecret",
+			"SessionToken":    "cachedToken",
+			"Expiration":      time.Now().UTC().Add(time.Minute).Format("2006-01-02T15:04:05UTC"),
+		},
+	}
+	b, _ := json.Marshal(cached)
+	filename := filepath.Join(dir, p.cacheKey()+".json")
+	if err := ioutil.WriteFile(filename, b, 0600); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	creds, err := p.Retrieve()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "roleARN", creds.AccessKeyID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := 1, assumed; e != a {
+		t.Errorf("expect %v AssumeRole calls, got %v", e, a)
+	}
+
+	var updated cachedCredentials
+	b, err = ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if err := json.Unmarshal(b, &updated); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "roleARN", updated.Credentials.AccessKeyId; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
@@ -75,18 +75,38 @@ single Credentials with an AssumeRoleProvider can be shared safely.
 	// from assumed role.
 	svc := s3.New(sess, &aws.Config{Credentials: creds})
 
+Caching Assumed Role Credentials
+
+The AssumeRoleProvider caches the assumed role's credentials in memory, so
+every process assumes the role, and prompts for a MFA token code, again. With
+CacheDir set the credentials are also cached in files in the directory, and
+shared by the processes assuming the same role until they expire.
+
+The cache is compatible with the AWS CLI's. The credentials are cached by the
+role ARN, session name, MFA serial number, duration, external ID and policy
+of the AssumeRole call, in files only readable by the user which are replaced
+atomically.
+
+	creds := stscreds.NewCredentials(sess, "myRoleArn", func(p *stscreds.AssumeRoleProvider) {
+		p.SerialNumber = aws.String("myTokenSerialNumber")
+		p.TokenProvider = stscreds.StdinTokenProvider
+		p.CacheDir = stscreds.DefaultCacheDir()
+	})
+
 */
 package stscreds
 
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/internal/sdkrand"
 	"github.com/aws/aws-sdk-go/service/sts"
 )
@@ -118,6 +138,10 @@ type AssumeRoler interface {
 	AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error)
 }
 
+type assumeRolerWithContext interface {
+	AssumeRoleWithContext(aws.Context, *sts.AssumeRoleInput, ...request.Option) (*sts.AssumeRoleOutput, error)
+}
+
 // DefaultDuration is the default amount of time in minutes that the credentials
 // will be valid for.
 var DefaultDuration = time.Duration(15) * time.Minute
@@ -207,6 +231,19 @@ type AssumeRoleProvider struct {
 	//
 	// MaxJitterFrac should not be negative.
 	MaxJitterFrac float64
+
+	// CacheDir is the directory to cache the assumed role's credentials in,
+	// so that processes assuming the same role share the credentials until
+	// they expire, instead of each assuming the role, and prompting for a
+	// MFA token code. The credentials are only cached in memory if CacheDir
+	// is empty.
+	//
+	// The cache is compatible with the AWS CLI's, see DefaultCacheDir.
+	CacheDir string
+
+	// Set if the RoleSessionName was generated by the provider, and is not
+	// part of the cache key.
+	sessionNameGenerated bool
 }
 
 // NewCredentials returns a pointer to a new Credentials object wrapping the
@@ -258,18 +295,36 @@ func NewCredentialsWithClient(svc AssumeRoler, roleARN string, options ...func(*
 
 // Retrieve generates a new set of temporary credentials using STS.
 func (p *AssumeRoleProvider) Retrieve() (credentials.Value, error) {
+	return p.RetrieveWithContext(aws.BackgroundContext())
+}
+
+// RetrieveWithContext generates a new set of temporary credentials using STS.
+// The Context is used for the AssumeRole request if the Client supports the
+// AssumeRoleWithContext method, as the STS client does.
+func (p *AssumeRoleProvider) RetrieveWithContext(ctx credentials.Context) (credentials.Value, error) {
 	// Apply defaults where parameters are not set.
 	if p.RoleSessionName == "" {
 		// Try to work out a role name that will hopefully end up unique.
 		p.RoleSessionName = fmt.Sprintf("%d", time.Now().UTC().UnixNano())
+		p.sessionNameGenerated = true
 	}
-	if p.Duration == 0 {
+
+	var cacheFile string
+	if len(p.CacheDir) != 0 {
+		cacheFile = filepath.Join(p.CacheDir, p.cacheKey()+".json")
+		if creds, ok := p.loadCachedCredentials(cacheFile); ok {
+			return creds, nil
+		}
+	}
+
+	duration := p.Duration
+	if duration == 0 {
 		// Expire as often as AWS permits.
-		p.Duration = DefaultDuration
+		duration = DefaultDuration
 	}
-	jitter := time.Duration(sdkrand.SeededRand.Float64() * p.MaxJitterFrac * float64(p.Duration))
+	jitter := time.Duration(sdkrand.SeededRand.Float64() * p.MaxJitterFrac * float64(duration))
 	input := &sts.AssumeRoleInput{
-		DurationSeconds: aws.Int64(int64((p.Duration - jitter) / time.Second)),
+		DurationSeconds: aws.Int64(int64((duration - jitter) / time.Second)),
 		RoleArn:         aws.String(p.RoleARN),
 		RoleSessionName: aws.String(p.RoleSessionName),
 		ExternalId:      p.ExternalID,
@@ -295,7 +350,13 @@ func (p *AssumeRoleProvider) Retrieve() (credentials.Value, error) {
 		}
 	}
 
-	roleOutput, err := p.Client.AssumeRole(input)
+	var roleOutput *sts.AssumeRoleOutput
+	var err error
+	if c, ok := p.Client.(assumeRolerWithContext); ok {
+		roleOutput, err = c.AssumeRoleWithContext(ctx, input)
+	} else {
+		roleOutput, err = p.Client.AssumeRole(input)
+	}
 	if err != nil {
 		return credentials.Value{ProviderName: ProviderName}, err
 	}
@@ -303,6 +364,12 @@ func (p *AssumeRoleProvider) Retrieve() (credentials.Value, error) {
 	// We will proactively generate new credentials before they expire.
 	p.SetExpiration(*roleOutput.Credentials.Expiration, p.ExpiryWindow)
 
+	if len(cacheFile) != 0 {
+		// The credentials are cached for other processes on a best effort
+		// basis, failing to cache them does not fail the retrieval.
+		storeCachedCredentials(cacheFile, roleOutput)
+	}
+
 	return credentials.Value{
 		AccessKeyID:     *roleOutput.Credentials.AccessKeyId,
 		SecretAccessKey: *roleOutput.Credentials.SecretAccessKey,
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting"
 	"github.com/aws/aws-sdk-go/service/sts"
 )
 
@@ -52,6 +54,42 @@ func TestAssumeRoleProvider(t *testing.T) {
 	}
 }
 
+type stubSTSWithContext struct {
+	stubSTS
+	called bool
+}
+
+func (s *stubSTSWithContext) AssumeRoleWithContext(ctx aws.Context, input *sts.AssumeRoleInput, opts ...request.Option) (*sts.AssumeRoleOutput, error) {
+	s.called = true
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.stubSTS.AssumeRole(input)
+}
+
+func TestAssumeRoleProvider_RetrieveWithContext(t *testing.T) {
+	stub := &stubSTSWithContext{}
+	p := &AssumeRoleProvider{
+		Client:  stub,
+		RoleARN: "roleARN",
+	}
+
+	ctx := &awstesting.FakeContext{
+		Error:  fmt.Errorf("context canceled"),
+		DoneCh: make(chan struct{}),
+	}
+	_, err := p.RetrieveWithContext(ctx)
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if !stub.called {
+		t.Errorf("expect AssumeRoleWithContext to be called")
+	}
+	if e, a := "context canceled", err.Error(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
 func TestAssumeRoleProvider_WithTokenCode(t *testing.T) {
 	stub := &stubSTS{
 		TestInput: func(in *sts.AssumeRoleInput) {
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -113,12 +114,19 @@ func CredProviders(cfg *aws.Config, handlers request.Handlers) []credentials.Pro
 }
 
 const (
-	httpProviderAuthorizationEnvVar = "AWS_CONTAINER_AUTHORIZATION_TOKEN"
-	httpProviderEnvVar              = "AWS_CONTAINER_CREDENTIALS_FULL_URI"
+	httpProviderAuthorizationEnvVar     = "AWS_CONTAINER_AUTHORIZATION_TOKEN"
+	httpProviderAuthorizationFileEnvVar = "AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"
+	httpProviderEnvVar                  = "AWS_CONTAINER_CREDENTIALS_FULL_URI"
 )
 
 // RemoteCredProvider returns a credentials provider for the default remote
 // endpoints such as EC2 or ECS Roles.
+//
+// The AWS_CONTAINER_CREDENTIALS_FULL_URI endpoint's host must be a loopback or
+// link-local address, or resolve to only such addresses. The container
+// endpoint's authorization token is read from the
+// AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE file on every retrieval if set,
+// otherwise from AWS_CONTAINER_AUTHORIZATION_TOKEN.
 func RemoteCredProvider(cfg aws.Config, handlers request.Handlers) credentials.Provider {
 	if u := os.Getenv(httpProviderEnvVar); len(u) > 0 {
 		return localHTTPCredProvider(cfg, handlers, u)
@@ -134,10 +142,25 @@ func RemoteCredProvider(cfg aws.Config, handlers request.Handlers) credentials.P
 
 var lookupHostFn = net.LookupHost
 
-func isLoopbackHost(host string) (bool, error) {
+// eksPodIdentityIPv6 is the address of the EKS Pod Identity Agent's IPv6
+// container credentials endpoint.
+var eksPodIdentityIPv6 = net.ParseIP("fd00:ec2::23")
+
+// isAllowedHostIP returns if the IP is a loopback, or link-local, address,
+// or the address of a known container credentials endpoint.
+func isAllowedHostIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.Equal(eksPodIdentityIPv6)
+}
+
+func isAllowedHost(host string) (bool, error) {
+	// Strip the zone of IPv6 link-local addresses, e.g. fe80::1%eth0
+	if i := strings.LastIndex(host, "%"); i != -1 {
+		host = host[:i]
+	}
+
 	ip := net.ParseIP(host)
 	if ip != nil {
-		return ip.IsLoopback(), nil
+		return isAllowedHostIP(ip), nil
 	}
 
 	// Host is not an ip, perform lookup
@@ -146,7 +169,7 @@ func isLoopbackHost(host string) (bool, error) {
 		return false, err
 	}
 	for _, addr := range addrs {
-		if !net.ParseIP(addr).IsLoopback() {
+		if ip := net.ParseIP(addr); ip == nil || !isAllowedHostIP(ip) {
 			return false, nil
 		}
 	}
@@ -164,10 +187,10 @@ func localHTTPCredProvider(cfg aws.Config, handlers request.Handlers, u string)
 		host := aws.URLHostname(parsed)
 		if len(host) == 0 {
 			errMsg = "unable to parse host from local HTTP cred provider URL"
-		} else if isLoopback, loopbackErr := isLoopbackHost(host); loopbackErr != nil {
-			errMsg = fmt.Sprintf("failed to resolve host %q, %v", host, loopbackErr)
-		} else if !isLoopback {
-			errMsg = fmt.Sprintf("invalid endpoint host, %q, only loopback hosts are allowed.", host)
+		} else if isAllowed, allowErr := isAllowedHost(host); allowErr != nil {
+			errMsg = fmt.Sprintf("failed to resolve host %q, %v", host, allowErr)
+		} else if !isAllowed {
+			errMsg = fmt.Sprintf("invalid endpoint host, %q, only loopback and link-local hosts are allowed.", host)
 		}
 	}
 
@@ -189,6 +212,7 @@ func httpCredProvider(cfg aws.Config, handlers request.Handlers, u string) crede
 		func(p *endpointcreds.Provider) {
 			p.ExpiryWindow = 5 * time.Minute
 			p.AuthorizationToken = os.Getenv(httpProviderAuthorizationEnvVar)
+			p.AuthorizationTokenFile = os.Getenv(httpProviderAuthorizationFileEnvVar)
 		},
 	)
 }
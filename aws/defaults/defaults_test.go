@@ -27,6 +27,7 @@ func TestHTTPCredProvider(t *testing.T) {
 			"actuallylocal":   {Addrs: []string{"127.0.0.2"}},
 			"notlocal":        {Addrs: []string{"::1", "127.0.0.1", "192.168.1.10"}},
 			"www.example.com": {Addrs: []string{"10.10.10.10"}},
+			"container":       {Addrs: []string{"169.254.170.23", "fd00:ec2::23"}},
 		}
 
 		h, ok := m[host]
@@ -49,7 +50,15 @@ func TestHTTPCredProvider(t *testing.T) {
 		{Host: "127.1.1.1", Fail: false},
 		{Host: "[::1]", Fail: false},
 		{Host: "www.example.com", Fail: true},
-		{Host: "169.254.170.2", Fail: true},
+		{Host: "notlocal", Fail: true},
+		{Host: "169.254.170.2", Fail: false},
+		{Host: "169.254.170.23", Fail: false},
+		{Host: "container", Fail: false},
+		{Host: "[fd00:ec2::23]", Fail: false},
+		{Host: "[fe80::1]", Fail: false},
+		{Host: "[fe80::1%25eth0]", Fail: false},
+		{Host: "[fd00::1]", Fail: true},
+		{Host: "192.168.1.10", Fail: true},
 		{Host: "localhost", Fail: false, AuthToken: "Basic abc123"},
 	}
 
@@ -91,6 +100,24 @@ func TestHTTPCredProvider(t *testing.T) {
 	}
 }
 
+func TestHTTPCredProvider_AuthorizationTokenFile(t *testing.T) {
+	restoreEnvFn := sdktesting.StashEnv()
+	defer restoreEnvFn()
+	os.Setenv(shareddefaults.ECSCredsProviderEnvVar, "/abc/123")
+	os.Setenv(httpProviderAuthorizationEnvVar, "Basic abc123")
+	os.Setenv(httpProviderAuthorizationFileEnvVar, "/path/to/token")
+
+	provider := RemoteCredProvider(aws.Config{}, request.Handlers{})
+
+	httpProvider := provider.(*endpointcreds.Provider)
+	if e, a := "Basic abc123", httpProvider.AuthorizationToken; e != a {
+		t.Errorf("expect %q auth token, got %q", e, a)
+	}
+	if e, a := "/path/to/token", httpProvider.AuthorizationTokenFile; e != a {
+		t.Errorf("expect %q auth token file, got %q", e, a)
+	}
+}
+
 func TestECSCredProvider(t *testing.T) {
 	restoreEnvFn := sdktesting.StashEnv()
 	defer restoreEnvFn()
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"path"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -305,3 +306,182 @@ func TestEC2RoleProviderInstanceIdentity(t *testing.T) {
 		t.Errorf("expect %v, got %v", e, a)
 	}
 }
+
+type tokenTestServer struct {
+	*httptest.Server
+	tokenStatus int
+	token       string
+	ttl         string
+
+	mu          sync.Mutex
+	tokenCalls  int
+	tokenHeader []string
+}
+
+func newTokenTestServer(t *testing.T, tokenStatus int, token, ttl string) *tokenTestServer {
+	s := &tokenTestServer{tokenStatus: tokenStatus, token: token, ttl: ttl}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/latest/api/token":
+			if e, a := "PUT", r.Method; e != a {
+				t.Errorf("expect %v token method, got %v", e, a)
+			}
+			if e, a := "21600", r.Header.Get("x-aws-ec2-metadata-token-ttl-seconds"); e != a {
+				t.Errorf("expect %v token TTL, got %v", e, a)
+			}
+			s.tokenCalls++
+			if s.tokenStatus != http.StatusOK {
+				http.Error(w, "token error", s.tokenStatus)
+				return
+			}
+			w.Header().Set("x-aws-ec2-metadata-token-ttl-seconds", s.ttl)
+			w.Write([]byte(s.token))
+		case "/latest/meta-data/some/path":
+			v := r.Header.Get("x-aws-ec2-metadata-token")
+			s.tokenHeader = append(s.tokenHeader, v)
+			if s.tokenStatus == http.StatusOK && v != s.token {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("success"))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+
+	return s
+}
+
+func TestGetMetadata_Token(t *testing.T) {
+	server := newTokenTestServer(t, http.StatusOK, "token-value", "21600")
+	defer server.Close()
+	c := ec2metadata.New(unit.Session, &aws.Config{Endpoint: aws.String(server.URL + "/latest")})
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.GetMetadata("some/path")
+		if err != nil {
+			t.Fatalf("%d, expect no error, got %v", i, err)
+		}
+		if e, a := "success", resp; e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+	}
+
+	if e, a := 1, server.tokenCalls; e != a {
+		t.Errorf("expect %v token requests, got %v", e, a)
+	}
+	for i, v := range server.tokenHeader {
+		if e, a := "token-value", v; e != a {
+			t.Errorf("%d, expect %v token header, got %v", i, e, a)
+		}
+	}
+}
+
+func TestGetMetadata_TokenExpired(t *testing.T) {
+	// TTL within the expiration window requires the token to be refreshed
+	// for every request.
+	server := newTokenTestServer(t, http.StatusOK, "token-value", "10")
+	defer server.Close()
+	c := ec2metadata.New(unit.Session, &aws.Config{Endpoint: aws.String(server.URL + "/latest")})
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetMetadata("some/path"); err != nil {
+			t.Fatalf("%d, expect no error, got %v", i, err)
+		}
+	}
+
+	if e, a := 2, server.tokenCalls; e != a {
+		t.Errorf("expect %v token requests, got %v", e, a)
+	}
+}
+
+func TestGetMetadata_TokenFallback(t *testing.T) {
+	cases := map[string]int{
+		"not found":          http.StatusNotFound,
+		"forbidden":          http.StatusForbidden,
+		"method not allowed": http.StatusMethodNotAllowed,
+	}
+
+	for name, status := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := newTokenTestServer(t, status, "", "")
+			defer server.Close()
+			c := ec2metadata.New(unit.Session, &aws.Config{Endpoint: aws.String(server.URL + "/latest")})
+
+			for i := 0; i < 2; i++ {
+				resp, err := c.GetMetadata("some/path")
+				if err != nil {
+					t.Fatalf("%d, expect no error, got %v", i, err)
+				}
+				if e, a := "success", resp; e != a {
+					t.Errorf("%d, expect %v, got %v", i, e, a)
+				}
+			}
+
+			if e, a := 1, server.tokenCalls; e != a {
+				t.Errorf("expect %v token requests, got %v", e, a)
+			}
+			for i, v := range server.tokenHeader {
+				if len(v) != 0 {
+					t.Errorf("%d, expect no token header, got %v", i, v)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMetadata_TokenBadRequest(t *testing.T) {
+	server := newTokenTestServer(t, http.StatusBadRequest, "", "")
+	defer server.Close()
+	c := ec2metadata.New(unit.Session, &aws.Config{Endpoint: aws.String(server.URL + "/latest")})
+
+	_, err := c.GetMetadata("some/path")
+	if err == nil {
+		t.Fatalf("expect error")
+	}
+	rf, ok := err.(awserr.RequestFailure)
+	if !ok {
+		t.Fatalf("expect request failure, got %T", err)
+	}
+	if e, a := http.StatusBadRequest, rf.StatusCode(); e != a {
+		t.Errorf("expect %v status code, got %v", e, a)
+	}
+	if len(server.tokenHeader) != 0 {
+		t.Errorf("expect no metadata requests, got %v", len(server.tokenHeader))
+	}
+}
+
+func TestGetMetadata_TokenTimeout(t *testing.T) {
+	c := ec2metadata.New(unit.Session, &aws.Config{MaxRetries: aws.Int(0)})
+	c.Handlers.Send.Clear()
+	c.Handlers.Send.PushBack(func(r *request.Request) {
+		if r.Operation.Name == "GetToken" {
+			r.HTTPResponse = &http.Response{
+				StatusCode: int(0),
+				Status:     http.StatusText(int(0)),
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte{})),
+			}
+			r.Error = awserr.New(request.ErrCodeRequestError, "send request failed", nil)
+			return
+		}
+		if v := r.HTTPRequest.Header.Get("x-aws-ec2-metadata-token"); len(v) != 0 {
+			t.Errorf("expect no token header, got %v", v)
+		}
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     http.StatusText(http.StatusOK),
+			Body:       ioutil.NopCloser(strings.NewReader("success")),
+		}
+	})
+
+	resp, err := c.GetMetadata("some/path")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "success", resp; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
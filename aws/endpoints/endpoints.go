@@ -3,6 +3,7 @@ package endpoints
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 )
@@ -46,6 +47,58 @@ type Options struct {
 	//
 	// This option is ignored if StrictMatching is enabled.
 	ResolveUnknownService bool
+
+	// STSRegionalEndpoint selects the endpoint the STS service is resolved
+	// to. By default, and with LegacySTSEndpoint, regions which use the
+	// global STS endpoint, sts.amazonaws.com, resolve to it. With
+	// RegionalSTSEndpoint every region resolves to its own STS endpoint, such
+	// as sts.us-west-2.amazonaws.com. The aws-global region always resolves
+	// to the global endpoint.
+	STSRegionalEndpoint STSRegionalEndpoint
+}
+
+// STSRegionalEndpoint is an enum for the states of the STS regional endpoint
+// option.
+type STSRegionalEndpoint int
+
+func (e STSRegionalEndpoint) String() string {
+	switch e {
+	case LegacySTSEndpoint:
+		return "legacy"
+	case RegionalSTSEndpoint:
+		return "regional"
+	case UnsetSTSEndpoint:
+		return ""
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// UnsetSTSEndpoint represents that the STS regional endpoint option is
+	// not specified, and the legacy endpoints are used.
+	UnsetSTSEndpoint STSRegionalEndpoint = iota
+
+	// LegacySTSEndpoint represents when the STS regional endpoint option is
+	// set to legacy. Regions which use the global STS endpoint resolve to it.
+	LegacySTSEndpoint
+
+	// RegionalSTSEndpoint represents when the STS regional endpoint option is
+	// set to regional. Every region resolves to its own STS endpoint.
+	RegionalSTSEndpoint
+)
+
+// GetSTSRegionalEndpoint returns the STSRegionalEndpoint for the string
+// value, legacy or regional. The value is not case sensitive.
+func GetSTSRegionalEndpoint(s string) (STSRegionalEndpoint, error) {
+	switch {
+	case strings.EqualFold(s, "legacy"):
+		return LegacySTSEndpoint, nil
+	case strings.EqualFold(s, "regional"):
+		return RegionalSTSEndpoint, nil
+	default:
+		return UnsetSTSEndpoint, fmt.Errorf("unable to resolve the value of STSRegionalEndpoint for %v", s)
+	}
 }
 
 // Set combines all of the option functions together.
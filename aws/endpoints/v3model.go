@@ -91,6 +91,18 @@ func (p partition) EndpointFor(service, region string, opts ...func(*Options)) (
 		return resolved, NewUnknownEndpointError(p.ID, service, region, endpointList(s.Endpoints))
 	}
 
+	if service == "sts" && opt.STSRegionalEndpoint == RegionalSTSEndpoint &&
+		len(region) != 0 && region != s.PartitionEndpoint {
+		// Regions modeled with the global STS endpoint resolve to their own
+		// regional endpoint instead.
+		if len(e.Hostname) == 0 {
+			e.Hostname = p.Defaults.Hostname
+		}
+		if len(e.CredentialScope.Region) == 0 {
+			e.CredentialScope.Region = region
+		}
+	}
+
 	defs := []endpoint{p.Defaults, s.Defaults}
 	return e.resolve(service, region, p.DNSSuffix, defs, opt), nil
 }
@@ -539,3 +539,83 @@ func TestResolveEndpoint_AwsGlobal(t *testing.T) {
 		t.Errorf("expect the signing name to be derived")
 	}
 }
+
+func TestResolveEndpoint_STSRegionalEndpoint(t *testing.T) {
+	cases := []struct {
+		Region           string
+		Option           STSRegionalEndpoint
+		ExpectURL        string
+		ExpectSignRegion string
+	}{
+		{
+			Region: "us-west-2", Option: UnsetSTSEndpoint,
+			ExpectURL: "https://sts.amazonaws.com", ExpectSignRegion: "us-east-1",
+		},
+		{
+			Region: "us-west-2", Option: LegacySTSEndpoint,
+			ExpectURL: "https://sts.amazonaws.com", ExpectSignRegion: "us-east-1",
+		},
+		{
+			Region: "us-west-2", Option: RegionalSTSEndpoint,
+			ExpectURL: "https://sts.us-west-2.amazonaws.com", ExpectSignRegion: "us-west-2",
+		},
+		{
+			Region: "us-east-1", Option: RegionalSTSEndpoint,
+			ExpectURL: "https://sts.us-east-1.amazonaws.com", ExpectSignRegion: "us-east-1",
+		},
+		{
+			Region: "ap-east-1", Option: LegacySTSEndpoint,
+			ExpectURL: "https://sts.ap-east-1.amazonaws.com", ExpectSignRegion: "ap-east-1",
+		},
+		{
+			Region: "us-east-1-fips", Option: RegionalSTSEndpoint,
+			ExpectURL: "https://sts-fips.us-east-1.amazonaws.com", ExpectSignRegion: "us-east-1",
+		},
+		{
+			Region: "aws-global", Option: RegionalSTSEndpoint,
+			ExpectURL: "https://sts.amazonaws.com", ExpectSignRegion: "us-east-1",
+		},
+		{
+			Region: "cn-north-1", Option: RegionalSTSEndpoint,
+			ExpectURL: "https://sts.cn-north-1.amazonaws.com.cn", ExpectSignRegion: "cn-north-1",
+		},
+	}
+
+	for i, c := range cases {
+		resolved, err := DefaultResolver().EndpointFor("sts", c.Region, func(o *Options) {
+			o.STSRegionalEndpoint = c.Option
+		})
+		if err != nil {
+			t.Fatalf("%d, expect no error, got %v", i, err)
+		}
+		if e, a := c.ExpectURL, resolved.URL; e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+		if e, a := c.ExpectSignRegion, resolved.SigningRegion; e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+	}
+}
+
+func TestGetSTSRegionalEndpoint(t *testing.T) {
+	cases := []struct {
+		Value     string
+		Expect    STSRegionalEndpoint
+		ExpectErr bool
+	}{
+		{Value: "legacy", Expect: LegacySTSEndpoint},
+		{Value: "Regional", Expect: RegionalSTSEndpoint},
+		{Value: "global", Expect: UnsetSTSEndpoint, ExpectErr: true},
+		{Value: "", Expect: UnsetSTSEndpoint, ExpectErr: true},
+	}
+
+	for i, c := range cases {
+		v, err := GetSTSRegionalEndpoint(c.Value)
+		if c.ExpectErr != (err != nil) {
+			t.Errorf("%d, expect error %t, got %v", i, c.ExpectErr, err)
+		}
+		if e, a := c.Expect, v; e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+	}
+}
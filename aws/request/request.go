@@ -24,6 +24,10 @@ const (
 	// ErrCodeRead is an error that is returned during HTTP reads.
 	ErrCodeRead = "ReadError"
 
+	// ErrCodeRequestError is the error code returned when the SDK was unable
+	// to send the request, e.g. a connection failure or timeout.
+	ErrCodeRequestError = "RequestError"
+
 	// ErrCodeResponseTimeout is the connection timeout error that is received
 	// during body reads.
 	ErrCodeResponseTimeout = "ResponseTimeout"
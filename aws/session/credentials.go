@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/processcreds"
+	"github.com/aws/aws-sdk-go/aws/credentials/ssocreds"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/defaults"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -99,6 +100,10 @@ func resolveCredsFromProfile(cfg *aws.Config,
 			sharedCfg.Creds,
 		)
 
+	case sharedCfg.hasSSOConfiguration():
+		// Credentials from an AWS SSO access token cached by the AWS CLI.
+		creds = resolveSSOCredentials(cfg, sharedCfg, handlers)
+
 	case len(sharedCfg.CredentialProcess) != 0:
 		// Get credentials from CredentialProcess
 		creds = processcreds.NewCredentials(sharedCfg.CredentialProcess)
@@ -150,6 +155,23 @@ func resolveCredsFromProfile(cfg *aws.Config,
 	return creds, nil
 }
 
+func resolveSSOCredentials(cfg *aws.Config, sharedCfg sharedConfig, handlers request.Handlers) *credentials.Credentials {
+	// The AWS SSO user portal client must be configured for the region the
+	// portal is located in, not the region of the session.
+	cfgCopy := cfg.Copy()
+	cfgCopy.Region = &sharedCfg.SSORegion
+
+	return ssocreds.NewCredentials(
+		&Session{
+			Config:   cfgCopy,
+			Handlers: handlers.Copy(),
+		},
+		sharedCfg.SSOAccountID,
+		sharedCfg.SSORoleName,
+		sharedCfg.SSOStartURL,
+	)
+}
+
 // valid credential source values
 const (
 	credSourceEc2Metadata  = "Ec2InstanceMetadata"
@@ -208,6 +230,12 @@ func credsFromAssumeRole(cfg aws.Config,
 			opt.RoleSessionName = sharedCfg.RoleSessionName
 			opt.Duration = sessOpts.AssumeRoleDuration
 
+			// Each profile of a source_profile chain assumes its role with
+			// its own duration.
+			if sharedCfg.AssumeRoleDuration != nil {
+				opt.Duration = *sharedCfg.AssumeRoleDuration
+			}
+
 			// Assume role with external ID
 			if len(sharedCfg.ExternalID) > 0 {
 				opt.ExternalID = aws.String(sharedCfg.ExternalID)
@@ -218,6 +246,11 @@ func credsFromAssumeRole(cfg aws.Config,
 				opt.SerialNumber = aws.String(sharedCfg.MFASerial)
 				opt.TokenProvider = sessOpts.AssumeRoleTokenProvider
 			}
+
+			// Share the assumed role's credentials with other processes
+			if aws.BoolValue(sharedCfg.AssumeRoleCacheEnabled) {
+				opt.CacheDir = stscreds.DefaultCacheDir()
+			}
 		},
 	), nil
 }
@@ -4,9 +4,11 @@ package session
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strconv"
@@ -15,7 +17,9 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/ssocreds"
 	"github.com/aws/aws-sdk-go/aws/defaults"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -330,6 +334,70 @@ func TestSessionAssumeRole(t *testing.T) {
 	}
 }
 
+func TestSessionAssumeRole_WithCache(t *testing.T) {
+	restoreEnvFn := initSessionTestEnv()
+	defer restoreEnvFn()
+
+	home, err := ioutil.TempDir(os.TempDir(), "aws-sdk-go-session-cache")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(home)
+	if runtime.GOOS == "windows" {
+		os.Setenv("USERPROFILE", home)
+	} else {
+		os.Setenv("HOME", home)
+	}
+
+	os.Setenv("AWS_REGION", "us-east-1")
+	os.Setenv("AWS_SDK_LOAD_CONFIG", "1")
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", testConfigFilename)
+	os.Setenv("AWS_PROFILE", "assume_role_w_cache")
+
+	var assumed int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assumed++
+		w.Write([]byte(fmt.Sprintf(
+			assumeRoleRespMsg,
+			time.Now().Add(15*time.Minute).Format("2006-01-02T15:04:05Z"))))
+	}))
+	defer server.Close()
+
+	// Each session, as in separate processes, shares the cached credentials.
+	for i := 0; i < 2; i++ {
+		s, err := NewSession(&aws.Config{
+			Endpoint:   aws.String(server.URL),
+			DisableSSL: aws.Bool(true),
+		})
+		if err != nil {
+			t.Fatalf("%d, expect no error, got %v", i, err)
+		}
+
+		creds, err := s.Config.Credentials.Get()
+		if err != nil {
+			t.Fatalf("%d, expect no error, got %v", i, err)
+		}
+		if e, a := "AKID", creds.AccessKeyID; e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+		if e, a := "SESSION_TOKEN", creds.SessionToken; e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+	}
+
+	if e, a := 1, assumed; e != a {
+		t.Errorf("expect %v AssumeRole calls, got %v", e, a)
+	}
+
+	files, err := ioutil.ReadDir(filepath.Join(home, ".aws", "cli", "cache"))
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 1, len(files); e != a {
+		t.Errorf("expect %v cached credentials, got %v", e, a)
+	}
+}
+
 func TestSessionAssumeRole_WithMFA(t *testing.T) {
 	restoreEnvFn := initSessionTestEnv()
 	defer restoreEnvFn()
@@ -470,6 +538,155 @@ func TestSessionAssumeRole_InvalidSourceProfile(t *testing.T) {
 	}
 }
 
+const ssoRoleCredentialsRespMsg = `{
+  "roleCredentials": {
+    "accessKeyId": "SSO_AKID",
+    "secretAccessKey": "SSO_SECRET",
+    "sessionToken": "SSO_SESSION_TOKEN",
+    "expiration": %d
+  }
+}`
+
+func setupSSOTokenCache(t *testing.T, startURL string, expiresAt time.Time) func() {
+	home, err := ioutil.TempDir(os.TempDir(), "aws-sdk-go-session-sso")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if runtime.GOOS == "windows" {
+		os.Setenv("USERPROFILE", home)
+	} else {
+		os.Setenv("HOME", home)
+	}
+
+	filename, err := ssocreds.StandardCachedTokenFilepath(startURL)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	token := fmt.Sprintf(`{"accessToken": "SSO_ACCESS_TOKEN", "expiresAt": %q}`,
+		expiresAt.UTC().Format(time.RFC3339))
+	if err := ioutil.WriteFile(filename, []byte(token), 0600); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	return func() {
+		os.RemoveAll(home)
+	}
+}
+
+func TestSessionSSOCredentials(t *testing.T) {
+	restoreEnvFn := initSessionTestEnv()
+	defer restoreEnvFn()
+
+	os.Setenv("AWS_REGION", "us-east-1")
+	os.Setenv("AWS_SDK_LOAD_CONFIG", "1")
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", testConfigFilename)
+	os.Setenv("AWS_PROFILE", "sso_creds")
+
+	cleanup := setupSSOTokenCache(t, "https://127.0.0.1/start", time.Now().Add(time.Hour))
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e, a := "/federation/credentials", r.URL.Path; e != a {
+			t.Errorf("expect %v, got %v", e, a)
+		}
+		if e, a := "012345678901", r.URL.Query().Get("account_id"); e != a {
+			t.Errorf("expect %v, got %v", e, a)
+		}
+		if e, a := "TestRole", r.URL.Query().Get("role_name"); e != a {
+			t.Errorf("expect %v, got %v", e, a)
+		}
+		if e, a := "SSO_ACCESS_TOKEN", r.Header.Get("X-Amz-Sso_bearer_token"); e != a {
+			t.Errorf("expect %v, got %v", e, a)
+		}
+		if v := r.Header.Get("Authorization"); len(v) != 0 {
+			t.Errorf("expect request not to be signed, got %v", v)
+		}
+		w.Write([]byte(fmt.Sprintf(ssoRoleCredentialsRespMsg,
+			time.Now().Add(time.Hour).Unix()*1000)))
+	}))
+	defer server.Close()
+
+	s, err := NewSession(&aws.Config{
+		Endpoint:   aws.String(server.URL),
+		DisableSSL: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	creds, err := s.Config.Credentials.Get()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "SSO_AKID", creds.AccessKeyID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "SSO_SECRET", creds.SecretAccessKey; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "SSO_SESSION_TOKEN", creds.SessionToken; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := ssocreds.ProviderName, creds.ProviderName; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestSessionSSOCredentials_ExpiredToken(t *testing.T) {
+	restoreEnvFn := initSessionTestEnv()
+	defer restoreEnvFn()
+
+	os.Setenv("AWS_REGION", "us-east-1")
+	os.Setenv("AWS_SDK_LOAD_CONFIG", "1")
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", testConfigFilename)
+	os.Setenv("AWS_PROFILE", "sso_creds")
+
+	cleanup := setupSSOTokenCache(t, "https://127.0.0.1/start", time.Now().Add(-time.Hour))
+	defer cleanup()
+
+	s, err := NewSession()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	_, err = s.Config.Credentials.Get()
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := ssocreds.ErrCodeSSOProviderInvalidToken, err.(awserr.Error).Code(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "the SSO session has expired or is invalid", err.Error(); !strings.Contains(a, e) {
+		t.Errorf("expect %v, to be in %v", e, a)
+	}
+}
+
+func TestSessionSSOCredentials_StaticPrecedence(t *testing.T) {
+	restoreEnvFn := initSessionTestEnv()
+	defer restoreEnvFn()
+
+	os.Setenv("AWS_REGION", "us-east-1")
+	os.Setenv("AWS_SDK_LOAD_CONFIG", "1")
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", testConfigFilename)
+	os.Setenv("AWS_PROFILE", "sso_and_static")
+
+	s, err := NewSession()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	creds, err := s.Config.Credentials.Get()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "sso_and_static_akid", creds.AccessKeyID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
 func TestSessionAssumeRole_ExtendedDuration(t *testing.T) {
 	restoreEnvFn := initSessionTestEnv()
 	defer restoreEnvFn()
@@ -586,3 +803,159 @@ func TestSessionAssumeRole_WithMFA_ExtendedDuration(t *testing.T) {
 		t.Errorf("expect %v, to be in %v", e, a)
 	}
 }
+
+func TestSessionAssumeRole_ChainedOptions(t *testing.T) {
+	restoreEnvFn := initSessionTestEnv()
+	defer restoreEnvFn()
+
+	os.Setenv("AWS_REGION", "us-west-2")
+	os.Setenv("AWS_SDK_LOAD_CONFIG", "1")
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", testConfigFilename)
+	os.Setenv("AWS_PROFILE", "assume_role_chain_w_options")
+
+	// Each role of the chain is assumed with its own profile's options.
+	expectParams := map[string]map[string]string{
+		"assume_role_w_options_role_arn": {
+			"DurationSeconds": "1800",
+			"SerialNumber":    "0123456789",
+			"TokenCode":       "tokencode",
+			"ExternalId":      "",
+		},
+		"assume_role_chain_w_options_role_arn": {
+			"DurationSeconds": "3600",
+			"SerialNumber":    "",
+			"ExternalId":      "assume_role_chain_w_options_external_id",
+		},
+	}
+
+	var assumed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		roleARN := r.FormValue("RoleArn")
+		assumed = append(assumed, roleARN)
+		for k, e := range expectParams[roleARN] {
+			if a := r.FormValue(k); e != a {
+				t.Errorf("%s, expect %v %v, got %v", roleARN, k, e, a)
+			}
+		}
+
+		w.Write([]byte(fmt.Sprintf(
+			assumeRoleRespMsg,
+			time.Now().Add(30*time.Minute).Format("2006-01-02T15:04:05Z"))))
+	}))
+	defer server.Close()
+
+	resolver := endpoints.ResolverFunc(
+		func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+			var opt endpoints.Options
+			opt.Set(opts...)
+			if e, a := endpoints.RegionalSTSEndpoint, opt.STSRegionalEndpoint; e != a {
+				t.Errorf("expect %v STS endpoint, got %v", e, a)
+			}
+			return endpoints.ResolvedEndpoint{URL: server.URL, SigningRegion: region}, nil
+		})
+
+	sess, err := NewSessionWithOptions(Options{
+		Config: aws.Config{
+			EndpointResolver: resolver,
+			DisableSSL:       aws.Bool(true),
+		},
+		AssumeRoleTokenProvider: func() (string, error) {
+			return "tokencode", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := endpoints.RegionalSTSEndpoint, sess.Config.STSRegionalEndpoint; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	creds, err := sess.Config.Credentials.Get()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "AssumeRoleProvider", creds.ProviderName; !strings.Contains(a, e) {
+		t.Errorf("expect %v, to be in %v", e, a)
+	}
+
+	expectAssumed := []string{
+		"assume_role_w_options_role_arn",
+		"assume_role_chain_w_options_role_arn",
+	}
+	if e, a := expectAssumed, assumed; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestSessionSTSConfig_Env(t *testing.T) {
+	restoreEnvFn := initSessionTestEnv()
+	defer restoreEnvFn()
+
+	os.Setenv("AWS_REGION", "us-west-2")
+	os.Setenv("AWS_SDK_LOAD_CONFIG", "1")
+	os.Setenv("AWS_SHARED_CREDENTIALS_FILE", testConfigFilename)
+	os.Setenv("AWS_PROFILE", "assume_role_w_creds")
+	os.Setenv("AWS_STS_REGIONAL_ENDPOINTS", "regional")
+	os.Setenv("AWS_ROLE_DURATION_SECONDS", "2700")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e, a := "2700", r.FormValue("DurationSeconds"); e != a {
+			t.Errorf("expect %v, got %v", e, a)
+		}
+
+		w.Write([]byte(fmt.Sprintf(
+			assumeRoleRespMsg,
+			time.Now().Add(30*time.Minute).Format("2006-01-02T15:04:05Z"))))
+	}))
+	defer server.Close()
+
+	sess, err := NewSession(&aws.Config{
+		Endpoint:   aws.String(server.URL),
+		DisableSSL: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := endpoints.RegionalSTSEndpoint, sess.Config.STSRegionalEndpoint; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if _, err := sess.Config.Credentials.Get(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	// The session's STS clients resolve the regional STS endpoint.
+	cfg := sess.ClientConfig(sts.EndpointsID, &aws.Config{
+		Endpoint:   aws.String(""),
+		DisableSSL: aws.Bool(false),
+	})
+	if e, a := "https://sts.us-west-2.amazonaws.com", cfg.Endpoint; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := "us-west-2", cfg.SigningRegion; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestSessionSTSConfig_InvalidEnv(t *testing.T) {
+	cases := map[string]string{
+		"AWS_STS_REGIONAL_ENDPOINTS": "global",
+		"AWS_ROLE_DURATION_SECONDS":  "1h",
+	}
+
+	for k, v := range cases {
+		t.Run(k, func(t *testing.T) {
+			restoreEnvFn := initSessionTestEnv()
+			defer restoreEnvFn()
+
+			os.Setenv(k, v)
+
+			_, err := NewSession()
+			if err == nil {
+				t.Fatalf("expect error, got none")
+			}
+			if e, a := ErrCodeInvalidSTSConfig, err.(awserr.Error).Code(); e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
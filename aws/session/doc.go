@@ -155,9 +155,78 @@ to load if the AssumeRoleTokenProvider is not specified.
         AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
     }))
 
+The assumed role's credentials are only cached by the Session. If
+"assume_role_cache_enabled" is set to true, the credentials are also cached
+in the AWS CLI's cache directory, ~/.aws/cli/cache, and shared by processes
+assuming the same role until they expire. With "mfa_serial" set, the
+AssumeRoleTokenProvider is only called if there are no cached credentials.
+
+	role_arn = arn:aws:iam::<account_number>:role/<role_name>
+	source_profile = profile_with_creds
+	mfa_serial = <serial or mfa arn>
+	assume_role_cache_enabled = true
+
+The "duration_seconds" field sets the duration of the assumed role's
+credentials, and has priority over the Session Option.AssumeRoleDuration.
+Profiles may be chained with "source_profile", each profile assuming its role
+with its own "duration_seconds", "external_id", and "mfa_serial" fields. A
+profile may be its own source profile to use its static credentials, but the
+Session will fail to load if the chain otherwise loops back to a profile.
+
+	[profile base]
+	role_arn = arn:aws:iam::<account_number>:role/<base_role_name>
+	source_profile = profile_with_creds
+	mfa_serial = <serial or mfa arn>
+	duration_seconds = 3600
+
+	[profile chained]
+	role_arn = arn:aws:iam::<account_number>:role/<role_name>
+	source_profile = base
+	external_id = 1234
+	duration_seconds = 900
+
 To setup Assume Role outside of a session see the stscreds.AssumeRoleProvider
 documentation.
 
+STS regional endpoints
+
+The sts_regional_endpoints field selects whether STS service clients, including
+those used to assume roles, use the regional STS endpoint of the Session's
+region, such as sts.us-west-2.amazonaws.com, or the legacy global endpoint,
+sts.amazonaws.com. Valid values are legacy, the default, and regional. The
+field is only supported if SharedConfigEnabled. The aws.Config
+STSRegionalEndpoint value has priority over this field.
+
+	sts_regional_endpoints = regional
+
+AWS Single Sign-On (AWS SSO) configuration
+
+The sso_* fields allow you to configure the SDK to retrieve credentials for
+an AWS SSO role, using the access token cached by the AWS CLI's
+"aws sso login" command in ~/.aws/sso/cache. All four fields must be
+provided, and are only supported if SharedConfigEnabled. Retrieving the
+credentials fails if the cached access token has expired, until you sign in
+again with "aws sso login".
+
+	sso_start_url = https://my-sso-portal.awsapps.com/start
+	sso_region = us-east-1
+	sso_account_id = 123456789012
+	sso_role_name = SSOReadOnlyRole
+
+To setup AWS SSO credentials outside of a session see the ssocreds package
+documentation.
+
+Retry configuration
+
+The retry_mode field selects the retryer service clients will use to retry
+failed requests, legacy, standard, or adaptive. The max_attempts field sets
+the maximum number of attempts, including the initial attempt, made for a
+request. Both fields are only supported if SharedConfigEnabled. The
+aws.Config RetryMode and MaxRetries values have priority over these fields.
+
+	retry_mode = adaptive
+	max_attempts = 5
+
 Environment Variables
 
 When a Session is created several environment variables can be set to adjust
@@ -241,5 +310,25 @@ over the AWS_CA_BUNDLE environment variable, and will be used if both are set.
 Setting a custom HTTPClient in the aws.Config options will override this setting.
 To use this option and custom HTTP client, the HTTP client needs to be provided
 when creating the session. Not the service client.
+
+The retry mode and maximum number of attempts, including the initial attempt,
+service clients will use can be set with the following environment variables.
+The environment variables have priority over the shared config file's
+retry_mode and max_attempts fields.
+
+	AWS_RETRY_MODE=standard
+	AWS_MAX_ATTEMPTS=3
+
+The STS endpoint service clients use can be set with the following environment
+variable, which has priority over the shared config file's
+sts_regional_endpoints field.
+
+	AWS_STS_REGIONAL_ENDPOINTS=regional
+
+The duration of assumed roles' credentials, in seconds, can be set with the
+following environment variable. The shared config file's duration_seconds
+field, and the Session Option.AssumeRoleDuration, have priority over it.
+
+	AWS_ROLE_DURATION_SECONDS=3600
 */
 package session
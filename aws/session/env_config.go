@@ -125,6 +125,31 @@ type envConfig struct {
 	//
 	//  AWS_ROLE_SESSION_NAME=session_name
 	RoleSessionName string
+
+	// Specifies the retry mode the service clients will use to retry failed
+	// requests. Valid values are legacy, standard, and adaptive.
+	//
+	//  AWS_RETRY_MODE=standard
+	RetryMode string
+
+	// Specifies the maximum number of attempts, including the initial
+	// attempt, the service clients will make for a request.
+	//
+	//  AWS_MAX_ATTEMPTS=3
+	MaxAttempts string
+
+	// Specifies the duration, in seconds, of the credentials of roles the
+	// shared config assumes, when the profile does not set duration_seconds.
+	//
+	//  AWS_ROLE_DURATION_SECONDS=3600
+	AssumeRoleDuration string
+
+	// Specifies whether STS service clients use the regional STS endpoint
+	// of the region, or the legacy global endpoint. Valid values are legacy
+	// and regional.
+	//
+	//  AWS_STS_REGIONAL_ENDPOINTS=regional
+	STSRegionalEndpoint string
 }
 
 var (
@@ -179,6 +204,18 @@ var (
 	roleSessionNameEnvKey = []string{
 		"AWS_ROLE_SESSION_NAME",
 	}
+	retryModeEnvKey = []string{
+		"AWS_RETRY_MODE",
+	}
+	maxAttemptsEnvKey = []string{
+		"AWS_MAX_ATTEMPTS",
+	}
+	assumeRoleDurationEnvKey = []string{
+		"AWS_ROLE_DURATION_SECONDS",
+	}
+	stsRegionalEndpointEnvKey = []string{
+		"AWS_STS_REGIONAL_ENDPOINTS",
+	}
 )
 
 // loadEnvConfig retrieves the SDK's environment configuration.
@@ -221,10 +258,16 @@ func envConfigLoad(enableSharedConfig bool) envConfig {
 	// Role Metadata
 	setFromEnvVal(&cfg.RoleARN, roleARNEnvKey)
 	setFromEnvVal(&cfg.RoleSessionName, roleSessionNameEnvKey)
+	setFromEnvVal(&cfg.AssumeRoleDuration, assumeRoleDurationEnvKey)
+	setFromEnvVal(&cfg.STSRegionalEndpoint, stsRegionalEndpointEnvKey)
 
 	// Web identity environment variables
 	setFromEnvVal(&cfg.WebIdentityTokenFilePath, webIdentityTokenFilePathEnvKey)
 
+	// Retry environment variables
+	setFromEnvVal(&cfg.RetryMode, retryModeEnvKey)
+	setFromEnvVal(&cfg.MaxAttempts, maxAttemptsEnvKey)
+
 	// CSM environment variables
 	setFromEnvVal(&cfg.csmEnabled, csmEnabledEnvKey)
 	setFromEnvVal(&cfg.CSMHost, csmHostEnvKey)
@@ -268,6 +268,30 @@ func TestLoadEnvConfig(t *testing.T) {
 				SharedConfigFile:      "/path/to/config/file",
 			},
 		},
+		{
+			Env: map[string]string{
+				"AWS_RETRY_MODE":   "standard",
+				"AWS_MAX_ATTEMPTS": "5",
+			},
+			Config: envConfig{
+				RetryMode:             "standard",
+				MaxAttempts:           "5",
+				SharedCredentialsFile: shareddefaults.SharedCredentialsFilename(),
+				SharedConfigFile:      shareddefaults.SharedConfigFilename(),
+			},
+		},
+		{
+			Env: map[string]string{
+				"AWS_ROLE_DURATION_SECONDS":  "3600",
+				"AWS_STS_REGIONAL_ENDPOINTS": "regional",
+			},
+			Config: envConfig{
+				AssumeRoleDuration:    "3600",
+				STSRegionalEndpoint:   "regional",
+				SharedCredentialsFile: shareddefaults.SharedCredentialsFilename(),
+				SharedConfigFile:      shareddefaults.SharedConfigFilename(),
+			},
+		},
 	}
 
 	for i, c := range cases {
@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -25,6 +26,16 @@ const (
 	// ErrCodeSharedConfig represents an error that occurs in the shared
 	// configuration logic
 	ErrCodeSharedConfig = "SharedConfigErr"
+
+	// ErrCodeInvalidRetryConfig represents an error that occurs when the
+	// retry mode or max attempts configured in the environment or shared
+	// config are not valid.
+	ErrCodeInvalidRetryConfig = "InvalidRetryConfig"
+
+	// ErrCodeInvalidSTSConfig represents an error that occurs when the
+	// assume role duration or STS regional endpoint configured in the
+	// environment or shared config are not valid.
+	ErrCodeInvalidSTSConfig = "InvalidSTSConfig"
 )
 
 // ErrSharedConfigSourceCollision will be returned if a section contains both
@@ -224,6 +235,10 @@ type Options struct {
 	// may be provided to set the expiry duration of the STS credentials.
 	// Defaults to 15 minutes if not set as documented in the
 	// stscreds.AssumeRoleProvider.
+	//
+	// A profile's duration_seconds value takes precedence over this option
+	// for the role the profile assumes. If this option is not set the
+	// AWS_ROLE_DURATION_SECONDS environment variable is used instead.
 	AssumeRoleDuration time.Duration
 
 	// Reader for a custom Credentials Authority (CA) bundle in PEM format that
@@ -550,6 +565,14 @@ func mergeConfigSrcs(cfg, userCfg *aws.Config,
 		}
 	}
 
+	if err := mergeRetryConfig(cfg, envCfg, sharedCfg); err != nil {
+		return err
+	}
+
+	if err := mergeSTSConfig(cfg, envCfg, sharedCfg, &sessOpts); err != nil {
+		return err
+	}
+
 	// Configure credentials if not already set by the user when creating the
 	// Session.
 	if cfg.Credentials == credentials.AnonymousCredentials && userCfg.Credentials == nil {
@@ -563,6 +586,84 @@ func mergeConfigSrcs(cfg, userCfg *aws.Config,
 	return nil
 }
 
+// mergeRetryConfig sets the retry mode and max retries from the environment
+// and shared config if not already set by the user.
+func mergeRetryConfig(cfg *aws.Config, envCfg envConfig, sharedCfg sharedConfig) error {
+	if len(cfg.RetryMode) == 0 {
+		var v string
+		if len(envCfg.RetryMode) != 0 {
+			v = envCfg.RetryMode
+		} else if envCfg.EnableSharedConfig {
+			v = sharedCfg.RetryMode
+		}
+
+		if len(v) != 0 {
+			mode, ok := aws.ParseRetryMode(v)
+			if !ok {
+				return awserr.New(ErrCodeInvalidRetryConfig,
+					fmt.Sprintf("invalid retry mode, %s", v), nil)
+			}
+			cfg.RetryMode = mode
+		}
+	}
+
+	if cfg.MaxRetries == nil || aws.IntValue(cfg.MaxRetries) == aws.UseServiceDefaultRetries {
+		var v string
+		if len(envCfg.MaxAttempts) != 0 {
+			v = envCfg.MaxAttempts
+		} else if envCfg.EnableSharedConfig {
+			v = sharedCfg.MaxAttempts
+		}
+
+		if len(v) != 0 {
+			attempts, err := strconv.Atoi(v)
+			if err != nil || attempts < 1 {
+				return awserr.New(ErrCodeInvalidRetryConfig,
+					fmt.Sprintf("invalid max attempts, %s, must be a positive integer", v), err)
+			}
+			// Max attempts includes the initial attempt of the request.
+			cfg.WithMaxRetries(attempts - 1)
+		}
+	}
+
+	return nil
+}
+
+// mergeSTSConfig sets the STS regional endpoint from the environment and
+// shared config if not already set by the user, and the default duration of
+// assumed roles from the environment if not set by the session's options.
+func mergeSTSConfig(cfg *aws.Config, envCfg envConfig, sharedCfg sharedConfig, sessOpts *Options) error {
+	if cfg.STSRegionalEndpoint == endpoints.UnsetSTSEndpoint {
+		var v string
+		if len(envCfg.STSRegionalEndpoint) != 0 {
+			v = envCfg.STSRegionalEndpoint
+		} else if envCfg.EnableSharedConfig {
+			v = sharedCfg.STSRegionalEndpoint
+		}
+
+		if len(v) != 0 {
+			sre, err := endpoints.GetSTSRegionalEndpoint(v)
+			if err != nil {
+				return awserr.New(ErrCodeInvalidSTSConfig,
+					fmt.Sprintf("invalid STS regional endpoint, %s, must be legacy or regional", v), nil)
+			}
+			cfg.STSRegionalEndpoint = sre
+		}
+	}
+
+	if sessOpts.AssumeRoleDuration == 0 && len(envCfg.AssumeRoleDuration) != 0 {
+		v := envCfg.AssumeRoleDuration
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 1 {
+			return awserr.New(ErrCodeInvalidSTSConfig,
+				fmt.Sprintf("invalid assume role duration, %s, must be a positive number of seconds", v), err)
+		}
+		sessOpts.AssumeRoleDuration = time.Duration(seconds) * time.Second
+	}
+
+	return nil
+}
+
 func initHandlers(s *Session) {
 	// Add the Validate parameter handler if it is not disabled.
 	s.Handlers.Validate.Remove(corehandlers.ValidateParametersHandler)
@@ -616,6 +717,7 @@ func (s *Session) clientConfigWithErr(serviceName string, cfgs ...*aws.Config) (
 			func(opt *endpoints.Options) {
 				opt.DisableSSL = aws.BoolValue(s.Config.DisableSSL)
 				opt.UseDualStack = aws.BoolValue(s.Config.UseDualStack)
+				opt.STSRegionalEndpoint = s.Config.STSRegionalEndpoint
 
 				// Support the condition where the service is modeled but its
 				// endpoint metadata is not available.
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -532,3 +533,96 @@ func TestNewSession_EnvCredsWithInvalidConfigFile(t *testing.T) {
 		})
 	}
 }
+
+func TestNewSession_RetryConfig(t *testing.T) {
+	cases := map[string]struct {
+		Env         map[string]string
+		Config      *aws.Config
+		ExpectMode  aws.RetryMode
+		ExpectRetry *int
+		Err         string
+	}{
+		"shared config": {
+			Env: map[string]string{
+				"AWS_SDK_LOAD_CONFIG": "1",
+				"AWS_PROFILE":         "retry_config",
+			},
+			ExpectMode:  aws.RetryModeAdaptive,
+			ExpectRetry: aws.Int(4),
+		},
+		"env overrides shared config": {
+			Env: map[string]string{
+				"AWS_SDK_LOAD_CONFIG": "1",
+				"AWS_PROFILE":         "retry_config",
+				"AWS_RETRY_MODE":      "Standard",
+				"AWS_MAX_ATTEMPTS":    "2",
+			},
+			ExpectMode:  aws.RetryModeStandard,
+			ExpectRetry: aws.Int(1),
+		},
+		"user config overrides env": {
+			Env: map[string]string{
+				"AWS_RETRY_MODE":   "standard",
+				"AWS_MAX_ATTEMPTS": "2",
+			},
+			Config:      aws.NewConfig().WithRetryMode(aws.RetryModeLegacy).WithMaxRetries(7),
+			ExpectMode:  aws.RetryModeLegacy,
+			ExpectRetry: aws.Int(7),
+		},
+		"shared config not enabled": {
+			Env: map[string]string{
+				"AWS_PROFILE": "retry_config",
+			},
+			ExpectRetry: aws.Int(aws.UseServiceDefaultRetries),
+		},
+		"invalid retry mode": {
+			Env: map[string]string{
+				"AWS_RETRY_MODE": "fast",
+			},
+			Err: "invalid retry mode, fast",
+		},
+		"invalid max attempts": {
+			Env: map[string]string{
+				"AWS_MAX_ATTEMPTS": "0",
+			},
+			Err: "invalid max attempts, 0",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			restoreEnvFn := initSessionTestEnv()
+			defer restoreEnvFn()
+
+			os.Setenv("AWS_CONFIG_FILE", testConfigFilename)
+			for k, v := range c.Env {
+				os.Setenv(k, v)
+			}
+
+			cfg := c.Config
+			if cfg == nil {
+				cfg = aws.NewConfig()
+			}
+			s, err := NewSession(cfg)
+			if len(c.Err) != 0 {
+				if err == nil {
+					t.Fatalf("expect session error, got none")
+				}
+				if e, a := c.Err, err.Error(); !strings.Contains(a, e) {
+					t.Fatalf("expect session error to contain %q, got %v", e, a)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			if e, a := c.ExpectMode, s.Config.RetryMode; e != a {
+				t.Errorf("expect %v retry mode, got %v", e, a)
+			}
+			if e, a := c.ExpectRetry, s.Config.MaxRetries; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v max retries, got %v", aws.IntValue(e), aws.IntValue(a))
+			}
+		})
+	}
+}
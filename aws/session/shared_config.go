@@ -2,6 +2,9 @@ package session
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -21,6 +24,16 @@ const (
 	externalIDKey       = `external_id`       // optional
 	mfaSerialKey        = `mfa_serial`        // optional
 	roleSessionNameKey  = `role_session_name` // optional
+	roleDurationKey     = `duration_seconds`  // optional
+
+	// Assume Role Credentials cache
+	assumeRoleCacheEnabledKey = `assume_role_cache_enabled` // optional
+
+	// AWS Single Sign-On (AWS SSO) group
+	ssoAccountIDKey = `sso_account_id` // group required
+	ssoRegionKey    = `sso_region`     // group required
+	ssoRoleNameKey  = `sso_role_name`  // group required
+	ssoStartURLKey  = `sso_start_url`  // group required
 
 	// CSM options
 	csmEnabledKey  = `csm_enabled`
@@ -31,9 +44,16 @@ const (
 	// Additional Config fields
 	regionKey = `region`
 
+	// STS regional endpoints
+	stsRegionalEndpointKey = `sts_regional_endpoints` // optional
+
 	// endpoint discovery group
 	enableEndpointDiscoveryKey = `endpoint_discovery_enabled` // optional
 
+	// Retry options
+	retryModeKey   = `retry_mode`   // optional
+	maxAttemptsKey = `max_attempts` // optional
+
 	// External Credential Process
 	credentialProcessKey = `credential_process` // optional
 
@@ -68,21 +88,66 @@ type sharedConfig struct {
 	ExternalID      string
 	MFASerial       string
 
+	// AssumeRoleDuration is the duration of the assumed role's credentials.
+	// Each profile of a source_profile chain assumes its role with its own
+	// duration.
+	//
+	//	duration_seconds = 3600
+	AssumeRoleDuration *time.Duration
+
 	SourceProfileName string
 	SourceProfile     *sharedConfig
 
+	// AssumeRoleCacheEnabled enables caching the assumed role's credentials
+	// in the AWS CLI's cache directory, ~/.aws/cli/cache, shared by processes
+	// assuming the same role.
+	//
+	//	assume_role_cache_enabled = true
+	AssumeRoleCacheEnabled *bool
+
+	// AWS Single Sign-On (AWS SSO) values from the config file. All four
+	// values must be provided together for the profile to be valid.
+	//
+	//	sso_account_id
+	//	sso_region
+	//	sso_role_name
+	//	sso_start_url
+	SSOAccountID string
+	SSORegion    string
+	SSORoleName  string
+	SSOStartURL  string
+
 	// Region is the region the SDK should use for looking up AWS service
 	// endpoints and signing requests.
 	//
 	//	region
 	Region string
 
+	// STSRegionalEndpoint selects whether STS service clients use the
+	// regional STS endpoint of the region, or the legacy global endpoint.
+	// Valid values are legacy and regional.
+	//
+	//	sts_regional_endpoints = regional
+	STSRegionalEndpoint string
+
 	// EnableEndpointDiscovery can be enabled in the shared config by setting
 	// endpoint_discovery_enabled to true
 	//
 	//	endpoint_discovery_enabled = true
 	EnableEndpointDiscovery *bool
 
+	// RetryMode is the retry mode service clients will use to retry failed
+	// requests. Valid values are legacy, standard, and adaptive.
+	//
+	//	retry_mode = standard
+	RetryMode string
+
+	// MaxAttempts is the maximum number of attempts, including the initial
+	// attempt, service clients will make for a request.
+	//
+	//	max_attempts = 3
+	MaxAttempts string
+
 	// CSM Options
 	CSMEnabled  *bool
 	CSMHost     string
@@ -117,8 +182,7 @@ func loadSharedConfig(profile string, filenames []string, exOpts bool) (sharedCo
 	}
 
 	cfg := sharedConfig{}
-	profiles := map[string]struct{}{}
-	if err = cfg.setFromIniFiles(profiles, profile, files, exOpts); err != nil {
+	if err = cfg.setFromIniFiles(nil, profile, files, exOpts); err != nil {
 		return sharedConfig{}, err
 	}
 
@@ -145,7 +209,10 @@ func loadSharedConfigIniFiles(filenames []string) ([]sharedConfigFile, error) {
 	return files, nil
 }
 
-func (cfg *sharedConfig) setFromIniFiles(profiles map[string]struct{}, profile string, files []sharedConfigFile, exOpts bool) error {
+// setFromIniFiles loads the profile from the files, and links the profile's
+// source profiles. The profiles are the chain of profiles which sourced this
+// profile.
+func (cfg *sharedConfig) setFromIniFiles(profiles []string, profile string, files []sharedConfigFile, exOpts bool) error {
 	// Trim files from the list that don't exist.
 	var skippedFiles int
 	var profileNotFoundErr error
@@ -166,7 +233,7 @@ func (cfg *sharedConfig) setFromIniFiles(profiles map[string]struct{}, profile s
 		return profileNotFoundErr
 	}
 
-	if _, ok := profiles[profile]; ok {
+	if containsString(profiles, profile) {
 		// if this is the second instance of the profile the Assume Role
 		// options must be cleared because they are only valid for the
 		// first reference of a profile. The self linked instance of the
@@ -180,14 +247,28 @@ func (cfg *sharedConfig) setFromIniFiles(profiles map[string]struct{}, profile s
 			return err
 		}
 	}
-	profiles[profile] = struct{}{}
+	profiles = append(profiles, profile)
 
 	if err := cfg.validateCredentialType(); err != nil {
 		return err
 	}
 
+	if err := cfg.validateSSOConfiguration(profile); err != nil {
+		return err
+	}
+
 	// Link source profiles for assume roles
 	if len(cfg.SourceProfileName) != 0 {
+		// A profile may only source itself, for its own credentials. Any
+		// other profile already in the chain would loop forever.
+		if cfg.SourceProfileName != profile && containsString(profiles, cfg.SourceProfileName) {
+			chain := make([]string, 0, len(profiles)+1)
+			chain = append(chain, profiles...)
+			return SharedConfigSourceProfileCycleError{
+				Profiles: append(chain, cfg.SourceProfileName),
+			}
+		}
+
 		// Linked profile via source_profile ignore credential provider
 		// options, the source profile must provide the credentials.
 		cfg.clearCredentialOptions()
@@ -244,8 +325,20 @@ func (cfg *sharedConfig) setFromIniFile(profile string, file sharedConfigFile, e
 		updateString(&cfg.RoleSessionName, section, roleSessionNameKey)
 		updateString(&cfg.SourceProfileName, section, sourceProfileKey)
 		updateString(&cfg.CredentialSource, section, credentialSourceKey)
+		updateBoolPtr(&cfg.AssumeRoleCacheEnabled, section, assumeRoleCacheEnabledKey)
+		if err := updateDurationSecondsPtr(&cfg.AssumeRoleDuration, section, roleDurationKey); err != nil {
+			return awserr.New(ErrCodeInvalidSTSConfig,
+				fmt.Sprintf("invalid %s in profile %s, %v", roleDurationKey, profile, err), nil)
+		}
+
+		// AWS Single Sign-On (AWS SSO)
+		updateString(&cfg.SSOAccountID, section, ssoAccountIDKey)
+		updateString(&cfg.SSORegion, section, ssoRegionKey)
+		updateString(&cfg.SSORoleName, section, ssoRoleNameKey)
+		updateString(&cfg.SSOStartURL, section, ssoStartURLKey)
 
 		updateString(&cfg.Region, section, regionKey)
+		updateString(&cfg.STSRegionalEndpoint, section, stsRegionalEndpointKey)
 	}
 
 	updateString(&cfg.CredentialProcess, section, credentialProcessKey)
@@ -265,6 +358,10 @@ func (cfg *sharedConfig) setFromIniFile(profile string, file sharedConfigFile, e
 	// Endpoint discovery
 	updateBoolPtr(&cfg.EnableEndpointDiscovery, section, enableEndpointDiscoveryKey)
 
+	// Retry options
+	updateString(&cfg.RetryMode, section, retryModeKey)
+	updateString(&cfg.MaxAttempts, section, maxAttemptsKey)
+
 	// CSM options
 	updateBoolPtr(&cfg.CSMEnabled, section, csmEnabledKey)
 	updateString(&cfg.CSMHost, section, csmHostKey)
@@ -303,6 +400,7 @@ func (cfg *sharedConfig) validateCredentialType() error {
 		len(cfg.CredentialSource) != 0,
 		len(cfg.CredentialProcess) != 0,
 		len(cfg.WebIdentityTokenFile) != 0,
+		cfg.hasSSOConfiguration(),
 	) {
 		return ErrSharedConfigSourceCollision
 	}
@@ -310,12 +408,58 @@ func (cfg *sharedConfig) validateCredentialType() error {
 	return nil
 }
 
+// validateSSOConfiguration returns an error if the profile only includes part
+// of the AWS SSO configuration.
+func (cfg *sharedConfig) validateSSOConfiguration(profile string) error {
+	if !cfg.hasSSOConfiguration() {
+		return nil
+	}
+
+	var missing []string
+	if len(cfg.SSOAccountID) == 0 {
+		missing = append(missing, ssoAccountIDKey)
+	}
+	if len(cfg.SSORegion) == 0 {
+		missing = append(missing, ssoRegionKey)
+	}
+	if len(cfg.SSORoleName) == 0 {
+		missing = append(missing, ssoRoleNameKey)
+	}
+	if len(cfg.SSOStartURL) == 0 {
+		missing = append(missing, ssoStartURLKey)
+	}
+
+	if len(missing) > 0 {
+		return SharedConfigSSOIncompleteError{
+			Profile: profile,
+			Missing: missing,
+		}
+	}
+
+	return nil
+}
+
+// hasSSOConfiguration returns if any of the AWS SSO keys are set for the
+// profile.
+func (cfg *sharedConfig) hasSSOConfiguration() bool {
+	switch {
+	case len(cfg.SSOAccountID) != 0:
+	case len(cfg.SSORegion) != 0:
+	case len(cfg.SSORoleName) != 0:
+	case len(cfg.SSOStartURL) != 0:
+	default:
+		return false
+	}
+	return true
+}
+
 func (cfg *sharedConfig) hasCredentials() bool {
 	switch {
 	case len(cfg.SourceProfileName) != 0:
 	case len(cfg.CredentialSource) != 0:
 	case len(cfg.CredentialProcess) != 0:
 	case len(cfg.WebIdentityTokenFile) != 0:
+	case cfg.hasSSOConfiguration():
 	case cfg.Creds.HasKeys():
 	default:
 		return false
@@ -328,6 +472,10 @@ func (cfg *sharedConfig) clearCredentialOptions() {
 	cfg.CredentialSource = ""
 	cfg.CredentialProcess = ""
 	cfg.WebIdentityTokenFile = ""
+	cfg.SSOAccountID = ""
+	cfg.SSORegion = ""
+	cfg.SSORoleName = ""
+	cfg.SSOStartURL = ""
 	cfg.Creds = credentials.Value{}
 }
 
@@ -337,6 +485,17 @@ func (cfg *sharedConfig) clearAssumeRoleOptions() {
 	cfg.MFASerial = ""
 	cfg.RoleSessionName = ""
 	cfg.SourceProfileName = ""
+	cfg.AssumeRoleCacheEnabled = nil
+	cfg.AssumeRoleDuration = nil
+}
+
+func containsString(vs []string, v string) bool {
+	for _, s := range vs {
+		if s == v {
+			return true
+		}
+	}
+	return false
 }
 
 func oneOrNone(bs ...bool) bool {
@@ -363,6 +522,25 @@ func updateString(dst *string, section ini.Section, key string) {
 	*dst = section.String(key)
 }
 
+// updateDurationSecondsPtr will only update the dst with the duration of the
+// number of seconds in the section key, key is present in the section.
+// Returns an error if the value is not a positive number of seconds.
+func updateDurationSecondsPtr(dst **time.Duration, section ini.Section, key string) error {
+	if !section.Has(key) {
+		return nil
+	}
+
+	v := section.String(key)
+	seconds, err := strconv.Atoi(v)
+	if err != nil || seconds < 1 {
+		return fmt.Errorf("%s, must be a positive number of seconds", v)
+	}
+
+	*dst = new(time.Duration)
+	**dst = time.Duration(seconds) * time.Second
+	return nil
+}
+
 // updateBoolPtr will only update the dst with the value in the section key,
 // key is present in the section.
 func updateBoolPtr(dst **bool, section ini.Section, key string) {
@@ -489,3 +667,67 @@ func (e CredentialRequiresARNError) OrigErr() error {
 func (e CredentialRequiresARNError) Error() string {
 	return awserr.SprintError(e.Code(), e.Message(), "", nil)
 }
+
+// SharedConfigSSOIncompleteError is an error for a shared config profile that
+// only includes part of the AWS Single Sign-On (AWS SSO) configuration.
+type SharedConfigSSOIncompleteError struct {
+	// Profile name the AWS SSO configuration was in.
+	Profile string
+
+	// Keys of the AWS SSO configuration missing from the profile.
+	Missing []string
+}
+
+// Code is the short id of the error.
+func (e SharedConfigSSOIncompleteError) Code() string {
+	return "SharedConfigSSOIncompleteError"
+}
+
+// Message is the description of the error
+func (e SharedConfigSSOIncompleteError) Message() string {
+	return fmt.Sprintf(
+		"profile %s is configured for AWS SSO, but is missing required keys %s",
+		e.Profile, strings.Join(e.Missing, ", "),
+	)
+}
+
+// OrigErr is the underlying error that caused the failure.
+func (e SharedConfigSSOIncompleteError) OrigErr() error {
+	return nil
+}
+
+// Error satisfies the error interface.
+func (e SharedConfigSSOIncompleteError) Error() string {
+	return awserr.SprintError(e.Code(), e.Message(), "", nil)
+}
+
+// SharedConfigSourceProfileCycleError is an error for a shared config profile
+// whose chain of source profiles loops back to a profile already in the
+// chain. A profile may only be its own source profile.
+type SharedConfigSourceProfileCycleError struct {
+	// Profiles of the chain, ending with the profile sourced again.
+	Profiles []string
+}
+
+// Code is the short id of the error.
+func (e SharedConfigSourceProfileCycleError) Code() string {
+	return "SharedConfigSourceProfileCycleError"
+}
+
+// Message is the description of the error
+func (e SharedConfigSourceProfileCycleError) Message() string {
+	return fmt.Sprintf(
+		"source_profile cycle detected, %s",
+		strings.Join(e.Profiles, " -> "),
+	)
+}
+
+// OrigErr is the underlying error that caused the failure.
+func (e SharedConfigSourceProfileCycleError) OrigErr() error {
+	return nil
+}
+
+// Error satisfies the error interface.
+func (e SharedConfigSourceProfileCycleError) Error() string {
+	return awserr.SprintError(e.Code(), e.Message(), "", nil)
+}
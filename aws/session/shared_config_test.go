@@ -9,7 +9,10 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/internal/ini"
 )
@@ -76,6 +79,23 @@ func TestLoadSharedConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Filenames: []string{testConfigOtherFilename, testConfigFilename},
+			Profile:   "assume_role_w_cache",
+			Expected: sharedConfig{
+				RoleARN:                "assume_role_w_cache_role_arn",
+				RoleSessionName:        "assume_role_w_cache_session_name",
+				AssumeRoleCacheEnabled: aws.Bool(true),
+				SourceProfileName:      "complete_creds",
+				SourceProfile: &sharedConfig{
+					Creds: credentials.Value{
+						AccessKeyID:     "complete_creds_akid",
+						SecretAccessKey: "complete_creds_secret",
+						ProviderName:    fmt.Sprintf("SharedConfigCredentials: %s", testConfigFilename),
+					},
+				},
+			},
+		},
 		{
 			Filenames: []string{testConfigOtherFilename, testConfigFilename},
 			Profile:   "assume_role_invalid_source_profile",
@@ -177,6 +197,83 @@ func TestLoadSharedConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Filenames: []string{testConfigFilename},
+			Profile:   "sso_creds",
+			Expected: sharedConfig{
+				SSOAccountID: "012345678901",
+				SSORegion:    "us-west-2",
+				SSORoleName:  "TestRole",
+				SSOStartURL:  "https://127.0.0.1/start",
+			},
+		},
+		{
+			Filenames: []string{testConfigFilename},
+			Profile:   "source_sso_creds",
+			Expected: sharedConfig{
+				RoleARN:           "source_sso_creds_arn",
+				SourceProfileName: "sso_creds",
+				SourceProfile: &sharedConfig{
+					SSOAccountID: "012345678901",
+					SSORegion:    "us-west-2",
+					SSORoleName:  "TestRole",
+					SSOStartURL:  "https://127.0.0.1/start",
+				},
+			},
+		},
+		{
+			Filenames: []string{testConfigFilename},
+			Profile:   "sso_incomplete",
+			Err: SharedConfigSSOIncompleteError{
+				Profile: "sso_incomplete",
+				Missing: []string{ssoRegionKey, ssoRoleNameKey},
+			},
+		},
+		{
+			Filenames: []string{testConfigFilename},
+			Profile:   "retry_config",
+			Expected: sharedConfig{
+				RetryMode:   "adaptive",
+				MaxAttempts: "5",
+			},
+		},
+		{
+			Filenames: []string{testConfigFilename},
+			Profile:   "assume_role_chain_w_options",
+			Expected: sharedConfig{
+				RoleARN:             "assume_role_chain_w_options_role_arn",
+				ExternalID:          "assume_role_chain_w_options_external_id",
+				AssumeRoleDuration:  durationPtr(time.Hour),
+				STSRegionalEndpoint: "regional",
+				SourceProfileName:   "assume_role_w_options",
+				SourceProfile: &sharedConfig{
+					RoleARN:            "assume_role_w_options_role_arn",
+					MFASerial:          "0123456789",
+					AssumeRoleDuration: durationPtr(30 * time.Minute),
+					SourceProfileName:  "complete_creds",
+					SourceProfile: &sharedConfig{
+						Creds: credentials.Value{
+							AccessKeyID:     "complete_creds_akid",
+							SecretAccessKey: "complete_creds_secret",
+							ProviderName:    fmt.Sprintf("SharedConfigCredentials: %s", testConfigFilename),
+						},
+					},
+				},
+			},
+		},
+		{
+			Filenames: []string{testConfigFilename},
+			Profile:   "source_profile_cycle",
+			Err: SharedConfigSourceProfileCycleError{
+				Profiles: []string{"source_profile_cycle", "source_profile_cycle2", "source_profile_cycle"},
+			},
+		},
+		{
+			Filenames: []string{testConfigFilename},
+			Profile:   "invalid_duration_seconds",
+			Err: awserr.New(ErrCodeInvalidSTSConfig,
+				"invalid duration_seconds in profile invalid_duration_seconds, 15m, must be a positive number of seconds", nil),
+		},
 	}
 
 	for i, c := range cases {
@@ -353,3 +450,7 @@ func TestLoadSharedConfigIniFiles(t *testing.T) {
 		})
 	}
 }
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
@@ -0,0 +1,15 @@
+// +build go1.7
+
+package v4
+
+import (
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// requestContext returns the context of the HTTP request, used to retrieve
+// the credentials the request is signed with.
+func requestContext(r *http.Request) aws.Context {
+	return r.Context()
+}
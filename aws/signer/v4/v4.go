@@ -337,7 +337,7 @@ func (v4 Signer) signWithBody(r *http.Request, body io.ReadSeeker, service, regi
 	}
 
 	var err error
-	ctx.credValues, err = v4.Credentials.Get()
+	ctx.credValues, err = v4.Credentials.GetWithContext(requestContext(r))
 	if err != nil {
 		return http.Header{}, err
 	}
@@ -559,12 +559,7 @@ func (ctx *signingCtx) buildTime() {
 }
 
 func (ctx *signingCtx) buildCredentialString() {
-	ctx.credentialString = strings.Join([]string{
-		ctx.formattedShortTime,
-		ctx.Region,
-		ctx.ServiceName,
-		"aws4_request",
-	}, "/")
+	ctx.credentialString = BuildCredentialScope(ctx.Time, ctx.Region, ctx.ServiceName)
 
 	if ctx.isPresign {
 		ctx.Query.Set("X-Amz-Credential", ctx.credValues.AccessKeyID+"/"+ctx.credentialString)
@@ -660,15 +655,40 @@ func (ctx *signingCtx) buildStringToSign() {
 }
 
 func (ctx *signingCtx) buildSignature() {
-	secret := ctx.credValues.SecretAccessKey
-	date := makeHmac([]byte("AWS4"+secret), []byte(ctx.formattedShortTime))
-	region := makeHmac(date, []byte(ctx.Region))
-	service := makeHmac(region, []byte(ctx.ServiceName))
-	credentials := makeHmac(service, []byte("aws4_request"))
-	signature := makeHmac(credentials, []byte(ctx.stringToSign))
+	creds := deriveSigningKey(ctx.credValues.SecretAccessKey, ctx.formattedShortTime, ctx.Region, ctx.ServiceName)
+	signature := makeHmac(creds, []byte(ctx.stringToSign))
 	ctx.signature = hex.EncodeToString(signature)
 }
 
+// DeriveSigningKey returns the SigV4 signing key for the secret access key
+// scoped to the date of the signing time, region, and service. The key is
+// the same key the Signer uses to sign requests.
+//
+// The signing key can be used to sign values that are not HTTP requests,
+// such as Amazon S3 browser based upload POST policies, by computing the
+// HMAC-SHA256 of the value with the signing key.
+func DeriveSigningKey(secretAccessKey string, signTime time.Time, region, service string) []byte {
+	return deriveSigningKey(secretAccessKey, signTime.UTC().Format(shortTimeFormat), region, service)
+}
+
+// BuildCredentialScope returns the SigV4 credential scope for the signing
+// time, region, and service. e.g. "20190101/us-east-1/s3/aws4_request".
+func BuildCredentialScope(signTime time.Time, region, service string) string {
+	return strings.Join([]string{
+		signTime.UTC().Format(shortTimeFormat),
+		region,
+		service,
+		"aws4_request",
+	}, "/")
+}
+
+func deriveSigningKey(secret, shortTime, region, service string) []byte {
+	date := makeHmac([]byte("AWS4"+secret), []byte(shortTime))
+	regionKey := makeHmac(date, []byte(region))
+	serviceKey := makeHmac(regionKey, []byte(service))
+	return makeHmac(serviceKey, []byte("aws4_request"))
+}
+
 func (ctx *signingCtx) buildBodyDigest() error {
 	hash := ctx.Request.Header.Get("X-Amz-Content-Sha256")
 	if hash == "" {
@@ -2,6 +2,7 @@ package v4
 
 import (
 	"bytes"
+	"encoding/hex"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -765,3 +766,17 @@ func (r *readerSeekerWrapper) Seek(offset int64, whence int) (int64, error) {
 func (r *readerSeekerWrapper) Len() int {
 	return r.r.Len()
 }
+
+func TestDeriveSigningKey(t *testing.T) {
+	signTime := time.Date(2012, 2, 15, 0, 0, 0, 0, time.UTC)
+	key := DeriveSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", signTime, "us-east-1", "iam")
+
+	expect := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if e, a := expect, hex.EncodeToString(key); e != a {
+		t.Errorf("expect %v signing key, got %v", e, a)
+	}
+
+	if e, a := "20120215/us-east-1/iam/aws4_request", BuildCredentialScope(signTime, "us-east-1", "iam"); e != a {
+		t.Errorf("expect %v credential scope, got %v", e, a)
+	}
+}
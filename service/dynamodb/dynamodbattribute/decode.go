@@ -34,6 +34,23 @@ type Unmarshaler interface {
 	UnmarshalDynamoDBAttributeValue(*dynamodb.AttributeValue) error
 }
 
+// An UnmarshalFunc unmarshals an AttributeValue to a Go value of the type it
+// is registered with. Use this to provide custom unmarshaling for types which
+// do not implement the Unmarshaler interface, such as types of other packages.
+//
+// The out value is a non-nil pointer to a value of the registered type.
+//
+//     d := dynamodbattribute.NewDecoder()
+//     d.RegisterUnmarshalFunc(reflect.TypeOf(net.IP{}),
+//         func(av *dynamodb.AttributeValue, out interface{}) error {
+//             if av.S == nil {
+//                 return nil
+//             }
+//             *out.(*net.IP) = net.ParseIP(*av.S)
+//             return nil
+//         })
+type UnmarshalFunc func(av *dynamodb.AttributeValue, out interface{}) error
+
 // Unmarshal will unmarshal DynamoDB AttributeValues to Go value types.
 // Both generic interface{} and concrete types are valid unmarshal
 // destination types.
@@ -119,6 +136,13 @@ type Decoder struct {
 	// Number type instead of float64 when the destination type
 	// is interface{}. Similar to encoding/json.Number
 	UseNumber bool
+
+	// Custom unmarshal functions, by the Go type they unmarshal. A value of
+	// a type with an unmarshal function is unmarshaled with it, taking
+	// precedence over the Unmarshaler interface and the built-in
+	// unmarshaling, including inside of structs, maps, slices and sets. Use
+	// RegisterUnmarshalFunc to add an unmarshal function.
+	UnmarshalFuncs map[reflect.Type]UnmarshalFunc
 }
 
 // NewDecoder creates a new Decoder with default configuration. Use
@@ -136,6 +160,15 @@ func NewDecoder(opts ...func(*Decoder)) *Decoder {
 	return d
 }
 
+// RegisterUnmarshalFunc registers the function to unmarshal the values of the
+// Go type t, replacing any function previously registered for t.
+func (d *Decoder) RegisterUnmarshalFunc(t reflect.Type, fn UnmarshalFunc) {
+	if d.UnmarshalFuncs == nil {
+		d.UnmarshalFuncs = map[reflect.Type]UnmarshalFunc{}
+	}
+	d.UnmarshalFuncs[t] = fn
+}
+
 // Decode will unmarshal an AttributeValue into a Go value type. An error
 // will be return if the decoder is unable to unmarshal the AttributeValue
 // to the provide Go value type.
@@ -157,6 +190,10 @@ var numberType = reflect.TypeOf(Number(""))
 var timeType = reflect.TypeOf(time.Time{})
 
 func (d *Decoder) decode(av *dynamodb.AttributeValue, v reflect.Value, fieldTag tag) error {
+	if used, err := d.tryUnmarshalFunc(av, v); used {
+		return err
+	}
+
 	var u Unmarshaler
 	if av == nil || av.NULL != nil {
 		u, v = indirect(v, true)
@@ -288,6 +325,12 @@ func (d *Decoder) decodeBinarySet(bs [][]byte, v reflect.Value) error {
 		if !isArray {
 			v.SetLen(i + 1)
 		}
+		if used, err := d.tryUnmarshalFunc(&dynamodb.AttributeValue{B: bs[i]}, v.Index(i)); used {
+			if err != nil {
+				return err
+			}
+			continue
+		}
 		u, elem := indirect(v.Index(i), false)
 		if u != nil {
 			return u.UnmarshalDynamoDBAttributeValue(&dynamodb.AttributeValue{BS: bs})
@@ -415,6 +458,12 @@ func (d *Decoder) decodeNumberSet(ns []*string, v reflect.Value) error {
 		if !isArray {
 			v.SetLen(i + 1)
 		}
+		if used, err := d.tryUnmarshalFunc(&dynamodb.AttributeValue{N: ns[i]}, v.Index(i)); used {
+			if err != nil {
+				return err
+			}
+			continue
+		}
 		u, elem := indirect(v.Index(i), false)
 		if u != nil {
 			return u.UnmarshalDynamoDBAttributeValue(&dynamodb.AttributeValue{NS: ns})
@@ -588,6 +637,12 @@ func (d *Decoder) decodeStringSet(ss []*string, v reflect.Value) error {
 		if !isArray {
 			v.SetLen(i + 1)
 		}
+		if used, err := d.tryUnmarshalFunc(&dynamodb.AttributeValue{S: ss[i]}, v.Index(i)); used {
+			if err != nil {
+				return err
+			}
+			continue
+		}
 		u, elem := indirect(v.Index(i), false)
 		if u != nil {
 			return u.UnmarshalDynamoDBAttributeValue(&dynamodb.AttributeValue{SS: ss})
@@ -611,6 +666,50 @@ func decodeUnixTime(n string) (time.Time, error) {
 	return time.Unix(v, 0), nil
 }
 
+// tryUnmarshalFunc unmarshals the AttributeValue with the unmarshal function
+// registered for the value's type, or for the type of the value it points
+// to, allocating nil pointers as needed. A NULL AttributeValue leaves nil
+// pointers unset instead. Returns false if there is no such function.
+func (d *Decoder) tryUnmarshalFunc(av *dynamodb.AttributeValue, v reflect.Value) (bool, error) {
+	if len(d.UnmarshalFuncs) == 0 {
+		return false, nil
+	}
+
+	isNull := av == nil || av.NULL != nil
+	for {
+		if fn, ok := d.UnmarshalFuncs[v.Type()]; ok {
+			if !v.CanAddr() {
+				return false, nil
+			}
+			return true, fn(av, v.Addr().Interface())
+		}
+		if v.Kind() != reflect.Ptr {
+			return false, nil
+		}
+		if v.IsNil() {
+			if isNull || !v.CanSet() || !d.hasUnmarshalFunc(v.Type().Elem()) {
+				return false, nil
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+}
+
+// hasUnmarshalFunc returns if an unmarshal function is registered for the
+// type, or for the type it points to.
+func (d *Decoder) hasUnmarshalFunc(t reflect.Type) bool {
+	for {
+		if _, ok := d.UnmarshalFuncs[t]; ok {
+			return true
+		}
+		if t.Kind() != reflect.Ptr {
+			return false
+		}
+		t = t.Elem()
+	}
+}
+
 // indirect will walk a value's interface or pointer value types. Returning
 // the final value or the value a unmarshaler is defined on.
 //
@@ -2,6 +2,7 @@ package dynamodbattribute
 
 import (
 	"fmt"
+	"net"
 	"reflect"
 	"strconv"
 	"testing"
@@ -620,3 +621,111 @@ func TestDecodeAliasedUnixTime(t *testing.T) {
 		t.Errorf("expect %v, got %v", expect, actual)
 	}
 }
+
+func TestDecodeUnmarshalFuncs(t *testing.T) {
+	type A struct {
+		IP       net.IP
+		IPPtr    *net.IP
+		NilIP    *net.IP
+		IPs      []net.IP
+		IPSet    []net.IP
+		IPArray  [1]net.IP
+		IPMap    map[string]net.IP
+		IPPtrMap map[string]*net.IP
+		Time     time.Time
+		Custom   funcMarshalerValue
+	}
+
+	input := &dynamodb.AttributeValue{
+		M: map[string]*dynamodb.AttributeValue{
+			"IP":    {S: aws.String("10.0.0.1")},
+			"IPPtr": {S: aws.String("10.0.0.2")},
+			"NilIP": {NULL: aws.Bool(true)},
+			"IPs": {L: []*dynamodb.AttributeValue{
+				{S: aws.String("::1")},
+			}},
+			"IPSet":   {SS: []*string{aws.String("10.0.0.3"), aws.String("10.0.0.4")}},
+			"IPArray": {SS: []*string{aws.String("10.0.0.5")}},
+			"IPMap": {M: map[string]*dynamodb.AttributeValue{
+				"a": {S: aws.String("10.0.0.6")},
+			}},
+			"IPPtrMap": {M: map[string]*dynamodb.AttributeValue{
+				"a": {S: aws.String("10.0.0.7")},
+			}},
+			"Time":   {N: aws.String("123")},
+			"Custom": {S: aws.String("value")},
+		},
+	}
+
+	d := NewDecoder()
+	d.RegisterUnmarshalFunc(reflect.TypeOf(net.IP{}), func(av *dynamodb.AttributeValue, out interface{}) error {
+		if av.S == nil {
+			return fmt.Errorf("expect string IP, got %v", av)
+		}
+		*out.(*net.IP) = net.ParseIP(*av.S)
+		return nil
+	})
+	d.RegisterUnmarshalFunc(reflect.TypeOf(time.Time{}), func(av *dynamodb.AttributeValue, out interface{}) error {
+		n, err := strconv.ParseInt(aws.StringValue(av.N), 10, 64)
+		if err != nil {
+			return err
+		}
+		*out.(*time.Time) = time.Unix(n, 0)
+		return nil
+	})
+	d.RegisterUnmarshalFunc(reflect.TypeOf(funcMarshalerValue("")), func(av *dynamodb.AttributeValue, out interface{}) error {
+		*out.(*funcMarshalerValue) = funcMarshalerValue("func " + aws.StringValue(av.S))
+		return nil
+	})
+
+	var actual A
+	if err := d.Decode(input, &actual); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	ip := net.ParseIP("10.0.0.2")
+	ptrIP := net.ParseIP("10.0.0.7")
+	expect := A{
+		IP:       net.ParseIP("10.0.0.1"),
+		IPPtr:    &ip,
+		IPs:      []net.IP{net.IPv6loopback},
+		IPSet:    []net.IP{net.ParseIP("10.0.0.3"), net.ParseIP("10.0.0.4")},
+		IPArray:  [1]net.IP{net.ParseIP("10.0.0.5")},
+		IPMap:    map[string]net.IP{"a": net.ParseIP("10.0.0.6")},
+		IPPtrMap: map[string]*net.IP{"a": &ptrIP},
+		Time:     time.Unix(123, 0),
+		Custom:   "func value",
+	}
+	if e, a := expect, actual; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestDecodeUnmarshalFuncNull(t *testing.T) {
+	d := NewDecoder(func(d *Decoder) {
+		d.RegisterUnmarshalFunc(reflect.TypeOf(net.IP{}), func(av *dynamodb.AttributeValue, out interface{}) error {
+			*out.(*net.IP) = net.IPv4zero
+			return nil
+		})
+	})
+
+	var actual struct {
+		IP    net.IP
+		IPPtr *net.IP
+	}
+	err := d.Decode(&dynamodb.AttributeValue{
+		M: map[string]*dynamodb.AttributeValue{
+			"IP":    {NULL: aws.Bool(true)},
+			"IPPtr": {NULL: aws.Bool(true)},
+		},
+	}, &actual)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := net.IPv4zero, actual.IP; !e.Equal(a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if actual.IPPtr != nil {
+		t.Errorf("expect nil IP pointer, got %v", *actual.IPPtr)
+	}
+}
@@ -78,6 +78,29 @@
 //         return true // keep paging
 //     })
 //
+// Custom Marshal and Unmarshal Functions
+//
+// Types which cannot implement the Marshaler and Unmarshaler interfaces, such
+// as types of other packages, can be marshaled and unmarshaled by functions
+// registered with an Encoder or Decoder for their reflect.Type. The registered
+// functions take precedence over the Marshaler and Unmarshaler interfaces and
+// the built-in marshaling, and are also used for values within structs, maps,
+// lists and sets.
+//
+//     e := dynamodbattribute.NewEncoder()
+//     e.RegisterMarshalFunc(reflect.TypeOf(net.IP{}),
+//         func(in interface{}, av *dynamodb.AttributeValue) error {
+//             av.S = aws.String(in.(net.IP).String())
+//             return nil
+//         })
+//
+//     d := dynamodbattribute.NewDecoder()
+//     d.RegisterUnmarshalFunc(reflect.TypeOf(net.IP{}),
+//         func(av *dynamodb.AttributeValue, out interface{}) error {
+//             *out.(*net.IP) = net.ParseIP(aws.StringValue(av.S))
+//             return nil
+//         })
+//
 // The ConvertTo, ConvertToList, ConvertToMap, ConvertFrom, ConvertFromMap
 // and ConvertFromList methods have been deprecated. The Marshal and Unmarshal
 // functions should be used instead. The ConvertTo|From marshallers do not
@@ -67,6 +67,21 @@ type Marshaler interface {
 	MarshalDynamoDBAttributeValue(*dynamodb.AttributeValue) error
 }
 
+// A MarshalFunc marshals a Go value of the type it is registered with to
+// an AttributeValue. Use this to provide custom marshaling for types which
+// do not implement the Marshaler interface, such as types of other packages.
+//
+// The value passed in has the registered type, and may be a nil pointer
+// if the registered type is a pointer type.
+//
+//     e := dynamodbattribute.NewEncoder()
+//     e.RegisterMarshalFunc(reflect.TypeOf(net.IP{}),
+//         func(in interface{}, av *dynamodb.AttributeValue) error {
+//             av.S = aws.String(in.(net.IP).String())
+//             return nil
+//         })
+type MarshalFunc func(in interface{}, av *dynamodb.AttributeValue) error
+
 // Marshal will serialize the passed in Go value type into a DynamoDB AttributeValue
 // type. This value can be used in DynamoDB API operations to simplify marshaling
 // your Go value types into AttributeValues.
@@ -207,6 +222,13 @@ type Encoder struct {
 	//
 	// Enabled by default.
 	NullEmptyString bool
+
+	// Custom marshal functions, by the Go type they marshal. A value of a
+	// type with a marshal function is marshaled with it, taking precedence
+	// over the Marshaler interface and the built-in marshaling, including
+	// inside of structs, maps, slices and sets. Use RegisterMarshalFunc to
+	// add a marshal function.
+	MarshalFuncs map[reflect.Type]MarshalFunc
 }
 
 // NewEncoder creates a new Encoder with default configuration. Use
@@ -225,6 +247,15 @@ func NewEncoder(opts ...func(*Encoder)) *Encoder {
 	return e
 }
 
+// RegisterMarshalFunc registers the function to marshal the values of the
+// Go type t, replacing any function previously registered for t.
+func (e *Encoder) RegisterMarshalFunc(t reflect.Type, fn MarshalFunc) {
+	if e.MarshalFuncs == nil {
+		e.MarshalFuncs = map[reflect.Type]MarshalFunc{}
+	}
+	e.MarshalFuncs[t] = fn
+}
+
 // Encode will marshal a Go value type to an AttributeValue. Returning
 // the AttributeValue constructed or error.
 func (e *Encoder) Encode(in interface{}) (*dynamodb.AttributeValue, error) {
@@ -260,6 +291,10 @@ func (e *Encoder) encode(av *dynamodb.AttributeValue, v reflect.Value, fieldTag
 		return nil
 	}
 
+	if used, err := e.tryMarshalFunc(av, v); used {
+		return err
+	}
+
 	// Handle both pointers and interface conversion into types
 	v = valueElem(v)
 
@@ -563,6 +598,27 @@ func emptyValue(v reflect.Value) bool {
 	return false
 }
 
+// tryMarshalFunc marshals the value with the marshal function registered for
+// its type, or for the type of the value it points to. Returns false if
+// there is no such function.
+func (e *Encoder) tryMarshalFunc(av *dynamodb.AttributeValue, v reflect.Value) (bool, error) {
+	if len(e.MarshalFuncs) == 0 {
+		return false, nil
+	}
+
+	for v.IsValid() {
+		if fn, ok := e.MarshalFuncs[v.Type()]; ok && v.CanInterface() {
+			return true, fn(v.Interface(), av)
+		}
+		if (v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface) || v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+
+	return false, nil
+}
+
 func tryMarshaler(av *dynamodb.AttributeValue, v reflect.Value) (bool, error) {
 	if v.Kind() != reflect.Ptr && v.Type().Name() != "" && v.CanAddr() {
 		v = v.Addr()
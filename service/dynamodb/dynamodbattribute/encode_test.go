@@ -2,6 +2,7 @@ package dynamodbattribute
 
 import (
 	"fmt"
+	"net"
 	"reflect"
 	"testing"
 	"time"
@@ -269,3 +270,93 @@ func TestEncodeAliasedUnixTime(t *testing.T) {
 		t.Errorf("expect %v, got %v", e, a)
 	}
 }
+
+type funcMarshalerValue string
+
+func (v funcMarshalerValue) MarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	av.S = aws.String("marshaler " + string(v))
+	return nil
+}
+
+func (v *funcMarshalerValue) UnmarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	*v = funcMarshalerValue("unmarshaler " + aws.StringValue(av.S))
+	return nil
+}
+
+func TestEncodeMarshalFuncs(t *testing.T) {
+	type A struct {
+		IP     net.IP
+		IPPtr  *net.IP
+		NilIP  *net.IP
+		IPs    []net.IP
+		IPSet  []net.IP `dynamodbav:",stringset"`
+		IPMap  map[string]net.IP
+		Time   time.Time
+		Custom funcMarshalerValue
+	}
+
+	ip := net.IPv4(10, 0, 0, 2)
+	a := A{
+		IP:     net.IPv4(10, 0, 0, 1),
+		IPPtr:  &ip,
+		IPs:    []net.IP{net.IPv6loopback},
+		IPSet:  []net.IP{net.IPv4(10, 0, 0, 3), net.IPv4(10, 0, 0, 4)},
+		IPMap:  map[string]net.IP{"a": net.IPv4(10, 0, 0, 5)},
+		Time:   time.Unix(123, 0),
+		Custom: "value",
+	}
+
+	e := NewEncoder()
+	e.RegisterMarshalFunc(reflect.TypeOf(net.IP{}), func(in interface{}, av *dynamodb.AttributeValue) error {
+		av.S = aws.String(in.(net.IP).String())
+		return nil
+	})
+	e.RegisterMarshalFunc(reflect.TypeOf(time.Time{}), func(in interface{}, av *dynamodb.AttributeValue) error {
+		av.N = aws.String(fmt.Sprintf("%d", in.(time.Time).Unix()))
+		return nil
+	})
+	e.RegisterMarshalFunc(reflect.TypeOf(funcMarshalerValue("")), func(in interface{}, av *dynamodb.AttributeValue) error {
+		av.S = aws.String("func " + string(in.(funcMarshalerValue)))
+		return nil
+	})
+
+	actual, err := e.Encode(a)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	expect := &dynamodb.AttributeValue{
+		M: map[string]*dynamodb.AttributeValue{
+			"IP":    {S: aws.String("10.0.0.1")},
+			"IPPtr": {S: aws.String("10.0.0.2")},
+			"NilIP": {NULL: aws.Bool(true)},
+			"IPs": {L: []*dynamodb.AttributeValue{
+				{S: aws.String("::1")},
+			}},
+			"IPSet": {SS: []*string{aws.String("10.0.0.3"), aws.String("10.0.0.4")}},
+			"IPMap": {M: map[string]*dynamodb.AttributeValue{
+				"a": {S: aws.String("10.0.0.5")},
+			}},
+			"Time":   {N: aws.String("123")},
+			"Custom": {S: aws.String("func value")},
+		},
+	}
+	if e, a := expect, actual; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func TestEncodeMarshalFuncError(t *testing.T) {
+	e := NewEncoder(func(e *Encoder) {
+		e.RegisterMarshalFunc(reflect.TypeOf(net.IP{}), func(in interface{}, av *dynamodb.AttributeValue) error {
+			return fmt.Errorf("invalid IP")
+		})
+	})
+
+	_, err := e.Encode(map[string]net.IP{"a": net.IPv4(10, 0, 0, 1)})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := "invalid IP", err.Error(); e != a {
+		t.Errorf("expect %v error, got %v", e, a)
+	}
+}
@@ -0,0 +1,719 @@
+package dynamodbmanager
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/internal/sdkrand"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+const (
+	// MaxBatchWriteItems is the maximum number of put and delete requests
+	// in a single BatchWriteItem request.
+	MaxBatchWriteItems = 25
+
+	// MaxBatchWriteSize is the maximum total size, in bytes, of the items
+	// in a single BatchWriteItem request.
+	MaxBatchWriteSize = 16 * 1024 * 1024
+
+	// DefaultBatchWriteConcurrency is the default number of BatchWriteItem
+	// requests the BatchWriter makes at once.
+	DefaultBatchWriteConcurrency = 4
+
+	// DefaultBatchWriteMaxRetries is the default number of times the
+	// BatchWriter resubmits the unprocessed items of a batch.
+	DefaultBatchWriteMaxRetries = 10
+
+	// DefaultBatchWriteMinBackoff is the default delay before the first
+	// resubmission of the unprocessed items of a batch.
+	DefaultBatchWriteMinBackoff = 50 * time.Millisecond
+
+	// DefaultBatchWriteMaxBackoff is the default maximum delay between
+	// resubmissions of the unprocessed items of a batch.
+	DefaultBatchWriteMaxBackoff = 5 * time.Second
+)
+
+const (
+	// ErrCodeBatchWriteIncomplete is the code of the BatchWriteError
+	// returned when one or more write requests failed.
+	ErrCodeBatchWriteIncomplete = "BatchWriteIncomplete"
+
+	// ErrCodeUnprocessedItem is the code of the error of a write request
+	// whose item was still unprocessed after the BatchWriter's retries.
+	ErrCodeUnprocessedItem = "UnprocessedItem"
+
+	// ErrCodeInvalidWriteRequest is the code of the error of a write
+	// request which could not be added to a batch, e.g. because its item
+	// could not be marshaled or is missing key attributes.
+	ErrCodeInvalidWriteRequest = "InvalidWriteRequest"
+)
+
+// WriteRequest is a request to put an item into, or delete an item from, a
+// table. Either Item or Key must be set.
+//
+// Item and Key may be a map[string]*dynamodb.AttributeValue, which is used
+// as is, or any other value which is marshaled to a map of attribute values
+// with the BatchWriter's Encoder, or dynamodbattribute.MarshalMap if it has
+// none.
+type WriteRequest struct {
+	// The name of the table of the item.
+	TableName string
+
+	// The item to put into the table.
+	Item interface{}
+
+	// The primary key of the item to delete from the table.
+	Key interface{}
+}
+
+// NewPut returns a WriteRequest putting the item into the table.
+func NewPut(tableName string, item interface{}) WriteRequest {
+	return WriteRequest{TableName: tableName, Item: item}
+}
+
+// NewDelete returns a WriteRequest deleting the item with the key from the
+// table.
+func NewDelete(tableName string, key interface{}) WriteRequest {
+	return WriteRequest{TableName: tableName, Key: key}
+}
+
+// WriteRequestIterator is an interface that uses the scanner pattern to
+// iterate through the requests to write.
+type WriteRequestIterator interface {
+	Next() bool
+	Err() error
+	WriteRequest() WriteRequest
+}
+
+// WriteRequestsIterator implements the WriteRequestIterator interface,
+// iterating through a list of write requests.
+type WriteRequestsIterator struct {
+	Requests []WriteRequest
+	index    int
+	inc      bool
+}
+
+// Next will increment the default iterator's index and ensure that there
+// is another request to iterate to.
+func (iter *WriteRequestsIterator) Next() bool {
+	if iter.inc {
+		iter.index++
+	} else {
+		iter.inc = true
+	}
+	return iter.index < len(iter.Requests)
+}
+
+// Err will return an error. Since this is just used to satisfy the
+// WriteRequestIterator interface this will only return nil.
+func (iter *WriteRequestsIterator) Err() error {
+	return nil
+}
+
+// WriteRequest will return the current request.
+func (iter *WriteRequestsIterator) WriteRequest() WriteRequest {
+	return iter.Requests[iter.index]
+}
+
+// WriteRequestChanIterator implements the WriteRequestIterator interface,
+// iterating through the requests received from a channel until it is closed.
+type WriteRequestChanIterator struct {
+	C   <-chan WriteRequest
+	req WriteRequest
+}
+
+// Next receives the next request from the channel, returning false once the
+// channel is closed.
+func (iter *WriteRequestChanIterator) Next() bool {
+	req, ok := <-iter.C
+	iter.req = req
+	return ok
+}
+
+// Err will return an error. Since this is just used to satisfy the
+// WriteRequestIterator interface this will only return nil.
+func (iter *WriteRequestChanIterator) Err() error {
+	return nil
+}
+
+// WriteRequest will return the current request.
+func (iter *WriteRequestChanIterator) WriteRequest() WriteRequest {
+	return iter.req
+}
+
+// BatchWriteFailure is a write request which failed, and the reason it
+// failed.
+type BatchWriteFailure struct {
+	Request WriteRequest
+	Err     error
+}
+
+// BatchWriteError is the error returned by the BatchWriter when one or more
+// of the write requests failed. It satisfies the awserr.Error interface.
+type BatchWriteError struct {
+	Failures []BatchWriteFailure
+}
+
+// Code returns the ErrCodeBatchWriteIncomplete error code.
+func (e *BatchWriteError) Code() string {
+	return ErrCodeBatchWriteIncomplete
+}
+
+// Message returns the number of write requests which failed.
+func (e *BatchWriteError) Message() string {
+	return fmt.Sprintf("%d write requests failed", len(e.Failures))
+}
+
+// OrigErr returns the errors of the failed write requests.
+func (e *BatchWriteError) OrigErr() error {
+	return batchWriteErrors(e.Failures)
+}
+
+func (e *BatchWriteError) Error() string {
+	return awserr.SprintError(e.Code(), e.Message(), "", e.OrigErr())
+}
+
+type batchWriteErrors []BatchWriteFailure
+
+func (errs batchWriteErrors) Error() string {
+	var buf bytes.Buffer
+	for i, f := range errs {
+		fmt.Fprintf(&buf, "failed to write item to %q:\n%v", f.Request.TableName, f.Err)
+		if i+1 < len(errs) {
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String()
+}
+
+// WithBatchWriterRequestOptions appends to the BatchWriter's API request
+// options.
+func WithBatchWriterRequestOptions(opts ...request.Option) func(*BatchWriter) {
+	return func(w *BatchWriter) {
+		w.RequestOptions = append(w.RequestOptions, opts...)
+	}
+}
+
+// BatchWriter writes items to, and deletes items from, DynamoDB tables in
+// batches with the BatchWriteItem API operation.
+//
+// The write requests are grouped into batches of at most MaxBatchWriteItems
+// requests and MaxBatchWriteSize bytes, which may span several tables. If a
+// batch contains more than one request for the same item, only the last one
+// is written, since BatchWriteItem rejects batches with duplicate keys. A
+// batch with a request for an item of a batch still being written is only
+// sent once that batch completes, so the requests for an item are written in
+// order. The key attributes of each table are described with DescribeTable.
+//
+// The items DynamoDB leaves unprocessed are resubmitted after an
+// exponential, jittered, backoff, until they are processed or the retries
+// are exhausted.
+//
+// It is safe to call Write concurrently across goroutines.
+type BatchWriter struct {
+	// The number of batches to write at once. If zero, the
+	// DefaultBatchWriteConcurrency is used.
+	Concurrency int
+
+	// The number of times the unprocessed items of a batch are resubmitted
+	// before their requests fail. If zero, the DefaultBatchWriteMaxRetries
+	// is used.
+	MaxRetries int
+
+	// The delay before the first resubmission of a batch's unprocessed
+	// items. The delay doubles with each resubmission, up to MaxBackoff, and
+	// is randomly jittered by up to half. If zero, the
+	// DefaultBatchWriteMinBackoff and DefaultBatchWriteMaxBackoff are used.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// The Encoder used to marshal items and keys which are not maps of
+	// attribute values. If nil, dynamodbattribute.MarshalMap is used.
+	Encoder *dynamodbattribute.Encoder
+
+	// The client to use when writing to DynamoDB.
+	Client dynamodbiface.DynamoDBAPI
+
+	// List of request options that will be passed down to individual API
+	// operation requests made by the BatchWriter.
+	//
+	// These request options are only valid for BatchWriteItem and
+	// DescribeTable requests.
+	RequestOptions []request.Option
+}
+
+// NewBatchWriter returns a new BatchWriter using a DynamoDB client created
+// from the session, with the default configuration. Options can be passed in
+// to modify it.
+//
+// Example:
+//     sess := session.Must(session.NewSession())
+//
+//     // Write up to 10 batches at once.
+//     writer := dynamodbmanager.NewBatchWriter(sess, func(w *dynamodbmanager.BatchWriter) {
+//         w.Concurrency = 10
+//     })
+func NewBatchWriter(c client.ConfigProvider, options ...func(*BatchWriter)) *BatchWriter {
+	return NewBatchWriterWithClient(dynamodb.New(c), options...)
+}
+
+// NewBatchWriterWithClient returns a new BatchWriter using the DynamoDB
+// client, with the default configuration. Options can be passed in to modify
+// it.
+func NewBatchWriterWithClient(svc dynamodbiface.DynamoDBAPI, options ...func(*BatchWriter)) *BatchWriter {
+	w := &BatchWriter{
+		Concurrency: DefaultBatchWriteConcurrency,
+		MaxRetries:  DefaultBatchWriteMaxRetries,
+		MinBackoff:  DefaultBatchWriteMinBackoff,
+		MaxBackoff:  DefaultBatchWriteMaxBackoff,
+		Client:      svc,
+	}
+
+	for _, option := range options {
+		option(w)
+	}
+
+	return w
+}
+
+// Write writes the requests of the iterator in batches. If any of the
+// requests failed, a *BatchWriteError listing them is returned once all the
+// requests have been written.
+func (w BatchWriter) Write(iter WriteRequestIterator, options ...func(*BatchWriter)) error {
+	return w.WriteWithContext(aws.BackgroundContext(), iter, options...)
+}
+
+// WriteWithContext is the same as Write with the additional support for
+// Context input parameters. The Context must not be nil. A nil Context will
+// cause a panic. Use the Context to add deadlining, timeouts, etc. If the
+// Context is canceled, no further batches are written, and the error of the
+// Context is returned.
+func (w BatchWriter) WriteWithContext(ctx aws.Context, iter WriteRequestIterator, options ...func(*BatchWriter)) error {
+	for _, option := range options {
+		option(&w)
+	}
+	w.RequestOptions = append(w.RequestOptions[:len(w.RequestOptions):len(w.RequestOptions)],
+		request.WithAppendUserAgent("DynamoDBManager"))
+
+	if w.Concurrency <= 0 {
+		w.Concurrency = DefaultBatchWriteConcurrency
+	}
+	if w.MaxRetries <= 0 {
+		w.MaxRetries = DefaultBatchWriteMaxRetries
+	}
+	if w.MinBackoff <= 0 {
+		w.MinBackoff = DefaultBatchWriteMinBackoff
+	}
+	if w.MaxBackoff <= 0 {
+		w.MaxBackoff = DefaultBatchWriteMaxBackoff
+	}
+
+	b := &batchWriter{
+		ctx:      ctx,
+		cfg:      w,
+		batches:  make(chan *writeBatch, w.Concurrency),
+		keys:     map[string]*tableKeys{},
+		inflight: map[string]int{},
+	}
+	b.written = sync.NewCond(&b.m)
+	return b.write(iter)
+}
+
+// batchWriter is the state of a single call to Write.
+type batchWriter struct {
+	ctx aws.Context
+	cfg BatchWriter
+
+	batches chan *writeBatch
+	wg      sync.WaitGroup
+
+	m        sync.Mutex
+	keys     map[string]*tableKeys
+	failures []BatchWriteFailure
+
+	// The number of batches being written with a request for each item,
+	// and the condition signaled when a batch is written.
+	inflight map[string]int
+	written  *sync.Cond
+}
+
+// tableKeys are the names of the key attributes of a table, or the error
+// describing it.
+type tableKeys struct {
+	// Closed once the table has been described.
+	done chan struct{}
+
+	names []string
+	err   error
+}
+
+// writeEntry is a write request marshaled for BatchWriteItem.
+type writeEntry struct {
+	req  WriteRequest
+	wr   *dynamodb.WriteRequest
+	id   string
+	size int
+}
+
+// writeBatch is the requests of a single BatchWriteItem request, at most one
+// per item.
+type writeBatch struct {
+	entries []*writeEntry
+	index   map[string]int
+	size    int
+}
+
+func newWriteBatch() *writeBatch {
+	return &writeBatch{index: map[string]int{}}
+}
+
+// add adds the entry to the batch, replacing an earlier entry of the same
+// item. Returns false if the batch is full.
+func (b *writeBatch) add(e *writeEntry) bool {
+	if i, ok := b.index[e.id]; ok {
+		if b.size-b.entries[i].size+e.size > MaxBatchWriteSize {
+			return false
+		}
+		b.size += e.size - b.entries[i].size
+		b.entries[i] = e
+		return true
+	}
+
+	if len(b.entries) == MaxBatchWriteItems || b.size+e.size > MaxBatchWriteSize {
+		return false
+	}
+	b.index[e.id] = len(b.entries)
+	b.entries = append(b.entries, e)
+	b.size += e.size
+	return true
+}
+
+func (b *batchWriter) write(iter WriteRequestIterator) error {
+	for i := 0; i < b.cfg.Concurrency; i++ {
+		b.wg.Add(1)
+		go b.writer()
+	}
+
+	batch := newWriteBatch()
+	canceled := false
+	for !canceled && iter.Next() {
+		e, err := b.newEntry(iter.WriteRequest())
+		if err != nil {
+			b.fail(err, iter.WriteRequest())
+			continue
+		}
+
+		if !batch.add(e) {
+			canceled = !b.send(batch)
+			batch = newWriteBatch()
+			batch.add(e)
+		}
+	}
+	if !canceled && len(batch.entries) != 0 {
+		canceled = !b.send(batch)
+	}
+
+	close(b.batches)
+	b.wg.Wait()
+
+	if canceled {
+		return b.ctx.Err()
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(b.failures) != 0 {
+		return &BatchWriteError{Failures: b.failures}
+	}
+	return nil
+}
+
+// send queues the batch to be written, returning false if the context was
+// canceled first. The batch is queued once no batch with a request for the
+// same item is being written.
+func (b *batchWriter) send(batch *writeBatch) bool {
+	b.m.Lock()
+	for b.hasInflight(batch) {
+		b.written.Wait()
+	}
+	for _, e := range batch.entries {
+		b.inflight[e.id]++
+	}
+	b.m.Unlock()
+
+	select {
+	case b.batches <- batch:
+		return true
+	case <-b.ctx.Done():
+		return false
+	}
+}
+
+// hasInflight returns if a batch with a request for an item of the batch is
+// being written. The batchWriter must be locked.
+func (b *batchWriter) hasInflight(batch *writeBatch) bool {
+	for _, e := range batch.entries {
+		if b.inflight[e.id] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *batchWriter) writer() {
+	defer b.wg.Done()
+	for batch := range b.batches {
+		b.writeBatch(batch)
+
+		b.m.Lock()
+		for _, e := range batch.entries {
+			if b.inflight[e.id]--; b.inflight[e.id] == 0 {
+				delete(b.inflight, e.id)
+			}
+		}
+		b.written.Broadcast()
+		b.m.Unlock()
+	}
+}
+
+// writeBatch writes the batch, resubmitting its unprocessed items until they
+// are all processed, or the retries are exhausted.
+func (b *batchWriter) writeBatch(batch *writeBatch) {
+	pending := map[string]*writeEntry{}
+	items := map[string][]*dynamodb.WriteRequest{}
+	for _, e := range batch.entries {
+		pending[e.id] = e
+		items[e.req.TableName] = append(items[e.req.TableName], e.wr)
+	}
+
+	for retry := 0; ; retry++ {
+		out, err := b.cfg.Client.BatchWriteItemWithContext(b.ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: items,
+		}, b.cfg.RequestOptions...)
+		if err != nil {
+			b.failAll(err, pending)
+			return
+		}
+		if len(out.UnprocessedItems) == 0 {
+			return
+		}
+
+		unprocessed := map[string]*writeEntry{}
+		for table, wrs := range out.UnprocessedItems {
+			for _, wr := range wrs {
+				id, err := b.writeRequestID(table, wr)
+				if err != nil {
+					continue
+				}
+				if e, ok := pending[id]; ok {
+					unprocessed[id] = e
+				}
+			}
+		}
+		pending = unprocessed
+		if len(pending) == 0 {
+			return
+		}
+
+		if retry == b.cfg.MaxRetries {
+			b.failAll(awserr.New(ErrCodeUnprocessedItem,
+				fmt.Sprintf("item still unprocessed after %d retries", retry), nil), pending)
+			return
+		}
+		if err := aws.SleepWithContext(b.ctx, b.backoff(retry)); err != nil {
+			b.failAll(err, pending)
+			return
+		}
+		items = out.UnprocessedItems
+	}
+}
+
+// backoff returns the delay before the retry, doubling with each retry up to
+// the maximum backoff, less a random jitter of up to half.
+func (b *batchWriter) backoff(retry int) time.Duration {
+	d := b.cfg.MaxBackoff
+	if retry < 32 {
+		if v := b.cfg.MinBackoff << uint(retry); v > 0 && v < d {
+			d = v
+		}
+	}
+	half := int64(d / 2)
+	return time.Duration(half + sdkrand.SeededRand.Int63n(half+1))
+}
+
+func (b *batchWriter) fail(err error, req WriteRequest) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.failures = append(b.failures, BatchWriteFailure{Request: req, Err: err})
+}
+
+func (b *batchWriter) failAll(err error, entries map[string]*writeEntry) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		b.failures = append(b.failures, BatchWriteFailure{Request: entries[id].req, Err: err})
+	}
+}
+
+// newEntry marshals the write request, and identifies the item it writes by
+// its table and key.
+func (b *batchWriter) newEntry(req WriteRequest) (*writeEntry, error) {
+	if len(req.TableName) == 0 {
+		return nil, awserr.New(ErrCodeInvalidWriteRequest, "table name must be set", nil)
+	}
+	if (req.Item == nil) == (req.Key == nil) {
+		return nil, awserr.New(ErrCodeInvalidWriteRequest,
+			"exactly one of the item or key must be set", nil)
+	}
+
+	wr := &dynamodb.WriteRequest{}
+	var av map[string]*dynamodb.AttributeValue
+	var err error
+	if req.Item != nil {
+		av, err = b.marshalMap(req.Item)
+		wr.PutRequest = &dynamodb.PutRequest{Item: av}
+	} else {
+		av, err = b.marshalMap(req.Key)
+		wr.DeleteRequest = &dynamodb.DeleteRequest{Key: av}
+	}
+	if err != nil {
+		return nil, awserr.New(ErrCodeInvalidWriteRequest, "failed to marshal item", err)
+	}
+
+	size := ItemSize(av)
+	if size > MaxItemSize {
+		return nil, awserr.New(ErrCodeInvalidWriteRequest, fmt.Sprintf(
+			"item size of %d bytes exceeds the maximum of %d bytes", size, MaxItemSize), nil)
+	}
+
+	id, err := b.itemID(req.TableName, av)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writeEntry{req: req, wr: wr, id: id, size: size}, nil
+}
+
+func (b *batchWriter) marshalMap(v interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	if m, ok := v.(map[string]*dynamodb.AttributeValue); ok {
+		return m, nil
+	}
+	if b.cfg.Encoder == nil {
+		return dynamodbattribute.MarshalMap(v)
+	}
+
+	av, err := b.cfg.Encoder.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	if av == nil || av.M == nil {
+		return nil, fmt.Errorf("%T did not marshal to a map", v)
+	}
+	return av.M, nil
+}
+
+// writeRequestID identifies the item written by the BatchWriteItem request.
+func (b *batchWriter) writeRequestID(table string, wr *dynamodb.WriteRequest) (string, error) {
+	switch {
+	case wr.PutRequest != nil:
+		return b.itemID(table, wr.PutRequest.Item)
+	case wr.DeleteRequest != nil:
+		return b.itemID(table, wr.DeleteRequest.Key)
+	}
+	return "", awserr.New(ErrCodeInvalidWriteRequest, "empty write request", nil)
+}
+
+// itemID returns a string identifying the item by its table and key
+// attributes.
+func (b *batchWriter) itemID(table string, item map[string]*dynamodb.AttributeValue) (string, error) {
+	names, err := b.keyNames(table)
+	if err != nil {
+		return "", err
+	}
+	return keyString(table, names, item)
+}
+
+// keyString returns a string identifying the item by its table and the
+// values of its key attributes.
+func keyString(table string, names []string, item map[string]*dynamodb.AttributeValue) (string, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%q", table)
+	for _, name := range names {
+		av, ok := item[name]
+		if !ok || av == nil {
+			return "", awserr.New(ErrCodeInvalidWriteRequest,
+				fmt.Sprintf("item is missing key attribute %q", name), nil)
+		}
+		switch {
+		case av.S != nil:
+			fmt.Fprintf(&buf, " S%q", *av.S)
+		case av.N != nil:
+			// Numbers are compared by value, so 1 and 1.0 are the same key.
+			r, ok := new(big.Rat).SetString(*av.N)
+			if !ok {
+				return "", awserr.New(ErrCodeInvalidWriteRequest,
+					fmt.Sprintf("key attribute %q is not a valid number, %q", name, *av.N), nil)
+			}
+			fmt.Fprintf(&buf, " N%q", r.RatString())
+		case av.B != nil:
+			fmt.Fprintf(&buf, " B%q", av.B)
+		default:
+			return "", awserr.New(ErrCodeInvalidWriteRequest,
+				fmt.Sprintf("key attribute %q must be a string, number or binary", name), nil)
+		}
+	}
+	return buf.String(), nil
+}
+
+// keyNames returns the names of the key attributes of the table, describing
+// the table the first time. Concurrent callers wait for the table to be
+// described, without holding the batchWriter's lock.
+func (b *batchWriter) keyNames(table string) ([]string, error) {
+	b.m.Lock()
+	k, ok := b.keys[table]
+	if !ok {
+		k = &tableKeys{done: make(chan struct{})}
+		b.keys[table] = k
+	}
+	b.m.Unlock()
+
+	if ok {
+		<-k.done
+		return k.names, k.err
+	}
+	defer close(k.done)
+
+	out, err := b.cfg.Client.DescribeTableWithContext(b.ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(table),
+	}, b.cfg.RequestOptions...)
+	if err != nil {
+		k.err = err
+	} else if out.Table != nil {
+		for _, ks := range out.Table.KeySchema {
+			k.names = append(k.names, aws.StringValue(ks.AttributeName))
+		}
+	}
+	if k.err == nil && len(k.names) == 0 {
+		k.err = awserr.New(ErrCodeInvalidWriteRequest,
+			fmt.Sprintf("table %q has no key schema", table), nil)
+	}
+
+	return k.names, k.err
+}
@@ -0,0 +1,380 @@
+package dynamodbmanager_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+)
+
+type record struct {
+	ID    string
+	Sort  int `dynamodbav:",omitempty"`
+	Value string
+}
+
+// batchWriteSvc records the BatchWriteItem requests made to it, leaving the
+// items unprocessed the number of times returned by unprocessed.
+type batchWriteSvc struct {
+	dynamodbiface.DynamoDBAPI
+
+	m           sync.Mutex
+	keys        map[string][]string
+	requests    []map[string][]*dynamodb.WriteRequest
+	written     map[string]*dynamodb.WriteRequest
+	attempts    map[string]int
+	unprocessed func(id string) int
+	err         error
+}
+
+func newBatchWriteSvc() *batchWriteSvc {
+	return &batchWriteSvc{
+		keys: map[string][]string{
+			"table":  {"ID"},
+			"sorted": {"ID", "Sort"},
+		},
+		written:  map[string]*dynamodb.WriteRequest{},
+		attempts: map[string]int{},
+	}
+}
+
+func (s *batchWriteSvc) DescribeTableWithContext(ctx aws.Context, in *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	keys, ok := s.keys[*in.TableName]
+	if !ok {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "table not found", nil)
+	}
+
+	out := &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{}}
+	for _, k := range keys {
+		out.Table.KeySchema = append(out.Table.KeySchema, &dynamodb.KeySchemaElement{
+			AttributeName: aws.String(k),
+		})
+	}
+	return out, nil
+}
+
+func (s *batchWriteSvc) BatchWriteItemWithContext(ctx aws.Context, in *dynamodb.BatchWriteItemInput, opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.requests = append(s.requests, in.RequestItems)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	out := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]*dynamodb.WriteRequest{}}
+	n := 0
+	for table, wrs := range in.RequestItems {
+		for _, wr := range wrs {
+			n++
+			id := table + "/"
+			if wr.PutRequest != nil {
+				id += *wr.PutRequest.Item["ID"].S
+			} else {
+				id += *wr.DeleteRequest.Key["ID"].S
+			}
+			if s.unprocessed != nil && s.attempts[id] < s.unprocessed(id) {
+				s.attempts[id]++
+				out.UnprocessedItems[table] = append(out.UnprocessedItems[table], wr)
+				continue
+			}
+			s.written[id] = wr
+		}
+	}
+	if n > dynamodbmanager.MaxBatchWriteItems {
+		return nil, awserr.New("ValidationException", "too many items", nil)
+	}
+	return out, nil
+}
+
+func newWriter(svc dynamodbiface.DynamoDBAPI) *dynamodbmanager.BatchWriter {
+	return dynamodbmanager.NewBatchWriterWithClient(svc, func(w *dynamodbmanager.BatchWriter) {
+		w.MinBackoff = 1
+		w.MaxBackoff = 1
+	})
+}
+
+func TestBatchWriter_Chunks(t *testing.T) {
+	svc := newBatchWriteSvc()
+
+	var reqs []dynamodbmanager.WriteRequest
+	for i := 0; i < 60; i++ {
+		reqs = append(reqs, dynamodbmanager.NewPut("table", record{ID: fmt.Sprintf("item%d", i)}))
+	}
+	reqs = append(reqs, dynamodbmanager.NewDelete("sorted", map[string]*dynamodb.AttributeValue{
+		"ID":   {S: aws.String("item0")},
+		"Sort": {N: aws.String("1")},
+	}))
+
+	err := newWriter(svc).Write(&dynamodbmanager.WriteRequestsIterator{Requests: reqs})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := 3, len(svc.requests); e != a {
+		t.Fatalf("expect %d requests, got %d", e, a)
+	}
+	var sizes []int
+	for _, r := range svc.requests {
+		sizes = append(sizes, len(r["table"])+len(r["sorted"]))
+	}
+	if e, a := "[11 25 25]", fmt.Sprint(sortedInts(sizes)); e != a {
+		t.Errorf("expect batch sizes %v, got %v", e, a)
+	}
+
+	if e, a := 61, len(svc.written); e != a {
+		t.Errorf("expect %d items written, got %d", e, a)
+	}
+	if wr := svc.written["sorted/item0"]; wr == nil || wr.DeleteRequest == nil {
+		t.Errorf("expect item deleted, got %v", wr)
+	}
+	if e, a := "item7", *svc.written["table/item7"].PutRequest.Item["ID"].S; e != a {
+		t.Errorf("expect %v put, got %v", e, a)
+	}
+}
+
+func TestBatchWriter_Size(t *testing.T) {
+	svc := newBatchWriteSvc()
+
+	value := strings.Repeat("x", 300*1024)
+	var reqs []dynamodbmanager.WriteRequest
+	for i := 0; i < 30; i++ {
+		reqs = append(reqs, dynamodbmanager.NewPut("table", record{ID: fmt.Sprintf("item%d", i), Value: value}))
+	}
+	reqs = append(reqs, dynamodbmanager.NewPut("table", record{
+		ID: "large", Value: strings.Repeat("x", dynamodbmanager.MaxItemSize),
+	}))
+
+	err := newWriter(svc).Write(&dynamodbmanager.WriteRequestsIterator{Requests: reqs})
+	bErr, ok := err.(*dynamodbmanager.BatchWriteError)
+	if !ok {
+		t.Fatalf("expect *BatchWriteError, got %v", err)
+	}
+	if e, a := 1, len(bErr.Failures); e != a {
+		t.Fatalf("expect %d failures, got %d", e, a)
+	}
+	f := bErr.Failures[0]
+	if e, a := "large", f.Request.Item.(record).ID; e != a {
+		t.Errorf("expect %v failed, got %v", e, a)
+	}
+	if e, a := dynamodbmanager.ErrCodeInvalidWriteRequest, f.Err.(awserr.Error).Code(); e != a {
+		t.Errorf("expect %v code, got %v", e, a)
+	}
+
+	var sizes []int
+	for _, r := range svc.requests {
+		sizes = append(sizes, len(r["table"]))
+	}
+	if e, a := "[5 25]", fmt.Sprint(sortedInts(sizes)); e != a {
+		t.Errorf("expect batch sizes %v, got %v", e, a)
+	}
+}
+
+func TestBatchWriter_Duplicates(t *testing.T) {
+	svc := newBatchWriteSvc()
+
+	err := newWriter(svc).Write(&dynamodbmanager.WriteRequestsIterator{
+		Requests: []dynamodbmanager.WriteRequest{
+			dynamodbmanager.NewPut("table", record{ID: "a", Value: "first"}),
+			dynamodbmanager.NewPut("table", record{ID: "b"}),
+			dynamodbmanager.NewPut("table", record{ID: "a", Value: "second"}),
+			dynamodbmanager.NewPut("sorted", record{ID: "a", Sort: 1}),
+			dynamodbmanager.NewPut("sorted", record{ID: "a", Sort: 2}),
+			dynamodbmanager.NewPut("sorted", map[string]*dynamodb.AttributeValue{
+				"ID":   {S: aws.String("a")},
+				"Sort": {N: aws.String("2.0")},
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := 1, len(svc.requests); e != a {
+		t.Fatalf("expect %d requests, got %d", e, a)
+	}
+	if e, a := 2, len(svc.requests[0]["table"]); e != a {
+		t.Errorf("expect %d table items, got %d", e, a)
+	}
+	if e, a := 2, len(svc.requests[0]["sorted"]); e != a {
+		t.Errorf("expect %d sorted items, got %d", e, a)
+	}
+	if e, a := "second", *svc.written["table/a"].PutRequest.Item["Value"].S; e != a {
+		t.Errorf("expect last put %v written, got %v", e, a)
+	}
+}
+
+func TestBatchWriter_OrderAcrossBatches(t *testing.T) {
+	svc := newBatchWriteSvc()
+	// The put of "a" is left unprocessed, and retried, while the batch with
+	// its delete could otherwise be written.
+	svc.unprocessed = func(id string) int {
+		if id == "table/a" {
+			return 1
+		}
+		return 0
+	}
+
+	reqs := []dynamodbmanager.WriteRequest{dynamodbmanager.NewPut("table", record{ID: "a"})}
+	for i := 0; i < dynamodbmanager.MaxBatchWriteItems; i++ {
+		reqs = append(reqs, dynamodbmanager.NewPut("table", record{ID: fmt.Sprint(i)}))
+	}
+	// The first batch is full, so the delete is in the second batch.
+	reqs = append(reqs, dynamodbmanager.NewDelete("table", record{ID: "a"}))
+
+	err := newWriter(svc).Write(&dynamodbmanager.WriteRequestsIterator{Requests: reqs},
+		func(w *dynamodbmanager.BatchWriter) {
+			w.Concurrency = 2
+			w.MinBackoff = 20 * time.Millisecond
+			w.MaxBackoff = 20 * time.Millisecond
+		})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := 3, len(svc.requests); e != a {
+		t.Fatalf("expect %d requests, got %d", e, a)
+	}
+	if svc.written["table/a"].DeleteRequest == nil {
+		t.Errorf("expect the later delete to be written last")
+	}
+}
+
+func TestBatchWriter_Unprocessed(t *testing.T) {
+	svc := newBatchWriteSvc()
+	svc.unprocessed = func(id string) int {
+		if id == "table/item3" || id == "table/item4" {
+			return 2
+		}
+		return 0
+	}
+
+	var reqs []dynamodbmanager.WriteRequest
+	for i := 0; i < 10; i++ {
+		reqs = append(reqs, dynamodbmanager.NewPut("table", record{ID: fmt.Sprintf("item%d", i)}))
+	}
+
+	err := newWriter(svc).Write(&dynamodbmanager.WriteRequestsIterator{Requests: reqs})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := 3, len(svc.requests); e != a {
+		t.Fatalf("expect %d requests, got %d", e, a)
+	}
+	if e, a := 2, len(svc.requests[1]["table"]); e != a {
+		t.Errorf("expect %d items resubmitted, got %d", e, a)
+	}
+	if e, a := 10, len(svc.written); e != a {
+		t.Errorf("expect %d items written, got %d", e, a)
+	}
+}
+
+func TestBatchWriter_Failures(t *testing.T) {
+	svc := newBatchWriteSvc()
+	svc.unprocessed = func(id string) int {
+		if id == "table/b" {
+			return 100
+		}
+		return 0
+	}
+
+	w := newWriter(svc)
+	w.MaxRetries = 2
+	w.Concurrency = 1
+
+	err := w.Write(&dynamodbmanager.WriteRequestsIterator{
+		Requests: []dynamodbmanager.WriteRequest{
+			dynamodbmanager.NewPut("table", record{ID: "a"}),
+			dynamodbmanager.NewPut("table", record{ID: "b"}),
+			dynamodbmanager.NewPut("table", map[string]string{"Other": "c"}),
+			dynamodbmanager.NewPut("missing", record{ID: "d"}),
+			dynamodbmanager.NewPut("table", func() {}),
+			{TableName: "table"},
+		},
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	bErr, ok := err.(*dynamodbmanager.BatchWriteError)
+	if !ok {
+		t.Fatalf("expect *BatchWriteError, got %T", err)
+	}
+	if e, a := dynamodbmanager.ErrCodeBatchWriteIncomplete, bErr.Code(); e != a {
+		t.Errorf("expect %v code, got %v", e, a)
+	}
+
+	expect := []struct {
+		code  string
+		table string
+	}{
+		{dynamodbmanager.ErrCodeInvalidWriteRequest, "table"},
+		{dynamodb.ErrCodeResourceNotFoundException, "missing"},
+		{dynamodbmanager.ErrCodeInvalidWriteRequest, "table"},
+		{dynamodbmanager.ErrCodeInvalidWriteRequest, "table"},
+		{dynamodbmanager.ErrCodeUnprocessedItem, "table"},
+	}
+	if e, a := len(expect), len(bErr.Failures); e != a {
+		t.Fatalf("expect %d failures, got %d, %v", e, a, err)
+	}
+	for i, c := range expect {
+		f := bErr.Failures[i]
+		if e, a := c.code, f.Err.(awserr.Error).Code(); e != a {
+			t.Errorf("%d, expect %v code, got %v", i, e, a)
+		}
+		if e, a := c.table, f.Request.TableName; e != a {
+			t.Errorf("%d, expect %v table, got %v", i, e, a)
+		}
+	}
+	if e, a := "b", bErr.Failures[4].Request.Item.(record).ID; e != a {
+		t.Errorf("expect %v unprocessed, got %v", e, a)
+	}
+	if e, a := 3, len(svc.requests); e != a {
+		t.Errorf("expect %d requests, got %d", e, a)
+	}
+	if _, ok := svc.written["table/a"]; !ok {
+		t.Errorf("expect item a written")
+	}
+}
+
+func TestBatchWriter_RequestError(t *testing.T) {
+	svc := newBatchWriteSvc()
+	svc.err = awserr.New("ValidationException", "invalid", nil)
+
+	ch := make(chan dynamodbmanager.WriteRequest, 3)
+	ch <- dynamodbmanager.NewPut("table", record{ID: "a"})
+	ch <- dynamodbmanager.NewDelete("table", record{ID: "b"})
+	close(ch)
+
+	err := newWriter(svc).Write(&dynamodbmanager.WriteRequestChanIterator{C: ch})
+	bErr, ok := err.(*dynamodbmanager.BatchWriteError)
+	if !ok {
+		t.Fatalf("expect *BatchWriteError, got %v", err)
+	}
+	if e, a := 2, len(bErr.Failures); e != a {
+		t.Fatalf("expect %d failures, got %d", e, a)
+	}
+	for _, f := range bErr.Failures {
+		if e, a := svc.err, f.Err; e != a {
+			t.Errorf("expect %v error, got %v", e, a)
+		}
+	}
+}
+
+func sortedInts(v []int) []int {
+	for i := 1; i < len(v); i++ {
+		for j := i; j > 0 && v[j] < v[j-1]; j-- {
+			v[j], v[j-1] = v[j-1], v[j]
+		}
+	}
+	return v
+}
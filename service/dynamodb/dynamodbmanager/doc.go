@@ -0,0 +1,122 @@
+// Package dynamodbmanager provides utilities for the Amazon DynamoDB API
+// operations which work with many items at once, built on the marshaling of
+// the dynamodbattribute package.
+//
+// BatchWriter
+//
+// The BatchWriter writes a stream of put and delete requests, for one or more
+// tables, with BatchWriteItem. The requests are split into batches within the
+// limits of the BatchWriteItem API, which are written concurrently. Any items
+// left unprocessed by DynamoDB are resubmitted with backoff.
+//
+//     type Record struct {
+//         ID    string
+//         Value int
+//     }
+//
+//     writer := dynamodbmanager.NewBatchWriter(sess)
+//
+//     err := writer.Write(&dynamodbmanager.WriteRequestsIterator{
+//         Requests: []dynamodbmanager.WriteRequest{
+//             dynamodbmanager.NewPut("myTable", Record{ID: "a", Value: 1}),
+//             dynamodbmanager.NewDelete("myTable", map[string]string{"ID": "b"}),
+//         },
+//     })
+//     if err != nil {
+//         if bErr, ok := err.(*dynamodbmanager.BatchWriteError); ok {
+//             for _, f := range bErr.Failures {
+//                 fmt.Println(f.Request.TableName, f.Err)
+//             }
+//         }
+//     }
+//
+// Scanner
+//
+// The Scanner reads the items of a table with parallel segmented Scan
+// requests, or with Query requests, optionally limited to a budget of read
+// capacity units. The items are returned by an ItemIterator, which decodes
+// them into Go values, and records the progress of each segment as a
+// Checkpoint which a failed scan can be resumed from.
+//
+//     iter := dynamodbmanager.NewScanner(sess).Scan(&dynamodb.ScanInput{
+//         TableName: aws.String("myTable"),
+//     })
+//     defer iter.Close()
+//
+//     for iter.Next() {
+//         var r Record
+//         if err := iter.Decode(&r); err != nil {
+//             return err
+//         }
+//     }
+//     if err := iter.Err(); err != nil {
+//         saveCheckpoints(iter.Checkpoints())
+//         return err
+//     }
+//
+// Table
+//
+// The Table gets, puts, updates and deletes single items as Go structs. The
+// fields of the item's key, and optionally its version and time to live, are
+// marked with the hashkey, rangekey, version and ttl options of the
+// `dynamodbav` struct tag. Writes of versioned items are conditional on the
+// stored item's version, and updates only write the changed attributes.
+//
+//     type Record struct {
+//         ID      string `dynamodbav:"id,hashkey"`
+//         Version int64  `dynamodbav:"version,version"`
+//         Value   string `dynamodbav:"value"`
+//     }
+//
+//     table := dynamodbmanager.NewTable(sess, "myTable")
+//
+//     record := &Record{ID: "a"}
+//     if err := table.Get(record); err != nil {
+//         return err
+//     }
+//
+//     original := *record
+//     record.Value = "updated"
+//     if err := table.Update(record, &original); err != nil {
+//         return err
+//     }
+//
+// Transactions
+//
+// The TransactWriteBuilder and TransactGetBuilder build TransactWriteItems
+// and TransactGetItems requests from items, keys, and the update and
+// condition builders of the expression package, checking the limits of the
+// transaction APIs. The reasons a transaction was canceled are returned in a
+// TransactionCanceledError, mapped to the operations which caused them.
+//
+//     tx := dynamodbmanager.NewTransactWriteBuilder()
+//     tx.Put("myTable", record)
+//     tx.Update("counts", map[string]string{"ID": "records"},
+//         expression.Add(expression.Name("Count"), expression.Value(1)))
+//
+//     if _, err := tx.Write(svc); err != nil {
+//         return err
+//     }
+//
+// Item Sizes and Capacity Units
+//
+// ItemSize returns the size DynamoDB counts for an item, with the size rules
+// of each attribute value type, and MarshalItemSize the size of a Go value
+// marshaled to an item. The BatchWriter and TransactWriteBuilder use the
+// sizes to reject items larger than MaxItemSize before sending them, and to
+// keep requests within the size limits of their API operations.
+//
+// The read and write capacity units an operation consumes are estimated from
+// the sizes of the items it reads or writes, with GetItemCapacity,
+// PutItemCapacity, UpdateItemCapacity, DeleteItemCapacity and QueryCapacity.
+//
+//     size, err := dynamodbmanager.MarshalItemSize(record)
+//     if err != nil {
+//         return err
+//     }
+//     if size > dynamodbmanager.MaxItemSize {
+//         return fmt.Errorf("record too large, %d bytes", size)
+//     }
+//
+//     units := dynamodbmanager.ReadCapacityUnits(size, dynamodbmanager.StronglyConsistent)
+package dynamodbmanager
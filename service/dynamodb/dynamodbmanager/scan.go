@@ -0,0 +1,503 @@
+package dynamodbmanager
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DefaultScanSegments is the default number of segments a table is scanned
+// in.
+const DefaultScanSegments = 4
+
+// ErrCodeInvalidCheckpoint is the code of the error returned when the
+// checkpoints a scan is resumed from do not match its segments.
+const ErrCodeInvalidCheckpoint = "InvalidCheckpoint"
+
+// Checkpoint is the progress of a segment of a scan, or of a query, as of
+// the last item returned by its ItemIterator.
+type Checkpoint struct {
+	// The segment, and the total number of segments of the scan.
+	Segment       int64
+	TotalSegments int64
+
+	// The primary key of the last item of the last page of the segment read.
+	// Resuming from the checkpoint starts reading the segment after it.
+	LastEvaluatedKey map[string]*dynamodb.AttributeValue
+
+	// If all the items of the segment have been read.
+	Done bool
+}
+
+// WithScannerRequestOptions appends to the Scanner's API request options.
+func WithScannerRequestOptions(opts ...request.Option) func(*Scanner) {
+	return func(s *Scanner) {
+		s.RequestOptions = append(s.RequestOptions, opts...)
+	}
+}
+
+// Scanner reads the items of a table or index with parallel Scan requests,
+// or with Query requests, returning them with an ItemIterator.
+//
+// A scan is split into TotalSegments segments, with the Segment and
+// TotalSegments parameters of the Scan API operation, and Concurrency
+// segments are scanned at once. The progress of each segment is available
+// as a Checkpoint, which the scan can be resumed from.
+//
+// It is safe to call Scan and Query concurrently across goroutines.
+type Scanner struct {
+	// The number of segments a table is scanned in. If zero, the
+	// DefaultScanSegments is used. Ignored by Query.
+	TotalSegments int64
+
+	// The number of segments scanned at once. If zero, all segments are
+	// scanned at once.
+	Concurrency int
+
+	// The read capacity units per second the scan or query may consume, as
+	// returned in the ConsumedCapacity of its responses. Requests are
+	// delayed while the capacity consumed exceeds the budget. If zero, the
+	// consumed capacity is not limited.
+	ReadCapacity float64
+
+	// The checkpoints to resume reading from, as returned by the
+	// ItemIterator's Checkpoints method of an earlier scan or query with the
+	// same input. Segments which are done are not read again.
+	Checkpoints []Checkpoint
+
+	// The Decoder used to unmarshal items by the ItemIterator's Decode
+	// method. If nil, dynamodbattribute.UnmarshalMap is used.
+	Decoder *dynamodbattribute.Decoder
+
+	// The client to use when reading from DynamoDB.
+	Client dynamodbiface.DynamoDBAPI
+
+	// List of request options that will be passed down to individual API
+	// operation requests made by the Scanner.
+	RequestOptions []request.Option
+}
+
+// NewScanner returns a new Scanner using a DynamoDB client created from the
+// session, with the default configuration. Options can be passed in to
+// modify it.
+//
+// Example:
+//     sess := session.Must(session.NewSession())
+//
+//     // Scan in 8 segments, consuming at most 100 RCUs per second.
+//     scanner := dynamodbmanager.NewScanner(sess, func(s *dynamodbmanager.Scanner) {
+//         s.TotalSegments = 8
+//         s.ReadCapacity = 100
+//     })
+func NewScanner(c client.ConfigProvider, options ...func(*Scanner)) *Scanner {
+	return NewScannerWithClient(dynamodb.New(c), options...)
+}
+
+// NewScannerWithClient returns a new Scanner using the DynamoDB client, with
+// the default configuration. Options can be passed in to modify it.
+func NewScannerWithClient(svc dynamodbiface.DynamoDBAPI, options ...func(*Scanner)) *Scanner {
+	s := &Scanner{
+		TotalSegments: DefaultScanSegments,
+		Client:        svc,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// Scan returns an ItemIterator of the items of the scan, in segments.
+// The input's Segment, TotalSegments and ExclusiveStartKey are set by the
+// Scanner for each segment.
+//
+// Example:
+//     iter := scanner.Scan(&dynamodb.ScanInput{
+//         TableName: aws.String("myTable"),
+//     })
+//     defer iter.Close()
+//
+//     for iter.Next() {
+//         var r Record
+//         if err := iter.Decode(&r); err != nil {
+//             return err
+//         }
+//         // ...
+//     }
+//     if err := iter.Err(); err != nil {
+//         return err
+//     }
+func (s Scanner) Scan(input *dynamodb.ScanInput, options ...func(*Scanner)) *ItemIterator {
+	return s.ScanWithContext(aws.BackgroundContext(), input, options...)
+}
+
+// ScanWithContext is the same as Scan with the additional support for
+// Context input parameters. The Context must not be nil. A nil Context will
+// cause a panic. Use the Context to add deadlining, timeouts, etc.
+func (s Scanner) ScanWithContext(ctx aws.Context, input *dynamodb.ScanInput, options ...func(*Scanner)) *ItemIterator {
+	for _, option := range options {
+		option(&s)
+	}
+	if s.TotalSegments <= 0 {
+		s.TotalSegments = DefaultScanSegments
+	}
+	s.RequestOptions = append(s.RequestOptions[:len(s.RequestOptions):len(s.RequestOptions)],
+		request.WithAppendUserAgent("DynamoDBManager"))
+
+	return s.start(ctx, s.TotalSegments, func(segment int64, startKey map[string]*dynamodb.AttributeValue) (*page, error) {
+		in := *input
+		if s.TotalSegments > 1 {
+			in.Segment = aws.Int64(segment)
+			in.TotalSegments = aws.Int64(s.TotalSegments)
+		}
+		in.ExclusiveStartKey = startKey
+		if s.ReadCapacity > 0 && in.ReturnConsumedCapacity == nil {
+			in.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+		}
+
+		out, err := s.Client.ScanWithContext(ctx, &in, s.RequestOptions...)
+		if err != nil {
+			return nil, err
+		}
+		return &page{
+			items:    out.Items,
+			lastKey:  out.LastEvaluatedKey,
+			consumed: out.ConsumedCapacity,
+		}, nil
+	})
+}
+
+// Query returns an ItemIterator of the items of the query, as a single
+// segment. The input's ExclusiveStartKey is set by the Scanner for each
+// page.
+func (s Scanner) Query(input *dynamodb.QueryInput, options ...func(*Scanner)) *ItemIterator {
+	return s.QueryWithContext(aws.BackgroundContext(), input, options...)
+}
+
+// QueryWithContext is the same as Query with the additional support for
+// Context input parameters. The Context must not be nil. A nil Context will
+// cause a panic. Use the Context to add deadlining, timeouts, etc.
+func (s Scanner) QueryWithContext(ctx aws.Context, input *dynamodb.QueryInput, options ...func(*Scanner)) *ItemIterator {
+	for _, option := range options {
+		option(&s)
+	}
+	s.RequestOptions = append(s.RequestOptions[:len(s.RequestOptions):len(s.RequestOptions)],
+		request.WithAppendUserAgent("DynamoDBManager"))
+
+	return s.start(ctx, 1, func(segment int64, startKey map[string]*dynamodb.AttributeValue) (*page, error) {
+		in := *input
+		in.ExclusiveStartKey = startKey
+		if s.ReadCapacity > 0 && in.ReturnConsumedCapacity == nil {
+			in.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+		}
+
+		out, err := s.Client.QueryWithContext(ctx, &in, s.RequestOptions...)
+		if err != nil {
+			return nil, err
+		}
+		return &page{
+			items:    out.Items,
+			lastKey:  out.LastEvaluatedKey,
+			consumed: out.ConsumedCapacity,
+		}, nil
+	})
+}
+
+// page is a page of the items of a segment.
+type page struct {
+	segment  int64
+	items    []map[string]*dynamodb.AttributeValue
+	lastKey  map[string]*dynamodb.AttributeValue
+	consumed *dynamodb.ConsumedCapacity
+	err      error
+}
+
+type fetchFunc func(segment int64, startKey map[string]*dynamodb.AttributeValue) (*page, error)
+
+// start starts reading the segments with the fetch function, returning the
+// iterator of their items.
+func (s Scanner) start(ctx aws.Context, total int64, fetch fetchFunc) *ItemIterator {
+	concurrency := s.Concurrency
+	if concurrency <= 0 || int64(concurrency) > total {
+		concurrency = int(total)
+	}
+
+	iter := &ItemIterator{
+		ctx:         ctx,
+		decoder:     s.Decoder,
+		checkpoints: make([]Checkpoint, total),
+		pages:       make(chan *page, concurrency),
+		done:        make(chan struct{}),
+	}
+	for i := range iter.checkpoints {
+		iter.checkpoints[i] = Checkpoint{Segment: int64(i), TotalSegments: total}
+	}
+
+	if err := iter.resume(s.Checkpoints); err != nil {
+		iter.err = err
+		close(iter.pages)
+		return iter
+	}
+
+	var limit *capacityLimiter
+	if s.ReadCapacity > 0 {
+		limit = newCapacityLimiter(s.ReadCapacity)
+	}
+
+	segments := make(chan Checkpoint, total)
+	for _, c := range iter.checkpoints {
+		if !c.Done {
+			segments <- c
+		}
+	}
+	close(segments)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range segments {
+				if !iter.readSegment(c.Segment, c.LastEvaluatedKey, limit, fetch) {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(iter.pages)
+	}()
+
+	return iter
+}
+
+// readSegment reads the pages of the segment until it is done, returning
+// false if reading was stopped or failed.
+func (iter *ItemIterator) readSegment(segment int64, startKey map[string]*dynamodb.AttributeValue,
+	limit *capacityLimiter, fetch fetchFunc) bool {
+	for {
+		if limit != nil {
+			if err := limit.wait(iter.ctx, iter.done); err != nil {
+				iter.send(&page{segment: segment, err: err})
+				return false
+			}
+		}
+
+		p, err := fetch(segment, startKey)
+		if err != nil {
+			p = &page{err: err}
+		}
+		p.segment = segment
+		if limit != nil && p.consumed != nil {
+			limit.consume(aws.Float64Value(p.consumed.CapacityUnits))
+		}
+
+		if !iter.send(p) || p.err != nil {
+			return false
+		}
+		if len(p.lastKey) == 0 {
+			return true
+		}
+		startKey = p.lastKey
+	}
+}
+
+// send sends the page to the iterator, returning false if the iterator was
+// closed first.
+func (iter *ItemIterator) send(p *page) bool {
+	select {
+	case iter.pages <- p:
+		return true
+	case <-iter.done:
+		return false
+	case <-iter.ctx.Done():
+		return false
+	}
+}
+
+// ItemIterator iterates through the items read by a Scanner. The items of
+// each segment are returned in order, but the items of different segments
+// are interleaved. An ItemIterator must not be used concurrently.
+//
+// The ItemIterator should be closed when finished with it, to stop reading
+// items if not all of them were read.
+type ItemIterator struct {
+	ctx     aws.Context
+	decoder *dynamodbattribute.Decoder
+
+	pages chan *page
+	done  chan struct{}
+	once  sync.Once
+
+	page        *page
+	index       int
+	err         error
+	closed      bool
+	checkpoints []Checkpoint
+}
+
+// resume applies the checkpoints to the iterator's segments.
+func (iter *ItemIterator) resume(checkpoints []Checkpoint) error {
+	for _, c := range checkpoints {
+		if c.TotalSegments != int64(len(iter.checkpoints)) || c.Segment < 0 ||
+			c.Segment >= c.TotalSegments {
+			return awserr.New(ErrCodeInvalidCheckpoint, fmt.Sprintf(
+				"checkpoint of segment %d of %d does not match the %d segments read",
+				c.Segment, c.TotalSegments, len(iter.checkpoints)), nil)
+		}
+		iter.checkpoints[c.Segment] = c
+	}
+	return nil
+}
+
+// Next advances the iterator to the next item, returning false when there
+// are no more items, or reading them failed.
+func (iter *ItemIterator) Next() bool {
+	if iter.err != nil || iter.closed {
+		return false
+	}
+
+	if iter.page != nil {
+		iter.index++
+		if iter.index < len(iter.page.items) {
+			return true
+		}
+		iter.completePage()
+	}
+
+	for {
+		p, ok := <-iter.pages
+		if !ok {
+			if err := iter.ctx.Err(); err != nil {
+				iter.err = err
+			}
+			return false
+		}
+		if p.err != nil {
+			iter.err = p.err
+			iter.Close()
+			return false
+		}
+
+		iter.page, iter.index = p, 0
+		if len(p.items) != 0 {
+			return true
+		}
+		iter.completePage()
+	}
+}
+
+// completePage advances the checkpoint of the current page's segment past
+// the page.
+func (iter *ItemIterator) completePage() {
+	p := iter.page
+	iter.page = nil
+
+	c := &iter.checkpoints[p.segment]
+	c.LastEvaluatedKey = p.lastKey
+	c.Done = len(p.lastKey) == 0
+}
+
+// Item returns the current item.
+func (iter *ItemIterator) Item() map[string]*dynamodb.AttributeValue {
+	return iter.page.items[iter.index]
+}
+
+// Segment returns the segment of the current item.
+func (iter *ItemIterator) Segment() int64 {
+	return iter.page.segment
+}
+
+// Decode unmarshals the current item into out, with the Scanner's Decoder,
+// or dynamodbattribute.UnmarshalMap if it has none.
+func (iter *ItemIterator) Decode(out interface{}) error {
+	if iter.decoder == nil {
+		return dynamodbattribute.UnmarshalMap(iter.Item(), out)
+	}
+	return iter.decoder.Decode(&dynamodb.AttributeValue{M: iter.Item()}, out)
+}
+
+// Err returns the error which stopped the iterator, if any.
+func (iter *ItemIterator) Err() error {
+	return iter.err
+}
+
+// Checkpoints returns the progress of each segment. The checkpoint of a
+// segment advances past a page once all of its items have been returned by
+// Next, so items after the checkpoint may already have been returned.
+func (iter *ItemIterator) Checkpoints() []Checkpoint {
+	checkpoints := make([]Checkpoint, len(iter.checkpoints))
+	copy(checkpoints, iter.checkpoints)
+	return checkpoints
+}
+
+// Close stops reading items. Next returns false once the iterator is closed.
+func (iter *ItemIterator) Close() error {
+	iter.once.Do(func() {
+		close(iter.done)
+	})
+	iter.closed = true
+	return nil
+}
+
+var errIteratorClosed = awserr.New(request.CanceledErrorCode, "iterator closed", nil)
+
+// capacityLimiter delays requests while the capacity consumed exceeds the
+// rate of capacity units per second, allowing bursts of up to one second.
+type capacityLimiter struct {
+	m      sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newCapacityLimiter(rate float64) *capacityLimiter {
+	return &capacityLimiter{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// wait blocks until capacity is available.
+func (l *capacityLimiter) wait(ctx aws.Context, done <-chan struct{}) error {
+	for {
+		l.m.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.rate {
+			l.tokens = l.rate
+		}
+		l.last = now
+		tokens := l.tokens
+		l.m.Unlock()
+
+		if tokens > 0 {
+			return nil
+		}
+
+		t := time.NewTimer(time.Duration(-tokens/l.rate*float64(time.Second)) + time.Millisecond)
+		select {
+		case <-t.C:
+		case <-done:
+			t.Stop()
+			return errIteratorClosed
+		case <-ctx.Done():
+			t.Stop()
+			return awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+		}
+	}
+}
+
+// consume removes the capacity consumed by a request.
+func (l *capacityLimiter) consume(units float64) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.tokens -= units
+}
@@ -0,0 +1,334 @@
+package dynamodbmanager_test
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+)
+
+// scanSvc serves pages of a table of numbered items, splitting them into
+// segments by their number.
+type scanSvc struct {
+	dynamodbiface.DynamoDBAPI
+
+	items    int
+	pageSize int
+	consumed float64
+	fail     func(segment int64, start int) error
+
+	m      sync.Mutex
+	scans  []*dynamodb.ScanInput
+	querys []*dynamodb.QueryInput
+}
+
+// page returns the page of the items of the segment after the start key.
+func (s *scanSvc) page(segment, total int64, startKey map[string]*dynamodb.AttributeValue) (
+	[]map[string]*dynamodb.AttributeValue, map[string]*dynamodb.AttributeValue, error) {
+	start := 0
+	if startKey != nil {
+		start, _ = strconv.Atoi(*startKey["Sort"].N)
+		start++
+	}
+	if s.fail != nil {
+		if err := s.fail(segment, start); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	var last map[string]*dynamodb.AttributeValue
+	for i := start; i < s.items; i++ {
+		if int64(i)%total != segment {
+			continue
+		}
+		if len(items) == s.pageSize {
+			last = items[len(items)-1]
+			break
+		}
+		items = append(items, map[string]*dynamodb.AttributeValue{
+			"ID":    {S: aws.String(fmt.Sprintf("item%d", i))},
+			"Sort":  {N: aws.String(strconv.Itoa(i))},
+			"Value": {S: aws.String("value")},
+		})
+	}
+	return items, last, nil
+}
+
+func (s *scanSvc) ScanWithContext(ctx aws.Context, in *dynamodb.ScanInput, opts ...request.Option) (*dynamodb.ScanOutput, error) {
+	s.m.Lock()
+	s.scans = append(s.scans, in)
+	s.m.Unlock()
+
+	segment, total := aws.Int64Value(in.Segment), aws.Int64Value(in.TotalSegments)
+	if total == 0 {
+		total = 1
+	}
+	items, last, err := s.page(segment, total, in.ExclusiveStartKey)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dynamodb.ScanOutput{Items: items, LastEvaluatedKey: last}
+	if in.ReturnConsumedCapacity != nil {
+		out.ConsumedCapacity = &dynamodb.ConsumedCapacity{CapacityUnits: aws.Float64(s.consumed)}
+	}
+	return out, nil
+}
+
+func (s *scanSvc) QueryWithContext(ctx aws.Context, in *dynamodb.QueryInput, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	s.m.Lock()
+	s.querys = append(s.querys, in)
+	s.m.Unlock()
+
+	items, last, err := s.page(0, 1, in.ExclusiveStartKey)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.QueryOutput{Items: items, LastEvaluatedKey: last}, nil
+}
+
+// readAll returns the items of the iterator by their Sort attribute.
+func readAll(t *testing.T, iter *dynamodbmanager.ItemIterator, max int) map[int]record {
+	items := map[int]record{}
+	for len(items) < max && iter.Next() {
+		var r record
+		if err := iter.Decode(&r); err != nil {
+			t.Fatalf("expect no decode error, got %v", err)
+		}
+		if e, a := int64(r.Sort%4), iter.Segment(); e != a && iter.Checkpoints()[0].TotalSegments == 4 {
+			t.Errorf("expect item %d in segment %d, got %d", r.Sort, e, a)
+		}
+		if _, ok := items[r.Sort]; ok {
+			t.Errorf("expect item %d read once", r.Sort)
+		}
+		items[r.Sort] = r
+	}
+	return items
+}
+
+func TestScanner_Scan(t *testing.T) {
+	svc := &scanSvc{items: 100, pageSize: 7}
+
+	scanner := dynamodbmanager.NewScannerWithClient(svc)
+	iter := scanner.Scan(&dynamodb.ScanInput{TableName: aws.String("table")})
+	defer iter.Close()
+
+	items := readAll(t, iter, 1000)
+	if err := iter.Err(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 100, len(items); e != a {
+		t.Errorf("expect %d items, got %d", e, a)
+	}
+	if e, a := "item42", items[42].ID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	segments := map[int64]int{}
+	for _, in := range svc.scans {
+		if e, a := "table", *in.TableName; e != a {
+			t.Errorf("expect %v table, got %v", e, a)
+		}
+		if e, a := int64(4), *in.TotalSegments; e != a {
+			t.Errorf("expect %d total segments, got %d", e, a)
+		}
+		if in.ReturnConsumedCapacity != nil {
+			t.Errorf("expect no consumed capacity returned, got %v", *in.ReturnConsumedCapacity)
+		}
+		segments[*in.Segment]++
+	}
+	// Each segment has 25 items, in 4 pages of 7.
+	if e, a := "map[0:4 1:4 2:4 3:4]", fmt.Sprint(segments); e != a {
+		t.Errorf("expect %v requests per segment, got %v", e, a)
+	}
+
+	for i, c := range iter.Checkpoints() {
+		if e, a := int64(i), c.Segment; e != a {
+			t.Errorf("expect checkpoint of segment %d, got %d", e, a)
+		}
+		if !c.Done {
+			t.Errorf("expect segment %d done", i)
+		}
+	}
+}
+
+func TestScanner_Resume(t *testing.T) {
+	svc := &scanSvc{items: 100, pageSize: 5}
+
+	scanner := dynamodbmanager.NewScannerWithClient(svc, func(s *dynamodbmanager.Scanner) {
+		s.Concurrency = 2
+	})
+	iter := scanner.Scan(&dynamodb.ScanInput{TableName: aws.String("table")})
+	first := readAll(t, iter, 40)
+	iter.Close()
+
+	if iter.Next() {
+		t.Errorf("expect no items after close")
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	checkpoints := iter.Checkpoints()
+	var done int
+	for _, c := range checkpoints {
+		if c.Done {
+			done++
+		}
+	}
+	if done == len(checkpoints) {
+		t.Fatalf("expect segments not done")
+	}
+
+	iter = scanner.Scan(&dynamodb.ScanInput{TableName: aws.String("table")},
+		func(s *dynamodbmanager.Scanner) {
+			s.Checkpoints = checkpoints
+		})
+	defer iter.Close()
+	second := readAll(t, iter, 1000)
+	if err := iter.Err(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		_, ok1 := first[i]
+		_, ok2 := second[i]
+		if !ok1 && !ok2 {
+			t.Errorf("expect item %d read", i)
+		}
+	}
+	if len(second) >= 100 {
+		t.Errorf("expect resumed scan to skip items, read %d", len(second))
+	}
+}
+
+func TestScanner_InvalidCheckpoint(t *testing.T) {
+	svc := &scanSvc{items: 10, pageSize: 5}
+
+	iter := dynamodbmanager.NewScannerWithClient(svc).Scan(&dynamodb.ScanInput{},
+		func(s *dynamodbmanager.Scanner) {
+			s.Checkpoints = []dynamodbmanager.Checkpoint{{Segment: 1, TotalSegments: 2}}
+		})
+	defer iter.Close()
+
+	if iter.Next() {
+		t.Fatalf("expect no items")
+	}
+	aerr, ok := iter.Err().(awserr.Error)
+	if !ok {
+		t.Fatalf("expect awserr.Error, got %v", iter.Err())
+	}
+	if e, a := dynamodbmanager.ErrCodeInvalidCheckpoint, aerr.Code(); e != a {
+		t.Errorf("expect %v code, got %v", e, a)
+	}
+	if e, a := 0, len(svc.scans); e != a {
+		t.Errorf("expect %d requests, got %d", e, a)
+	}
+}
+
+func TestScanner_Error(t *testing.T) {
+	svc := &scanSvc{items: 100, pageSize: 5}
+	svc.fail = func(segment int64, start int) error {
+		if segment == 2 && start > 0 {
+			return awserr.New("InternalServerError", "failed", nil)
+		}
+		return nil
+	}
+
+	iter := dynamodbmanager.NewScannerWithClient(svc).Scan(&dynamodb.ScanInput{})
+	defer iter.Close()
+
+	for iter.Next() {
+	}
+	aerr, ok := iter.Err().(awserr.Error)
+	if !ok {
+		t.Fatalf("expect awserr.Error, got %v", iter.Err())
+	}
+	if e, a := "InternalServerError", aerr.Code(); e != a {
+		t.Errorf("expect %v code, got %v", e, a)
+	}
+
+	c := iter.Checkpoints()[2]
+	if c.Done {
+		t.Errorf("expect failed segment not done")
+	}
+	if e, a := "18", *c.LastEvaluatedKey["Sort"].N; e != a {
+		t.Errorf("expect checkpoint at %v, got %v", e, a)
+	}
+}
+
+func TestScanner_ReadCapacity(t *testing.T) {
+	svc := &scanSvc{items: 30, pageSize: 2, consumed: 100}
+
+	scanner := dynamodbmanager.NewScannerWithClient(svc, func(s *dynamodbmanager.Scanner) {
+		s.TotalSegments = 1
+		s.ReadCapacity = 1000
+	})
+
+	start := time.Now()
+	iter := scanner.Scan(&dynamodb.ScanInput{})
+	defer iter.Close()
+	items := readAll(t, iter, 1000)
+	elapsed := time.Since(start)
+
+	if err := iter.Err(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 30, len(items); e != a {
+		t.Errorf("expect %d items, got %d", e, a)
+	}
+	if e, a := 15, len(svc.scans); e != a {
+		t.Fatalf("expect %d requests, got %d", e, a)
+	}
+	if e, a := dynamodb.ReturnConsumedCapacityTotal, aws.StringValue(svc.scans[0].ReturnConsumedCapacity); e != a {
+		t.Errorf("expect %v consumed capacity returned, got %v", e, a)
+	}
+	if svc.scans[0].Segment != nil {
+		t.Errorf("expect no segment for a single segment scan")
+	}
+	// 1500 units consumed, with a burst of 1000 units, at 1000 units per
+	// second.
+	if e, a := 350*time.Millisecond, elapsed; a < e {
+		t.Errorf("expect scan to take at least %v, took %v", e, a)
+	}
+}
+
+func TestScanner_Query(t *testing.T) {
+	svc := &scanSvc{items: 23, pageSize: 10}
+
+	iter := dynamodbmanager.NewScannerWithClient(svc).Query(&dynamodb.QueryInput{
+		TableName: aws.String("table"),
+	})
+	defer iter.Close()
+
+	items := readAll(t, iter, 1000)
+	if err := iter.Err(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 23, len(items); e != a {
+		t.Errorf("expect %d items, got %d", e, a)
+	}
+	if e, a := 3, len(svc.querys); e != a {
+		t.Errorf("expect %d requests, got %d", e, a)
+	}
+	if e, a := "19", *svc.querys[2].ExclusiveStartKey["Sort"].N; e != a {
+		t.Errorf("expect start key %v, got %v", e, a)
+	}
+
+	checkpoints := iter.Checkpoints()
+	if e, a := 1, len(checkpoints); e != a {
+		t.Fatalf("expect %d checkpoints, got %d", e, a)
+	}
+	if !checkpoints[0].Done {
+		t.Errorf("expect query done")
+	}
+}
@@ -0,0 +1,225 @@
+package dynamodbmanager
+
+import (
+	"math"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	// MaxItemSize is the maximum size, in bytes, of an item.
+	MaxItemSize = 400 * 1024
+
+	// MaxPageSize is the maximum size, in bytes, of the items read by a
+	// single Query or Scan request, before the filter expression is applied.
+	MaxPageSize = 1024 * 1024
+
+	// ReadCapacityUnitSize is the size, in bytes, of the items a strongly
+	// consistent read capacity unit reads.
+	ReadCapacityUnitSize = 4 * 1024
+
+	// WriteCapacityUnitSize is the size, in bytes, of the item a write
+	// capacity unit writes.
+	WriteCapacityUnitSize = 1024
+)
+
+// ReadConsistency is the consistency of a read, which determines the read
+// capacity units it consumes.
+type ReadConsistency int
+
+// The consistencies of reads.
+const (
+	// An eventually consistent read consumes half a read capacity unit per
+	// 4 KB.
+	EventuallyConsistent ReadConsistency = iota
+
+	// A strongly consistent read consumes one read capacity unit per 4 KB.
+	StronglyConsistent
+
+	// A transactional read, with TransactGetItems, consumes two read
+	// capacity units per 4 KB.
+	Transactional
+)
+
+// ItemSize returns the size, in bytes, DynamoDB counts for the item, the sum
+// of the lengths of its attribute names and the sizes of their values. An
+// item may not be larger than MaxItemSize.
+func ItemSize(item map[string]*dynamodb.AttributeValue) int {
+	var n int
+	for name, av := range item {
+		n += len(name) + AttributeValueSize(av)
+	}
+	return n
+}
+
+// MarshalItemSize returns the size, in bytes, of the item the value is
+// marshaled to with dynamodbattribute.MarshalMap. The value may also be a
+// map[string]*dynamodb.AttributeValue, which is used as is.
+func MarshalItemSize(in interface{}) (int, error) {
+	item, err := marshalTransactMap(in)
+	if err != nil {
+		return 0, err
+	}
+	return ItemSize(item), nil
+}
+
+// AttributeValueSize returns the size, in bytes, of the attribute value.
+// Strings and binary values are their length, numbers are approximately one
+// byte per two significant digits, and lists and maps have an overhead of
+// three bytes plus one byte per element.
+func AttributeValueSize(av *dynamodb.AttributeValue) int {
+	if av == nil {
+		return 0
+	}
+
+	switch {
+	case av.S != nil:
+		return len(*av.S)
+	case av.N != nil:
+		return numberSize(*av.N)
+	case av.B != nil:
+		return len(av.B)
+	case av.BOOL != nil, av.NULL != nil:
+		return 1
+	case av.SS != nil:
+		var n int
+		for _, s := range av.SS {
+			if s != nil {
+				n += len(*s)
+			}
+		}
+		return n
+	case av.NS != nil:
+		var n int
+		for _, s := range av.NS {
+			if s != nil {
+				n += numberSize(*s)
+			}
+		}
+		return n
+	case av.BS != nil:
+		var n int
+		for _, b := range av.BS {
+			n += len(b)
+		}
+		return n
+	case av.L != nil:
+		n := 3
+		for _, v := range av.L {
+			n += 1 + AttributeValueSize(v)
+		}
+		return n
+	case av.M != nil:
+		n := 3
+		for k, v := range av.M {
+			n += 1 + len(k) + AttributeValueSize(v)
+		}
+		return n
+	}
+	return 0
+}
+
+// numberSize returns the size of the number, one byte per two significant
+// digits plus one byte.
+func numberSize(n string) int {
+	n = strings.TrimLeft(n, "+-")
+	if i := strings.IndexAny(n, "eE"); i >= 0 {
+		n = n[:i]
+	}
+	if strings.Contains(n, ".") {
+		n = strings.TrimRight(n, "0")
+	}
+	n = strings.Replace(n, ".", "", 1)
+	n = strings.TrimLeft(n, "0")
+	n = strings.TrimRight(n, "0")
+
+	return (len(n)+1)/2 + 1
+}
+
+// ReadCapacityUnits returns the read capacity units consumed reading items of
+// the total size, in bytes, with the consistency. The size is rounded up to
+// the next 4 KB, and a read consumes at least the units of 4 KB, even if it
+// reads no items.
+func ReadCapacityUnits(size int, consistency ReadConsistency) float64 {
+	units := capacityUnits(size, ReadCapacityUnitSize)
+	switch consistency {
+	case EventuallyConsistent:
+		return units / 2
+	case Transactional:
+		return units * 2
+	}
+	return units
+}
+
+// WriteCapacityUnits returns the write capacity units consumed writing an
+// item of the size, in bytes. The size is rounded up to the next 1 KB, and a
+// write consumes at least one unit. Transactional writes, with
+// TransactWriteItems, consume twice the units.
+func WriteCapacityUnits(size int, transactional bool) float64 {
+	units := capacityUnits(size, WriteCapacityUnitSize)
+	if transactional {
+		return units * 2
+	}
+	return units
+}
+
+func capacityUnits(size, unitSize int) float64 {
+	if size <= 0 {
+		return 1
+	}
+	return math.Ceil(float64(size) / float64(unitSize))
+}
+
+// GetItemCapacity returns the read capacity units consumed by a GetItem
+// request, or an operation of a TransactGetItems request, reading the item.
+// The item is nil if it does not exist. The size of the whole item is
+// counted, even if a projection expression returns only some of its
+// attributes.
+func GetItemCapacity(item map[string]*dynamodb.AttributeValue, consistency ReadConsistency) float64 {
+	return ReadCapacityUnits(ItemSize(item), consistency)
+}
+
+// PutItemCapacity returns the write capacity units consumed by a PutItem
+// request, or a put operation of a TransactWriteItems request, replacing the
+// old item with the new item. The old item is nil if it does not exist. The
+// larger of the two items is counted.
+func PutItemCapacity(newItem, oldItem map[string]*dynamodb.AttributeValue, transactional bool) float64 {
+	return WriteCapacityUnits(maxInt(ItemSize(newItem), ItemSize(oldItem)), transactional)
+}
+
+// UpdateItemCapacity returns the write capacity units consumed by an
+// UpdateItem request, or an update operation of a TransactWriteItems request,
+// updating the old item to the new item. The old item is nil if it does not
+// exist. The larger of the item before and after the update is counted.
+func UpdateItemCapacity(newItem, oldItem map[string]*dynamodb.AttributeValue, transactional bool) float64 {
+	return WriteCapacityUnits(maxInt(ItemSize(newItem), ItemSize(oldItem)), transactional)
+}
+
+// DeleteItemCapacity returns the write capacity units consumed by a
+// DeleteItem request, or a delete operation of a TransactWriteItems request,
+// deleting the item. The item is nil if it does not exist.
+func DeleteItemCapacity(item map[string]*dynamodb.AttributeValue, transactional bool) float64 {
+	return WriteCapacityUnits(ItemSize(item), transactional)
+}
+
+// QueryCapacity returns the read capacity units consumed by a Query or Scan
+// request reading the items, before its filter expression is applied. The
+// sizes of the items are summed before being rounded up to the next 4 KB.
+func QueryCapacity(items []map[string]*dynamodb.AttributeValue, consistentRead bool) float64 {
+	var size int
+	for _, item := range items {
+		size += ItemSize(item)
+	}
+	if consistentRead {
+		return ReadCapacityUnits(size, StronglyConsistent)
+	}
+	return ReadCapacityUnits(size, EventuallyConsistent)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
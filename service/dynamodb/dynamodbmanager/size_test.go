@@ -0,0 +1,112 @@
+package dynamodbmanager_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+)
+
+func TestItemSize(t *testing.T) {
+	cases := []struct {
+		item   map[string]*dynamodb.AttributeValue
+		expect int
+	}{
+		{nil, 0},
+		{map[string]*dynamodb.AttributeValue{"id": {S: aws.String("abc")}}, 5},
+		{map[string]*dynamodb.AttributeValue{"n": {N: aws.String("0")}}, 2},
+		{map[string]*dynamodb.AttributeValue{"n": {N: aws.String("12345")}}, 5},
+		{map[string]*dynamodb.AttributeValue{"n": {N: aws.String("-0.001200")}}, 3},
+		{map[string]*dynamodb.AttributeValue{"n": {N: aws.String("1.5E10")}}, 3},
+		{map[string]*dynamodb.AttributeValue{"b": {B: []byte{1, 2, 3}}}, 4},
+		{map[string]*dynamodb.AttributeValue{"ok": {BOOL: aws.Bool(true)}}, 3},
+		{map[string]*dynamodb.AttributeValue{"z": {NULL: aws.Bool(true)}}, 2},
+		{map[string]*dynamodb.AttributeValue{"ss": {SS: []*string{aws.String("a"), aws.String("bc")}}}, 5},
+		{map[string]*dynamodb.AttributeValue{"l": {L: []*dynamodb.AttributeValue{
+			{S: aws.String("ab")}, {BOOL: aws.Bool(false)},
+		}}}, 1 + 3 + (1 + 2) + (1 + 1)},
+		{map[string]*dynamodb.AttributeValue{"m": {M: map[string]*dynamodb.AttributeValue{
+			"k": {S: aws.String("v")},
+		}}}, 1 + 3 + (1 + 1 + 1)},
+	}
+
+	for i, c := range cases {
+		if e, a := c.expect, dynamodbmanager.ItemSize(c.item); e != a {
+			t.Errorf("%d, expect size %d, got %d", i, e, a)
+		}
+	}
+}
+
+func TestMarshalItemSize(t *testing.T) {
+	size, err := dynamodbmanager.MarshalItemSize(record{ID: "abc", Value: "value"})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := len("ID")+3+len("Value")+5, size; e != a {
+		t.Errorf("expect size %d, got %d", e, a)
+	}
+
+	item := map[string]*dynamodb.AttributeValue{"ID": {S: aws.String("abc")}}
+	size, err = dynamodbmanager.MarshalItemSize(item)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 5, size; e != a {
+		t.Errorf("expect size %d, got %d", e, a)
+	}
+}
+
+func TestCapacityUnits(t *testing.T) {
+	cases := []struct {
+		actual, expect float64
+	}{
+		{dynamodbmanager.ReadCapacityUnits(0, dynamodbmanager.StronglyConsistent), 1},
+		{dynamodbmanager.ReadCapacityUnits(4096, dynamodbmanager.StronglyConsistent), 1},
+		{dynamodbmanager.ReadCapacityUnits(4097, dynamodbmanager.StronglyConsistent), 2},
+		{dynamodbmanager.ReadCapacityUnits(4097, dynamodbmanager.EventuallyConsistent), 1},
+		{dynamodbmanager.ReadCapacityUnits(100, dynamodbmanager.EventuallyConsistent), 0.5},
+		{dynamodbmanager.ReadCapacityUnits(4097, dynamodbmanager.Transactional), 4},
+		{dynamodbmanager.WriteCapacityUnits(0, false), 1},
+		{dynamodbmanager.WriteCapacityUnits(1025, false), 2},
+		{dynamodbmanager.WriteCapacityUnits(1025, true), 4},
+	}
+
+	for i, c := range cases {
+		if e, a := c.expect, c.actual; e != a {
+			t.Errorf("%d, expect %v units, got %v", i, e, a)
+		}
+	}
+}
+
+func TestOperationCapacity(t *testing.T) {
+	small := map[string]*dynamodb.AttributeValue{"id": {S: aws.String("a")}}
+	large := map[string]*dynamodb.AttributeValue{
+		"id": {S: aws.String("a")},
+		"v":  {S: aws.String(strings.Repeat("x", 5000))},
+	}
+
+	cases := []struct {
+		actual, expect float64
+	}{
+		{dynamodbmanager.GetItemCapacity(nil, dynamodbmanager.EventuallyConsistent), 0.5},
+		{dynamodbmanager.GetItemCapacity(large, dynamodbmanager.StronglyConsistent), 2},
+		{dynamodbmanager.GetItemCapacity(large, dynamodbmanager.Transactional), 4},
+		{dynamodbmanager.PutItemCapacity(small, nil, false), 1},
+		{dynamodbmanager.PutItemCapacity(small, large, false), 5},
+		{dynamodbmanager.PutItemCapacity(large, small, true), 10},
+		{dynamodbmanager.UpdateItemCapacity(large, small, false), 5},
+		{dynamodbmanager.DeleteItemCapacity(large, false), 5},
+		{dynamodbmanager.DeleteItemCapacity(nil, true), 2},
+		{dynamodbmanager.QueryCapacity(nil, true), 1},
+		{dynamodbmanager.QueryCapacity([]map[string]*dynamodb.AttributeValue{small, small, small}, false), 0.5},
+		{dynamodbmanager.QueryCapacity([]map[string]*dynamodb.AttributeValue{large, large}, true), 3},
+	}
+
+	for i, c := range cases {
+		if e, a := c.expect, c.actual; e != a {
+			t.Errorf("%d, expect %v units, got %v", i, e, a)
+		}
+	}
+}
@@ -0,0 +1,763 @@
+package dynamodbmanager
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+const (
+	// ErrCodeItemNotFound is the code of the error returned by Get when the
+	// table has no item with the key.
+	ErrCodeItemNotFound = "ItemNotFound"
+
+	// ErrCodeInvalidItem is the code of the error returned when an item is
+	// not a pointer to a struct, or its struct's tags do not describe the
+	// item's key.
+	ErrCodeInvalidItem = "InvalidItem"
+)
+
+// WriteOptions are the options of a Put, Update or Delete of an item by a
+// Table.
+type WriteOptions struct {
+	// Conditions the item must satisfy for the write to succeed, in
+	// addition to the optimistic locking condition of a versioned item.
+	Conditions []expression.ConditionBuilder
+
+	// Disables the optimistic locking condition of a versioned item. The
+	// version is still incremented.
+	SkipVersionCheck bool
+
+	// List of request options that will be passed down to the API
+	// operation request, after the Table's request options.
+	RequestOptions []request.Option
+}
+
+// WithCondition adds a condition the item must satisfy for the write to
+// succeed.
+func WithCondition(cond expression.ConditionBuilder) func(*WriteOptions) {
+	return func(o *WriteOptions) {
+		o.Conditions = append(o.Conditions, cond)
+	}
+}
+
+// WithSkipVersionCheck disables the optimistic locking condition of a write.
+func WithSkipVersionCheck(o *WriteOptions) {
+	o.SkipVersionCheck = true
+}
+
+// Table gets, puts, updates and deletes the items of a DynamoDB table as Go
+// structs, marshaled with dynamodbattribute.
+//
+// The struct fields of the table's primary key, and optionally its version
+// and time to live attributes, are marked with options of the `dynamodbav`
+// struct tag, which are ignored by the dynamodbattribute package:
+//
+//     type Record struct {
+//         // The partition key of the table.
+//         ID string `dynamodbav:"id,hashkey"`
+//
+//         // The sort key of the table, if it has one.
+//         Created int64 `dynamodbav:"created,rangekey"`
+//
+//         // The version of the item, for optimistic locking. Must be an
+//         // integer.
+//         Version int64 `dynamodbav:"version,version"`
+//
+//         // The time to live attribute of the table. A time.Time is
+//         // stored as Unix time in seconds, and omitted if zero.
+//         Expires time.Time `dynamodbav:"expires,ttl"`
+//
+//         Value string `dynamodbav:"value"`
+//     }
+//
+// Writes of an item with a version attribute are conditional on the item's
+// version. If the version is zero, the item must not exist, otherwise the
+// stored item's version must equal it. The item's version is incremented by
+// successful writes.
+//
+// Items passed to a Table must be pointers to structs.
+type Table struct {
+	// The name of the table.
+	Name string
+
+	// If Get reads items with strongly consistent reads.
+	ConsistentRead bool
+
+	// The Encoder used to marshal items. If nil,
+	// dynamodbattribute.MarshalMap is used.
+	Encoder *dynamodbattribute.Encoder
+
+	// The Decoder used to unmarshal items. If nil,
+	// dynamodbattribute.UnmarshalMap is used.
+	Decoder *dynamodbattribute.Decoder
+
+	// The client to use when reading from and writing to DynamoDB.
+	Client dynamodbiface.DynamoDBAPI
+
+	// List of request options that will be passed down to individual API
+	// operation requests made by the Table.
+	RequestOptions []request.Option
+}
+
+// NewTable returns a new Table with the name using a DynamoDB client
+// created from the session. Options can be passed in to modify it.
+//
+// Example:
+//     sess := session.Must(session.NewSession())
+//
+//     table := dynamodbmanager.NewTable(sess, "myTable")
+//
+//     record := &Record{ID: "abc"}
+//     if err := table.Get(record); err != nil {
+//         return err
+//     }
+//
+//     record.Value = "updated"
+//     if err := table.Put(record); err != nil {
+//         return err
+//     }
+func NewTable(c client.ConfigProvider, name string, options ...func(*Table)) *Table {
+	return NewTableWithClient(dynamodb.New(c), name, options...)
+}
+
+// NewTableWithClient returns a new Table with the name using the DynamoDB
+// client. Options can be passed in to modify it.
+func NewTableWithClient(svc dynamodbiface.DynamoDBAPI, name string, options ...func(*Table)) *Table {
+	t := &Table{
+		Name:   name,
+		Client: svc,
+	}
+
+	for _, option := range options {
+		option(t)
+	}
+
+	return t
+}
+
+// Get reads the item with the key of the item, and unmarshals it into the
+// item. An error with the ErrCodeItemNotFound code is returned if the table
+// has no item with the key. The item is only modified if it is read and
+// unmarshaled successfully.
+func (t *Table) Get(item interface{}, opts ...request.Option) error {
+	return t.GetWithContext(aws.BackgroundContext(), item, opts...)
+}
+
+// GetWithContext is the same as Get with the additional support for Context
+// input parameters. The Context must not be nil. A nil Context will cause a
+// panic. Use the Context to add deadlining, timeouts, etc.
+func (t *Table) GetWithContext(ctx aws.Context, item interface{}, opts ...request.Option) error {
+	v, s, err := itemValue(item)
+	if err != nil {
+		return err
+	}
+	av, err := t.marshal(v, s)
+	if err != nil {
+		return err
+	}
+	key, err := s.key(av)
+	if err != nil {
+		return err
+	}
+
+	out, err := t.Client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(t.Name),
+		Key:            key,
+		ConsistentRead: aws.Bool(t.ConsistentRead),
+	}, t.requestOptions(opts)...)
+	if err != nil {
+		return err
+	}
+	if out.Item == nil {
+		return awserr.New(ErrCodeItemNotFound,
+			fmt.Sprintf("table %q has no item with the key", t.Name), nil)
+	}
+
+	return t.unmarshal(out.Item, v, s)
+}
+
+// Put writes the item, replacing the item with the same key if there is
+// one.
+func (t *Table) Put(item interface{}, options ...func(*WriteOptions)) error {
+	return t.PutWithContext(aws.BackgroundContext(), item, options...)
+}
+
+// PutWithContext is the same as Put with the additional support for Context
+// input parameters. The Context must not be nil. A nil Context will cause a
+// panic. Use the Context to add deadlining, timeouts, etc.
+func (t *Table) PutWithContext(ctx aws.Context, item interface{}, options ...func(*WriteOptions)) error {
+	in, commit, err := t.putInput(item, options...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := t.Client.PutItemWithContext(ctx, in, t.requestOptions(writeOptions(options).RequestOptions)...); err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+// putInput returns the PutItem input writing the item, and the function
+// incrementing the item's version once written.
+func (t *Table) putInput(item interface{}, options ...func(*WriteOptions)) (*dynamodb.PutItemInput, func(), error) {
+	v, s, err := itemValue(item)
+	if err != nil {
+		return nil, nil, err
+	}
+	av, err := t.marshal(v, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := s.key(av); err != nil {
+		return nil, nil, err
+	}
+
+	conds, commit := s.versionCondition(v, av, writeOptions(options))
+	expr, err := buildExpression(conds, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &dynamodb.PutItemInput{
+		TableName:                 aws.String(t.Name),
+		Item:                      av,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}, commit, nil
+}
+
+// Update writes the attributes of the item which differ from the original
+// item, removing the attributes the item no longer has, and unmarshals the
+// updated item into the item. If original is nil, all of the item's
+// attributes are written. No request is made if there are no changes to
+// write.
+//
+// Example:
+//     original := *record
+//     record.Value = "updated"
+//
+//     // Only sets the value attribute.
+//     err := table.Update(record, &original)
+func (t *Table) Update(item, original interface{}, options ...func(*WriteOptions)) error {
+	return t.UpdateWithContext(aws.BackgroundContext(), item, original, options...)
+}
+
+// UpdateWithContext is the same as Update with the additional support for
+// Context input parameters. The Context must not be nil. A nil Context will
+// cause a panic. Use the Context to add deadlining, timeouts, etc.
+func (t *Table) UpdateWithContext(ctx aws.Context, item, original interface{}, options ...func(*WriteOptions)) error {
+	in, commit, err := t.updateInput(item, original, options...)
+	if err != nil || in == nil {
+		return err
+	}
+
+	in.ReturnValues = aws.String(dynamodb.ReturnValueAllNew)
+	out, err := t.Client.UpdateItemWithContext(ctx, in, t.requestOptions(writeOptions(options).RequestOptions)...)
+	if err != nil {
+		return err
+	}
+	commit()
+
+	if out.Attributes == nil {
+		return nil
+	}
+	v, s, _ := itemValue(item)
+	return t.unmarshal(out.Attributes, v, s)
+}
+
+// updateInput returns the UpdateItem input writing the changes to the item,
+// or nil if there are none, and the function incrementing the item's version
+// once written.
+func (t *Table) updateInput(item, original interface{}, options ...func(*WriteOptions)) (*dynamodb.UpdateItemInput, func(), error) {
+	v, s, err := itemValue(item)
+	if err != nil {
+		return nil, nil, err
+	}
+	av, err := t.marshal(v, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := s.key(av)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var old map[string]*dynamodb.AttributeValue
+	if original != nil {
+		ov, oschema, err := itemValue(original)
+		if err != nil {
+			return nil, nil, err
+		}
+		if oschema != s {
+			return nil, nil, awserr.New(ErrCodeInvalidItem, fmt.Sprintf(
+				"original item %s is not a %s", ov.Type(), v.Type()), nil)
+		}
+		if old, err = t.marshal(ov, s); err != nil {
+			return nil, nil, err
+		}
+		oldKey, err := s.key(old)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !reflect.DeepEqual(key, oldKey) {
+			return nil, nil, awserr.New(ErrCodeInvalidItem,
+				"original item has a different key", nil)
+		}
+	}
+
+	update, changed := s.changes(old, av)
+	conds, commit := s.versionCondition(v, av, writeOptions(options))
+	if s.version != nil {
+		name := s.version.name
+		update = update.Set(expression.Name(name), expression.Value(rawValue{av[name]}))
+		changed = true
+	}
+	if !changed {
+		return nil, nil, nil
+	}
+
+	expr, err := buildExpression(conds, &update)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(t.Name),
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}, commit, nil
+}
+
+// Delete deletes the item with the key of the item.
+func (t *Table) Delete(item interface{}, options ...func(*WriteOptions)) error {
+	return t.DeleteWithContext(aws.BackgroundContext(), item, options...)
+}
+
+// DeleteWithContext is the same as Delete with the additional support for
+// Context input parameters. The Context must not be nil. A nil Context will
+// cause a panic. Use the Context to add deadlining, timeouts, etc.
+func (t *Table) DeleteWithContext(ctx aws.Context, item interface{}, options ...func(*WriteOptions)) error {
+	in, err := t.deleteInput(item, options...)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.Client.DeleteItemWithContext(ctx, in, t.requestOptions(writeOptions(options).RequestOptions)...)
+	return err
+}
+
+// deleteInput returns the DeleteItem input deleting the item.
+func (t *Table) deleteInput(item interface{}, options ...func(*WriteOptions)) (*dynamodb.DeleteItemInput, error) {
+	v, s, err := itemValue(item)
+	if err != nil {
+		return nil, err
+	}
+	av, err := t.marshal(v, s)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.key(av)
+	if err != nil {
+		return nil, err
+	}
+
+	o := writeOptions(options)
+	conds := o.Conditions
+	if s.version != nil && !o.SkipVersionCheck {
+		if version := s.version.int(v); version != 0 {
+			conds = append(conds, expression.Name(s.version.name).Equal(expression.Value(version)))
+		}
+	}
+	expr, err := buildExpression(conds, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(t.Name),
+		Key:                       key,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}, nil
+}
+
+// Key returns the primary key of the item.
+func (t *Table) Key(item interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	v, s, err := itemValue(item)
+	if err != nil {
+		return nil, err
+	}
+	av, err := t.marshal(v, s)
+	if err != nil {
+		return nil, err
+	}
+	return s.key(av)
+}
+
+func (t *Table) requestOptions(opts []request.Option) []request.Option {
+	return append(append([]request.Option{request.WithAppendUserAgent("DynamoDBManager")},
+		t.RequestOptions...), opts...)
+}
+
+// marshal marshals the item, storing its time to live as Unix time.
+func (t *Table) marshal(v reflect.Value, s *itemSchema) (map[string]*dynamodb.AttributeValue, error) {
+	var av map[string]*dynamodb.AttributeValue
+	var err error
+	if t.Encoder == nil {
+		av, err = dynamodbattribute.MarshalMap(v.Interface())
+	} else {
+		var m *dynamodb.AttributeValue
+		if m, err = t.Encoder.Encode(v.Interface()); err == nil {
+			av = m.M
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if av == nil {
+		av = map[string]*dynamodb.AttributeValue{}
+	}
+
+	if s.ttl != nil {
+		if tm, ok := v.FieldByIndex(s.ttl.index).Interface().(time.Time); ok {
+			if tm.IsZero() {
+				delete(av, s.ttl.name)
+			} else {
+				av[s.ttl.name] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(tm.Unix(), 10))}
+			}
+		}
+	}
+	return av, nil
+}
+
+// unmarshal unmarshals the item into a new value of the item's type, reading
+// its time to live as Unix time, and replaces the item with it if successful.
+func (t *Table) unmarshal(av map[string]*dynamodb.AttributeValue, v reflect.Value, s *itemSchema) error {
+	var ttl *dynamodb.AttributeValue
+	if s.ttl != nil && v.FieldByIndex(s.ttl.index).Type() == timeType {
+		if ttl = av[s.ttl.name]; ttl != nil && ttl.N != nil {
+			m := make(map[string]*dynamodb.AttributeValue, len(av))
+			for name, a := range av {
+				m[name] = a
+			}
+			delete(m, s.ttl.name)
+			av = m
+		} else {
+			ttl = nil
+		}
+	}
+
+	item := reflect.New(v.Type())
+	var err error
+	if t.Decoder == nil {
+		err = dynamodbattribute.UnmarshalMap(av, item.Interface())
+	} else {
+		err = t.Decoder.Decode(&dynamodb.AttributeValue{M: av}, item.Interface())
+	}
+	if err != nil {
+		return err
+	}
+
+	if ttl != nil {
+		var tm dynamodbattribute.UnixTime
+		if err := tm.UnmarshalDynamoDBAttributeValue(ttl); err != nil {
+			return err
+		}
+		item.Elem().FieldByIndex(s.ttl.index).Set(reflect.ValueOf(time.Time(tm)))
+	}
+
+	v.Set(item.Elem())
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func writeOptions(options []func(*WriteOptions)) WriteOptions {
+	var o WriteOptions
+	for _, option := range options {
+		option(&o)
+	}
+	return o
+}
+
+// buildExpression builds the expression of the conditions, all of which
+// must be satisfied, and the update.
+func buildExpression(conds []expression.ConditionBuilder, update *expression.UpdateBuilder) (expression.Expression, error) {
+	if len(conds) == 0 && update == nil {
+		return expression.Expression{}, nil
+	}
+
+	b := expression.NewBuilder()
+	switch len(conds) {
+	case 0:
+	case 1:
+		b = b.WithCondition(conds[0])
+	default:
+		b = b.WithCondition(expression.And(conds[0], conds[1], conds[2:]...))
+	}
+	if update != nil {
+		b = b.WithUpdate(*update)
+	}
+	return b.Build()
+}
+
+// rawValue is an attribute value used as is by the expression package.
+type rawValue struct {
+	av *dynamodb.AttributeValue
+}
+
+func (v rawValue) MarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	*av = *v.av
+	return nil
+}
+
+// itemSchema are the struct fields of an item type marked as its key,
+// version and time to live attributes.
+type itemSchema struct {
+	hash, rng, version, ttl *schemaField
+}
+
+type schemaField struct {
+	name  string
+	index []int
+}
+
+// int returns the value of the integer field of the item.
+func (f *schemaField) int(v reflect.Value) int64 {
+	fv := v.FieldByIndex(f.index)
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fv.Int()
+	default:
+		return int64(fv.Uint())
+	}
+}
+
+// setInt sets the value of the integer field of the item.
+func (f *schemaField) setInt(v reflect.Value, n int64) {
+	fv := v.FieldByIndex(f.index)
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fv.SetInt(n)
+	default:
+		fv.SetUint(uint64(n))
+	}
+}
+
+// key returns the key attributes of the marshaled item.
+func (s *itemSchema) key(av map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	key := map[string]*dynamodb.AttributeValue{}
+	for _, f := range []*schemaField{s.hash, s.rng} {
+		if f == nil {
+			continue
+		}
+		v, ok := av[f.name]
+		if !ok || v == nil || v.NULL != nil {
+			return nil, awserr.New(ErrCodeInvalidItem,
+				fmt.Sprintf("item is missing key attribute %q", f.name), nil)
+		}
+		key[f.name] = v
+	}
+	return key, nil
+}
+
+// versionCondition sets the next version of the item in the marshaled item,
+// returning the write's conditions, and the function setting the item's
+// version once written.
+func (s *itemSchema) versionCondition(v reflect.Value, av map[string]*dynamodb.AttributeValue,
+	o WriteOptions) ([]expression.ConditionBuilder, func()) {
+	conds := o.Conditions
+	if s.version == nil {
+		return conds, func() {}
+	}
+
+	version := s.version.int(v)
+	if !o.SkipVersionCheck {
+		if version == 0 {
+			conds = append(conds, expression.Name(s.hash.name).AttributeNotExists())
+		} else {
+			conds = append(conds, expression.Name(s.version.name).Equal(expression.Value(version)))
+		}
+	}
+	av[s.version.name] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(version+1, 10))}
+
+	return conds, func() {
+		s.version.setInt(v, version+1)
+	}
+}
+
+// changes returns the update setting the attributes of the item which
+// differ from the original item, and removing those it no longer has. The
+// key and version attributes are not included.
+func (s *itemSchema) changes(old, av map[string]*dynamodb.AttributeValue) (expression.UpdateBuilder, bool) {
+	skip := func(name string) bool {
+		for _, f := range []*schemaField{s.hash, s.rng, s.version} {
+			if f != nil && f.name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	var update expression.UpdateBuilder
+	changed := false
+	for _, name := range sortedNames(av) {
+		if skip(name) || reflect.DeepEqual(old[name], av[name]) {
+			continue
+		}
+		update = update.Set(expression.Name(name), expression.Value(rawValue{av[name]}))
+		changed = true
+	}
+	for _, name := range sortedNames(old) {
+		if _, ok := av[name]; ok || skip(name) {
+			continue
+		}
+		update = update.Remove(expression.Name(name))
+		changed = true
+	}
+	return update, changed
+}
+
+func sortedNames(av map[string]*dynamodb.AttributeValue) []string {
+	names := make([]string, 0, len(av))
+	for name := range av {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var schemas = struct {
+	sync.RWMutex
+	m map[reflect.Type]*itemSchema
+}{m: map[reflect.Type]*itemSchema{}}
+
+// itemValue returns the struct the item points to, and its schema, which
+// must have a hash key.
+func itemValue(item interface{}) (reflect.Value, *itemSchema, error) {
+	v, s, err := structValue(item)
+	if err != nil {
+		return reflect.Value{}, nil, err
+	}
+	if s.hash == nil {
+		return reflect.Value{}, nil, awserr.New(ErrCodeInvalidItem,
+			fmt.Sprintf("%s has no field tagged hashkey", v.Type()), nil)
+	}
+	return v, s, nil
+}
+
+// structValue returns the struct the item points to, and its schema.
+func structValue(item interface{}) (reflect.Value, *itemSchema, error) {
+	v := reflect.ValueOf(item)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, nil, awserr.New(ErrCodeInvalidItem,
+			fmt.Sprintf("item must be a non-nil pointer to a struct, got %T", item), nil)
+	}
+	v = v.Elem()
+
+	schemas.RLock()
+	s, ok := schemas.m[v.Type()]
+	schemas.RUnlock()
+	if ok {
+		return v, s, nil
+	}
+
+	s, err := parseItemSchema(v.Type())
+	if err != nil {
+		return reflect.Value{}, nil, err
+	}
+
+	schemas.Lock()
+	schemas.m[v.Type()] = s
+	schemas.Unlock()
+	return v, s, nil
+}
+
+// parseItemSchema returns the schema of the struct type from the options of
+// the `dynamodbav` tags of its fields.
+func parseItemSchema(t reflect.Type) (*itemSchema, error) {
+	s := &itemSchema{}
+	if err := s.parseFields(t, nil); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *itemSchema) parseFields(t reflect.Type, index []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(index[:len(index):len(index)], i)
+
+		tagStr := f.Tag.Get("dynamodbav")
+		if len(tagStr) == 0 {
+			tagStr = f.Tag.Get("json")
+		}
+		parts := strings.Split(tagStr, ",")
+		name := parts[0]
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && len(name) == 0 && f.Type.Kind() == reflect.Struct {
+			if err := s.parseFields(f.Type, fieldIndex); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(f.PkgPath) != 0 {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+
+		for _, opt := range parts[1:] {
+			var dst **schemaField
+			switch opt {
+			case "hashkey":
+				dst = &s.hash
+			case "rangekey":
+				dst = &s.rng
+			case "version":
+				switch f.Type.Kind() {
+				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				default:
+					return awserr.New(ErrCodeInvalidItem, fmt.Sprintf(
+						"version field %s of %s must be an integer", f.Name, t), nil)
+				}
+				dst = &s.version
+			case "ttl":
+				dst = &s.ttl
+			default:
+				continue
+			}
+			if *dst != nil {
+				return awserr.New(ErrCodeInvalidItem, fmt.Sprintf(
+					"%s has more than one field tagged %s", t, opt), nil)
+			}
+			*dst = &schemaField{name: name, index: fieldIndex}
+		}
+	}
+	return nil
+}
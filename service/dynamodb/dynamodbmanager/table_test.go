@@ -0,0 +1,414 @@
+package dynamodbmanager_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbtest"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+type tableItem struct {
+	ID      string    `dynamodbav:"id,hashkey"`
+	Sort    int       `dynamodbav:"sort,rangekey"`
+	Version int64     `dynamodbav:"version,version"`
+	Expires time.Time `dynamodbav:"expires,ttl"`
+	Value   string    `dynamodbav:"value,omitempty"`
+	Count   int       `dynamodbav:"count"`
+}
+
+type unversionedItem struct {
+	ID    string `json:"id,hashkey"`
+	Value string
+}
+
+// tableSvc records the item requests made to it, returning its output and
+// error.
+type tableSvc struct {
+	dynamodbiface.DynamoDBAPI
+
+	get    *dynamodb.GetItemInput
+	put    *dynamodb.PutItemInput
+	update *dynamodb.UpdateItemInput
+	delete *dynamodb.DeleteItemInput
+
+	item map[string]*dynamodb.AttributeValue
+	err  error
+}
+
+func (s *tableSvc) GetItemWithContext(ctx aws.Context, in *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	s.get = in
+	return &dynamodb.GetItemOutput{Item: s.item}, s.err
+}
+
+func (s *tableSvc) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	s.put = in
+	return &dynamodb.PutItemOutput{}, s.err
+}
+
+func (s *tableSvc) UpdateItemWithContext(ctx aws.Context, in *dynamodb.UpdateItemInput, opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	s.update = in
+	return &dynamodb.UpdateItemOutput{Attributes: s.item}, s.err
+}
+
+func (s *tableSvc) DeleteItemWithContext(ctx aws.Context, in *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	s.delete = in
+	return &dynamodb.DeleteItemOutput{}, s.err
+}
+
+func TestTable_Get(t *testing.T) {
+	svc := &tableSvc{item: map[string]*dynamodb.AttributeValue{
+		"id":      {S: aws.String("a")},
+		"sort":    {N: aws.String("1")},
+		"version": {N: aws.String("3")},
+		"count":   {N: aws.String("7")},
+	}}
+	table := dynamodbmanager.NewTableWithClient(svc, "table", func(t *dynamodbmanager.Table) {
+		t.ConsistentRead = true
+	})
+
+	item := &tableItem{ID: "a", Sort: 1, Value: "stale"}
+	if err := table.Get(item); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := "table", *svc.get.TableName; e != a {
+		t.Errorf("expect %v table, got %v", e, a)
+	}
+	if e, a := 2, len(svc.get.Key); e != a {
+		t.Errorf("expect %d key attributes, got %d", e, a)
+	}
+	if e, a := "a", *svc.get.Key["id"].S; e != a {
+		t.Errorf("expect %v hash key, got %v", e, a)
+	}
+	if e, a := "1", *svc.get.Key["sort"].N; e != a {
+		t.Errorf("expect %v range key, got %v", e, a)
+	}
+	if !*svc.get.ConsistentRead {
+		t.Errorf("expect consistent read")
+	}
+
+	if e, a := int64(3), item.Version; e != a {
+		t.Errorf("expect %v version, got %v", e, a)
+	}
+	if e, a := 7, item.Count; e != a {
+		t.Errorf("expect %v count, got %v", e, a)
+	}
+	if e, a := "", item.Value; e != a {
+		t.Errorf("expect value %q reset, got %q", e, a)
+	}
+
+	svc.item = nil
+	err := table.Get(item)
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodbmanager.ErrCodeItemNotFound {
+		t.Errorf("expect %v error, got %v", dynamodbmanager.ErrCodeItemNotFound, err)
+	}
+}
+
+// newRoundTripTable returns a Table of tableItems backed by an in-memory
+// DynamoDB.
+func newRoundTripTable(t *testing.T) *dynamodbmanager.Table {
+	db := dynamodbtest.NewDB()
+	_, err := db.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("table"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("id"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("sort"), AttributeType: aws.String("N")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("id"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("sort"), KeyType: aws.String("RANGE")},
+		},
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	return dynamodbmanager.NewTableWithClient(db, "table")
+}
+
+func TestTable_PutGetRoundTrip(t *testing.T) {
+	table := newRoundTripTable(t)
+
+	expires := time.Unix(1500000000, 0)
+	item := &tableItem{ID: "a", Sort: 1, Expires: expires, Value: "value", Count: 2}
+	if err := table.Put(item); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	actual := &tableItem{ID: "a", Sort: 1}
+	if err := table.Get(actual); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := expires, actual.Expires; !e.Equal(a) {
+		t.Errorf("expect %v expires, got %v", e, a)
+	}
+	if e, a := int64(1), actual.Version; e != a {
+		t.Errorf("expect %v version, got %v", e, a)
+	}
+	if e, a := "value", actual.Value; e != a {
+		t.Errorf("expect %v value, got %v", e, a)
+	}
+	if e, a := 2, actual.Count; e != a {
+		t.Errorf("expect %v count, got %v", e, a)
+	}
+}
+
+func TestTable_UpdateRoundTrip(t *testing.T) {
+	table := newRoundTripTable(t)
+
+	expires := time.Unix(1500000000, 0)
+	item := &tableItem{ID: "a", Sort: 1, Expires: expires, Value: "value"}
+	if err := table.Put(item); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	original := *item
+	item.Expires = expires.Add(time.Hour)
+	item.Count = 3
+	if err := table.Update(item, &original); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := expires.Add(time.Hour), item.Expires; !e.Equal(a) {
+		t.Errorf("expect %v expires, got %v", e, a)
+	}
+	if e, a := int64(2), item.Version; e != a {
+		t.Errorf("expect %v version, got %v", e, a)
+	}
+	if e, a := "value", item.Value; e != a {
+		t.Errorf("expect %v value, got %v", e, a)
+	}
+	if e, a := 3, item.Count; e != a {
+		t.Errorf("expect %v count, got %v", e, a)
+	}
+}
+
+func TestTable_GetUnmarshalError(t *testing.T) {
+	svc := &tableSvc{item: map[string]*dynamodb.AttributeValue{
+		"id":    {S: aws.String("a")},
+		"sort":  {N: aws.String("1")},
+		"count": {S: aws.String("not a number")},
+	}}
+	table := dynamodbmanager.NewTableWithClient(svc, "table")
+
+	item := &tableItem{ID: "a", Sort: 1, Value: "value"}
+	if err := table.Get(item); err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := "value", item.Value; e != a {
+		t.Errorf("expect item to be unchanged, got %v value", a)
+	}
+}
+
+func TestTable_PutNew(t *testing.T) {
+	svc := &tableSvc{}
+	table := dynamodbmanager.NewTableWithClient(svc, "table")
+
+	expires := time.Unix(1500000000, 0)
+	item := &tableItem{ID: "a", Sort: 1, Expires: expires, Value: "value"}
+	if err := table.Put(item); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	in := svc.put
+	if e, a := "attribute_not_exists (#0)", aws.StringValue(in.ConditionExpression); e != a {
+		t.Errorf("expect %q condition, got %q", e, a)
+	}
+	if e, a := "id", *in.ExpressionAttributeNames["#0"]; e != a {
+		t.Errorf("expect %v name, got %v", e, a)
+	}
+	if e, a := "1", *in.Item["version"].N; e != a {
+		t.Errorf("expect %v version written, got %v", e, a)
+	}
+	if e, a := "1500000000", *in.Item["expires"].N; e != a {
+		t.Errorf("expect %v expires, got %v", e, a)
+	}
+	if e, a := int64(1), item.Version; e != a {
+		t.Errorf("expect %v version, got %v", e, a)
+	}
+}
+
+func TestTable_PutVersioned(t *testing.T) {
+	svc := &tableSvc{}
+	table := dynamodbmanager.NewTableWithClient(svc, "table")
+
+	item := &tableItem{ID: "a", Sort: 1, Version: 3}
+	err := table.Put(item, dynamodbmanager.WithCondition(expression.Name("count").LessThan(expression.Value(10))))
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	in := svc.put
+	if e, a := "(#0 < :0) AND (#1 = :1)", aws.StringValue(in.ConditionExpression); e != a {
+		t.Errorf("expect %q condition, got %q", e, a)
+	}
+	if e, a := "version", *in.ExpressionAttributeNames["#1"]; e != a {
+		t.Errorf("expect %v name, got %v", e, a)
+	}
+	if e, a := "3", *in.ExpressionAttributeValues[":1"].N; e != a {
+		t.Errorf("expect %v version condition, got %v", e, a)
+	}
+	if _, ok := in.Item["expires"]; ok {
+		t.Errorf("expect zero expires omitted")
+	}
+	if e, a := int64(4), item.Version; e != a {
+		t.Errorf("expect %v version, got %v", e, a)
+	}
+
+	svc.err = awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "failed", nil)
+	if err := table.Put(item); err != svc.err {
+		t.Errorf("expect %v error, got %v", svc.err, err)
+	}
+	if e, a := int64(4), item.Version; e != a {
+		t.Errorf("expect %v version unchanged, got %v", e, a)
+	}
+
+	svc.err = nil
+	if err := table.Put(item, dynamodbmanager.WithSkipVersionCheck); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if svc.put.ConditionExpression != nil {
+		t.Errorf("expect no condition, got %v", *svc.put.ConditionExpression)
+	}
+	if e, a := int64(5), item.Version; e != a {
+		t.Errorf("expect %v version, got %v", e, a)
+	}
+}
+
+func TestTable_PutUnversioned(t *testing.T) {
+	svc := &tableSvc{}
+	table := dynamodbmanager.NewTableWithClient(svc, "table")
+
+	if err := table.Put(&unversionedItem{ID: "a", Value: "v"}); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if svc.put.ConditionExpression != nil {
+		t.Errorf("expect no condition, got %v", *svc.put.ConditionExpression)
+	}
+	if e, a := "v", *svc.put.Item["Value"].S; e != a {
+		t.Errorf("expect %v value, got %v", e, a)
+	}
+}
+
+func TestTable_Update(t *testing.T) {
+	svc := &tableSvc{item: map[string]*dynamodb.AttributeValue{
+		"id":      {S: aws.String("a")},
+		"sort":    {N: aws.String("1")},
+		"version": {N: aws.String("3")},
+		"count":   {N: aws.String("5")},
+	}}
+	table := dynamodbmanager.NewTableWithClient(svc, "table")
+
+	item := &tableItem{ID: "a", Sort: 1, Version: 2, Value: "value", Count: 4}
+	original := *item
+	item.Value = ""
+	item.Count = 5
+
+	if err := table.Update(item, &original); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	in := svc.update
+	if e, a := "REMOVE #1\nSET #2 = :1, #0 = :2\n", aws.StringValue(in.UpdateExpression); e != a {
+		t.Errorf("expect %q update, got %q", e, a)
+	}
+	for k, e := range map[string]string{"#0": "version", "#1": "value", "#2": "count"} {
+		if a := aws.StringValue(in.ExpressionAttributeNames[k]); e != a {
+			t.Errorf("expect %v name %v, got %v", k, e, a)
+		}
+	}
+	if e, a := "#0 = :0", aws.StringValue(in.ConditionExpression); e != a {
+		t.Errorf("expect %q condition, got %q", e, a)
+	}
+	if e, a := "3", *in.ExpressionAttributeValues[":2"].N; e != a {
+		t.Errorf("expect %v version set, got %v", e, a)
+	}
+	if e, a := "2", *in.ExpressionAttributeValues[":0"].N; e != a {
+		t.Errorf("expect %v version condition, got %v", e, a)
+	}
+	if e, a := 2, len(in.Key); e != a {
+		t.Errorf("expect %d key attributes, got %d", e, a)
+	}
+	if e, a := dynamodb.ReturnValueAllNew, *in.ReturnValues; e != a {
+		t.Errorf("expect %v return values, got %v", e, a)
+	}
+	if e, a := int64(3), item.Version; e != a {
+		t.Errorf("expect %v version, got %v", e, a)
+	}
+
+	svc.update = nil
+	if err := table.Update(&unversionedItem{ID: "a"}, &unversionedItem{ID: "a"}); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if svc.update != nil {
+		t.Errorf("expect no update request without changes")
+	}
+
+	err := table.Update(item, &tableItem{ID: "b", Sort: 1})
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodbmanager.ErrCodeInvalidItem {
+		t.Errorf("expect %v error, got %v", dynamodbmanager.ErrCodeInvalidItem, err)
+	}
+}
+
+func TestTable_Delete(t *testing.T) {
+	svc := &tableSvc{}
+	table := dynamodbmanager.NewTableWithClient(svc, "table")
+
+	if err := table.Delete(&tableItem{ID: "a", Sort: 1, Version: 2}); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	in := svc.delete
+	if e, a := "#0 = :0", aws.StringValue(in.ConditionExpression); e != a {
+		t.Errorf("expect %q condition, got %q", e, a)
+	}
+	if e, a := "2", *in.ExpressionAttributeValues[":0"].N; e != a {
+		t.Errorf("expect %v version condition, got %v", e, a)
+	}
+	if e, a := 2, len(in.Key); e != a {
+		t.Errorf("expect %d key attributes, got %d", e, a)
+	}
+
+	if err := table.Delete(&unversionedItem{ID: "a"}); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if svc.delete.ConditionExpression != nil {
+		t.Errorf("expect no condition, got %v", *svc.delete.ConditionExpression)
+	}
+}
+
+func TestTable_InvalidItem(t *testing.T) {
+	type noKey struct {
+		ID string
+	}
+	type twoKeys struct {
+		A string `dynamodbav:",hashkey"`
+		B string `dynamodbav:",hashkey"`
+	}
+	type badVersion struct {
+		ID      string `dynamodbav:",hashkey"`
+		Version string `dynamodbav:",version"`
+	}
+
+	table := dynamodbmanager.NewTableWithClient(&tableSvc{}, "table")
+	cases := []interface{}{
+		unversionedItem{ID: "a"},
+		(*unversionedItem)(nil),
+		&unversionedItem{},
+		&noKey{ID: "a"},
+		&twoKeys{A: "a"},
+		&badVersion{ID: "a"},
+	}
+	for i, c := range cases {
+		err := table.Put(c)
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodbmanager.ErrCodeInvalidItem {
+			t.Errorf("%d, expect %v error, got %v", i, dynamodbmanager.ErrCodeInvalidItem, err)
+		}
+	}
+}
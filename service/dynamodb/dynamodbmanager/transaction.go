@@ -0,0 +1,687 @@
+package dynamodbmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// MaxTransactItems is the maximum number of operations in a single
+// TransactWriteItems or TransactGetItems request.
+const MaxTransactItems = 25
+
+// MaxTransactSize is the maximum total size, in bytes, of the items and keys
+// of the operations of a single TransactWriteItems request.
+const MaxTransactSize = 4 * 1024 * 1024
+
+// ErrCodeInvalidTransaction is the code of the error returned when a
+// transaction cannot be built, e.g. because it has too many operations, or
+// more than one operation on the same item.
+const ErrCodeInvalidTransaction = "InvalidTransaction"
+
+// The operations of a transaction.
+const (
+	TransactOpPut            = "Put"
+	TransactOpUpdate         = "Update"
+	TransactOpDelete         = "Delete"
+	TransactOpConditionCheck = "ConditionCheck"
+	TransactOpGet            = "Get"
+)
+
+// transactOp is an operation of a transaction, or the error building it.
+type transactOp struct {
+	op    string
+	table string
+	key   map[string]*dynamodb.AttributeValue
+
+	// The item of a put, whose key attributes may not be known.
+	item map[string]*dynamodb.AttributeValue
+
+	write  *dynamodb.TransactWriteItem
+	get    *dynamodb.TransactGetItem
+	out    interface{}
+	commit func()
+	err    error
+}
+
+// TransactWriteBuilder builds the input of a TransactWriteItems request from
+// put, update, delete and condition check operations. The expression
+// attribute names and values of each operation are aliased separately.
+//
+// Items and keys may be a map[string]*dynamodb.AttributeValue, which is used
+// as is, a pointer to a struct with fields tagged as the item's key for the
+// Table mapper, or any other value which is marshaled to a map of attribute
+// values with dynamodbattribute.MarshalMap. Puts and deletes of tagged items
+// with a version attribute are conditional on the item's version as they are
+// for a Table, and the versions of the items are incremented once the
+// transaction is written by Write.
+//
+// The key attributes of an item put into a table are taken from the tags of
+// its struct, the TableKeys, or the keys of the other operations on the
+// table, in that order, to check that the transaction does not contain more
+// than one operation on the same item. They are only needed if the put is
+// not the only operation on the table.
+//
+// Example:
+//     tx := dynamodbmanager.NewTransactWriteBuilder()
+//     tx.Put("orders", &order, expression.AttributeNotExists(expression.Name("id")))
+//     tx.Update("customers", map[string]string{"id": order.Customer},
+//         expression.Set(expression.Name("orders"),
+//             expression.Name("orders").Plus(expression.Value(1))))
+//
+//     if _, err := tx.Write(svc); err != nil {
+//         if cErr, ok := err.(*dynamodbmanager.TransactionCanceledError); ok {
+//             for _, r := range cErr.Failed() {
+//                 fmt.Println(r.Operation, r.TableName, r.Code)
+//             }
+//         }
+//         return err
+//     }
+type TransactWriteBuilder struct {
+	// The names of the key attributes of tables, by table name.
+	TableKeys map[string][]string
+
+	// The ReturnValuesOnConditionCheckFailure of each operation, to return
+	// the item of an operation whose condition failed in the
+	// TransactionCanceledError.
+	ReturnValuesOnConditionCheckFailure string
+
+	// The idempotency token of the request.
+	ClientRequestToken string
+
+	ops []*transactOp
+}
+
+// NewTransactWriteBuilder returns a new TransactWriteBuilder without
+// operations.
+func NewTransactWriteBuilder() *TransactWriteBuilder {
+	return &TransactWriteBuilder{}
+}
+
+// Put adds an operation putting the item into the table, if the conditions
+// are satisfied.
+func (b *TransactWriteBuilder) Put(table string, item interface{}, conds ...expression.ConditionBuilder) *TransactWriteBuilder {
+	op := &transactOp{op: TransactOpPut, table: table}
+	b.ops = append(b.ops, op)
+
+	if tagged, err := isTaggedItem(item); err != nil {
+		op.err = err
+		return b
+	} else if tagged {
+		t := &Table{Name: table}
+		in, commit, err := t.putInput(item, conditionOptions(conds))
+		if err != nil {
+			op.err = err
+			return b
+		}
+		op.key, _ = t.Key(item)
+		op.item, op.commit = in.Item, commit
+		op.write = &dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+			TableName:                 in.TableName,
+			Item:                      in.Item,
+			ConditionExpression:       in.ConditionExpression,
+			ExpressionAttributeNames:  in.ExpressionAttributeNames,
+			ExpressionAttributeValues: in.ExpressionAttributeValues,
+		}}
+		return b
+	}
+
+	av, err := marshalTransactMap(item)
+	if err != nil {
+		op.err = err
+		return b
+	}
+	expr, err := buildExpression(conds, nil)
+	if err != nil {
+		op.err = err
+		return b
+	}
+	op.item = av
+	op.write = &dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+		TableName:                 aws.String(table),
+		Item:                      av,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}}
+	return b
+}
+
+// Update adds an operation updating the item with the key, if the
+// conditions are satisfied.
+func (b *TransactWriteBuilder) Update(table string, key interface{}, update expression.UpdateBuilder, conds ...expression.ConditionBuilder) *TransactWriteBuilder {
+	op := &transactOp{op: TransactOpUpdate, table: table}
+	b.ops = append(b.ops, op)
+
+	if op.key, op.err = transactKey(table, key); op.err != nil {
+		return b
+	}
+	expr, err := buildExpression(conds, &update)
+	if err != nil {
+		op.err = err
+		return b
+	}
+	op.write = &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+		TableName:                 aws.String(table),
+		Key:                       op.key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}}
+	return b
+}
+
+// Delete adds an operation deleting the item with the key, if the
+// conditions are satisfied.
+func (b *TransactWriteBuilder) Delete(table string, key interface{}, conds ...expression.ConditionBuilder) *TransactWriteBuilder {
+	op := &transactOp{op: TransactOpDelete, table: table}
+	b.ops = append(b.ops, op)
+
+	if tagged, err := isTaggedItem(key); err != nil {
+		op.err = err
+		return b
+	} else if tagged {
+		in, err := (&Table{Name: table}).deleteInput(key, conditionOptions(conds))
+		if err != nil {
+			op.err = err
+			return b
+		}
+		op.key = in.Key
+		op.write = &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+			TableName:                 in.TableName,
+			Key:                       in.Key,
+			ConditionExpression:       in.ConditionExpression,
+			ExpressionAttributeNames:  in.ExpressionAttributeNames,
+			ExpressionAttributeValues: in.ExpressionAttributeValues,
+		}}
+		return b
+	}
+
+	if op.key, op.err = transactKey(table, key); op.err != nil {
+		return b
+	}
+	expr, err := buildExpression(conds, nil)
+	if err != nil {
+		op.err = err
+		return b
+	}
+	op.write = &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+		TableName:                 aws.String(table),
+		Key:                       op.key,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}}
+	return b
+}
+
+// ConditionCheck adds an operation checking the condition of the item with
+// the key, without writing it.
+func (b *TransactWriteBuilder) ConditionCheck(table string, key interface{}, cond expression.ConditionBuilder) *TransactWriteBuilder {
+	op := &transactOp{op: TransactOpConditionCheck, table: table}
+	b.ops = append(b.ops, op)
+
+	if op.key, op.err = transactKey(table, key); op.err != nil {
+		return b
+	}
+	expr, err := buildExpression([]expression.ConditionBuilder{cond}, nil)
+	if err != nil {
+		op.err = err
+		return b
+	}
+	op.write = &dynamodb.TransactWriteItem{ConditionCheck: &dynamodb.ConditionCheck{
+		TableName:                 aws.String(table),
+		Key:                       op.key,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}}
+	return b
+}
+
+// Build returns the TransactWriteItems input of the operations, or the
+// first error building them.
+func (b *TransactWriteBuilder) Build() (*dynamodb.TransactWriteItemsInput, error) {
+	if err := checkTransactOps(b.ops, b.TableKeys); err != nil {
+		return nil, err
+	}
+
+	in := &dynamodb.TransactWriteItemsInput{}
+	if len(b.ClientRequestToken) != 0 {
+		in.ClientRequestToken = aws.String(b.ClientRequestToken)
+	}
+	for _, op := range b.ops {
+		item := *op.write
+		if rv := b.ReturnValuesOnConditionCheckFailure; len(rv) != 0 {
+			switch {
+			case item.Put != nil:
+				put := *item.Put
+				put.ReturnValuesOnConditionCheckFailure = aws.String(rv)
+				item.Put = &put
+			case item.Update != nil:
+				update := *item.Update
+				update.ReturnValuesOnConditionCheckFailure = aws.String(rv)
+				item.Update = &update
+			case item.Delete != nil:
+				del := *item.Delete
+				del.ReturnValuesOnConditionCheckFailure = aws.String(rv)
+				item.Delete = &del
+			case item.ConditionCheck != nil:
+				check := *item.ConditionCheck
+				check.ReturnValuesOnConditionCheckFailure = aws.String(rv)
+				item.ConditionCheck = &check
+			}
+		}
+		in.TransactItems = append(in.TransactItems, &item)
+	}
+	return in, nil
+}
+
+// Write builds the operations and writes them with TransactWriteItems. If
+// the transaction is canceled, a *TransactionCanceledError is returned,
+// mapping the cancellation reasons to the operations.
+func (b *TransactWriteBuilder) Write(svc dynamodbiface.DynamoDBAPI) (*dynamodb.TransactWriteItemsOutput, error) {
+	return b.WriteWithContext(aws.BackgroundContext(), svc)
+}
+
+// WriteWithContext is the same as Write with the additional support for
+// Context input parameters. The Context must not be nil. A nil Context will
+// cause a panic. Use the Context to add deadlining, timeouts, etc.
+func (b *TransactWriteBuilder) WriteWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	in, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var reasons []*dynamodb.CancellationReason
+	opts = append(opts[:len(opts):len(opts)], request.WithAppendUserAgent("DynamoDBManager"),
+		withCancellationReasons(&reasons))
+
+	out, err := svc.TransactWriteItemsWithContext(ctx, in, opts...)
+	if err != nil {
+		return nil, newTransactionCanceledError(err, reasons, b.ops)
+	}
+
+	for _, op := range b.ops {
+		if op.commit != nil {
+			op.commit()
+		}
+	}
+	return out, nil
+}
+
+// TransactGetBuilder builds the input of a TransactGetItems request from get
+// operations, and unmarshals the items read into the operations' values.
+//
+// Example:
+//     var order Order
+//     var customer Customer
+//
+//     tx := dynamodbmanager.NewTransactGetBuilder()
+//     tx.Get("orders", map[string]string{"id": "o1"}, &order)
+//     tx.Get("customers", map[string]string{"id": "c1"}, &customer)
+//
+//     if _, err := tx.Read(svc); err != nil {
+//         return err
+//     }
+type TransactGetBuilder struct {
+	ops []*transactOp
+}
+
+// NewTransactGetBuilder returns a new TransactGetBuilder without operations.
+func NewTransactGetBuilder() *TransactGetBuilder {
+	return &TransactGetBuilder{}
+}
+
+// Get adds an operation reading the item with the key, which is unmarshaled
+// into out by Read, if out is not nil. If a projection is given, only its
+// attributes are read.
+func (b *TransactGetBuilder) Get(table string, key interface{}, out interface{}, projection ...expression.ProjectionBuilder) *TransactGetBuilder {
+	op := &transactOp{op: TransactOpGet, table: table, out: out}
+	b.ops = append(b.ops, op)
+
+	if op.key, op.err = transactKey(table, key); op.err != nil {
+		return b
+	}
+
+	get := &dynamodb.Get{TableName: aws.String(table), Key: op.key}
+	if len(projection) != 0 {
+		expr, err := expression.NewBuilder().WithProjection(projection[0]).Build()
+		if err != nil {
+			op.err = err
+			return b
+		}
+		get.ProjectionExpression = expr.Projection()
+		get.ExpressionAttributeNames = expr.Names()
+	}
+	op.get = &dynamodb.TransactGetItem{Get: get}
+	return b
+}
+
+// Build returns the TransactGetItems input of the operations, or the first
+// error building them.
+func (b *TransactGetBuilder) Build() (*dynamodb.TransactGetItemsInput, error) {
+	if err := checkTransactOps(b.ops, nil); err != nil {
+		return nil, err
+	}
+
+	in := &dynamodb.TransactGetItemsInput{}
+	for _, op := range b.ops {
+		in.TransactItems = append(in.TransactItems, op.get)
+	}
+	return in, nil
+}
+
+// Read builds the operations and reads them with TransactGetItems,
+// unmarshaling the items read into the operations' values. The values of
+// items which do not exist are left unchanged. If the transaction is
+// canceled, a *TransactionCanceledError is returned.
+func (b *TransactGetBuilder) Read(svc dynamodbiface.DynamoDBAPI) (*dynamodb.TransactGetItemsOutput, error) {
+	return b.ReadWithContext(aws.BackgroundContext(), svc)
+}
+
+// ReadWithContext is the same as Read with the additional support for
+// Context input parameters. The Context must not be nil. A nil Context will
+// cause a panic. Use the Context to add deadlining, timeouts, etc.
+func (b *TransactGetBuilder) ReadWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, opts ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+	in, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var reasons []*dynamodb.CancellationReason
+	opts = append(opts[:len(opts):len(opts)], request.WithAppendUserAgent("DynamoDBManager"),
+		withCancellationReasons(&reasons))
+
+	out, err := svc.TransactGetItemsWithContext(ctx, in, opts...)
+	if err != nil {
+		return nil, newTransactionCanceledError(err, reasons, b.ops)
+	}
+
+	for i, resp := range out.Responses {
+		if i >= len(b.ops) || b.ops[i].out == nil || resp == nil || resp.Item == nil {
+			continue
+		}
+		if err := unmarshalTransactItem(b.ops[i].table, resp.Item, b.ops[i].out); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// CancellationReason is the reason an operation of a canceled transaction
+// failed, or the code "None" if it did not.
+type CancellationReason struct {
+	// The index of the operation in the transaction.
+	Index int
+
+	// The operation, one of the TransactOp constants, its table, and the
+	// key of the item it operates on, if known.
+	Operation string
+	TableName string
+	Key       map[string]*dynamodb.AttributeValue
+
+	// The code and message of the reason, e.g. ConditionalCheckFailed.
+	Code    string
+	Message string
+
+	// The item, if the operation's condition failed and
+	// ReturnValuesOnConditionCheckFailure was set.
+	Item map[string]*dynamodb.AttributeValue
+}
+
+// TransactionCanceledError is the TransactionCanceledException error of a
+// canceled transaction, with its cancellation reasons mapped to the
+// operations of the transaction. It satisfies the awserr.RequestFailure
+// interface.
+type TransactionCanceledError struct {
+	awserr.RequestFailure
+
+	// The reasons of each operation, in the order of the operations.
+	Reasons []CancellationReason
+}
+
+// Failed returns the reasons of the operations which caused the
+// transaction to be canceled.
+func (e *TransactionCanceledError) Failed() []CancellationReason {
+	var failed []CancellationReason
+	for _, r := range e.Reasons {
+		if r.Code != "None" && len(r.Code) != 0 {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// newTransactionCanceledError returns the error of the request, decoding
+// the reasons a transaction was canceled, from the response's reasons if
+// available, or otherwise from the error's message.
+func newTransactionCanceledError(err error, reasons []*dynamodb.CancellationReason, ops []*transactOp) error {
+	rf, ok := err.(awserr.RequestFailure)
+	if !ok || rf.Code() != dynamodb.ErrCodeTransactionCanceledException {
+		return err
+	}
+
+	if len(reasons) == 0 {
+		reasons = parseCancellationReasons(rf.Message())
+	}
+
+	cErr := &TransactionCanceledError{RequestFailure: rf}
+	for i, r := range reasons {
+		reason := CancellationReason{
+			Index:   i,
+			Code:    aws.StringValue(r.Code),
+			Message: aws.StringValue(r.Message),
+			Item:    r.Item,
+		}
+		if i < len(ops) {
+			reason.Operation = ops[i].op
+			reason.TableName = ops[i].table
+			reason.Key = ops[i].key
+		}
+		cErr.Reasons = append(cErr.Reasons, reason)
+	}
+	return cErr
+}
+
+// parseCancellationReasons parses the codes of the cancellation reasons
+// from the message of a TransactionCanceledException, e.g.
+//     Transaction cancelled, please refer cancellation reasons for specific
+//     reasons [None, ConditionalCheckFailed]
+func parseCancellationReasons(msg string) []*dynamodb.CancellationReason {
+	start, end := strings.LastIndex(msg, "["), strings.LastIndex(msg, "]")
+	if start < 0 || end < start {
+		return nil
+	}
+
+	var reasons []*dynamodb.CancellationReason
+	for _, code := range strings.Split(msg[start+1:end], ",") {
+		reasons = append(reasons, &dynamodb.CancellationReason{
+			Code: aws.String(strings.TrimSpace(code)),
+		})
+	}
+	return reasons
+}
+
+// withCancellationReasons is a request option reading the cancellation
+// reasons of a TransactionCanceledException error response into reasons.
+// The reasons are not unmarshaled by the JSON protocol's error unmarshaler.
+func withCancellationReasons(reasons *[]*dynamodb.CancellationReason) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.UnmarshalError.PushFrontNamed(request.NamedHandler{
+			Name: "dynamodbmanager.CancellationReasons",
+			Fn: func(r *request.Request) {
+				if r.HTTPResponse == nil || r.HTTPResponse.Body == nil {
+					return
+				}
+				body, err := ioutil.ReadAll(r.HTTPResponse.Body)
+				r.HTTPResponse.Body.Close()
+				r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader(body))
+				if err != nil {
+					return
+				}
+
+				var resp struct {
+					CancellationReasons []*dynamodb.CancellationReason
+				}
+				if err := json.Unmarshal(body, &resp); err == nil {
+					*reasons = resp.CancellationReasons
+				}
+			},
+		})
+	}
+}
+
+// checkTransactOps returns the first error building the operations, or an
+// error if there are too many operations, an item or the operations are too
+// large, or more than one operation on the same item.
+func checkTransactOps(ops []*transactOp, tableKeys map[string][]string) error {
+	if len(ops) == 0 {
+		return awserr.New(ErrCodeInvalidTransaction, "transaction has no operations", nil)
+	}
+	if len(ops) > MaxTransactItems {
+		return awserr.New(ErrCodeInvalidTransaction, fmt.Sprintf(
+			"transaction has %d operations, more than the maximum of %d",
+			len(ops), MaxTransactItems), nil)
+	}
+
+	keyNames := map[string][]string{}
+	for table, names := range tableKeys {
+		keyNames[table] = names
+	}
+	for i, op := range ops {
+		if op.err != nil {
+			return awserr.New(ErrCodeInvalidTransaction, fmt.Sprintf(
+				"failed to build %s operation %d on table %q", op.op, i, op.table), op.err)
+		}
+		if _, ok := keyNames[op.table]; !ok && op.key != nil {
+			keyNames[op.table] = sortedNames(op.key)
+		}
+	}
+
+	var size int
+	for i, op := range ops {
+		if op.item == nil {
+			size += ItemSize(op.key)
+			continue
+		}
+		n := ItemSize(op.item)
+		if n > MaxItemSize {
+			return awserr.New(ErrCodeInvalidTransaction, fmt.Sprintf(
+				"item size of %d bytes of %s operation %d on table %q exceeds the maximum of %d bytes",
+				n, op.op, i, op.table, MaxItemSize), nil)
+		}
+		size += n
+	}
+	if size > MaxTransactSize {
+		return awserr.New(ErrCodeInvalidTransaction, fmt.Sprintf(
+			"transaction size of %d bytes exceeds the maximum of %d bytes",
+			size, MaxTransactSize), nil)
+	}
+
+	tableOps := map[string]int{}
+	for _, op := range ops {
+		tableOps[op.table]++
+	}
+
+	seen := map[string]int{}
+	for i, op := range ops {
+		key := op.key
+		if key == nil {
+			names, ok := keyNames[op.table]
+			if !ok && tableOps[op.table] == 1 {
+				// The only operation on the table cannot be on the same item
+				// as another operation.
+				continue
+			}
+			if !ok {
+				return awserr.New(ErrCodeInvalidTransaction, fmt.Sprintf(
+					"key attributes of table %q are not known for %s operation %d",
+					op.table, op.op, i), nil)
+			}
+			key = map[string]*dynamodb.AttributeValue{}
+			for _, name := range names {
+				key[name] = op.item[name]
+			}
+		}
+
+		names := sortedNames(key)
+		id, err := keyString(op.table, names, key)
+		if err != nil {
+			return awserr.New(ErrCodeInvalidTransaction, fmt.Sprintf(
+				"invalid key for %s operation %d on table %q", op.op, i, op.table), err)
+		}
+		if j, ok := seen[id]; ok {
+			return awserr.New(ErrCodeInvalidTransaction, fmt.Sprintf(
+				"%s operation %d and %s operation %d are on the same item of table %q",
+				ops[j].op, j, op.op, i, op.table), nil)
+		}
+		seen[id] = i
+	}
+	return nil
+}
+
+// isTaggedItem returns if the item is a pointer to a struct with a field
+// tagged as its hash key.
+func isTaggedItem(item interface{}) (bool, error) {
+	v := reflect.ValueOf(item)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return false, nil
+	}
+	_, s, err := structValue(item)
+	if err != nil {
+		return false, err
+	}
+	return s.hash != nil, nil
+}
+
+// transactKey returns the key of a transaction operation.
+func transactKey(table string, key interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	if tagged, err := isTaggedItem(key); err != nil {
+		return nil, err
+	} else if tagged {
+		return (&Table{Name: table}).Key(key)
+	}
+	return marshalTransactMap(key)
+}
+
+// unmarshalTransactItem unmarshals the item read by a transaction into out,
+// the same way as a Table if out is a tagged item.
+func unmarshalTransactItem(table string, item map[string]*dynamodb.AttributeValue, out interface{}) error {
+	if tagged, err := isTaggedItem(out); err != nil {
+		return err
+	} else if tagged {
+		v, s, err := itemValue(out)
+		if err != nil {
+			return err
+		}
+		return (&Table{Name: table}).unmarshal(item, v, s)
+	}
+	return dynamodbattribute.UnmarshalMap(item, out)
+}
+
+func marshalTransactMap(v interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	if m, ok := v.(map[string]*dynamodb.AttributeValue); ok {
+		return m, nil
+	}
+	return dynamodbattribute.MarshalMap(v)
+}
+
+func conditionOptions(conds []expression.ConditionBuilder) func(*WriteOptions) {
+	return func(o *WriteOptions) {
+		o.Conditions = append(o.Conditions, conds...)
+	}
+}
+
@@ -0,0 +1,331 @@
+package dynamodbmanager_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// transactSvc records the transaction requests made to it, returning its
+// outputs and error.
+type transactSvc struct {
+	dynamodbiface.DynamoDBAPI
+
+	write *dynamodb.TransactWriteItemsInput
+	get   *dynamodb.TransactGetItemsInput
+
+	responses []*dynamodb.ItemResponse
+	err       error
+}
+
+func (s *transactSvc) TransactWriteItemsWithContext(ctx aws.Context, in *dynamodb.TransactWriteItemsInput, opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	s.write = in
+	return &dynamodb.TransactWriteItemsOutput{}, s.err
+}
+
+func (s *transactSvc) TransactGetItemsWithContext(ctx aws.Context, in *dynamodb.TransactGetItemsInput, opts ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+	s.get = in
+	return &dynamodb.TransactGetItemsOutput{Responses: s.responses}, s.err
+}
+
+func TestTransactWriteBuilder_Write(t *testing.T) {
+	svc := &transactSvc{}
+
+	item := &tableItem{ID: "a", Sort: 1, Version: 2}
+	tx := dynamodbmanager.NewTransactWriteBuilder()
+	tx.ClientRequestToken = "token"
+	tx.Put("table", item)
+	tx.Update("other", map[string]string{"id": "b"},
+		expression.Set(expression.Name("count"), expression.Name("count").Plus(expression.Value(1))),
+		expression.Name("count").LessThan(expression.Value(10)))
+	tx.Delete("other", map[string]string{"id": "c"})
+	tx.ConditionCheck("other", map[string]string{"id": "d"},
+		expression.AttributeExists(expression.Name("id")))
+
+	if _, err := tx.Write(svc); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	in := svc.write
+	if e, a := "token", aws.StringValue(in.ClientRequestToken); e != a {
+		t.Errorf("expect %v token, got %v", e, a)
+	}
+	if e, a := 4, len(in.TransactItems); e != a {
+		t.Fatalf("expect %d operations, got %d", e, a)
+	}
+
+	put := in.TransactItems[0].Put
+	if e, a := "#0 = :0", aws.StringValue(put.ConditionExpression); e != a {
+		t.Errorf("expect %q put condition, got %q", e, a)
+	}
+	if e, a := "3", *put.Item["version"].N; e != a {
+		t.Errorf("expect %v version written, got %v", e, a)
+	}
+
+	update := in.TransactItems[1].Update
+	if e, a := "#0 < :0", aws.StringValue(update.ConditionExpression); e != a {
+		t.Errorf("expect %q update condition, got %q", e, a)
+	}
+	if e, a := "SET #0 = #0 + :1\n", aws.StringValue(update.UpdateExpression); e != a {
+		t.Errorf("expect %q update expression, got %q", e, a)
+	}
+	if e, a := "b", *update.Key["id"].S; e != a {
+		t.Errorf("expect %v update key, got %v", e, a)
+	}
+
+	del := in.TransactItems[2].Delete
+	if del.ConditionExpression != nil {
+		t.Errorf("expect no delete condition, got %v", *del.ConditionExpression)
+	}
+	check := in.TransactItems[3].ConditionCheck
+	if e, a := "attribute_exists (#0)", aws.StringValue(check.ConditionExpression); e != a {
+		t.Errorf("expect %q check condition, got %q", e, a)
+	}
+
+	if e, a := int64(3), item.Version; e != a {
+		t.Errorf("expect %v version, got %v", e, a)
+	}
+}
+
+func TestTransactWriteBuilder_Invalid(t *testing.T) {
+	cases := []func(*dynamodbmanager.TransactWriteBuilder){
+		func(tx *dynamodbmanager.TransactWriteBuilder) {},
+		func(tx *dynamodbmanager.TransactWriteBuilder) {
+			for i := 0; i < 26; i++ {
+				tx.Delete("table", map[string]int{"id": i})
+			}
+		},
+		func(tx *dynamodbmanager.TransactWriteBuilder) {
+			tx.Delete("table", map[string]string{"id": "a"})
+			tx.Update("table", map[string]string{"id": "a"},
+				expression.Set(expression.Name("v"), expression.Value(1)))
+		},
+		func(tx *dynamodbmanager.TransactWriteBuilder) {
+			tx.Put("table", map[string]string{"id": "a", "v": "x"})
+			tx.ConditionCheck("table", map[string]string{"id": "a"},
+				expression.AttributeExists(expression.Name("id")))
+		},
+		func(tx *dynamodbmanager.TransactWriteBuilder) {
+			tx.TableKeys = map[string][]string{"table": {"id"}}
+			tx.Put("table", map[string]string{"id": "a", "v": "x"})
+			tx.Put("table", map[string]string{"id": "a", "v": "y"})
+		},
+		func(tx *dynamodbmanager.TransactWriteBuilder) {
+			tx.Put("table", map[string]string{"id": "a"})
+			tx.Put("table", map[string]string{"id": "b"})
+		},
+		func(tx *dynamodbmanager.TransactWriteBuilder) {
+			tx.Put("table", &unversionedItem{})
+		},
+		func(tx *dynamodbmanager.TransactWriteBuilder) {
+			tx.TableKeys = map[string][]string{"table": {"id"}}
+			tx.Put("table", map[string]string{
+				"id": "a", "v": strings.Repeat("x", dynamodbmanager.MaxItemSize),
+			})
+		},
+		func(tx *dynamodbmanager.TransactWriteBuilder) {
+			tx.TableKeys = map[string][]string{"table": {"id"}}
+			for i := 0; i < 12; i++ {
+				tx.Put("table", map[string]string{
+					"id": fmt.Sprint(i), "v": strings.Repeat("x", 390*1024),
+				})
+			}
+		},
+	}
+
+	for i, c := range cases {
+		tx := dynamodbmanager.NewTransactWriteBuilder()
+		c(tx)
+		_, err := tx.Build()
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodbmanager.ErrCodeInvalidTransaction {
+			t.Errorf("%d, expect %v error, got %v", i, dynamodbmanager.ErrCodeInvalidTransaction, err)
+		}
+	}
+}
+
+func TestTransactWriteBuilder_UnknownKeys(t *testing.T) {
+	tx := dynamodbmanager.NewTransactWriteBuilder()
+	tx.Put("table", map[string]string{"id": "a"})
+	tx.Put("other", record{ID: "b"})
+	tx.Delete("other", map[string]string{"ID": "c"})
+
+	in, err := tx.Build()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 3, len(in.TransactItems); e != a {
+		t.Errorf("expect %d items, got %d", e, a)
+	}
+}
+
+func TestTransactWriteBuilder_Canceled(t *testing.T) {
+	svc := dynamodb.New(unit.Session)
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{
+			StatusCode: 400,
+			Header:     http.Header{},
+			Body: ioutil.NopCloser(bytes.NewReader([]byte(`{
+				"__type": "com.amazonaws.dynamodb.v20120810#TransactionCanceledException",
+				"Message": "Transaction cancelled, please refer cancellation reasons for specific reasons [None, ConditionalCheckFailed]",
+				"CancellationReasons": [
+					{"Code": "None"},
+					{"Code": "ConditionalCheckFailed", "Message": "The conditional request failed",
+						"Item": {"id": {"S": "b"}, "count": {"N": "10"}}}
+				]}`))),
+		}
+	})
+
+	item := &tableItem{ID: "a", Sort: 1}
+	tx := dynamodbmanager.NewTransactWriteBuilder()
+	tx.ReturnValuesOnConditionCheckFailure = dynamodb.ReturnValuesOnConditionCheckFailureAllOld
+	tx.Put("table", item)
+	tx.Update("other", map[string]string{"id": "b"},
+		expression.Set(expression.Name("count"), expression.Name("count").Plus(expression.Value(1))),
+		expression.Name("count").LessThan(expression.Value(10)))
+
+	_, err := tx.Write(svc)
+	cErr, ok := err.(*dynamodbmanager.TransactionCanceledError)
+	if !ok {
+		t.Fatalf("expect TransactionCanceledError, got %v", err)
+	}
+	if e, a := dynamodb.ErrCodeTransactionCanceledException, cErr.Code(); e != a {
+		t.Errorf("expect %v code, got %v", e, a)
+	}
+	if e, a := 2, len(cErr.Reasons); e != a {
+		t.Fatalf("expect %d reasons, got %d", e, a)
+	}
+
+	failed := cErr.Failed()
+	if e, a := 1, len(failed); e != a {
+		t.Fatalf("expect %d failed, got %d", e, a)
+	}
+	r := failed[0]
+	if e, a := 1, r.Index; e != a {
+		t.Errorf("expect %v index, got %v", e, a)
+	}
+	if e, a := dynamodbmanager.TransactOpUpdate, r.Operation; e != a {
+		t.Errorf("expect %v operation, got %v", e, a)
+	}
+	if e, a := "other", r.TableName; e != a {
+		t.Errorf("expect %v table, got %v", e, a)
+	}
+	if e, a := "b", *r.Key["id"].S; e != a {
+		t.Errorf("expect %v key, got %v", e, a)
+	}
+	if e, a := "ConditionalCheckFailed", r.Code; e != a {
+		t.Errorf("expect %v reason, got %v", e, a)
+	}
+	if e, a := "10", *r.Item["count"].N; e != a {
+		t.Errorf("expect %v item count, got %v", e, a)
+	}
+
+	if e, a := int64(0), item.Version; e != a {
+		t.Errorf("expect %v version unchanged, got %v", e, a)
+	}
+}
+
+func TestTransactWriteBuilder_CanceledMessage(t *testing.T) {
+	svc := &transactSvc{err: awserr.NewRequestFailure(awserr.New(
+		dynamodb.ErrCodeTransactionCanceledException,
+		"Transaction cancelled, please refer cancellation reasons for specific reasons [ConditionalCheckFailed, None]",
+		nil), 400, "id")}
+
+	tx := dynamodbmanager.NewTransactWriteBuilder()
+	tx.Delete("table", map[string]string{"id": "a"}, expression.AttributeExists(expression.Name("id")))
+	tx.Delete("table", map[string]string{"id": "b"})
+
+	_, err := tx.Write(svc)
+	cErr, ok := err.(*dynamodbmanager.TransactionCanceledError)
+	if !ok {
+		t.Fatalf("expect TransactionCanceledError, got %v", err)
+	}
+	failed := cErr.Failed()
+	if e, a := 1, len(failed); e != a {
+		t.Fatalf("expect %d failed, got %d", e, a)
+	}
+	if e, a := "a", *failed[0].Key["id"].S; e != a {
+		t.Errorf("expect %v key, got %v", e, a)
+	}
+	if e, a := dynamodbmanager.TransactOpDelete, failed[0].Operation; e != a {
+		t.Errorf("expect %v operation, got %v", e, a)
+	}
+}
+
+func TestTransactGetBuilder_Read(t *testing.T) {
+	svc := &transactSvc{responses: []*dynamodb.ItemResponse{
+		{Item: map[string]*dynamodb.AttributeValue{
+			"id":    {S: aws.String("a")},
+			"count": {N: aws.String("7")},
+		}},
+		{},
+	}}
+
+	var found, missing tableItem
+	missing.Value = "unchanged"
+
+	tx := dynamodbmanager.NewTransactGetBuilder()
+	tx.Get("table", map[string]string{"id": "a"}, &found,
+		expression.NamesList(expression.Name("id"), expression.Name("count")))
+	tx.Get("table", map[string]string{"id": "b"}, &missing)
+
+	if _, err := tx.Read(svc); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	get := svc.get.TransactItems[0].Get
+	if e, a := "#0, #1", aws.StringValue(get.ProjectionExpression); e != a {
+		t.Errorf("expect %q projection, got %q", e, a)
+	}
+	if e, a := 7, found.Count; e != a {
+		t.Errorf("expect %v count, got %v", e, a)
+	}
+	if e, a := "unchanged", missing.Value; e != a {
+		t.Errorf("expect %v value, got %v", e, a)
+	}
+
+	tx.Get("table", map[string]string{"id": "a"}, nil)
+	_, err := tx.Build()
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodbmanager.ErrCodeInvalidTransaction {
+		t.Errorf("expect %v error, got %v", dynamodbmanager.ErrCodeInvalidTransaction, err)
+	}
+}
+
+func TestTransactGetBuilder_RoundTrip(t *testing.T) {
+	svc := newRoundTripTable(t).Client
+
+	expires := time.Unix(1500000000, 0)
+	item := &tableItem{ID: "a", Sort: 1, Expires: expires, Value: "value"}
+	if _, err := dynamodbmanager.NewTransactWriteBuilder().Put("table", item).Write(svc); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	actual := &tableItem{}
+	tx := dynamodbmanager.NewTransactGetBuilder()
+	tx.Get("table", &tableItem{ID: "a", Sort: 1}, actual)
+	if _, err := tx.Read(svc); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := expires, actual.Expires; !e.Equal(a) {
+		t.Errorf("expect %v expires, got %v", e, a)
+	}
+	if e, a := int64(1), actual.Version; e != a {
+		t.Errorf("expect %v version, got %v", e, a)
+	}
+	if e, a := "value", actual.Value; e != a {
+		t.Errorf("expect %v value, got %v", e, a)
+	}
+}
@@ -0,0 +1,397 @@
+package dynamodbtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	// maxBatchGetKeys is the maximum number of keys of a BatchGetItem
+	// request.
+	maxBatchGetKeys = 100
+
+	// maxBatchWriteRequests is the maximum number of requests of a
+	// BatchWriteItem request.
+	maxBatchWriteRequests = 25
+
+	// maxTransactItems is the maximum number of items of a
+	// TransactWriteItems or TransactGetItems request.
+	maxTransactItems = 25
+)
+
+// BatchGetItem returns the items with the keys of the tables. All keys are
+// processed.
+func (db *DB) BatchGetItem(in *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	return db.BatchGetItemWithContext(aws.BackgroundContext(), in)
+}
+
+// BatchGetItemWithContext is the same as BatchGetItem with the additional
+// support for Context input parameters.
+func (db *DB) BatchGetItemWithContext(ctx aws.Context, in *dynamodb.BatchGetItemInput, opts ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	var n int
+	for _, ka := range in.RequestItems {
+		n += len(ka.Keys)
+	}
+	if n > maxBatchGetKeys {
+		return nil, db.validationError("Too many items requested for the BatchGetItem call")
+	}
+
+	out := &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]*dynamodb.AttributeValue{},
+		UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{},
+	}
+	for name, ka := range in.RequestItems {
+		if err := db.rejectLegacy(map[string]bool{"AttributesToGet": ka.AttributesToGet != nil}); err != nil {
+			return nil, err
+		}
+		t, err := db.getTable(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+
+		seen := map[string]bool{}
+		for _, key := range ka.Keys {
+			k := keyString(t.key.names(), key)
+			if seen[k] {
+				return nil, db.validationError("Provided list of item keys contains duplicates")
+			}
+			seen[k] = true
+		}
+
+		items := []map[string]*dynamodb.AttributeValue{}
+		for _, key := range ka.Keys {
+			it, _, err := db.getItem(aws.String(name), key, ka.ProjectionExpression, ka.ExpressionAttributeNames)
+			if err != nil {
+				return nil, err
+			}
+			if it != nil {
+				items = append(items, it)
+			}
+		}
+		out.Responses[name] = items
+	}
+	return out, nil
+}
+
+// BatchGetItemPages calls fn with each page of the batch's items.
+func (db *DB) BatchGetItemPages(in *dynamodb.BatchGetItemInput, fn func(*dynamodb.BatchGetItemOutput, bool) bool) error {
+	return db.BatchGetItemPagesWithContext(aws.BackgroundContext(), in, fn)
+}
+
+// BatchGetItemPagesWithContext is the same as BatchGetItemPages with the
+// additional support for Context input parameters.
+func (db *DB) BatchGetItemPagesWithContext(ctx aws.Context, in *dynamodb.BatchGetItemInput, fn func(*dynamodb.BatchGetItemOutput, bool) bool, opts ...request.Option) error {
+	out, err := db.BatchGetItemWithContext(ctx, in, opts...)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+// BatchWriteItem puts and deletes the items of the tables. The requests are
+// validated before any item is written, and all requests are processed.
+func (db *DB) BatchWriteItem(in *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	return db.BatchWriteItemWithContext(aws.BackgroundContext(), in)
+}
+
+// BatchWriteItemWithContext is the same as BatchWriteItem with the additional
+// support for Context input parameters.
+func (db *DB) BatchWriteItemWithContext(ctx aws.Context, in *dynamodb.BatchWriteItemInput, opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	var n int
+	for _, reqs := range in.RequestItems {
+		n += len(reqs)
+	}
+	if n > maxBatchWriteRequests {
+		return nil, db.validationError("Too many items requested for the BatchWriteItem call")
+	}
+
+	var writes []*write
+	for name, reqs := range in.RequestItems {
+		t, err := db.getTable(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+
+		seen := map[string]bool{}
+		for _, req := range reqs {
+			var w *write
+			switch {
+			case req.PutRequest != nil && req.DeleteRequest != nil, req.PutRequest == nil && req.DeleteRequest == nil:
+				return nil, db.validationError("Supplied AttributeValue has more than one datatypes set, must contain exactly one of the supported datatypes")
+			case req.PutRequest != nil:
+				w, err = db.preparePut(t, req.PutRequest.Item, expressionInput{})
+			default:
+				w, err = db.prepareDelete(t, req.DeleteRequest.Key, expressionInput{})
+			}
+			if err != nil {
+				return nil, err
+			}
+			if seen[w.key] {
+				return nil, db.validationError("Provided list of item keys contains duplicates")
+			}
+			seen[w.key] = true
+			writes = append(writes, w)
+		}
+	}
+
+	for _, w := range writes {
+		w.commit()
+	}
+	return &dynamodb.BatchWriteItemOutput{
+		UnprocessedItems: map[string][]*dynamodb.WriteRequest{},
+	}, nil
+}
+
+// TransactWriteItems atomically puts, updates and deletes items, if all of
+// the condition expressions are satisfied. If any condition is not
+// satisfied, no item is written, and a TransactionCanceledException error is
+// returned.
+//
+// The cancellation reasons of the error are unmarshaled by the UnmarshalError
+// handlers added by the request options, as for the DynamoDB API client.
+func (db *DB) TransactWriteItems(in *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return db.TransactWriteItemsWithContext(aws.BackgroundContext(), in)
+}
+
+// TransactWriteItemsWithContext is the same as TransactWriteItems with the
+// additional support for Context input parameters.
+func (db *DB) TransactWriteItemsWithContext(ctx aws.Context, in *dynamodb.TransactWriteItemsInput, opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	if len(in.TransactItems) > maxTransactItems {
+		return nil, db.validationError("1 validation error detected: Value at 'transactItems' failed to satisfy constraint: Member must have length less than or equal to %d",
+			maxTransactItems)
+	}
+
+	var writes []*write
+	reasons := make([]*dynamodb.CancellationReason, len(in.TransactItems))
+	var canceled bool
+	seen := map[string]bool{}
+	for i, ti := range in.TransactItems {
+		w, rv, err := db.prepareTransactWrite(ti)
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			reasons[i] = &dynamodb.CancellationReason{
+				Code:    aws.String("ConditionalCheckFailed"),
+				Message: aws.String(aerr.Message()),
+			}
+			if aws.StringValue(rv) == dynamodb.ReturnValuesOnConditionCheckFailureAllOld {
+				reasons[i].Item = db.transactItemOld(ti)
+			}
+			canceled = true
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		id := aws.StringValue(w.table.description.TableName) + "/" + w.key
+		if seen[id] {
+			return nil, db.validationError("Transaction request cannot include multiple operations on one item")
+		}
+		seen[id] = true
+		reasons[i] = &dynamodb.CancellationReason{Code: aws.String("None")}
+		writes = append(writes, w)
+	}
+	if canceled {
+		return nil, db.transactionCanceled(reasons, opts)
+	}
+
+	for _, w := range writes {
+		if w.new != nil || w.old != nil {
+			w.commit()
+		}
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// prepareTransactWrite returns the write of the transaction item, and its
+// ReturnValuesOnConditionCheckFailure parameter. The write of a condition
+// check leaves the item unchanged. The DB must be locked.
+func (db *DB) prepareTransactWrite(ti *dynamodb.TransactWriteItem) (*write, *string, error) {
+	var n int
+	for _, set := range []bool{ti.Put != nil, ti.Update != nil, ti.Delete != nil, ti.ConditionCheck != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, nil, db.validationError("TransactItems can only contain one of Check, Put, Update or Delete")
+	}
+
+	switch {
+	case ti.Put != nil:
+		t, err := db.getTable(ti.Put.TableName)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, err := db.preparePut(t, ti.Put.Item, expressionInput{
+			condition: ti.Put.ConditionExpression,
+			names:     ti.Put.ExpressionAttributeNames,
+			values:    ti.Put.ExpressionAttributeValues,
+		})
+		return w, ti.Put.ReturnValuesOnConditionCheckFailure, err
+	case ti.Update != nil:
+		t, err := db.getTable(ti.Update.TableName)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, err := db.prepareUpdate(t, ti.Update.Key, expressionInput{
+			condition: ti.Update.ConditionExpression,
+			update:    ti.Update.UpdateExpression,
+			names:     ti.Update.ExpressionAttributeNames,
+			values:    ti.Update.ExpressionAttributeValues,
+		})
+		return w, ti.Update.ReturnValuesOnConditionCheckFailure, err
+	case ti.Delete != nil:
+		t, err := db.getTable(ti.Delete.TableName)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, err := db.prepareDelete(t, ti.Delete.Key, expressionInput{
+			condition: ti.Delete.ConditionExpression,
+			names:     ti.Delete.ExpressionAttributeNames,
+			values:    ti.Delete.ExpressionAttributeValues,
+		})
+		return w, ti.Delete.ReturnValuesOnConditionCheckFailure, err
+	default:
+		t, err := db.getTable(ti.ConditionCheck.TableName)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, err := db.prepareDelete(t, ti.ConditionCheck.Key, expressionInput{
+			condition: ti.ConditionCheck.ConditionExpression,
+			names:     ti.ConditionCheck.ExpressionAttributeNames,
+			values:    ti.ConditionCheck.ExpressionAttributeValues,
+		})
+		if w != nil {
+			w.new = w.old
+		}
+		return w, ti.ConditionCheck.ReturnValuesOnConditionCheckFailure, err
+	}
+}
+
+// transactItemOld returns a copy of the existing item of the transaction
+// item. The DB must be locked.
+func (db *DB) transactItemOld(ti *dynamodb.TransactWriteItem) map[string]*dynamodb.AttributeValue {
+	var tableName *string
+	var key item
+	switch {
+	case ti.Put != nil:
+		tableName = ti.Put.TableName
+	case ti.Update != nil:
+		tableName, key = ti.Update.TableName, ti.Update.Key
+	case ti.Delete != nil:
+		tableName, key = ti.Delete.TableName, ti.Delete.Key
+	case ti.ConditionCheck != nil:
+		tableName, key = ti.ConditionCheck.TableName, ti.ConditionCheck.Key
+	}
+
+	t := db.tables[aws.StringValue(tableName)]
+	if ti.Put != nil {
+		key = ti.Put.Item
+	}
+	return copyItem(t.items[keyString(t.key.names(), key)])
+}
+
+// TransactGetItems atomically returns the items with the keys.
+func (db *DB) TransactGetItems(in *dynamodb.TransactGetItemsInput) (*dynamodb.TransactGetItemsOutput, error) {
+	return db.TransactGetItemsWithContext(aws.BackgroundContext(), in)
+}
+
+// TransactGetItemsWithContext is the same as TransactGetItems with the
+// additional support for Context input parameters.
+func (db *DB) TransactGetItemsWithContext(ctx aws.Context, in *dynamodb.TransactGetItemsInput, opts ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	if len(in.TransactItems) > maxTransactItems {
+		return nil, db.validationError("1 validation error detected: Value at 'transactItems' failed to satisfy constraint: Member must have length less than or equal to %d",
+			maxTransactItems)
+	}
+
+	out := &dynamodb.TransactGetItemsOutput{}
+	seen := map[string]bool{}
+	for _, ti := range in.TransactItems {
+		get := ti.Get
+		t, err := db.getTable(get.TableName)
+		if err != nil {
+			return nil, err
+		}
+		id := aws.StringValue(get.TableName) + "/" + keyString(t.key.names(), get.Key)
+		if seen[id] {
+			return nil, db.validationError("Transaction request cannot include multiple operations on one item")
+		}
+		seen[id] = true
+
+		it, _, err := db.getItem(get.TableName, get.Key, get.ProjectionExpression, get.ExpressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+		out.Responses = append(out.Responses, &dynamodb.ItemResponse{Item: it})
+	}
+	return out, nil
+}
+
+// transactionCanceled returns a TransactionCanceledException error with the
+// cancellation reasons. The JSON error document of the error is unmarshaled
+// by the UnmarshalError handlers of the request options. The DB must be
+// locked.
+func (db *DB) transactionCanceled(reasons []*dynamodb.CancellationReason, opts []request.Option) error {
+	codes := make([]string, len(reasons))
+	for i, r := range reasons {
+		codes[i] = aws.StringValue(r.Code)
+	}
+	msg := fmt.Sprintf("Transaction cancelled, please refer cancellation reasons for specific reasons [%s]",
+		strings.Join(codes, ", "))
+	err := db.newError(dynamodb.ErrCodeTransactionCanceledException, "%s", msg)
+
+	body, jsonErr := json.Marshal(struct {
+		Type                string `json:"__type"`
+		Message             string
+		CancellationReasons []*dynamodb.CancellationReason
+	}{
+		Type:                "com.amazonaws.dynamodb.v20120810#" + dynamodb.ErrCodeTransactionCanceledException,
+		Message:             msg,
+		CancellationReasons: reasons,
+	})
+	if jsonErr != nil {
+		return err
+	}
+
+	r := request.New(aws.Config{}, metadata.ClientInfo{ServiceName: dynamodb.ServiceName},
+		request.Handlers{}, nil, &request.Operation{Name: "TransactWriteItems"}, nil, nil)
+	r.ApplyOptions(opts...)
+	r.HTTPResponse = &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}
+	r.Error = err
+	r.Handlers.UnmarshalError.Run(r)
+	return r.Error
+}
@@ -0,0 +1,623 @@
+// Package dynamodbtest provides an in-memory Amazon DynamoDB implementation of
+// the dynamodbiface.DynamoDBAPI interface, for testing code which uses the
+// DynamoDB API client without making network requests to DynamoDB.
+//
+// The DB stores the items of its tables in memory, and evaluates the
+// condition, filter, key condition, projection and update expressions of
+// requests with the expression package, returning the same awserr error codes
+// as DynamoDB when a request is invalid or a condition is not satisfied. It
+// supports tables with local and global secondary indexes, Query and Scan
+// pagination, batch operations and transactions.
+//
+// Items larger than 400 KB are rejected, and Query and Scan requests read at
+// most 1 MB of items per page, as DynamoDB does. The capacity units returned
+// when ReturnConsumedCapacity is set are estimated from the sizes of the
+// items, with the dynamodbmanager package.
+//
+// Only the table, item, query, scan, batch and transaction API operations,
+// and the table waiters, are implemented. Calling any other method of the
+// interface, including the Request methods, panics. The legacy parameters of
+// the API operations, such as Expected, AttributeUpdates and KeyConditions,
+// are not supported.
+//
+// Example:
+//     db := dynamodbtest.NewDB()
+//
+//     _, err := db.CreateTable(&dynamodb.CreateTableInput{
+//         TableName: aws.String("table"),
+//         AttributeDefinitions: []*dynamodb.AttributeDefinition{
+//             {AttributeName: aws.String("id"), AttributeType: aws.String("S")},
+//         },
+//         KeySchema: []*dynamodb.KeySchemaElement{
+//             {AttributeName: aws.String("id"), KeyType: aws.String("HASH")},
+//         },
+//     })
+//
+//     // Use db wherever a dynamodbiface.DynamoDBAPI is expected.
+//     table := dynamodbmanager.NewTableWithClient(db, "table")
+package dynamodbtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+)
+
+// ErrCodeValidationException is the code of the error returned by DynamoDB
+// for requests with invalid parameters or expressions.
+const ErrCodeValidationException = "ValidationException"
+
+// DefaultRegion is the region in the ARNs of the DB's tables if no region is
+// set.
+const DefaultRegion = "us-east-1"
+
+// DB is an in-memory DynamoDB implementing the dynamodbiface.DynamoDBAPI
+// interface. It is safe to use the DB concurrently. Its configuration must not
+// be changed while serving requests.
+type DB struct {
+	// The DB embeds the interface so that it satisfies it. The methods which
+	// are not implemented by the DB panic, since the embedded interface is
+	// nil.
+	dynamodbiface.DynamoDBAPI
+
+	// The region in the ARNs of tables. If empty, the DefaultRegion is used.
+	Region string
+
+	m         sync.Mutex
+	tables    map[string]*table
+	requestID int64
+}
+
+// NewDB returns a new DB without tables.
+func NewDB() *DB {
+	return &DB{tables: map[string]*table{}}
+}
+
+var _ dynamodbiface.DynamoDBAPI = (*DB)(nil)
+
+func (db *DB) region() string {
+	if len(db.Region) == 0 {
+		return DefaultRegion
+	}
+	return db.Region
+}
+
+// newError returns a request failure with the code and message, as returned
+// by the DynamoDB API client. The DB must be locked.
+func (db *DB) newError(code, format string, args ...interface{}) error {
+	db.requestID++
+	status := 400
+	if code == dynamodb.ErrCodeInternalServerError {
+		status = 500
+	}
+	return awserr.NewRequestFailure(awserr.New(code, fmt.Sprintf(format, args...), nil),
+		status, fmt.Sprintf("DYNAMODBTEST%016d", db.requestID))
+}
+
+// validationError returns a ValidationException request failure. The DB must
+// be locked.
+func (db *DB) validationError(format string, args ...interface{}) error {
+	return db.newError(ErrCodeValidationException, format, args...)
+}
+
+// begin validates the input, and returns an error if the context is done.
+// On success, the DB is locked, and must be unlocked by the caller.
+func (db *DB) begin(ctx aws.Context, in request.Validator) error {
+	if err := in.Validate(); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+	default:
+	}
+
+	db.m.Lock()
+	if db.tables == nil {
+		db.tables = map[string]*table{}
+	}
+	return nil
+}
+
+// getTable returns the table, or a ResourceNotFoundException error. The DB
+// must be locked.
+func (db *DB) getTable(name *string) (*table, error) {
+	t, ok := db.tables[aws.StringValue(name)]
+	if !ok {
+		return nil, db.newError(dynamodb.ErrCodeResourceNotFoundException,
+			"Requested resource not found: Table: %s not found", aws.StringValue(name))
+	}
+	return t, nil
+}
+
+// item is the attributes of an item.
+type item map[string]*dynamodb.AttributeValue
+
+// table is a table, its indexes, and its items by key.
+type table struct {
+	description *dynamodb.TableDescription
+	key         keySchema
+	types       map[string]string
+	indexes     map[string]*index
+	items       map[string]item
+}
+
+// keySchema is the names of the hash key and range key, if any, of a table
+// or index.
+type keySchema struct {
+	hash, rng string
+}
+
+// names returns the names of the key attributes.
+func (k keySchema) names() []string {
+	if len(k.rng) == 0 {
+		return []string{k.hash}
+	}
+	return []string{k.hash, k.rng}
+}
+
+// index is a local or global secondary index of a table.
+type index struct {
+	name   string
+	global bool
+	key    keySchema
+
+	// The projection type, and the non-key attributes of an INCLUDE
+	// projection.
+	projection string
+	include    []string
+}
+
+func newKeySchema(elements []*dynamodb.KeySchemaElement) (keySchema, bool) {
+	var k keySchema
+	for _, e := range elements {
+		switch aws.StringValue(e.KeyType) {
+		case dynamodb.KeyTypeHash:
+			if len(k.hash) != 0 {
+				return k, false
+			}
+			k.hash = aws.StringValue(e.AttributeName)
+		case dynamodb.KeyTypeRange:
+			if len(k.rng) != 0 {
+				return k, false
+			}
+			k.rng = aws.StringValue(e.AttributeName)
+		}
+	}
+	return k, len(k.hash) != 0
+}
+
+// keyString returns a string identifying the item by the values of the key
+// attributes.
+func keyString(names []string, it item) string {
+	var b strings.Builder
+	for _, name := range names {
+		av := it[name]
+		switch {
+		case av == nil:
+			b.WriteString("-")
+		case av.S != nil:
+			fmt.Fprintf(&b, "S%q", *av.S)
+		case av.N != nil:
+			fmt.Fprintf(&b, "N%q", normalizeNumber(*av.N))
+		case av.B != nil:
+			fmt.Fprintf(&b, "B%q", av.B)
+		}
+	}
+	return b.String()
+}
+
+// keyOf returns the key attributes of the item.
+func (k keySchema) keyOf(it item) item {
+	key := item{}
+	for _, name := range k.names() {
+		if av, ok := it[name]; ok {
+			key[name] = av
+		}
+	}
+	return key
+}
+
+// validateKey returns an error if the key attributes of the item are missing
+// or have the wrong types. If exact is true, the item must only have the key
+// attributes. The DB must be locked.
+func (db *DB) validateKey(t *table, it item, exact bool) error {
+	if exact && len(it) != len(t.key.names()) {
+		return db.validationError("The provided key element does not match the schema")
+	}
+	for _, name := range t.key.names() {
+		av, ok := it[name]
+		if !ok || av == nil {
+			if exact {
+				return db.validationError("The provided key element does not match the schema")
+			}
+			return db.validationError("One or more parameter values were invalid: Missing the key %s in the item", name)
+		}
+		if e, a := t.types[name], attributeType(av); e != a {
+			if exact {
+				return db.validationError("The provided key element does not match the schema")
+			}
+			return db.validationError("One or more parameter values were invalid: Type mismatch for key %s expected: %s actual: %s",
+				name, e, a)
+		}
+		if (av.S != nil && len(*av.S) == 0) || (av.B != nil && len(av.B) == 0) {
+			return db.validationError("One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: %s", name)
+		}
+		if err := db.validateValue(av); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateItem returns an error if the item's attribute values are invalid,
+// the item's index key attributes have the wrong types, or the item is larger
+// than the maximum item size. The DB must be locked.
+func (db *DB) validateItem(t *table, it item) error {
+	if err := db.validateKey(t, it, false); err != nil {
+		return err
+	}
+	if dynamodbmanager.ItemSize(it) > dynamodbmanager.MaxItemSize {
+		return db.validationError("Item size has exceeded the maximum allowed size")
+	}
+	for name, av := range it {
+		if err := db.validateValue(av); err != nil {
+			return err
+		}
+		if e, ok := t.types[name]; ok && e != attributeType(av) {
+			return db.validationError("One or more parameter values were invalid: Type mismatch for Index Key %s Expected: %s Actual: %s",
+				name, e, attributeType(av))
+		}
+	}
+	return nil
+}
+
+// validateValue returns an error if the attribute value has no type, is an
+// empty set, or has a number which is not valid. The DB must be locked.
+func (db *DB) validateValue(av *dynamodb.AttributeValue) error {
+	switch attributeType(av) {
+	case "":
+		return db.validationError("Supplied AttributeValue is empty, must contain exactly one of the supported datatypes")
+	case "N":
+		if _, ok := parseNumber(*av.N); !ok {
+			return db.validationError("A value provided cannot be converted into a number")
+		}
+	case "SS", "NS", "BS":
+		if len(av.SS)+len(av.NS)+len(av.BS) == 0 {
+			return db.validationError("One or more parameter values were invalid: An number set  may not be empty")
+		}
+		for _, n := range av.NS {
+			if _, ok := parseNumber(aws.StringValue(n)); !ok {
+				return db.validationError("A value provided cannot be converted into a number")
+			}
+		}
+	case "L":
+		for _, v := range av.L {
+			if err := db.validateValue(v); err != nil {
+				return err
+			}
+		}
+	case "M":
+		for _, v := range av.M {
+			if err := db.validateValue(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// consumedCapacity returns the capacity units consumed by a request on the
+// table, if the request's ReturnConsumedCapacity parameter is set. The units
+// are estimated from the sizes of the items read or written, and are only
+// returned as the total for the table.
+func consumedCapacity(returnConsumedCapacity, tableName *string, units float64) *dynamodb.ConsumedCapacity {
+	switch aws.StringValue(returnConsumedCapacity) {
+	case dynamodb.ReturnConsumedCapacityTotal, dynamodb.ReturnConsumedCapacityIndexes:
+		return &dynamodb.ConsumedCapacity{
+			TableName:     tableName,
+			CapacityUnits: aws.Float64(units),
+		}
+	}
+	return nil
+}
+
+// readConsistency returns the consistency of a read with the ConsistentRead
+// parameter.
+func readConsistency(consistentRead *bool) dynamodbmanager.ReadConsistency {
+	if aws.BoolValue(consistentRead) {
+		return dynamodbmanager.StronglyConsistent
+	}
+	return dynamodbmanager.EventuallyConsistent
+}
+
+// CreateTable creates a table, which is immediately active.
+func (db *DB) CreateTable(in *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	return db.CreateTableWithContext(aws.BackgroundContext(), in)
+}
+
+// CreateTableWithContext is the same as CreateTable with the additional
+// support for Context input parameters.
+func (db *DB) CreateTableWithContext(ctx aws.Context, in *dynamodb.CreateTableInput, opts ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	name := aws.StringValue(in.TableName)
+	if _, ok := db.tables[name]; ok {
+		return nil, db.newError(dynamodb.ErrCodeResourceInUseException, "Table already exists: %s", name)
+	}
+
+	t := &table{
+		types:   map[string]string{},
+		indexes: map[string]*index{},
+		items:   map[string]item{},
+	}
+	for _, def := range in.AttributeDefinitions {
+		t.types[aws.StringValue(def.AttributeName)] = aws.StringValue(def.AttributeType)
+	}
+
+	var ok bool
+	if t.key, ok = newKeySchema(in.KeySchema); !ok {
+		return nil, db.validationError("1 validation error detected: Invalid KeySchema")
+	}
+	used := map[string]bool{}
+	checkDefined := func(k keySchema) error {
+		for _, name := range k.names() {
+			if _, ok := t.types[name]; !ok {
+				return db.validationError("One or more parameter values were invalid: Some index key attributes are not defined in AttributeDefinitions. Keys: [%s], AttributeDefinitions: %v",
+					name, sortedKeys(t.types))
+			}
+			used[name] = true
+		}
+		return nil
+	}
+	if err := checkDefined(t.key); err != nil {
+		return nil, err
+	}
+
+	desc := &dynamodb.TableDescription{
+		TableName:            aws.String(name),
+		TableArn:             aws.String(fmt.Sprintf("arn:aws:dynamodb:%s:000000000000:table/%s", db.region(), name)),
+		TableStatus:          aws.String(dynamodb.TableStatusActive),
+		CreationDateTime:     aws.Time(time.Now()),
+		KeySchema:            in.KeySchema,
+		AttributeDefinitions: in.AttributeDefinitions,
+		StreamSpecification:  in.StreamSpecification,
+	}
+	if in.BillingMode != nil {
+		desc.BillingModeSummary = &dynamodb.BillingModeSummary{BillingMode: in.BillingMode}
+	}
+	if in.ProvisionedThroughput != nil {
+		desc.ProvisionedThroughput = &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  in.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: in.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+
+	addIndex := func(indexName *string, elements []*dynamodb.KeySchemaElement, projection *dynamodb.Projection, global bool) error {
+		idx := &index{name: aws.StringValue(indexName), global: global}
+		if idx.key, ok = newKeySchema(elements); !ok {
+			return db.validationError("1 validation error detected: Invalid KeySchema of index %s", idx.name)
+		}
+		if !global && (idx.key.hash != t.key.hash || len(idx.key.rng) == 0) {
+			return db.validationError("One or more parameter values were invalid: Table KeySchema does not have a range key, which is required when specifying a LocalSecondaryIndex")
+		}
+		if _, ok := t.indexes[idx.name]; ok {
+			return db.validationError("One or more parameter values were invalid: Duplicate index name: %s", idx.name)
+		}
+		if err := checkDefined(idx.key); err != nil {
+			return err
+		}
+		if projection != nil {
+			idx.projection = aws.StringValue(projection.ProjectionType)
+			idx.include = aws.StringValueSlice(projection.NonKeyAttributes)
+		}
+		if len(idx.projection) == 0 {
+			idx.projection = dynamodb.ProjectionTypeAll
+		}
+		t.indexes[idx.name] = idx
+		return nil
+	}
+
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		if err := addIndex(gsi.IndexName, gsi.KeySchema, gsi.Projection, true); err != nil {
+			return nil, err
+		}
+		desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:   gsi.IndexName,
+			IndexArn:    aws.String(aws.StringValue(desc.TableArn) + "/index/" + aws.StringValue(gsi.IndexName)),
+			IndexStatus: aws.String(dynamodb.IndexStatusActive),
+			KeySchema:   gsi.KeySchema,
+			Projection:  gsi.Projection,
+		})
+	}
+	for _, lsi := range in.LocalSecondaryIndexes {
+		if err := addIndex(lsi.IndexName, lsi.KeySchema, lsi.Projection, false); err != nil {
+			return nil, err
+		}
+		desc.LocalSecondaryIndexes = append(desc.LocalSecondaryIndexes, &dynamodb.LocalSecondaryIndexDescription{
+			IndexName:  lsi.IndexName,
+			IndexArn:   aws.String(aws.StringValue(desc.TableArn) + "/index/" + aws.StringValue(lsi.IndexName)),
+			KeySchema:  lsi.KeySchema,
+			Projection: lsi.Projection,
+		})
+	}
+
+	for name := range t.types {
+		if !used[name] {
+			return nil, db.validationError("One or more parameter values were invalid: Number of attributes in KeySchema does not exactly match number of attributes defined in AttributeDefinitions")
+		}
+	}
+
+	t.description = desc
+	db.tables[name] = t
+	return &dynamodb.CreateTableOutput{TableDescription: t.describe()}, nil
+}
+
+// describe returns the description of the table with its current item
+// count.
+func (t *table) describe() *dynamodb.TableDescription {
+	desc := *t.description
+	desc.ItemCount = aws.Int64(int64(len(t.items)))
+	return &desc
+}
+
+// DeleteTable deletes a table and its items.
+func (db *DB) DeleteTable(in *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
+	return db.DeleteTableWithContext(aws.BackgroundContext(), in)
+}
+
+// DeleteTableWithContext is the same as DeleteTable with the additional
+// support for Context input parameters.
+func (db *DB) DeleteTableWithContext(ctx aws.Context, in *dynamodb.DeleteTableInput, opts ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	t, err := db.getTable(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	delete(db.tables, aws.StringValue(in.TableName))
+
+	desc := t.describe()
+	desc.TableStatus = aws.String(dynamodb.TableStatusDeleting)
+	return &dynamodb.DeleteTableOutput{TableDescription: desc}, nil
+}
+
+// DescribeTable returns the description of a table.
+func (db *DB) DescribeTable(in *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	return db.DescribeTableWithContext(aws.BackgroundContext(), in)
+}
+
+// DescribeTableWithContext is the same as DescribeTable with the additional
+// support for Context input parameters.
+func (db *DB) DescribeTableWithContext(ctx aws.Context, in *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	t, err := db.getTable(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.DescribeTableOutput{Table: t.describe()}, nil
+}
+
+// ListTables returns the names of the tables, in order.
+func (db *DB) ListTables(in *dynamodb.ListTablesInput) (*dynamodb.ListTablesOutput, error) {
+	return db.ListTablesWithContext(aws.BackgroundContext(), in)
+}
+
+// ListTablesWithContext is the same as ListTables with the additional
+// support for Context input parameters.
+func (db *DB) ListTablesWithContext(ctx aws.Context, in *dynamodb.ListTablesInput, opts ...request.Option) (*dynamodb.ListTablesOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	limit := int(aws.Int64Value(in.Limit))
+	if limit == 0 {
+		limit = 100
+	}
+
+	out := &dynamodb.ListTablesOutput{TableNames: []*string{}}
+	for _, name := range sortedTableNames(db.tables) {
+		if in.ExclusiveStartTableName != nil && name <= *in.ExclusiveStartTableName {
+			continue
+		}
+		if len(out.TableNames) == limit {
+			out.LastEvaluatedTableName = out.TableNames[len(out.TableNames)-1]
+			break
+		}
+		out.TableNames = append(out.TableNames, aws.String(name))
+	}
+	return out, nil
+}
+
+// ListTablesPages calls fn with each page of table names.
+func (db *DB) ListTablesPages(in *dynamodb.ListTablesInput, fn func(*dynamodb.ListTablesOutput, bool) bool) error {
+	return db.ListTablesPagesWithContext(aws.BackgroundContext(), in, fn)
+}
+
+// ListTablesPagesWithContext is the same as ListTablesPages with the
+// additional support for Context input parameters.
+func (db *DB) ListTablesPagesWithContext(ctx aws.Context, in *dynamodb.ListTablesInput, fn func(*dynamodb.ListTablesOutput, bool) bool, opts ...request.Option) error {
+	page := *in
+	for {
+		out, err := db.ListTablesWithContext(ctx, &page, opts...)
+		if err != nil {
+			return err
+		}
+		last := out.LastEvaluatedTableName == nil
+		if !fn(out, last) || last {
+			return nil
+		}
+		page.ExclusiveStartTableName = out.LastEvaluatedTableName
+	}
+}
+
+// WaitUntilTableExists returns nil if the table exists, since tables are
+// created immediately, or a ResourceNotFoundException error if it does not.
+func (db *DB) WaitUntilTableExists(in *dynamodb.DescribeTableInput) error {
+	return db.WaitUntilTableExistsWithContext(aws.BackgroundContext(), in)
+}
+
+// WaitUntilTableExistsWithContext is the same as WaitUntilTableExists with
+// the additional support for Context input parameters.
+func (db *DB) WaitUntilTableExistsWithContext(ctx aws.Context, in *dynamodb.DescribeTableInput, opts ...request.WaiterOption) error {
+	_, err := db.DescribeTableWithContext(ctx, in)
+	return err
+}
+
+// WaitUntilTableNotExists returns nil if the table does not exist, since
+// tables are deleted immediately, or an error if it does.
+func (db *DB) WaitUntilTableNotExists(in *dynamodb.DescribeTableInput) error {
+	return db.WaitUntilTableNotExistsWithContext(aws.BackgroundContext(), in)
+}
+
+// WaitUntilTableNotExistsWithContext is the same as WaitUntilTableNotExists
+// with the additional support for Context input parameters.
+func (db *DB) WaitUntilTableNotExistsWithContext(ctx aws.Context, in *dynamodb.DescribeTableInput, opts ...request.WaiterOption) error {
+	_, err := db.DescribeTableWithContext(ctx, in)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return awserr.New(request.WaiterResourceNotReadyErrorCode,
+		fmt.Sprintf("table %s still exists", aws.StringValue(in.TableName)), nil)
+}
+
+func sortedTableNames(tables map[string]*table) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
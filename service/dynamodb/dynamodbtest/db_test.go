@@ -0,0 +1,709 @@
+package dynamodbtest_test
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbtest"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+type record struct {
+	ID      string `dynamodbav:"id,hashkey"`
+	Sort    int    `dynamodbav:"sort,rangekey"`
+	Version int64  `dynamodbav:"version,version"`
+	Group   string `dynamodbav:"group,omitempty"`
+	Rank    int    `dynamodbav:"rank"`
+	Value   string `dynamodbav:"value,omitempty"`
+}
+
+// newTestDB returns a DB with a table "records" keyed by id and sort, with
+// a global secondary index "group" keyed by group and rank, and a local
+// secondary index "rank" keyed by id and rank.
+func newTestDB(t *testing.T) *dynamodbtest.DB {
+	db := dynamodbtest.NewDB()
+	_, err := db.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("records"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("id"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("sort"), AttributeType: aws.String("N")},
+			{AttributeName: aws.String("group"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("rank"), AttributeType: aws.String("N")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("id"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("sort"), KeyType: aws.String("RANGE")},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{{
+			IndexName: aws.String("group"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("group"), KeyType: aws.String("HASH")},
+				{AttributeName: aws.String("rank"), KeyType: aws.String("RANGE")},
+			},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("KEYS_ONLY")},
+		}},
+		LocalSecondaryIndexes: []*dynamodb.LocalSecondaryIndex{{
+			IndexName: aws.String("rank"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("id"), KeyType: aws.String("HASH")},
+				{AttributeName: aws.String("rank"), KeyType: aws.String("RANGE")},
+			},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("ALL")},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	return db
+}
+
+func putRecords(t *testing.T, db *dynamodbtest.DB, records ...record) {
+	for _, r := range records {
+		item, err := dynamodbattribute.MarshalMap(r)
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		if _, err := db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("records"), Item: item}); err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+	}
+}
+
+func recordKey(id string, sort int) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id":   {S: aws.String(id)},
+		"sort": {N: aws.String(strconv.Itoa(sort))},
+	}
+}
+
+func assertErrorCode(t *testing.T, err error, code string) {
+	aerr, ok := err.(awserr.RequestFailure)
+	if !ok {
+		t.Fatalf("expect %v request failure, got %v", code, err)
+	}
+	if e, a := code, aerr.Code(); e != a {
+		t.Errorf("expect %v error code, got %v: %v", e, a, aerr.Message())
+	}
+}
+
+func TestDB_Tables(t *testing.T) {
+	db := newTestDB(t)
+
+	_, err := db.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("records"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("id"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("id"), KeyType: aws.String("HASH")},
+		},
+	})
+	assertErrorCode(t, err, dynamodb.ErrCodeResourceInUseException)
+
+	out, err := db.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("records")})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := dynamodb.TableStatusActive, aws.StringValue(out.Table.TableStatus); e != a {
+		t.Errorf("expect %v status, got %v", e, a)
+	}
+	if err := db.WaitUntilTableExists(&dynamodb.DescribeTableInput{TableName: aws.String("records")}); err != nil {
+		t.Errorf("expect no error, got %v", err)
+	}
+
+	list, err := db.ListTables(&dynamodb.ListTablesInput{})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := []string{"records"}, aws.StringValueSlice(list.TableNames); !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v tables, got %v", e, a)
+	}
+
+	if _, err := db.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String("records")}); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	_, err = db.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("records")})
+	assertErrorCode(t, err, dynamodb.ErrCodeResourceNotFoundException)
+	if err := db.WaitUntilTableNotExists(&dynamodb.DescribeTableInput{TableName: aws.String("records")}); err != nil {
+		t.Errorf("expect no error, got %v", err)
+	}
+}
+
+func TestDB_PutItem(t *testing.T) {
+	db := newTestDB(t)
+	putRecords(t, db, record{ID: "a", Sort: 1, Value: "first"})
+
+	item, _ := dynamodbattribute.MarshalMap(record{ID: "a", Sort: 1, Value: "second"})
+	_, err := db.PutItem(&dynamodb.PutItemInput{
+		TableName:                aws.String("records"),
+		Item:                     item,
+		ConditionExpression:      aws.String("attribute_not_exists(id)"),
+		ExpressionAttributeNames: map[string]*string{"#unused": aws.String("value")},
+	})
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+
+	_, err = db.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String("records"),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+	})
+	assertErrorCode(t, err, dynamodb.ErrCodeConditionalCheckFailedException)
+
+	out, err := db.PutItem(&dynamodb.PutItemInput{
+		TableName:                 aws.String("records"),
+		Item:                      item,
+		ConditionExpression:       aws.String("#v = :v"),
+		ExpressionAttributeNames:  map[string]*string{"#v": aws.String("value")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":v": {S: aws.String("first")}},
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "first", aws.StringValue(out.Attributes["value"].S); e != a {
+		t.Errorf("expect %v old value, got %v", e, a)
+	}
+
+	get, err := db.GetItem(&dynamodb.GetItemInput{
+		TableName:            aws.String("records"),
+		Key:                  recordKey("a", 1),
+		ProjectionExpression: aws.String("#v"),
+		ExpressionAttributeNames: map[string]*string{
+			"#v": aws.String("value"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	expected := map[string]*dynamodb.AttributeValue{"value": {S: aws.String("second")}}
+	if e, a := expected, get.Item; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v item, got %v", e, a)
+	}
+
+	invalid := []*dynamodb.PutItemInput{
+		{TableName: aws.String("records"), Item: map[string]*dynamodb.AttributeValue{"id": {S: aws.String("a")}}},
+		{TableName: aws.String("records"), Item: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String("a")}, "sort": {S: aws.String("1")},
+		}},
+		{TableName: aws.String("records"), Item: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String("a")}, "sort": {N: aws.String("1")}, "rank": {S: aws.String("1")},
+		}},
+		{TableName: aws.String("records"), Item: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String("a")}, "sort": {N: aws.String("abc")},
+		}},
+		{TableName: aws.String("records"), Item: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String("a")}, "sort": {N: aws.String("1")}, "values": {NS: []*string{aws.String("1"), aws.String("x")}},
+		}},
+		{TableName: aws.String("records"), Item: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String("a")}, "sort": {N: aws.String("1")}, "nested": {M: map[string]*dynamodb.AttributeValue{
+				"n": {N: aws.String("1e")},
+			}},
+		}},
+		{TableName: aws.String("records"), Item: item, ConditionExpression: aws.String("id =")},
+		{TableName: aws.String("records"), Item: item, Expected: map[string]*dynamodb.ExpectedAttributeValue{}},
+	}
+	for i, in := range invalid {
+		_, err := db.PutItem(in)
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodbtest.ErrCodeValidationException {
+			t.Errorf("%d, expect validation error, got %v", i, err)
+		}
+	}
+
+	_, err = db.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("records"),
+		Key:       map[string]*dynamodb.AttributeValue{"id": {S: aws.String("a")}},
+	})
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+
+	_, err = db.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("records"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String("a")}, "sort": {N: aws.String("abc")},
+		},
+	})
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+}
+
+func TestDB_UpdateItem(t *testing.T) {
+	db := newTestDB(t)
+
+	update := func(expr string, values map[string]*dynamodb.AttributeValue, rv string) (*dynamodb.UpdateItemOutput, error) {
+		return db.UpdateItem(&dynamodb.UpdateItemInput{
+			TableName:                 aws.String("records"),
+			Key:                       recordKey("a", 1),
+			UpdateExpression:          aws.String(expr),
+			ExpressionAttributeValues: values,
+			ReturnValues:              aws.String(rv),
+		})
+	}
+
+	out, err := update("ADD #c :one", map[string]*dynamodb.AttributeValue{":one": {N: aws.String("1")}},
+		dynamodb.ReturnValueAllNew)
+	if err == nil {
+		t.Fatalf("expect error for undefined name")
+	}
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+
+	out, err = update("ADD rank :one SET #value = :v", nil, dynamodb.ReturnValueAllNew)
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+
+	out, err = update("ADD rank :one", map[string]*dynamodb.AttributeValue{":one": {N: aws.String("1")}},
+		dynamodb.ReturnValueAllNew)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	expected := recordKey("a", 1)
+	expected["rank"] = &dynamodb.AttributeValue{N: aws.String("1")}
+	if e, a := expected, out.Attributes; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	out, err = update("ADD rank :one", map[string]*dynamodb.AttributeValue{":one": {N: aws.String("2")}},
+		dynamodb.ReturnValueUpdatedOld)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	expected = map[string]*dynamodb.AttributeValue{"rank": {N: aws.String("1")}}
+	if e, a := expected, out.Attributes; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	_, err = update("SET id = :id", map[string]*dynamodb.AttributeValue{":id": {S: aws.String("b")}},
+		dynamodb.ReturnValueNone)
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+
+	_, err = update("SET #value = :v", map[string]*dynamodb.AttributeValue{":v": {S: aws.String("v")}},
+		dynamodb.ReturnValueNone)
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+
+	_, err = db.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:                 aws.String("records"),
+		Key:                       recordKey("a", 1),
+		ConditionExpression:       aws.String("rank > :n"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":n": {N: aws.String("3")}},
+	})
+	assertErrorCode(t, err, dynamodb.ErrCodeConditionalCheckFailedException)
+
+	del, err := db.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:    aws.String("records"),
+		Key:          recordKey("a", 1),
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "3", aws.StringValue(del.Attributes["rank"].N); e != a {
+		t.Errorf("expect %v rank, got %v", e, a)
+	}
+}
+
+func TestDB_Query(t *testing.T) {
+	db := newTestDB(t)
+	putRecords(t, db,
+		record{ID: "a", Sort: 1, Group: "g", Rank: 3, Value: "a1"},
+		record{ID: "a", Sort: 2, Group: "g", Rank: 1, Value: "a2"},
+		record{ID: "a", Sort: 10, Rank: 2, Value: "a10"},
+		record{ID: "b", Sort: 1, Group: "g", Rank: 2, Value: "b1"},
+	)
+
+	query := func(in *dynamodb.QueryInput) []string {
+		in.TableName = aws.String("records")
+		var values []string
+		err := db.QueryPages(in, func(page *dynamodb.QueryOutput, last bool) bool {
+			if in.Limit != nil && int64(len(page.Items)) > *in.Limit {
+				t.Errorf("expect at most %d items, got %d", *in.Limit, len(page.Items))
+			}
+			for _, item := range page.Items {
+				var r record
+				if err := dynamodbattribute.UnmarshalMap(item, &r); err != nil {
+					t.Fatalf("expect no error, got %v", err)
+				}
+				values = append(values, r.ID+strconv.Itoa(r.Sort)+r.Value)
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		return values
+	}
+
+	aValues := map[string]*dynamodb.AttributeValue{":a": {S: aws.String("a")}}
+	cases := []struct {
+		input    *dynamodb.QueryInput
+		expected []string
+	}{
+		{
+			input: &dynamodb.QueryInput{
+				KeyConditionExpression:    aws.String("id = :a"),
+				ExpressionAttributeValues: aValues,
+				Limit:                     aws.Int64(1),
+			},
+			expected: []string{"a1a1", "a2a2", "a10a10"},
+		},
+		{
+			input: &dynamodb.QueryInput{
+				KeyConditionExpression:    aws.String("id = :a"),
+				ExpressionAttributeValues: aValues,
+				ScanIndexForward:          aws.Bool(false),
+				Limit:                     aws.Int64(2),
+			},
+			expected: []string{"a10a10", "a2a2", "a1a1"},
+		},
+		{
+			input: &dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("id = :a AND #s BETWEEN :one AND :two"),
+				FilterExpression:       aws.String("#v <> :v"),
+				ExpressionAttributeNames: map[string]*string{
+					"#s": aws.String("sort"), "#v": aws.String("value"),
+				},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":a": {S: aws.String("a")}, ":one": {N: aws.String("1")},
+					":two": {N: aws.String("2")}, ":v": {S: aws.String("a1")},
+				},
+			},
+			expected: []string{"a2a2"},
+		},
+		{
+			input: &dynamodb.QueryInput{
+				IndexName:                 aws.String("rank"),
+				KeyConditionExpression:    aws.String("id = :a"),
+				ExpressionAttributeValues: aValues,
+				Limit:                     aws.Int64(1),
+			},
+			expected: []string{"a2a2", "a10a10", "a1a1"},
+		},
+		{
+			input: &dynamodb.QueryInput{
+				IndexName:                 aws.String("group"),
+				KeyConditionExpression:    aws.String("#g = :g"),
+				ExpressionAttributeNames:  map[string]*string{"#g": aws.String("group")},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":g": {S: aws.String("g")}},
+				Limit:                     aws.Int64(2),
+			},
+			expected: []string{"a2", "b1", "a1"},
+		},
+	}
+	for i, c := range cases {
+		if e, a := c.expected, query(c.input); !reflect.DeepEqual(e, a) {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+	}
+
+	count, err := db.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String("records"),
+		KeyConditionExpression:    aws.String("id = :a"),
+		ExpressionAttributeValues: aValues,
+		Select:                    aws.String(dynamodb.SelectCount),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := int64(3), aws.Int64Value(count.Count); e != a {
+		t.Errorf("expect %v count, got %v", e, a)
+	}
+	if count.Items != nil {
+		t.Errorf("expect no items, got %v", count.Items)
+	}
+
+	invalid := []*dynamodb.QueryInput{
+		{KeyConditionExpression: aws.String("#v = :a"), ExpressionAttributeValues: aValues,
+			ExpressionAttributeNames: map[string]*string{"#v": aws.String("value")}},
+		{KeyConditionExpression: aws.String("id = :a"), ExpressionAttributeValues: aValues,
+			IndexName: aws.String("missing")},
+		{KeyConditionExpression: aws.String("id = :a"), ExpressionAttributeValues: aValues,
+			IndexName: aws.String("group"), ConsistentRead: aws.Bool(true)},
+		{KeyConditionExpression: aws.String("id = :a"), ExpressionAttributeValues: aValues,
+			ExclusiveStartKey: map[string]*dynamodb.AttributeValue{"id": {S: aws.String("a")}}},
+		{ExpressionAttributeValues: aValues},
+	}
+	for i, in := range invalid {
+		in.TableName = aws.String("records")
+		_, err := db.Query(in)
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodbtest.ErrCodeValidationException {
+			t.Errorf("%d, expect validation error, got %v", i, err)
+		}
+	}
+}
+
+func TestDB_Scan(t *testing.T) {
+	db := newTestDB(t)
+	var expected []int
+	for i := 0; i < 50; i++ {
+		putRecords(t, db, record{ID: strconv.Itoa(i), Sort: i, Rank: i % 2})
+		if i%2 == 0 {
+			expected = append(expected, i)
+		}
+	}
+
+	var actual []int
+	var pages int
+	for segment := int64(0); segment < 4; segment++ {
+		err := db.ScanPages(&dynamodb.ScanInput{
+			TableName:                 aws.String("records"),
+			FilterExpression:          aws.String("rank = :zero"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":zero": {N: aws.String("0")}},
+			Segment:                   aws.Int64(segment),
+			TotalSegments:             aws.Int64(4),
+			Limit:                     aws.Int64(5),
+		}, func(page *dynamodb.ScanOutput, last bool) bool {
+			pages++
+			for _, item := range page.Items {
+				var r record
+				if err := dynamodbattribute.UnmarshalMap(item, &r); err != nil {
+					t.Fatalf("expect no error, got %v", err)
+				}
+				actual = append(actual, r.Sort)
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+	}
+	sort.Ints(actual)
+	if e, a := expected, actual; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if pages < 10 {
+		t.Errorf("expect at least 10 pages, got %d", pages)
+	}
+
+	_, err := db.Scan(&dynamodb.ScanInput{
+		TableName:     aws.String("records"),
+		Segment:       aws.Int64(4),
+		TotalSegments: aws.Int64(4),
+	})
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+}
+
+func TestDB_Size(t *testing.T) {
+	db := newTestDB(t)
+	value := strings.Repeat("x", 300*1024)
+	var records []record
+	for i := 1; i <= 5; i++ {
+		records = append(records, record{ID: "a", Sort: i, Value: value})
+	}
+	putRecords(t, db, records...)
+
+	item, _ := dynamodbattribute.MarshalMap(record{
+		ID: "b", Sort: 1, Value: strings.Repeat("x", dynamodbmanager.MaxItemSize),
+	})
+	_, err := db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("records"), Item: item})
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+
+	_, err = db.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String("records"),
+		Key:                       recordKey("a", 1),
+		UpdateExpression:          aws.String("SET extra = :v"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":v": {S: aws.String(value)}},
+	})
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+
+	out, err := db.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String("records"),
+		KeyConditionExpression:    aws.String("id = :id"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":id": {S: aws.String("a")}},
+		ReturnConsumedCapacity:    aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 4, len(out.Items); e != a {
+		t.Fatalf("expect %d items in the first 1MB page, got %d", e, a)
+	}
+	if e, a := recordKey("a", 4), out.LastEvaluatedKey; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v last key, got %v", e, a)
+	}
+	if out.ConsumedCapacity == nil {
+		t.Fatalf("expect consumed capacity, got none")
+	}
+	if e, a := dynamodbmanager.QueryCapacity(out.Items, false), aws.Float64Value(out.ConsumedCapacity.CapacityUnits); e != a {
+		t.Errorf("expect %v capacity units, got %v", e, a)
+	}
+
+	get, err := db.GetItem(&dynamodb.GetItemInput{
+		TableName:              aws.String("records"),
+		Key:                    recordKey("a", 1),
+		ConsistentRead:         aws.Bool(true),
+		ProjectionExpression:   aws.String("id"),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := dynamodbmanager.GetItemCapacity(out.Items[0], dynamodbmanager.StronglyConsistent),
+		aws.Float64Value(get.ConsumedCapacity.CapacityUnits); e != a {
+		t.Errorf("expect %v capacity units, got %v", e, a)
+	}
+
+	del, err := db.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String("records"),
+		Key:       recordKey("a", 1),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if del.ConsumedCapacity != nil {
+		t.Errorf("expect no consumed capacity, got %v", del.ConsumedCapacity)
+	}
+}
+
+func TestDB_Batch(t *testing.T) {
+	db := newTestDB(t)
+	putRecords(t, db, record{ID: "a", Sort: 1}, record{ID: "b", Sort: 1})
+
+	put, _ := dynamodbattribute.MarshalMap(record{ID: "c", Sort: 1})
+	_, err := db.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{
+			"records": {
+				{PutRequest: &dynamodb.PutRequest{Item: put}},
+				{DeleteRequest: &dynamodb.DeleteRequest{Key: recordKey("a", 1)}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	out, err := db.BatchGetItem(&dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{
+			"records": {
+				Keys:                 []map[string]*dynamodb.AttributeValue{recordKey("a", 1), recordKey("b", 1), recordKey("c", 1)},
+				ProjectionExpression: aws.String("id"),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	var ids []string
+	for _, item := range out.Responses["records"] {
+		ids = append(ids, aws.StringValue(item["id"].S))
+	}
+	sort.Strings(ids)
+	if e, a := []string{"b", "c"}, ids; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	_, err = db.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{
+			"records": {
+				{PutRequest: &dynamodb.PutRequest{Item: put}},
+				{DeleteRequest: &dynamodb.DeleteRequest{Key: recordKey("c", 1)}},
+			},
+		},
+	})
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+
+	var reqs []*dynamodb.WriteRequest
+	for i := 0; i < 26; i++ {
+		reqs = append(reqs, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: recordKey("a", i)}})
+	}
+	_, err = db.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{"records": reqs},
+	})
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+}
+
+func TestDB_Transactions(t *testing.T) {
+	db := newTestDB(t)
+	putRecords(t, db, record{ID: "b", Sort: 1, Rank: 10})
+
+	item := &record{ID: "a", Sort: 1}
+	tx := dynamodbmanager.NewTransactWriteBuilder()
+	tx.ReturnValuesOnConditionCheckFailure = dynamodb.ReturnValuesOnConditionCheckFailureAllOld
+	tx.Put("records", item)
+	tx.Update("records", recordKey("b", 1),
+		expression.Set(expression.Name("rank"), expression.Name("rank").Plus(expression.Value(1))),
+		expression.Name("rank").LessThan(expression.Value(10)))
+
+	_, err := tx.Write(db)
+	cErr, ok := err.(*dynamodbmanager.TransactionCanceledError)
+	if !ok {
+		t.Fatalf("expect TransactionCanceledError, got %v", err)
+	}
+	failed := cErr.Failed()
+	if e, a := 1, len(failed); e != a {
+		t.Fatalf("expect %d failed, got %d", e, a)
+	}
+	if e, a := "ConditionalCheckFailed", failed[0].Code; e != a {
+		t.Errorf("expect %v reason, got %v", e, a)
+	}
+	if e, a := "10", aws.StringValue(failed[0].Item["rank"].N); e != a {
+		t.Errorf("expect %v item rank, got %v", e, a)
+	}
+
+	get, err := db.GetItem(&dynamodb.GetItemInput{TableName: aws.String("records"), Key: recordKey("a", 1)})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if get.Item != nil {
+		t.Errorf("expect item not written, got %v", get.Item)
+	}
+
+	tx = dynamodbmanager.NewTransactWriteBuilder()
+	tx.Put("records", item)
+	tx.Update("records", recordKey("b", 1),
+		expression.Set(expression.Name("rank"), expression.Name("rank").Plus(expression.Value(1))))
+	if _, err := tx.Write(db); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	var a, b record
+	read := dynamodbmanager.NewTransactGetBuilder()
+	read.Get("records", recordKey("a", 1), &a)
+	read.Get("records", recordKey("b", 1), &b)
+	if _, err := read.Read(db); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := int64(1), a.Version; e != a {
+		t.Errorf("expect %v version, got %v", e, a)
+	}
+	if e, a := 11, b.Rank; e != a {
+		t.Errorf("expect %v rank, got %v", e, a)
+	}
+
+	_, err = db.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Delete: &dynamodb.Delete{TableName: aws.String("records"), Key: recordKey("a", 1)}},
+			{ConditionCheck: &dynamodb.ConditionCheck{TableName: aws.String("records"), Key: recordKey("a", 1),
+				ConditionExpression: aws.String("attribute_exists(id)")}},
+		},
+	})
+	assertErrorCode(t, err, dynamodbtest.ErrCodeValidationException)
+}
+
+func TestDB_Table(t *testing.T) {
+	db := newTestDB(t)
+	table := dynamodbmanager.NewTableWithClient(db, "records")
+
+	item := &record{ID: "a", Sort: 1, Value: "first"}
+	if err := table.Put(item); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	stale := *item
+	item.Value = "second"
+	if err := table.Put(item); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	err := table.Put(&stale)
+	assertErrorCode(t, err, dynamodb.ErrCodeConditionalCheckFailedException)
+
+	actual := &record{ID: "a", Sort: 1}
+	if err := table.Get(actual); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := *item, *actual; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
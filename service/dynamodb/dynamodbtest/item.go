@@ -0,0 +1,624 @@
+package dynamodbtest
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// write is a pending change to an item of a table. If the new item is nil,
+// the item is deleted.
+type write struct {
+	table    *table
+	key      string
+	old, new item
+}
+
+// commit applies the write to the table.
+func (w *write) commit() {
+	if w.new == nil {
+		delete(w.table.items, w.key)
+		return
+	}
+	w.table.items[w.key] = w.new
+}
+
+// expressionInput is the expressions of a request, and their attribute
+// names and values.
+type expressionInput struct {
+	condition, update *string
+	names             map[string]*string
+	values            map[string]*dynamodb.AttributeValue
+}
+
+var (
+	namePlaceholder  = regexp.MustCompile(`#[A-Za-z0-9_]+`)
+	valuePlaceholder = regexp.MustCompile(`:[A-Za-z0-9_]+`)
+)
+
+// checkUnused returns an error if any of the names or values are not used by
+// the expressions. The DB must be locked.
+func (db *DB) checkUnused(names map[string]*string, values map[string]*dynamodb.AttributeValue, exprs ...*string) error {
+	if len(names) == 0 && len(values) == 0 {
+		return nil
+	}
+
+	var all []string
+	for _, expr := range exprs {
+		if expr != nil {
+			all = append(all, *expr)
+		}
+	}
+	if len(all) == 0 {
+		if len(names) != 0 {
+			return db.validationError("ExpressionAttributeNames can only be specified when using expressions")
+		}
+		return db.validationError("ExpressionAttributeValues can only be specified when using expressions: UpdateExpression, ConditionExpression, FilterExpression and KeyConditionExpression")
+	}
+
+	joined := strings.Join(all, " ")
+	used := map[string]bool{}
+	for _, name := range namePlaceholder.FindAllString(joined, -1) {
+		used[name] = true
+	}
+	for _, value := range valuePlaceholder.FindAllString(joined, -1) {
+		used[value] = true
+	}
+
+	var unused []string
+	for name := range names {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) != 0 {
+		sort.Strings(unused)
+		return db.validationError("Value provided in ExpressionAttributeNames unused in expressions: keys: {%s}",
+			strings.Join(unused, ", "))
+	}
+	for value := range values {
+		if !used[value] {
+			unused = append(unused, value)
+		}
+	}
+	if len(unused) != 0 {
+		sort.Strings(unused)
+		return db.validationError("Value provided in ExpressionAttributeValues unused in expressions: keys: {%s}",
+			strings.Join(unused, ", "))
+	}
+	for _, av := range values {
+		if err := db.validateValue(av); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseCondition parses the condition or filter expression of the request
+// parameter, returning nil if the expression is not set. The DB must be
+// locked.
+func (db *DB) parseCondition(param string, expr *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (*expression.ConditionBuilder, error) {
+	if expr == nil {
+		return nil, nil
+	}
+	cond, err := expression.ParseCondition(*expr, names, values)
+	if err != nil {
+		return nil, db.validationError("Invalid %s: %v", param, err)
+	}
+	return &cond, nil
+}
+
+// parseProjection parses the projection expression, returning nil if the
+// expression is not set. The DB must be locked.
+func (db *DB) parseProjection(expr *string, names map[string]*string) (*expression.ProjectionBuilder, error) {
+	if expr == nil {
+		return nil, nil
+	}
+	projection, err := expression.ParseProjection(*expr, names)
+	if err != nil {
+		return nil, db.validationError("Invalid ProjectionExpression: %v", err)
+	}
+	return &projection, nil
+}
+
+// checkCondition returns a ConditionalCheckFailedException error if the
+// condition is not satisfied by the item. The DB must be locked.
+func (db *DB) checkCondition(cond *expression.ConditionBuilder, it item) error {
+	if cond == nil {
+		return nil
+	}
+	if it == nil {
+		it = item{}
+	}
+	ok, err := cond.Evaluate(it)
+	if err != nil {
+		return db.validationError("Invalid ConditionExpression: %v", err)
+	}
+	if !ok {
+		return db.newError(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed")
+	}
+	return nil
+}
+
+// project returns a copy of the item with only the attributes of the
+// projection, or the whole item if the projection is nil. The DB must be
+// locked.
+func (db *DB) project(projection *expression.ProjectionBuilder, it item) (item, error) {
+	if projection == nil {
+		return copyItem(it), nil
+	}
+	projected, err := projection.Project(it)
+	if err != nil {
+		return nil, db.validationError("Invalid ProjectionExpression: %v", err)
+	}
+	return item(projected), nil
+}
+
+// rejectLegacy returns a ValidationException error if any of the legacy
+// parameters are set, since they are not supported. The DB must be locked.
+func (db *DB) rejectLegacy(params map[string]bool) error {
+	var set []string
+	for name, ok := range params {
+		if ok {
+			set = append(set, name)
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	sort.Strings(set)
+	return db.validationError("Legacy parameters are not supported: %s", strings.Join(set, ", "))
+}
+
+// preparePut returns the write putting the item, if the condition is
+// satisfied by the existing item. The DB must be locked.
+func (db *DB) preparePut(t *table, it item, in expressionInput) (*write, error) {
+	if err := db.validateItem(t, it); err != nil {
+		return nil, err
+	}
+	if err := db.checkUnused(in.names, in.values, in.condition); err != nil {
+		return nil, err
+	}
+	cond, err := db.parseCondition("ConditionExpression", in.condition, in.names, in.values)
+	if err != nil {
+		return nil, err
+	}
+
+	key := keyString(t.key.names(), it)
+	old := t.items[key]
+	if err := db.checkCondition(cond, old); err != nil {
+		return nil, err
+	}
+	return &write{table: t, key: key, old: old, new: copyItem(it)}, nil
+}
+
+// prepareDelete returns the write deleting the item with the key, if the
+// condition is satisfied by the existing item. The DB must be locked.
+func (db *DB) prepareDelete(t *table, key item, in expressionInput) (*write, error) {
+	if err := db.validateKey(t, key, true); err != nil {
+		return nil, err
+	}
+	if err := db.checkUnused(in.names, in.values, in.condition); err != nil {
+		return nil, err
+	}
+	cond, err := db.parseCondition("ConditionExpression", in.condition, in.names, in.values)
+	if err != nil {
+		return nil, err
+	}
+
+	k := keyString(t.key.names(), key)
+	old := t.items[k]
+	if err := db.checkCondition(cond, old); err != nil {
+		return nil, err
+	}
+	return &write{table: t, key: k, old: old}, nil
+}
+
+// prepareUpdate returns the write updating the item with the key, if the
+// condition is satisfied by the existing item. If the item does not exist,
+// the update is applied to the key attributes. The DB must be locked.
+func (db *DB) prepareUpdate(t *table, key item, in expressionInput) (*write, error) {
+	if err := db.validateKey(t, key, true); err != nil {
+		return nil, err
+	}
+	if err := db.checkUnused(in.names, in.values, in.condition, in.update); err != nil {
+		return nil, err
+	}
+	cond, err := db.parseCondition("ConditionExpression", in.condition, in.names, in.values)
+	if err != nil {
+		return nil, err
+	}
+
+	k := keyString(t.key.names(), key)
+	old := t.items[k]
+	if err := db.checkCondition(cond, old); err != nil {
+		return nil, err
+	}
+
+	current := old
+	if current == nil {
+		current = copyItem(key)
+	}
+	if in.update == nil {
+		return &write{table: t, key: k, old: old, new: copyItem(current)}, nil
+	}
+
+	update, err := expression.ParseUpdate(*in.update, in.names, in.values)
+	if err != nil {
+		return nil, db.validationError("Invalid UpdateExpression: %v", err)
+	}
+	updated, err := update.Apply(current)
+	if err != nil {
+		return nil, db.validationError("Invalid UpdateExpression: %v", err)
+	}
+	for _, name := range t.key.names() {
+		if !reflect.DeepEqual(key[name], updated[name]) {
+			return nil, db.validationError("One or more parameter values were invalid: Cannot update attribute %s. This attribute is part of the key", name)
+		}
+	}
+	if err := db.validateItem(t, updated); err != nil {
+		return nil, err
+	}
+	return &write{table: t, key: k, old: old, new: updated}, nil
+}
+
+// returnValues returns the attributes of the item before or after the write,
+// as selected by the ReturnValues parameter. The DB must be locked.
+func (db *DB) returnValues(returnValues *string, w *write, allowed ...string) (item, error) {
+	rv := aws.StringValue(returnValues)
+	if len(rv) == 0 {
+		rv = dynamodb.ReturnValueNone
+	}
+	valid := rv == dynamodb.ReturnValueNone
+	for _, a := range allowed {
+		valid = valid || rv == a
+	}
+	if !valid {
+		return nil, db.validationError("ReturnValues can only be %s for this operation",
+			strings.Join(append([]string{dynamodb.ReturnValueNone}, allowed...), " or "))
+	}
+
+	switch rv {
+	case dynamodb.ReturnValueAllOld:
+		if w.old == nil {
+			return nil, nil
+		}
+		return copyItem(w.old), nil
+	case dynamodb.ReturnValueAllNew:
+		return copyItem(w.new), nil
+	case dynamodb.ReturnValueUpdatedOld, dynamodb.ReturnValueUpdatedNew:
+		from := w.old
+		if rv == dynamodb.ReturnValueUpdatedNew {
+			from = w.new
+		}
+		changed := item{}
+		for name, av := range from {
+			var other *dynamodb.AttributeValue
+			if rv == dynamodb.ReturnValueUpdatedNew {
+				other = w.old[name]
+			} else {
+				other = w.new[name]
+			}
+			if !reflect.DeepEqual(av, other) {
+				changed[name] = copyValue(av)
+			}
+		}
+		if len(changed) == 0 {
+			return nil, nil
+		}
+		return changed, nil
+	}
+	return nil, nil
+}
+
+// GetItem returns the attributes of the item with the key.
+func (db *DB) GetItem(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return db.GetItemWithContext(aws.BackgroundContext(), in)
+}
+
+// GetItemWithContext is the same as GetItem with the additional support for
+// Context input parameters.
+func (db *DB) GetItemWithContext(ctx aws.Context, in *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	if err := db.rejectLegacy(map[string]bool{"AttributesToGet": in.AttributesToGet != nil}); err != nil {
+		return nil, err
+	}
+	it, size, err := db.getItem(in.TableName, in.Key, in.ProjectionExpression, in.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{
+		Item: it,
+		ConsumedCapacity: consumedCapacity(in.ReturnConsumedCapacity, in.TableName,
+			dynamodbmanager.ReadCapacityUnits(size, readConsistency(in.ConsistentRead))),
+	}, nil
+}
+
+// getItem returns the projected attributes of the item with the key, or nil
+// if the item does not exist, and the size of the whole item. The DB must be
+// locked.
+func (db *DB) getItem(tableName *string, key item, projectionExpr *string, names map[string]*string) (item, int, error) {
+	t, err := db.getTable(tableName)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := db.validateKey(t, key, true); err != nil {
+		return nil, 0, err
+	}
+	if err := db.checkUnused(names, nil, projectionExpr); err != nil {
+		return nil, 0, err
+	}
+	projection, err := db.parseProjection(projectionExpr, names)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	it, ok := t.items[keyString(t.key.names(), key)]
+	if !ok {
+		return nil, 0, nil
+	}
+	projected, err := db.project(projection, it)
+	return projected, dynamodbmanager.ItemSize(it), err
+}
+
+// PutItem creates or replaces an item, if the condition expression is
+// satisfied.
+func (db *DB) PutItem(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return db.PutItemWithContext(aws.BackgroundContext(), in)
+}
+
+// PutItemWithContext is the same as PutItem with the additional support for
+// Context input parameters.
+func (db *DB) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	if err := db.rejectLegacy(map[string]bool{
+		"Expected":            in.Expected != nil,
+		"ConditionalOperator": in.ConditionalOperator != nil,
+	}); err != nil {
+		return nil, err
+	}
+	t, err := db.getTable(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	w, err := db.preparePut(t, in.Item, expressionInput{
+		condition: in.ConditionExpression,
+		names:     in.ExpressionAttributeNames,
+		values:    in.ExpressionAttributeValues,
+	})
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := db.returnValues(in.ReturnValues, w, dynamodb.ReturnValueAllOld)
+	if err != nil {
+		return nil, err
+	}
+	w.commit()
+	return &dynamodb.PutItemOutput{
+		Attributes: attrs,
+		ConsumedCapacity: consumedCapacity(in.ReturnConsumedCapacity, in.TableName,
+			dynamodbmanager.PutItemCapacity(w.new, w.old, false)),
+	}, nil
+}
+
+// UpdateItem updates or creates an item with the update expression, if the
+// condition expression is satisfied.
+func (db *DB) UpdateItem(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	return db.UpdateItemWithContext(aws.BackgroundContext(), in)
+}
+
+// UpdateItemWithContext is the same as UpdateItem with the additional support
+// for Context input parameters.
+func (db *DB) UpdateItemWithContext(ctx aws.Context, in *dynamodb.UpdateItemInput, opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	if err := db.rejectLegacy(map[string]bool{
+		"AttributeUpdates":    in.AttributeUpdates != nil,
+		"Expected":            in.Expected != nil,
+		"ConditionalOperator": in.ConditionalOperator != nil,
+	}); err != nil {
+		return nil, err
+	}
+	t, err := db.getTable(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	w, err := db.prepareUpdate(t, in.Key, expressionInput{
+		condition: in.ConditionExpression,
+		update:    in.UpdateExpression,
+		names:     in.ExpressionAttributeNames,
+		values:    in.ExpressionAttributeValues,
+	})
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := db.returnValues(in.ReturnValues, w, dynamodb.ReturnValueAllOld, dynamodb.ReturnValueUpdatedOld,
+		dynamodb.ReturnValueAllNew, dynamodb.ReturnValueUpdatedNew)
+	if err != nil {
+		return nil, err
+	}
+	w.commit()
+	return &dynamodb.UpdateItemOutput{
+		Attributes: attrs,
+		ConsumedCapacity: consumedCapacity(in.ReturnConsumedCapacity, in.TableName,
+			dynamodbmanager.UpdateItemCapacity(w.new, w.old, false)),
+	}, nil
+}
+
+// DeleteItem deletes the item with the key, if the condition expression is
+// satisfied.
+func (db *DB) DeleteItem(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	return db.DeleteItemWithContext(aws.BackgroundContext(), in)
+}
+
+// DeleteItemWithContext is the same as DeleteItem with the additional support
+// for Context input parameters.
+func (db *DB) DeleteItemWithContext(ctx aws.Context, in *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	if err := db.rejectLegacy(map[string]bool{
+		"Expected":            in.Expected != nil,
+		"ConditionalOperator": in.ConditionalOperator != nil,
+	}); err != nil {
+		return nil, err
+	}
+	t, err := db.getTable(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	w, err := db.prepareDelete(t, in.Key, expressionInput{
+		condition: in.ConditionExpression,
+		names:     in.ExpressionAttributeNames,
+		values:    in.ExpressionAttributeValues,
+	})
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := db.returnValues(in.ReturnValues, w, dynamodb.ReturnValueAllOld)
+	if err != nil {
+		return nil, err
+	}
+	w.commit()
+	return &dynamodb.DeleteItemOutput{
+		Attributes: attrs,
+		ConsumedCapacity: consumedCapacity(in.ReturnConsumedCapacity, in.TableName,
+			dynamodbmanager.DeleteItemCapacity(w.old, false)),
+	}, nil
+}
+
+// attributeType returns the DynamoDB type of the attribute value, or an
+// empty string if no type is set.
+func attributeType(av *dynamodb.AttributeValue) string {
+	switch {
+	case av == nil:
+		return ""
+	case av.S != nil:
+		return dynamodb.ScalarAttributeTypeS
+	case av.N != nil:
+		return dynamodb.ScalarAttributeTypeN
+	case av.B != nil:
+		return dynamodb.ScalarAttributeTypeB
+	case av.BOOL != nil:
+		return "BOOL"
+	case av.NULL != nil:
+		return "NULL"
+	case av.SS != nil:
+		return "SS"
+	case av.NS != nil:
+		return "NS"
+	case av.BS != nil:
+		return "BS"
+	case av.L != nil:
+		return "L"
+	case av.M != nil:
+		return "M"
+	}
+	return ""
+}
+
+// parseNumber parses a DynamoDB number, the same way as the expression
+// package.
+func parseNumber(n string) (*big.Rat, bool) {
+	return new(big.Rat).SetString(strings.TrimSpace(n))
+}
+
+// normalizeNumber returns the number in a canonical form, so that equal
+// numbers with different formatting have the same key.
+func normalizeNumber(n string) string {
+	r, ok := parseNumber(n)
+	if !ok {
+		return n
+	}
+	return r.RatString()
+}
+
+// compareKeyValues compares two scalar key attribute values, ordering
+// numbers numerically, and strings and binary values by their bytes.
+func compareKeyValues(a, b *dynamodb.AttributeValue) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	case a.N != nil && b.N != nil:
+		ra, okA := parseNumber(*a.N)
+		rb, okB := parseNumber(*b.N)
+		if okA && okB {
+			return ra.Cmp(rb)
+		}
+		return strings.Compare(*a.N, *b.N)
+	case a.S != nil && b.S != nil:
+		return strings.Compare(*a.S, *b.S)
+	case a.B != nil && b.B != nil:
+		return bytes.Compare(a.B, b.B)
+	}
+	return strings.Compare(attributeType(a), attributeType(b))
+}
+
+func copyItem(it item) item {
+	if it == nil {
+		return nil
+	}
+	c := make(item, len(it))
+	for k, v := range it {
+		c[k] = copyValue(v)
+	}
+	return c
+}
+
+func copyValue(av *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if av == nil {
+		return nil
+	}
+	c := *av
+	if av.B != nil {
+		c.B = append([]byte{}, av.B...)
+	}
+	if av.SS != nil {
+		c.SS = aws.StringSlice(aws.StringValueSlice(av.SS))
+	}
+	if av.NS != nil {
+		c.NS = aws.StringSlice(aws.StringValueSlice(av.NS))
+	}
+	if av.BS != nil {
+		c.BS = make([][]byte, len(av.BS))
+		for i, b := range av.BS {
+			c.BS[i] = append([]byte{}, b...)
+		}
+	}
+	if av.L != nil {
+		c.L = make([]*dynamodb.AttributeValue, len(av.L))
+		for i, v := range av.L {
+			c.L[i] = copyValue(v)
+		}
+	}
+	if av.M != nil {
+		c.M = copyItem(av.M)
+	}
+	return &c
+}
@@ -0,0 +1,540 @@
+package dynamodbtest
+
+import (
+	"hash/fnv"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbmanager"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// readInput is the parameters shared by Query and Scan requests.
+type readInput struct {
+	tableName, indexName *string
+	consistentRead       *bool
+	exclusiveStartKey    item
+	limit                *int64
+	selectAttributes     *string
+	filter, projection   *string
+	names                map[string]*string
+	values               map[string]*dynamodb.AttributeValue
+}
+
+// readResult is the page of items read by a Query or Scan request.
+type readResult struct {
+	items            []item
+	count, scanned   int64
+	lastEvaluatedKey item
+
+	// The total size of the items read, before the filter is applied.
+	size int
+}
+
+// source is the items of a table or index to read, and their key schema.
+type source struct {
+	table *table
+	index *index
+
+	// The key attributes ordering the items: the index key, if any, followed
+	// by the table key.
+	order []string
+}
+
+// getSource returns the table or index read by the request. The DB must be
+// locked.
+func (db *DB) getSource(in readInput) (*source, error) {
+	t, err := db.getTable(in.tableName)
+	if err != nil {
+		return nil, err
+	}
+	s := &source{table: t, order: t.key.names()}
+	if in.indexName == nil {
+		return s, nil
+	}
+
+	idx, ok := t.indexes[*in.indexName]
+	if !ok {
+		return nil, db.validationError("The table does not have the specified index: %s", *in.indexName)
+	}
+	if idx.global && aws.BoolValue(in.consistentRead) {
+		return nil, db.validationError("Consistent reads are not supported on global secondary indexes")
+	}
+	s.index = idx
+	s.order = idx.key.names()
+	for _, name := range t.key.names() {
+		if name != idx.key.hash && name != idx.key.rng {
+			s.order = append(s.order, name)
+		}
+	}
+	return s, nil
+}
+
+// keySchema returns the key schema of the index, or of the table.
+func (s *source) keySchema() keySchema {
+	if s.index != nil {
+		return s.index.key
+	}
+	return s.table.key
+}
+
+// items returns the items of the table, or the projected items of the sparse
+// index, in order of the source's key attributes.
+func (s *source) items(selectAttributes string) []item {
+	items := make([]item, 0, len(s.table.items))
+	for _, it := range s.table.items {
+		if s.index == nil {
+			items = append(items, it)
+			continue
+		}
+		if !hasAttributes(it, s.index.key.names()) {
+			continue
+		}
+		if selectAttributes == dynamodb.SelectAllAttributes {
+			items = append(items, it)
+			continue
+		}
+		items = append(items, s.indexProjection(it))
+	}
+	sort.Sort(itemSorter{items: items, order: s.order})
+	return items
+}
+
+// indexProjection returns the attributes of the item projected into the
+// index.
+func (s *source) indexProjection(it item) item {
+	if s.index.projection == dynamodb.ProjectionTypeAll {
+		return it
+	}
+	projected := item{}
+	for _, name := range s.order {
+		projected[name] = it[name]
+	}
+	if s.index.projection == dynamodb.ProjectionTypeInclude {
+		for _, name := range s.index.include {
+			if av, ok := it[name]; ok {
+				projected[name] = av
+			}
+		}
+	}
+	return projected
+}
+
+// validateSelect returns an error if the Select parameter is invalid for
+// the source, and returns the value of the Select parameter, or its default.
+// The DB must be locked.
+func (db *DB) validateSelect(s *source, in readInput) (string, error) {
+	sel := aws.StringValue(in.selectAttributes)
+	if in.projection != nil {
+		if len(sel) != 0 && sel != dynamodb.SelectSpecificAttributes {
+			return "", db.validationError("Cannot specify the ProjectionExpression when choosing to get %s", sel)
+		}
+		sel = dynamodb.SelectSpecificAttributes
+	}
+	if len(sel) == 0 {
+		sel = dynamodb.SelectAllAttributes
+		if s.index != nil {
+			sel = dynamodb.SelectAllProjectedAttributes
+		}
+	}
+
+	switch sel {
+	case dynamodb.SelectAllProjectedAttributes:
+		if s.index == nil {
+			return "", db.validationError("ALL_PROJECTED_ATTRIBUTES can be used only when Querying using an IndexName")
+		}
+	case dynamodb.SelectAllAttributes:
+		if s.index != nil && s.index.global && s.index.projection != dynamodb.ProjectionTypeAll {
+			return "", db.validationError("One or more parameter values were invalid: Select type ALL_ATTRIBUTES is not supported for global secondary index %s because its projection type is not ALL",
+				s.index.name)
+		}
+	case dynamodb.SelectSpecificAttributes:
+		if in.projection == nil {
+			return "", db.validationError("SPECIFIC_ATTRIBUTES requires the ProjectionExpression to be set")
+		}
+	}
+	return sel, nil
+}
+
+// validateStartKey returns an error if the exclusive start key does not have
+// the key attributes of the source. The DB must be locked.
+func (db *DB) validateStartKey(s *source, key item) error {
+	if key == nil {
+		return nil
+	}
+	if len(key) != len(s.order) || !hasAttributes(key, s.order) {
+		return db.validationError("The provided starting key is invalid: The provided key element does not match the schema")
+	}
+	for _, name := range s.order {
+		if e, a := s.table.types[name], attributeType(key[name]); e != a {
+			return db.validationError("The provided starting key is invalid: The provided key element does not match the schema")
+		}
+	}
+	return nil
+}
+
+// read evaluates the items from the exclusive start key, until the limit,
+// returning the items satisfying the filter. The DB must be locked.
+func (db *DB) read(s *source, in readInput, items []item, forward bool) (*readResult, error) {
+	sel, err := db.validateSelect(s, in)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.validateStartKey(s, in.exclusiveStartKey); err != nil {
+		return nil, err
+	}
+	filter, err := db.parseCondition("FilterExpression", in.filter, in.names, in.values)
+	if err != nil {
+		return nil, err
+	}
+	projection, err := db.parseProjection(in.projection, in.names)
+	if err != nil {
+		return nil, err
+	}
+
+	if !forward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	start := 0
+	if in.exclusiveStartKey != nil {
+		start = sort.Search(len(items), func(i int) bool {
+			c := compareItems(items[i], in.exclusiveStartKey, s.order)
+			if forward {
+				return c > 0
+			}
+			return c < 0
+		})
+	}
+
+	result := &readResult{}
+	limit := aws.Int64Value(in.limit)
+	for i := start; i < len(items); i++ {
+		if (limit > 0 && result.scanned == limit) || result.size >= dynamodbmanager.MaxPageSize {
+			result.lastEvaluatedKey = copyItem(keyAttributes(items[i-1], s.order))
+			break
+		}
+		it := items[i]
+		result.scanned++
+		result.size += dynamodbmanager.ItemSize(it)
+
+		if filter != nil {
+			ok, err := filter.Evaluate(it)
+			if err != nil {
+				return nil, db.validationError("Invalid FilterExpression: %v", err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		result.count++
+		if sel == dynamodb.SelectCount {
+			continue
+		}
+		projected, err := db.project(projection, it)
+		if err != nil {
+			return nil, err
+		}
+		result.items = append(result.items, projected)
+	}
+	return result, nil
+}
+
+// Query returns the items of a table or index with the partition key, and
+// sort key, of the key condition expression, which satisfy the filter
+// expression.
+func (db *DB) Query(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	return db.QueryWithContext(aws.BackgroundContext(), in)
+}
+
+// QueryWithContext is the same as Query with the additional support for
+// Context input parameters.
+func (db *DB) QueryWithContext(ctx aws.Context, in *dynamodb.QueryInput, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	if err := db.rejectLegacy(map[string]bool{
+		"AttributesToGet":     in.AttributesToGet != nil,
+		"KeyConditions":       in.KeyConditions != nil,
+		"QueryFilter":         in.QueryFilter != nil,
+		"ConditionalOperator": in.ConditionalOperator != nil,
+	}); err != nil {
+		return nil, err
+	}
+	r := readInput{
+		tableName:         in.TableName,
+		indexName:         in.IndexName,
+		consistentRead:    in.ConsistentRead,
+		exclusiveStartKey: in.ExclusiveStartKey,
+		limit:             in.Limit,
+		selectAttributes:  in.Select,
+		filter:            in.FilterExpression,
+		projection:        in.ProjectionExpression,
+		names:             in.ExpressionAttributeNames,
+		values:            in.ExpressionAttributeValues,
+	}
+	s, err := db.getSource(r)
+	if err != nil {
+		return nil, err
+	}
+	if in.KeyConditionExpression == nil {
+		return nil, db.validationError("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+	if err := db.checkUnused(in.ExpressionAttributeNames, in.ExpressionAttributeValues,
+		in.KeyConditionExpression, in.FilterExpression, in.ProjectionExpression); err != nil {
+		return nil, err
+	}
+	keyCond, err := expression.ParseKeyCondition(*in.KeyConditionExpression,
+		in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, db.validationError("Invalid KeyConditionExpression: %v", err)
+	}
+	if err := db.validateKeyCondition(s, keyCond); err != nil {
+		return nil, err
+	}
+
+	sel, err := db.validateSelect(s, r)
+	if err != nil {
+		return nil, err
+	}
+	var items []item
+	for _, it := range s.items(sel) {
+		ok, err := keyCond.Evaluate(it)
+		if err != nil {
+			return nil, db.validationError("Invalid KeyConditionExpression: %v", err)
+		}
+		if ok {
+			items = append(items, it)
+		}
+	}
+
+	forward := in.ScanIndexForward == nil || *in.ScanIndexForward
+	result, err := db.read(s, r, items, forward)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.QueryOutput{
+		Items:            itemMaps(result.items, sel),
+		Count:            aws.Int64(result.count),
+		ScannedCount:     aws.Int64(result.scanned),
+		LastEvaluatedKey: result.lastEvaluatedKey,
+		ConsumedCapacity: consumedCapacity(in.ReturnConsumedCapacity, in.TableName,
+			dynamodbmanager.ReadCapacityUnits(result.size, readConsistency(in.ConsistentRead))),
+	}, nil
+}
+
+// validateKeyCondition returns an error if the key condition does not
+// select a partition of the source, or references non-key attributes. The
+// DB must be locked.
+func (db *DB) validateKeyCondition(s *source, keyCond expression.KeyConditionBuilder) error {
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return db.validationError("Invalid KeyConditionExpression: %v", err)
+	}
+
+	key := s.keySchema()
+	var hasHash bool
+	for _, name := range expr.Names() {
+		switch aws.StringValue(name) {
+		case key.hash:
+			hasHash = true
+		case key.rng:
+		default:
+			return db.validationError("Query condition missed key schema element: %s", aws.StringValue(name))
+		}
+	}
+	if !hasHash {
+		return db.validationError("Query condition missed key schema element: %s", key.hash)
+	}
+	return nil
+}
+
+// QueryPages calls fn with each page of the query's items.
+func (db *DB) QueryPages(in *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) error {
+	return db.QueryPagesWithContext(aws.BackgroundContext(), in, fn)
+}
+
+// QueryPagesWithContext is the same as QueryPages with the additional
+// support for Context input parameters.
+func (db *DB) QueryPagesWithContext(ctx aws.Context, in *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, opts ...request.Option) error {
+	page := *in
+	for {
+		out, err := db.QueryWithContext(ctx, &page, opts...)
+		if err != nil {
+			return err
+		}
+		last := len(out.LastEvaluatedKey) == 0
+		if !fn(out, last) || last {
+			return nil
+		}
+		page.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// Scan returns the items of a table or index which satisfy the filter
+// expression. If TotalSegments is set, only the items of the Segment are
+// returned.
+func (db *DB) Scan(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	return db.ScanWithContext(aws.BackgroundContext(), in)
+}
+
+// ScanWithContext is the same as Scan with the additional support for
+// Context input parameters.
+func (db *DB) ScanWithContext(ctx aws.Context, in *dynamodb.ScanInput, opts ...request.Option) (*dynamodb.ScanOutput, error) {
+	if err := db.begin(ctx, in); err != nil {
+		return nil, err
+	}
+	defer db.m.Unlock()
+
+	if err := db.rejectLegacy(map[string]bool{
+		"AttributesToGet":     in.AttributesToGet != nil,
+		"ScanFilter":          in.ScanFilter != nil,
+		"ConditionalOperator": in.ConditionalOperator != nil,
+	}); err != nil {
+		return nil, err
+	}
+	r := readInput{
+		tableName:         in.TableName,
+		indexName:         in.IndexName,
+		consistentRead:    in.ConsistentRead,
+		exclusiveStartKey: in.ExclusiveStartKey,
+		limit:             in.Limit,
+		selectAttributes:  in.Select,
+		filter:            in.FilterExpression,
+		projection:        in.ProjectionExpression,
+		names:             in.ExpressionAttributeNames,
+		values:            in.ExpressionAttributeValues,
+	}
+	s, err := db.getSource(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.checkUnused(in.ExpressionAttributeNames, in.ExpressionAttributeValues,
+		in.FilterExpression, in.ProjectionExpression); err != nil {
+		return nil, err
+	}
+
+	segment, total := aws.Int64Value(in.Segment), aws.Int64Value(in.TotalSegments)
+	if (in.Segment == nil) != (in.TotalSegments == nil) {
+		return nil, db.validationError("The TotalSegments parameter is required but was not present in the request when Segment parameter is present")
+	}
+	if in.TotalSegments != nil && segment >= total {
+		return nil, db.validationError("The Segment parameter is zero-based and must be less than parameter TotalSegments: Segment: %d is not less than TotalSegments: %d",
+			segment, total)
+	}
+
+	sel, err := db.validateSelect(s, r)
+	if err != nil {
+		return nil, err
+	}
+	var items []item
+	for _, it := range s.items(sel) {
+		if total > 0 && scanSegment(it, s.keySchema().hash, total) != segment {
+			continue
+		}
+		items = append(items, it)
+	}
+
+	result, err := db.read(s, r, items, true)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.ScanOutput{
+		Items:            itemMaps(result.items, sel),
+		Count:            aws.Int64(result.count),
+		ScannedCount:     aws.Int64(result.scanned),
+		LastEvaluatedKey: result.lastEvaluatedKey,
+		ConsumedCapacity: consumedCapacity(in.ReturnConsumedCapacity, in.TableName,
+			dynamodbmanager.ReadCapacityUnits(result.size, readConsistency(in.ConsistentRead))),
+	}, nil
+}
+
+// ScanPages calls fn with each page of the scan's items.
+func (db *DB) ScanPages(in *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	return db.ScanPagesWithContext(aws.BackgroundContext(), in, fn)
+}
+
+// ScanPagesWithContext is the same as ScanPages with the additional support
+// for Context input parameters.
+func (db *DB) ScanPagesWithContext(ctx aws.Context, in *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool, opts ...request.Option) error {
+	page := *in
+	for {
+		out, err := db.ScanWithContext(ctx, &page, opts...)
+		if err != nil {
+			return err
+		}
+		last := len(out.LastEvaluatedKey) == 0
+		if !fn(out, last) || last {
+			return nil
+		}
+		page.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// scanSegment returns the segment of the item, by the hash of its partition
+// key.
+func scanSegment(it item, hashKey string, total int64) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(keyString([]string{hashKey}, it)))
+	return int64(h.Sum32()) % total
+}
+
+// itemMaps returns the items as attribute maps, or nil if only the count was
+// selected.
+func itemMaps(items []item, sel string) []map[string]*dynamodb.AttributeValue {
+	if sel == dynamodb.SelectCount {
+		return nil
+	}
+	maps := make([]map[string]*dynamodb.AttributeValue, len(items))
+	for i, it := range items {
+		maps[i] = it
+	}
+	return maps
+}
+
+// hasAttributes returns true if the item has all of the attributes.
+func hasAttributes(it item, names []string) bool {
+	for _, name := range names {
+		if _, ok := it[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// keyAttributes returns the key attributes of the item.
+func keyAttributes(it item, names []string) item {
+	key := item{}
+	for _, name := range names {
+		key[name] = it[name]
+	}
+	return key
+}
+
+// compareItems compares the items by the values of the key attributes, in
+// order.
+func compareItems(a, b item, order []string) int {
+	for _, name := range order {
+		if c := compareKeyValues(a[name], b[name]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// itemSorter sorts items by the values of the key attributes.
+type itemSorter struct {
+	items []item
+	order []string
+}
+
+func (s itemSorter) Len() int      { return len(s.items) }
+func (s itemSorter) Swap(i, j int) { s.items[i], s.items[j] = s.items[j], s.items[i] }
+func (s itemSorter) Less(i, j int) bool {
+	return compareItems(s.items[i], s.items[j], s.order) < 0
+}
@@ -44,5 +44,34 @@ attribute names and values are aliased. That means that if the
 ExpressionAttributeNames and ExpressionAttributeValues member is not assigned
 with the corresponding Names() and Values() methods, the DynamoDB operation will
 run into a logic error.
+
+Document Paths
+
+Name() splits its argument into nested attributes at dots, and list indexes in
+square brackets. To refer to list elements and map keys without formatting
+strings, or to attributes whose names contain dots or square brackets, build the
+document path with the Index() and Key() methods of NameBuilder. Each name and
+key in the path is aliased, so it may also be a DynamoDB reserved word.
+
+  // "Record[6].#key" where #key is an ExpressionAttributeName for "song.title"
+  name := expression.Name("Record").Index(6).Key("song.title")
+
+Build() returns an error if the document paths of two actions of an Update
+Expression overlap, since DynamoDB would reject the expression.
+
+Parsing Expressions
+
+Expression strings, with the ExpressionAttributeNames and
+ExpressionAttributeValues they refer to, can be parsed back into builders with
+ParseCondition(), ParseKeyCondition(), ParseProjection() and ParseUpdate().
+ParseCondition() also parses Filter Expressions. The builders can be changed and
+built like any other, giving back an equivalent expression.
+
+  cond, err := expression.ParseCondition(aws.StringValue(input.ConditionExpression),
+    input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+  if err != nil {
+    // err is a ParseError with the offset of the problem in the expression
+    fmt.Println(err)
+  }
 */
 package expression
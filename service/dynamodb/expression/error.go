@@ -57,3 +57,53 @@ func newUnsetParameterError(funcName, paramType string) UnsetParameterError {
 		functionName:  funcName,
 	}
 }
+
+// OverlappingPathsError is returned if the document paths of two actions of
+// an UpdateBuilder overlap, i.e. if they are equal or one is nested in the
+// other. DynamoDB rejects Update Expressions with overlapping paths. The error
+// message includes the function that returned the error originally and the
+// two overlapping actions.
+//
+// Example:
+//
+//     // err is of type OverlappingPathsError
+//     _, err := expression.NewBuilder().
+//                 WithUpdate(expression.Set(expression.Name("foo"), expression.Value(5)).
+//                     Remove(expression.Name("foo"))).
+//                 Build()
+type OverlappingPathsError struct {
+	functionName string
+	first        string
+	second       string
+}
+
+func (ope OverlappingPathsError) Error() string {
+	return fmt.Sprintf("%s error: update paths overlap: %s and %s", ope.functionName, ope.first, ope.second)
+}
+
+func newOverlappingPathsError(funcName, first, second string) OverlappingPathsError {
+	return OverlappingPathsError{
+		functionName: funcName,
+		first:        first,
+		second:       second,
+	}
+}
+
+// ParseError is returned if an expression string passed to one of the Parse
+// functions cannot be parsed. Offset is the byte offset in the expression
+// string at which the problem was found, which is the length of the string
+// if the expression ended unexpectedly.
+//
+// Example:
+//
+//     // err is of type ParseError, with an Offset of 6
+//     _, err := expression.ParseCondition("foo = AND", nil, nil)
+type ParseError struct {
+	Expression string
+	Offset     int
+	Message    string
+}
+
+func (pe ParseError) Error() string {
+	return fmt.Sprintf("parse error: %s at offset %d of %q", pe.Message, pe.Offset, pe.Expression)
+}
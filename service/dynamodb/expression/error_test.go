@@ -49,3 +49,13 @@ func TestUnsetParameterError(t *testing.T) {
 		})
 	}
 }
+
+func TestOverlappingPathsError(t *testing.T) {
+	_, err := NewBuilder().WithUpdate(Set(Name("foo"), Value(5)).Remove(Name("foo"))).Build()
+	if _, ok := err.(OverlappingPathsError); !ok {
+		t.Fatalf("expect OverlappingPathsError, got %T", err)
+	}
+	if e, a := "Build error: update paths overlap: REMOVE foo and SET foo", err.Error(); e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,877 @@
+package expression
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Evaluate returns whether the item satisfies the condition, evaluating the
+// condition the way DynamoDB evaluates Condition and Filter Expressions. A
+// comparison with an attribute the item does not have, or with a value of a
+// different type, is not satisfied, except for the not equal comparison.
+//
+// Example:
+//
+//     condition := expression.Name("Price").LessThan(expression.Value(10))
+//     ok, err := condition.Evaluate(item)
+func (cb ConditionBuilder) Evaluate(item map[string]*dynamodb.AttributeValue) (bool, error) {
+	switch cb.mode {
+	case andCond, orCond:
+		for _, condition := range cb.conditionList {
+			ok, err := condition.Evaluate(item)
+			if err != nil {
+				return false, err
+			}
+			if ok == (cb.mode == orCond) {
+				return ok, nil
+			}
+		}
+		return cb.mode == andCond, nil
+	case notCond:
+		if len(cb.conditionList) != 1 {
+			return false, newInvalidParameterError("Evaluate", "ConditionBuilder")
+		}
+		ok, err := cb.conditionList[0].Evaluate(item)
+		return !ok, err
+	case unsetCond:
+		return false, newUnsetParameterError("Evaluate", "ConditionBuilder")
+	}
+
+	operands := make([]*dynamodb.AttributeValue, 0, len(cb.operandList))
+	for _, operand := range cb.operandList {
+		av, err := evaluateOperand(operand, item)
+		if err != nil {
+			return false, err
+		}
+		operands = append(operands, av)
+	}
+
+	switch cb.mode {
+	case equalCond, notEqualCond, lessThanCond, lessThanEqualCond, greaterThanCond, greaterThanEqualCond:
+		if len(operands) != 2 {
+			return false, newInvalidParameterError("Evaluate", "ConditionBuilder")
+		}
+		return compareCondition(cb.mode, operands[0], operands[1]), nil
+	case betweenCond:
+		if len(operands) != 3 {
+			return false, newInvalidParameterError("Evaluate", "ConditionBuilder")
+		}
+		return compareCondition(greaterThanEqualCond, operands[0], operands[1]) &&
+			compareCondition(lessThanEqualCond, operands[0], operands[2]), nil
+	case inCond:
+		for _, operand := range operands[1:] {
+			if equalValues(operands[0], operand) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case attrExistsCond:
+		return operands[0] != nil, nil
+	case attrNotExistsCond:
+		return operands[0] == nil, nil
+	case attrTypeCond:
+		return operands[0] != nil && operands[1] != nil && operands[1].S != nil &&
+			attributeType(operands[0]) == *operands[1].S, nil
+	case beginsWithCond:
+		return beginsWith(operands[0], operands[1]), nil
+	case containsCond:
+		return contains(operands[0], operands[1]), nil
+	}
+
+	return false, fmt.Errorf("evaluate condition error: unsupported mode: %v", cb.mode)
+}
+
+// Evaluate returns whether the item satisfies the key condition.
+//
+// Example:
+//
+//     keyCondition := expression.Key("Artist").Equal(expression.Value("No One You Know"))
+//     ok, err := keyCondition.Evaluate(item)
+func (kcb KeyConditionBuilder) Evaluate(item map[string]*dynamodb.AttributeValue) (bool, error) {
+	switch kcb.mode {
+	case andKeyCond:
+		for _, keyCondition := range kcb.keyConditionList {
+			ok, err := keyCondition.Evaluate(item)
+			if !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case unsetKeyCond:
+		return false, newUnsetParameterError("Evaluate", "KeyConditionBuilder")
+	case invalidKeyCond:
+		return false, fmt.Errorf("evaluate key condition error: invalid key condition constructed")
+	}
+
+	operands := make([]*dynamodb.AttributeValue, 0, len(kcb.operandList))
+	for _, operand := range kcb.operandList {
+		av, err := evaluateOperand(operand, item)
+		if err != nil {
+			return false, err
+		}
+		operands = append(operands, av)
+	}
+
+	switch kcb.mode {
+	case equalKeyCond:
+		return compareCondition(equalCond, operands[0], operands[1]), nil
+	case lessThanKeyCond:
+		return compareCondition(lessThanCond, operands[0], operands[1]), nil
+	case lessThanEqualKeyCond:
+		return compareCondition(lessThanEqualCond, operands[0], operands[1]), nil
+	case greaterThanKeyCond:
+		return compareCondition(greaterThanCond, operands[0], operands[1]), nil
+	case greaterThanEqualKeyCond:
+		return compareCondition(greaterThanEqualCond, operands[0], operands[1]), nil
+	case betweenKeyCond:
+		return compareCondition(greaterThanEqualCond, operands[0], operands[1]) &&
+			compareCondition(lessThanEqualCond, operands[0], operands[2]), nil
+	case beginsWithKeyCond:
+		return beginsWith(operands[0], operands[1]), nil
+	}
+
+	return false, fmt.Errorf("evaluate key condition error: unsupported mode: %v", kcb.mode)
+}
+
+// Apply returns a copy of the item updated by the update, the way DynamoDB
+// applies Update Expressions. The values of all SET actions are evaluated
+// against the item before any action is applied. The item is not modified.
+// An error is returned if an action refers to an attribute the item does not
+// have where DynamoDB requires one, or to a value of the wrong type.
+//
+// Example:
+//
+//     update := expression.Set(expression.Name("Plays"), expression.Name("Plays").Plus(expression.Value(1)))
+//     updated, err := update.Apply(item)
+func (ub UpdateBuilder) Apply(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	if ub.operationList == nil {
+		return nil, newUnsetParameterError("Apply", "UpdateBuilder")
+	}
+	if err := ub.checkOverlaps(); err != nil {
+		return nil, err
+	}
+
+	type setAction struct {
+		path  []pathElement
+		value *dynamodb.AttributeValue
+	}
+	var sets []setAction
+	for _, op := range ub.operationList[setOperation] {
+		path, err := op.name.documentPath()
+		if err != nil {
+			return nil, err
+		}
+		value, err := evaluateOperand(op.value, item)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return nil, fmt.Errorf("apply update error: the value of SET %s refers to an attribute that does not exist in the item",
+				formatPath(path))
+		}
+		sets = append(sets, setAction{path: path, value: value})
+	}
+
+	updated := copyItem(item)
+	for _, set := range sets {
+		if err := setPath(updated, set.path, copyValue(set.value)); err != nil {
+			return nil, err
+		}
+	}
+
+	removed := false
+	for _, op := range ub.operationList[removeOperation] {
+		path, err := op.name.documentPath()
+		if err != nil {
+			return nil, err
+		}
+		removed = removePath(updated, path) || removed
+	}
+	if removed {
+		// Removed list elements are replaced with nil by removePath, so that
+		// the indexes of other REMOVE actions refer to the elements of the
+		// original list.
+		for _, av := range updated {
+			compactLists(av)
+		}
+	}
+
+	for _, mode := range []operationMode{addOperation, deleteOperation} {
+		for _, op := range ub.operationList[mode] {
+			path, err := op.name.documentPath()
+			if err != nil {
+				return nil, err
+			}
+			value, err := evaluateOperand(op.value, item)
+			if err != nil {
+				return nil, err
+			}
+			current, _ := getPath(updated, path)
+			result, err := addOrDelete(mode, current, value)
+			if err != nil {
+				return nil, fmt.Errorf("apply update error: %s %s: %v", mode, formatPath(path), err)
+			}
+			if result == nil {
+				removePath(updated, path)
+				continue
+			}
+			if err := setPath(updated, path, result); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return updated, nil
+}
+
+// Project returns the attributes of the item in the projection. Nested
+// attributes are returned in their maps and lists, which only contain the
+// projected elements. Attributes the item does not have are omitted.
+//
+// Example:
+//
+//     projection := expression.NamesList(expression.Name("Artist"), expression.Name("Songs").Index(0))
+//     projected, err := projection.Project(item)
+func (pb ProjectionBuilder) Project(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	if len(pb.names) == 0 {
+		return nil, newUnsetParameterError("Project", "ProjectionBuilder")
+	}
+
+	type listElement struct {
+		index int
+		av    *dynamodb.AttributeValue
+	}
+
+	projected := map[string]*dynamodb.AttributeValue{}
+	// The elements of the lists of projected, by the list, and the index of
+	// the elements in the item's list, so that elements are kept in order.
+	lists := map[*dynamodb.AttributeValue]map[int]*dynamodb.AttributeValue{}
+
+	for _, name := range pb.names {
+		path, err := name.documentPath()
+		if err != nil {
+			return nil, err
+		}
+		value, ok := getPath(item, path)
+		if !ok {
+			continue
+		}
+
+		var parent *dynamodb.AttributeValue
+		for i, element := range path {
+			last := i == len(path)-1
+			var next *dynamodb.AttributeValue
+			if last {
+				next = copyValue(value)
+			}
+
+			if i == 0 {
+				if existing, ok := projected[element.name]; ok && !last {
+					next = existing
+				} else {
+					if next == nil {
+						next = emptyContainer(path[i+1])
+					}
+					projected[element.name] = next
+				}
+				parent = next
+				continue
+			}
+
+			switch element.mode {
+			case namePathElement:
+				if existing, ok := parent.M[element.name]; ok && !last {
+					next = existing
+				} else {
+					if next == nil {
+						next = emptyContainer(path[i+1])
+					}
+					parent.M[element.name] = next
+				}
+			case indexPathElement:
+				elements := lists[parent]
+				if elements == nil {
+					elements = map[int]*dynamodb.AttributeValue{}
+					lists[parent] = elements
+				}
+				if existing, ok := elements[element.index]; ok && !last {
+					next = existing
+				} else {
+					if next == nil {
+						next = emptyContainer(path[i+1])
+					}
+					elements[element.index] = next
+				}
+			}
+			parent = next
+		}
+	}
+
+	for list, elements := range lists {
+		indexes := make([]int, 0, len(elements))
+		for index := range elements {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		for _, index := range indexes {
+			list.L = append(list.L, elements[index])
+		}
+	}
+
+	return projected, nil
+}
+
+// evaluateOperand returns the value of the operand for the item, or nil if
+// the operand refers to an attribute the item does not have.
+func evaluateOperand(operand OperandBuilder, item map[string]*dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	switch o := operand.(type) {
+	case NameBuilder:
+		path, err := o.documentPath()
+		if err != nil {
+			return nil, err
+		}
+		av, _ := getPath(item, path)
+		return av, nil
+	case KeyBuilder:
+		if o.key == "" {
+			return nil, newUnsetParameterError("Evaluate", "KeyBuilder")
+		}
+		return item[o.key], nil
+	case ValueBuilder:
+		av, err := dynamodbattribute.Marshal(o.value)
+		if err != nil {
+			return nil, newInvalidParameterError("Evaluate", "ValueBuilder")
+		}
+		return av, nil
+	case SizeBuilder:
+		av, err := evaluateOperand(o.nameBuilder, item)
+		if err != nil || av == nil {
+			return nil, err
+		}
+		size, ok := attributeSize(av)
+		if !ok {
+			return nil, nil
+		}
+		return &dynamodb.AttributeValue{N: aws.String(fmt.Sprint(size))}, nil
+	case SetValueBuilder:
+		return evaluateSetValue(o, item)
+	}
+	return nil, fmt.Errorf("evaluate operand error: unsupported operand: %T", operand)
+}
+
+// evaluateSetValue returns the value of an operand of a SET action.
+func evaluateSetValue(svb SetValueBuilder, item map[string]*dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	if svb.mode == unsetValue {
+		return nil, newUnsetParameterError("Evaluate", "SetValueBuilder")
+	}
+
+	left, err := evaluateOperand(svb.leftOperand, item)
+	if err != nil {
+		return nil, err
+	}
+	if svb.mode == ifNotExistsValueMode && left != nil {
+		return left, nil
+	}
+	right, err := evaluateOperand(svb.rightOperand, item)
+	if err != nil {
+		return nil, err
+	}
+
+	switch svb.mode {
+	case ifNotExistsValueMode:
+		return right, nil
+	case plusValueMode, minusValueMode:
+		if left == nil || right == nil {
+			return nil, fmt.Errorf("evaluate operand error: an operand of %s refers to an attribute that does not exist in the item",
+				map[setValueMode]string{plusValueMode: "+", minusValueMode: "-"}[svb.mode])
+		}
+		if left.N == nil || right.N == nil {
+			return nil, fmt.Errorf("evaluate operand error: incorrect operand type for arithmetic: %s and %s",
+				attributeType(left), attributeType(right))
+		}
+		a, aok := parseNumber(*left.N)
+		b, bok := parseNumber(*right.N)
+		if !aok || !bok {
+			return nil, fmt.Errorf("evaluate operand error: invalid number")
+		}
+		if svb.mode == plusValueMode {
+			a.Add(a, b)
+		} else {
+			a.Sub(a, b)
+		}
+		return &dynamodb.AttributeValue{N: aws.String(formatNumber(a))}, nil
+	case listAppendValueMode:
+		if left == nil || right == nil {
+			return nil, fmt.Errorf("evaluate operand error: an operand of list_append refers to an attribute that does not exist in the item")
+		}
+		if left.L == nil || right.L == nil {
+			return nil, fmt.Errorf("evaluate operand error: incorrect operand type for list_append: %s and %s",
+				attributeType(left), attributeType(right))
+		}
+		list := make([]*dynamodb.AttributeValue, 0, len(left.L)+len(right.L))
+		list = append(list, left.L...)
+		list = append(list, right.L...)
+		return &dynamodb.AttributeValue{L: list}, nil
+	}
+
+	return nil, fmt.Errorf("evaluate operand error: unsupported mode: %v", svb.mode)
+}
+
+// addOrDelete returns the result of the ADD or DELETE action of the value on
+// the current value of the attribute, or nil if the attribute is removed.
+func addOrDelete(mode operationMode, current, value *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	if value == nil {
+		return nil, fmt.Errorf("missing value")
+	}
+	valueType := attributeType(value)
+
+	if mode == addOperation && valueType == "N" {
+		if current == nil {
+			return copyValue(value), nil
+		}
+		if current.N == nil {
+			return nil, fmt.Errorf("incorrect operand type %s for number", attributeType(current))
+		}
+		a, aok := parseNumber(*current.N)
+		b, bok := parseNumber(*value.N)
+		if !aok || !bok {
+			return nil, fmt.Errorf("invalid number")
+		}
+		return &dynamodb.AttributeValue{N: aws.String(formatNumber(a.Add(a, b)))}, nil
+	}
+
+	if valueType != "SS" && valueType != "NS" && valueType != "BS" {
+		return nil, fmt.Errorf("incorrect operand type %s", valueType)
+	}
+	if current == nil {
+		if mode == addOperation {
+			return copyValue(value), nil
+		}
+		return nil, nil
+	}
+	if attributeType(current) != valueType {
+		return nil, fmt.Errorf("incorrect operand type %s for %s", valueType, attributeType(current))
+	}
+
+	members := setMembers(current)
+	for _, member := range setMembers(value) {
+		index := -1
+		for i, m := range members {
+			if equalValues(m, member) {
+				index = i
+				break
+			}
+		}
+		switch {
+		case mode == addOperation && index < 0:
+			members = append(members, member)
+		case mode == deleteOperation && index >= 0:
+			members = append(members[:index], members[index+1:]...)
+		}
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	result := &dynamodb.AttributeValue{}
+	for _, m := range members {
+		switch valueType {
+		case "SS":
+			result.SS = append(result.SS, m.S)
+		case "NS":
+			result.NS = append(result.NS, m.N)
+		case "BS":
+			result.BS = append(result.BS, m.B)
+		}
+	}
+	return result, nil
+}
+
+// getPath returns the value at the document path of the item.
+func getPath(item map[string]*dynamodb.AttributeValue, path []pathElement) (*dynamodb.AttributeValue, bool) {
+	av, ok := item[path[0].name]
+	if !ok || av == nil {
+		return nil, false
+	}
+	for _, element := range path[1:] {
+		switch element.mode {
+		case namePathElement:
+			if av.M == nil {
+				return nil, false
+			}
+			if av, ok = av.M[element.name]; !ok || av == nil {
+				return nil, false
+			}
+		case indexPathElement:
+			if av.L == nil || element.index >= len(av.L) || av.L[element.index] == nil {
+				return nil, false
+			}
+			av = av.L[element.index]
+		default:
+			return nil, false
+		}
+	}
+	return av, true
+}
+
+// setPath sets the value at the document path of the item. The parent of
+// the value must exist, and setting an index past the end of a list appends
+// the value to the list.
+func setPath(item map[string]*dynamodb.AttributeValue, path []pathElement, value *dynamodb.AttributeValue) error {
+	if len(path) == 1 {
+		item[path[0].name] = value
+		return nil
+	}
+
+	parent, ok := getPath(item, path[:len(path)-1])
+	last := path[len(path)-1]
+	switch {
+	case ok && last.mode == namePathElement && parent.M != nil:
+		parent.M[last.name] = value
+		return nil
+	case ok && last.mode == indexPathElement && parent.L != nil:
+		if last.index < len(parent.L) {
+			parent.L[last.index] = value
+		} else {
+			parent.L = append(parent.L, value)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("apply update error: the document path %s is invalid for update", formatPath(path))
+}
+
+// removePath removes the value at the document path of the item, returning
+// true if a list element was replaced with nil, to be removed by
+// compactLists.
+func removePath(item map[string]*dynamodb.AttributeValue, path []pathElement) bool {
+	if len(path) == 1 {
+		delete(item, path[0].name)
+		return false
+	}
+
+	parent, ok := getPath(item, path[:len(path)-1])
+	if !ok {
+		return false
+	}
+	last := path[len(path)-1]
+	switch {
+	case last.mode == namePathElement && parent.M != nil:
+		delete(parent.M, last.name)
+	case last.mode == indexPathElement && last.index < len(parent.L):
+		parent.L[last.index] = nil
+		return true
+	}
+	return false
+}
+
+// compactLists removes the nil elements of the lists of the value.
+func compactLists(av *dynamodb.AttributeValue) {
+	if av == nil {
+		return
+	}
+	if av.L != nil {
+		list := av.L[:0]
+		for _, element := range av.L {
+			if element != nil {
+				compactLists(element)
+				list = append(list, element)
+			}
+		}
+		av.L = list
+	}
+	for _, element := range av.M {
+		compactLists(element)
+	}
+}
+
+// emptyContainer returns an empty map or list for the parent of the path
+// element.
+func emptyContainer(child pathElement) *dynamodb.AttributeValue {
+	if child.mode == indexPathElement {
+		return &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}}
+	}
+	return &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{}}
+}
+
+// compareCondition returns the result of the comparison of the values.
+func compareCondition(mode conditionMode, left, right *dynamodb.AttributeValue) bool {
+	if mode == notEqualCond {
+		return !equalValues(left, right)
+	}
+	if mode == equalCond {
+		return left != nil && right != nil && equalValues(left, right)
+	}
+
+	cmp, ok := compareValues(left, right)
+	if !ok {
+		return false
+	}
+	switch mode {
+	case lessThanCond:
+		return cmp < 0
+	case lessThanEqualCond:
+		return cmp <= 0
+	case greaterThanCond:
+		return cmp > 0
+	case greaterThanEqualCond:
+		return cmp >= 0
+	}
+	return false
+}
+
+// compareValues compares two scalar values of the same type, string, number
+// or binary, the way DynamoDB orders them, returning false if they cannot be
+// compared.
+func compareValues(left, right *dynamodb.AttributeValue) (int, bool) {
+	if left == nil || right == nil {
+		return 0, false
+	}
+	switch {
+	case left.S != nil && right.S != nil:
+		return strings.Compare(*left.S, *right.S), true
+	case left.N != nil && right.N != nil:
+		a, aok := parseNumber(*left.N)
+		b, bok := parseNumber(*right.N)
+		if !aok || !bok {
+			return 0, false
+		}
+		return a.Cmp(b), true
+	case left.B != nil && right.B != nil:
+		return bytes.Compare(left.B, right.B), true
+	}
+	return 0, false
+}
+
+// equalValues returns true if the values are of the same type and equal.
+// Numbers are equal if they have the same value, and sets if they have the
+// same members in any order.
+func equalValues(left, right *dynamodb.AttributeValue) bool {
+	if left == nil || right == nil {
+		return left == right
+	}
+	leftType := attributeType(left)
+	if leftType != attributeType(right) {
+		return false
+	}
+
+	switch leftType {
+	case "S", "N", "B":
+		cmp, ok := compareValues(left, right)
+		return ok && cmp == 0
+	case "BOOL":
+		return *left.BOOL == *right.BOOL
+	case "NULL":
+		return true
+	case "SS", "NS", "BS":
+		a, b := setMembers(left), setMembers(right)
+		if len(a) != len(b) {
+			return false
+		}
+		for _, m := range a {
+			found := false
+			for _, n := range b {
+				if equalValues(m, n) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case "L":
+		if len(left.L) != len(right.L) {
+			return false
+		}
+		for i := range left.L {
+			if !equalValues(left.L[i], right.L[i]) {
+				return false
+			}
+		}
+		return true
+	case "M":
+		if len(left.M) != len(right.M) {
+			return false
+		}
+		for k, v := range left.M {
+			if !equalValues(v, right.M[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// beginsWith returns true if the string or binary value begins with the
+// prefix of the same type.
+func beginsWith(av, prefix *dynamodb.AttributeValue) bool {
+	if av == nil || prefix == nil {
+		return false
+	}
+	switch {
+	case av.S != nil && prefix.S != nil:
+		return strings.HasPrefix(*av.S, *prefix.S)
+	case av.B != nil && prefix.B != nil:
+		return bytes.HasPrefix(av.B, prefix.B)
+	}
+	return false
+}
+
+// contains returns true if the string contains the substring, or the set or
+// list contains the element.
+func contains(av, operand *dynamodb.AttributeValue) bool {
+	if av == nil || operand == nil {
+		return false
+	}
+	switch {
+	case av.S != nil:
+		return operand.S != nil && strings.Contains(*av.S, *operand.S)
+	case av.B != nil:
+		return operand.B != nil && bytes.Contains(av.B, operand.B)
+	case av.L != nil:
+		for _, element := range av.L {
+			if equalValues(element, operand) {
+				return true
+			}
+		}
+	default:
+		for _, member := range setMembers(av) {
+			if equalValues(member, operand) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// attributeSize returns the value of the size function for the value.
+func attributeSize(av *dynamodb.AttributeValue) (int, bool) {
+	switch attributeType(av) {
+	case "S":
+		return len(*av.S), true
+	case "B":
+		return len(av.B), true
+	case "SS", "NS", "BS":
+		return len(setMembers(av)), true
+	case "L":
+		return len(av.L), true
+	case "M":
+		return len(av.M), true
+	}
+	return 0, false
+}
+
+// attributeType returns the DynamoDB type of the value, e.g. "S" or "NS".
+func attributeType(av *dynamodb.AttributeValue) string {
+	switch {
+	case av == nil:
+		return ""
+	case av.S != nil:
+		return "S"
+	case av.N != nil:
+		return "N"
+	case av.B != nil:
+		return "B"
+	case av.BOOL != nil:
+		return "BOOL"
+	case av.NULL != nil:
+		return "NULL"
+	case av.SS != nil:
+		return "SS"
+	case av.NS != nil:
+		return "NS"
+	case av.BS != nil:
+		return "BS"
+	case av.L != nil:
+		return "L"
+	case av.M != nil:
+		return "M"
+	}
+	return ""
+}
+
+// setMembers returns the members of a set as scalar values.
+func setMembers(av *dynamodb.AttributeValue) []*dynamodb.AttributeValue {
+	var members []*dynamodb.AttributeValue
+	for _, s := range av.SS {
+		members = append(members, &dynamodb.AttributeValue{S: s})
+	}
+	for _, n := range av.NS {
+		members = append(members, &dynamodb.AttributeValue{N: n})
+	}
+	for _, b := range av.BS {
+		members = append(members, &dynamodb.AttributeValue{B: b})
+	}
+	return members
+}
+
+// parseNumber parses a DynamoDB number.
+func parseNumber(n string) (*big.Rat, bool) {
+	return new(big.Rat).SetString(strings.TrimSpace(n))
+}
+
+// formatNumber formats a number parsed from DynamoDB numbers as a decimal,
+// which is exact since the denominator is a product of powers of 2 and 5.
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	scaled := new(big.Rat).Set(r)
+	ten := big.NewRat(10, 1)
+	for digits := 1; digits <= 40; digits++ {
+		scaled.Mul(scaled, ten)
+		if scaled.IsInt() {
+			return r.FloatString(digits)
+		}
+	}
+	return strings.TrimRight(r.FloatString(40), "0")
+}
+
+// copyItem returns a deep copy of the item.
+func copyItem(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	copied := make(map[string]*dynamodb.AttributeValue, len(item))
+	for k, v := range item {
+		copied[k] = copyValue(v)
+	}
+	return copied
+}
+
+// copyValue returns a deep copy of the value.
+func copyValue(av *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if av == nil {
+		return nil
+	}
+	copied := *av
+	if av.L != nil {
+		copied.L = make([]*dynamodb.AttributeValue, len(av.L))
+		for i, v := range av.L {
+			copied.L[i] = copyValue(v)
+		}
+	}
+	if av.M != nil {
+		copied.M = copyItem(av.M)
+	}
+	if av.B != nil {
+		copied.B = append([]byte{}, av.B...)
+	}
+	if av.SS != nil {
+		copied.SS = append([]*string{}, av.SS...)
+	}
+	if av.NS != nil {
+		copied.NS = append([]*string{}, av.NS...)
+	}
+	if av.BS != nil {
+		copied.BS = append([][]byte{}, av.BS...)
+	}
+	return &copied
+}
@@ -0,0 +1,189 @@
+// +build go1.7
+
+package expression
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func evaluateTestItem() map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"s":    {S: aws.String("hello world")},
+		"n":    {N: aws.String("10")},
+		"f":    {N: aws.String("1.5")},
+		"b":    {B: []byte{1, 2, 3}},
+		"ss":   {SS: []*string{aws.String("a"), aws.String("b")}},
+		"ns":   {NS: []*string{aws.String("1"), aws.String("2")}},
+		"bool": {BOOL: aws.Bool(true)},
+		"l": {L: []*dynamodb.AttributeValue{
+			{S: aws.String("x")},
+			{N: aws.String("1")},
+			{S: aws.String("z")},
+		}},
+		"m": {M: map[string]*dynamodb.AttributeValue{
+			"a.b":  {S: aws.String("dotted")},
+			"list": {L: []*dynamodb.AttributeValue{{N: aws.String("5")}}},
+		}},
+	}
+}
+
+// setValue returns a ValueBuilder of the set, since sets are not marshaled
+// from Go values outside of struct fields.
+func setValue(av *dynamodb.AttributeValue) ValueBuilder {
+	return ValueBuilder{value: parsedValue{av: av}}
+}
+
+func TestConditionEvaluate(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    ConditionBuilder
+		expected bool
+	}{
+		{"equal", Name("n").Equal(Value(10)), true},
+		{"equal number formats", Name("n").Equal(Value("10")), false},
+		{"equal decimal", Name("f").Equal(Value(1.50)), true},
+		{"not equal missing", Name("missing").NotEqual(Value(1)), true},
+		{"less than", Name("f").LessThan(Name("n")), true},
+		{"less than type mismatch", Name("s").LessThan(Value(1)), false},
+		{"greater than equal string", Name("s").GreaterThanEqual(Value("hello")), true},
+		{"between", Name("n").Between(Value(5), Value(10)), true},
+		{"in", Name("s").In(Value(1), Value("hello world")), true},
+		{"nested", Name("m").Key("list").Index(0).Equal(Value(5)), true},
+		{"dotted key", Name("m").Key("a.b").Equal(Value("dotted")), true},
+		{"dotted name", Name("m.a.b").Equal(Value("dotted")), false},
+		{"exists", Name("l[2]").AttributeExists(), true},
+		{"exists out of range", Name("l[3]").AttributeExists(), false},
+		{"not exists", Name("missing").AttributeNotExists(), true},
+		{"type", Name("ns").AttributeType(NumberSet), true},
+		{"begins with", Name("s").BeginsWith("hello"), true},
+		{"contains string", Name("s").Contains("o w"), true},
+		{"contains set", Name("ss").Contains("b"), true},
+		{"contains list", Contains(Name("l"), "z"), true},
+		{"size", Name("s").Size().Equal(Value(11)), true},
+		{"size set", Name("ns").Size().GreaterThan(Value(2)), false},
+		{"and", Name("n").Equal(Value(10)).And(Name("bool").Equal(Value(false))), false},
+		{"or", Name("n").Equal(Value(1)).Or(Name("bool").Equal(Value(true))), true},
+		{"not", Name("n").Equal(Value(1)).Not(), true},
+	}
+
+	item := evaluateTestItem()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := c.input.Evaluate(item)
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := c.expected, actual; e != a {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+
+	if _, err := (ConditionBuilder{}).Evaluate(item); err == nil {
+		t.Errorf("expect error for unset condition")
+	}
+}
+
+func TestKeyConditionEvaluate(t *testing.T) {
+	item := evaluateTestItem()
+
+	cases := []struct {
+		input    KeyConditionBuilder
+		expected bool
+	}{
+		{Key("s").Equal(Value("hello world")), true},
+		{Key("s").Equal(Value("hello world")).And(Key("n").Between(Value(1), Value(9))), false},
+		{Key("s").Equal(Value("hello world")).And(Key("n").GreaterThanEqual(Value(10))), true},
+		{Key("s").BeginsWith("hell"), true},
+	}
+	for i, c := range cases {
+		actual, err := c.input.Evaluate(item)
+		if err != nil {
+			t.Fatalf("%d, expect no error, got %v", i, err)
+		}
+		if e, a := c.expected, actual; e != a {
+			t.Errorf("%d, expect %v, got %v", i, e, a)
+		}
+	}
+}
+
+func TestUpdateApply(t *testing.T) {
+	item := evaluateTestItem()
+	update := Set(Name("n"), Name("n").Plus(Value(0.25))).
+		Set(Name("f"), Name("n")).
+		Set(Name("new"), IfNotExists(Name("new"), Value("default"))).
+		Set(Name("m").Key("list").Index(5), Value(6)).
+		Set(Name("l2"), ListAppend(Name("l"), Value([]int{7}))).
+		Remove(Name("l[0]")).
+		Remove(Name("l[2]")).
+		Remove(Name("s")).
+		Add(Name("ss"), setValue(&dynamodb.AttributeValue{SS: []*string{aws.String("b"), aws.String("c")}})).
+		Add(Name("count"), Value(1)).
+		Delete(Name("ns"), setValue(&dynamodb.AttributeValue{NS: []*string{aws.String("1"), aws.String("2")}}))
+
+	updated, err := update.Apply(item)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expected := evaluateTestItem()
+	expected["n"] = &dynamodb.AttributeValue{N: aws.String("10.25")}
+	expected["f"] = &dynamodb.AttributeValue{N: aws.String("10")}
+	expected["new"] = &dynamodb.AttributeValue{S: aws.String("default")}
+	expected["m"].M["list"].L = append(expected["m"].M["list"].L, &dynamodb.AttributeValue{N: aws.String("6")})
+	expected["l2"] = &dynamodb.AttributeValue{L: append(evaluateTestItem()["l"].L, &dynamodb.AttributeValue{N: aws.String("7")})}
+	expected["l"].L = expected["l"].L[1:2]
+	delete(expected, "s")
+	expected["ss"].SS = append(expected["ss"].SS, aws.String("c"))
+	expected["count"] = &dynamodb.AttributeValue{N: aws.String("1")}
+	delete(expected, "ns")
+
+	if e, a := expected, updated; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+	if e, a := evaluateTestItem(), item; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect item unchanged, got %v", a)
+	}
+
+	errorCases := []UpdateBuilder{
+		Set(Name("a"), Name("missing")),
+		Set(Name("missing.a"), Value(1)),
+		Set(Name("a"), Name("s").Plus(Value(1))),
+		Add(Name("s"), Value(1)),
+		Add(Name("ss"), setValue(&dynamodb.AttributeValue{NS: []*string{aws.String("1")}})),
+		Set(Name("a"), Value(1)).Remove(Name("a")),
+	}
+	for i, c := range errorCases {
+		if _, err := c.Apply(item); err == nil {
+			t.Errorf("%d, expect error, got none", i)
+		}
+	}
+}
+
+func TestProjectionProject(t *testing.T) {
+	item := evaluateTestItem()
+	projection := NamesList(Name("n"), Name("l[2]"), Name("l[0]"), Name("m").Key("a.b"), Name("missing"))
+
+	projected, err := projection.Project(item)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expected := map[string]*dynamodb.AttributeValue{
+		"n": {N: aws.String("10")},
+		"l": {L: []*dynamodb.AttributeValue{
+			{S: aws.String("x")},
+			{S: aws.String("z")},
+		}},
+		"m": {M: map[string]*dynamodb.AttributeValue{
+			"a.b": {S: aws.String("dotted")},
+		}},
+	}
+	if e, a := expected, projected; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
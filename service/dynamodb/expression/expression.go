@@ -77,7 +77,8 @@ func NewBuilder() Builder {
 // Expressions. Getter methods on the resulting Expression struct returns the
 // DynamoDB Expression strings as well as the maps that correspond to
 // ExpressionAttributeNames and ExpressionAttributeValues. Calling Build() on an
-// empty Builder returns the typed error EmptyParameterError.
+// empty Builder returns the typed error EmptyParameterError. Build() returns an
+// error if the document paths of two actions of an Update Expression overlap.
 //
 // Example:
 //
@@ -103,6 +104,12 @@ func (b Builder) Build() (Expression, error) {
 		return Expression{}, newUnsetParameterError("Build", "Builder")
 	}
 
+	if updateBuilder, ok := b.expressionMap[update].(UpdateBuilder); ok {
+		if err := updateBuilder.checkOverlaps(); err != nil {
+			return Expression{}, err
+		}
+	}
+
 	aliasList, expressionMap, err := b.buildChildTrees()
 	if err != nil {
 		return Expression{}, err
@@ -29,6 +29,9 @@ const (
 	// unsetConditionBuilder error will occur if an unset ConditionBuilder is
 	// used in WithCondition()
 	unsetConditionBuilder = "unset parameter: ConditionBuilder"
+	// overlappingPaths error will occur if the document paths of two update
+	// actions overlap
+	overlappingPaths = "update paths overlap"
 )
 
 func TestBuild(t *testing.T) {
@@ -157,6 +160,49 @@ func TestBuild(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "update with structured paths",
+			input: NewBuilder().
+				WithCondition(AttributeExists(Name("foo").Index(2).Key("a.b"))).
+				WithUpdate(Set(Name("foo").Index(2).Key("a.c"), Value(5)).
+					Remove(Name("foo").Index(3)).
+					Set(Name("foo[2].a"), Value(6))),
+			expected: Expression{
+				expressionMap: map[expressionType]string{
+					condition: "attribute_exists (#0[2].#1)",
+					update:    "REMOVE #0[3]\nSET #0[2].#2 = :0, #0[2].#3 = :1\n",
+				},
+				namesMap: map[string]*string{
+					"#0": aws.String("foo"),
+					"#1": aws.String("a.b"),
+					"#2": aws.String("a.c"),
+					"#3": aws.String("a"),
+				},
+				valuesMap: map[string]*dynamodb.AttributeValue{
+					":0": {
+						N: aws.String("5"),
+					},
+					":1": {
+						N: aws.String("6"),
+					},
+				},
+			},
+		},
+		{
+			name:  "update with equal paths",
+			input: NewBuilder().WithUpdate(Set(Name("foo"), Value(5)).Remove(Name("foo"))),
+			err:   overlappingPaths,
+		},
+		{
+			name:  "update with nested paths",
+			input: NewBuilder().WithUpdate(Set(Name("foo").Index(1), Value(5)).Set(Name("foo[1].bar"), Value(6))),
+			err:   overlappingPaths,
+		},
+		{
+			name:  "update with nested map key",
+			input: NewBuilder().WithUpdate(Set(NameNoDotSplit("foo.bar"), Value(5)).Set(Name("foo").Key("bar"), Value(6)).Set(Name("foo.bar.baz"), Value(6))),
+			err:   overlappingPaths,
+		},
 		{
 			name:  "invalid Builder",
 			input: NewBuilder().WithCondition(Name("").Equal(Value(5))),
@@ -2,6 +2,7 @@ package expression
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -31,8 +32,46 @@ type ValueBuilder struct {
 //
 //     // Create a NameBuilder representing the item attribute "aName"
 //     nameBuilder := expression.Name("aName")
+//     // Create a NameBuilder representing the map key "a.key" of the fourth
+//     // element of the list "aList"
+//     nameBuilder := expression.Name("aList").Index(3).Key("a.key")
 type NameBuilder struct {
 	name string
+	path []pathElement
+}
+
+// pathElementMode specifies the type of a pathElement of a document path.
+type pathElementMode int
+
+const (
+	namePathElement pathElementMode = iota
+	indexPathElement
+	rawPathElement
+)
+
+// pathElement is an element of the document path to an item attribute. It is
+// either the name of an attribute or map key, the index of a list element, or
+// list indexes parsed by Name() which are written to the expression as is.
+type pathElement struct {
+	mode  pathElementMode
+	name  string
+	index int
+}
+
+// String returns the document path element as it would be written in an
+// expression without aliasing. Names containing characters with a meaning in
+// document paths are quoted.
+func (pe pathElement) String() string {
+	switch pe.mode {
+	case indexPathElement:
+		return "[" + strconv.Itoa(pe.index) + "]"
+	case rawPathElement:
+		return pe.name
+	}
+	if strings.ContainsAny(pe.name, ".[]") {
+		return strconv.Quote(pe.name)
+	}
+	return pe.name
 }
 
 // SizeBuilder represents the output of the function size ("someName"), which
@@ -137,6 +176,140 @@ func Name(name string) NameBuilder {
 	}
 }
 
+// NameNoDotSplit creates a NameBuilder representing the top level item
+// attribute with the name of the argument. Unlike Name(), the dots and square
+// brackets in the argument are not treated as separators of nested
+// attributes, so NameNoDotSplit() can refer to attributes whose names contain
+// them.
+//
+// Example:
+//
+//     // Specify the top-level attribute "user.name"
+//     name := expression.NameNoDotSplit("user.name")
+//     // Specify the attribute "first" of the map attribute "user.name"
+//     nested := expression.NameNoDotSplit("user.name").Key("first")
+func NameNoDotSplit(name string) NameBuilder {
+	return NameBuilder{
+		path: []pathElement{{mode: namePathElement, name: name}},
+	}
+}
+
+// Index returns a NameBuilder representing the element of the list item
+// attribute specified by the NameBuilder at the index of the argument.
+//
+// Example:
+//
+//     // Specify the element at index 3 of the list attribute "aList"
+//     name := expression.Name("aList").Index(3)
+//
+// Expression Equivalent:
+//
+//     expression.Name("aList").Index(3)
+//     "aList[3]"
+func (nb NameBuilder) Index(index int) NameBuilder {
+	return nb.appendPath(pathElement{mode: indexPathElement, index: index})
+}
+
+// Key returns a NameBuilder representing the value of the key of the
+// argument in the map item attribute specified by the NameBuilder. The key is
+// always a single element of the document path, even if it contains dots or
+// square brackets, and is aliased like all other names.
+//
+// Example:
+//
+//     // Specify the key "weird.key" of the map attribute "aMap"
+//     name := expression.Name("aMap").Key("weird.key")
+//
+// Expression Equivalent:
+//
+//     expression.Name("aMap").Key("weird.key")
+//     // let #key be an ExpressionAttributeName for "weird.key"
+//     "aMap.#key"
+func (nb NameBuilder) Key(key string) NameBuilder {
+	return nb.appendPath(pathElement{mode: namePathElement, name: key})
+}
+
+// appendPath returns a copy of the NameBuilder with the element appended to
+// its path, which does not share the path of the NameBuilder.
+func (nb NameBuilder) appendPath(element pathElement) NameBuilder {
+	path := make([]pathElement, len(nb.path), len(nb.path)+1)
+	copy(path, nb.path)
+	nb.path = append(path, element)
+	return nb
+}
+
+// documentPath returns the elements of the document path represented by the
+// NameBuilder, parsing the name passed to Name() at dots and square brackets.
+func (nb NameBuilder) documentPath() ([]pathElement, error) {
+	if nb.name == "" && len(nb.path) == 0 {
+		return nil, newUnsetParameterError("BuildOperand", "NameBuilder")
+	}
+
+	var path []pathElement
+	if nb.name != "" {
+		for _, word := range strings.Split(nb.name, ".") {
+			var substr string
+			if word == "" {
+				return nil, newInvalidParameterError("BuildOperand", "NameBuilder")
+			}
+
+			if word[len(word)-1] == ']' {
+				for j, char := range word {
+					if char == '[' {
+						substr = word[j:]
+						word = word[:j]
+						break
+					}
+				}
+			}
+
+			if word == "" {
+				return nil, newInvalidParameterError("BuildOperand", "NameBuilder")
+			}
+
+			path = append(path, pathElement{mode: namePathElement, name: word})
+			if substr != "" {
+				path = append(path, parseIndexes(substr)...)
+			}
+		}
+	}
+
+	for i, element := range nb.path {
+		switch element.mode {
+		case namePathElement:
+			if element.name == "" {
+				return nil, newInvalidParameterError("BuildOperand", "NameBuilder")
+			}
+		case indexPathElement:
+			if element.index < 0 || (i == 0 && nb.name == "") {
+				return nil, newInvalidParameterError("BuildOperand", "NameBuilder")
+			}
+		}
+	}
+
+	return append(path, nb.path...), nil
+}
+
+// parseIndexes parses the list indexes following a name passed to Name(),
+// e.g. "[1][2]". Indexes which cannot be parsed are returned as a single
+// element which is written to the expression as is.
+func parseIndexes(substr string) []pathElement {
+	var indexes []pathElement
+	for rest := substr; rest != ""; {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 {
+			return []pathElement{{mode: rawPathElement, name: substr}}
+		}
+		index, err := strconv.Atoi(rest[1:end])
+		if err != nil || index < 0 || strconv.Itoa(index) != rest[1:end] {
+			return []pathElement{{mode: rawPathElement, name: substr}}
+		}
+		indexes = append(indexes, pathElement{mode: indexPathElement, index: index})
+		rest = rest[end+1:]
+	}
+	return indexes
+}
+
 // Value creates a ValueBuilder. The argument should represent the desired item
 // attribute. The value is marshalled using the dynamodbattribute package by the
 // Build() method for type Builder.
@@ -469,42 +642,28 @@ func (nb NameBuilder) IfNotExists(rightOperand OperandBuilder) SetValueBuilder {
 // words.
 // More information on reserved words at http://docs.aws.amazon.com/amazondynamodb/latest/developerguide/ReservedWords.html
 func (nb NameBuilder) BuildOperand() (Operand, error) {
-	if nb.name == "" {
-		return Operand{}, newUnsetParameterError("BuildOperand", "NameBuilder")
+	path, err := nb.documentPath()
+	if err != nil {
+		return Operand{}, err
 	}
 
 	node := exprNode{
 		names: []string{},
 	}
 
-	nameSplit := strings.Split(nb.name, ".")
-	fmtNames := make([]string, 0, len(nameSplit))
-
-	for _, word := range nameSplit {
-		var substr string
-		if word == "" {
-			return Operand{}, newInvalidParameterError("BuildOperand", "NameBuilder")
-		}
-
-		if word[len(word)-1] == ']' {
-			for j, char := range word {
-				if char == '[' {
-					substr = word[j:]
-					word = word[:j]
-					break
-				}
+	for i, element := range path {
+		switch element.mode {
+		case namePathElement:
+			if i > 0 {
+				node.fmtExpr += "."
 			}
+			// Create a string with special characters that can be substituted later: $p
+			node.names = append(node.names, element.name)
+			node.fmtExpr += "$n"
+		default:
+			node.fmtExpr += element.String()
 		}
-
-		if word == "" {
-			return Operand{}, newInvalidParameterError("BuildOperand", "NameBuilder")
-		}
-
-		// Create a string with special characters that can be substituted later: $p
-		node.names = append(node.names, word)
-		fmtNames = append(fmtNames, "$n"+substr)
 	}
-	node.fmtExpr = strings.Join(fmtNames, ".")
 	return Operand{
 		exprNode: node,
 	}, nil
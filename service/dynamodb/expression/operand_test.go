@@ -76,6 +76,30 @@ func TestBuildOperand(t *testing.T) {
 				fmtExpr: "$n.$n[0].$n",
 			},
 		},
+		{
+			name:  "name with index and key",
+			input: Name("foo").Index(3).Key("weird.key[1]"),
+			expected: exprNode{
+				names:   []string{"foo", "weird.key[1]"},
+				fmtExpr: "$n[3].$n",
+			},
+		},
+		{
+			name:  "nested name with indexes",
+			input: Name("foo[1][2].bar").Index(0),
+			expected: exprNode{
+				names:   []string{"foo", "bar"},
+				fmtExpr: "$n[1][2].$n[0]",
+			},
+		},
+		{
+			name:  "name no dot split",
+			input: NameNoDotSplit("foo.bar").Key("baz"),
+			expected: exprNode{
+				names:   []string{"foo.bar", "baz"},
+				fmtExpr: "$n.$n",
+			},
+		},
 		{
 			name:  "basic size",
 			input: Name("foo").Size(),
@@ -116,6 +140,24 @@ func TestBuildOperand(t *testing.T) {
 			expected: exprNode{},
 			err:      invalidName,
 		},
+		{
+			name:     "negative index",
+			input:    Name("foo").Index(-1),
+			expected: exprNode{},
+			err:      invalidName,
+		},
+		{
+			name:     "index without name",
+			input:    Name("").Index(1),
+			expected: exprNode{},
+			err:      invalidName,
+		},
+		{
+			name:     "empty key",
+			input:    Name("foo").Key(""),
+			expected: exprNode{},
+			err:      invalidName,
+		},
 	}
 
 	for _, c := range cases {
@@ -142,3 +184,25 @@ func TestBuildOperand(t *testing.T) {
 		})
 	}
 }
+
+func TestNameBuilderPathNotShared(t *testing.T) {
+	base := Name("foo").Index(1)
+	a := base.Key("a")
+	b := base.Key("b")
+
+	operand, err := a.BuildOperand()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := []string{"foo", "a"}, operand.exprNode.names; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v names, got %v", e, a)
+	}
+
+	operand, err = b.BuildOperand()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := []string{"foo", "b"}, operand.exprNode.names; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v names, got %v", e, a)
+	}
+}
@@ -0,0 +1,877 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ParseCondition parses a Condition Expression or Filter Expression string,
+// with the ExpressionAttributeNames and ExpressionAttributeValues it refers
+// to, into a ConditionBuilder. Either map may be nil if the expression does
+// not refer to any names or values. If the expression cannot be parsed, the
+// returned error is a ParseError with the offset of the problem.
+//
+// Building the ConditionBuilder gives back an equivalent expression, with the
+// names and values aliased by the Builder.
+//
+// Example:
+//
+//     condition, err := expression.ParseCondition(
+//         "attribute_exists(#n) AND Price < :p",
+//         map[string]*string{"#n": aws.String("Name")},
+//         map[string]*dynamodb.AttributeValue{":p": {N: aws.String("10")}},
+//     )
+//     if err != nil {
+//         return err
+//     }
+//     condition = condition.And(expression.Name("Stock").GreaterThan(expression.Value(0)))
+func ParseCondition(expr string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (ConditionBuilder, error) {
+	p, err := newParser(expr, names, values)
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+
+	condition, err := p.parseOr()
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+	if err := p.expectEnd(); err != nil {
+		return ConditionBuilder{}, err
+	}
+	return condition, nil
+}
+
+// ParseKeyCondition parses a Key Condition Expression string, with the
+// ExpressionAttributeNames and ExpressionAttributeValues it refers to, into a
+// KeyConditionBuilder. The equality condition on the partition key may be on
+// either side of the AND of a key condition on both keys. If the expression
+// cannot be parsed, the returned error is a ParseError with the offset of the
+// problem.
+//
+// Example:
+//
+//     keyCondition, err := expression.ParseKeyCondition(
+//         "Artist = :a AND begins_with(SongTitle, :t)",
+//         nil,
+//         map[string]*dynamodb.AttributeValue{
+//             ":a": {S: aws.String("No One You Know")},
+//             ":t": {S: aws.String("Call")},
+//         },
+//     )
+func ParseKeyCondition(expr string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (KeyConditionBuilder, error) {
+	p, err := newParser(expr, names, values)
+	if err != nil {
+		return KeyConditionBuilder{}, err
+	}
+
+	keyCondition, err := p.parseKeyCondition()
+	if err != nil {
+		return KeyConditionBuilder{}, err
+	}
+	if err := p.expectEnd(); err != nil {
+		return KeyConditionBuilder{}, err
+	}
+	return keyCondition, nil
+}
+
+// ParseProjection parses a Projection Expression string, with the
+// ExpressionAttributeNames it refers to, into a ProjectionBuilder. If the
+// expression cannot be parsed, the returned error is a ParseError with the
+// offset of the problem.
+//
+// Example:
+//
+//     projection, err := expression.ParseProjection(
+//         "SongTitle, #r[0].Title",
+//         map[string]*string{"#r": aws.String("Records")},
+//     )
+func ParseProjection(expr string, names map[string]*string) (ProjectionBuilder, error) {
+	p, err := newParser(expr, names, nil)
+	if err != nil {
+		return ProjectionBuilder{}, err
+	}
+
+	nameBuilders := []NameBuilder{}
+	for {
+		name, err := p.parsePath()
+		if err != nil {
+			return ProjectionBuilder{}, err
+		}
+		nameBuilders = append(nameBuilders, name)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expectEnd(); err != nil {
+		return ProjectionBuilder{}, err
+	}
+	return NamesList(nameBuilders[0], nameBuilders[1:]...), nil
+}
+
+// ParseUpdate parses an Update Expression string, with the
+// ExpressionAttributeNames and ExpressionAttributeValues it refers to, into
+// an UpdateBuilder. The SET, REMOVE, ADD and DELETE clauses may be in any
+// order, but each may only appear once. If the expression cannot be parsed,
+// the returned error is a ParseError with the offset of the problem.
+//
+// Example:
+//
+//     update, err := expression.ParseUpdate(
+//         "SET Plays = if_not_exists(Plays, :zero) + :one REMOVE Draft",
+//         nil,
+//         map[string]*dynamodb.AttributeValue{
+//             ":zero": {N: aws.String("0")},
+//             ":one":  {N: aws.String("1")},
+//         },
+//     )
+func ParseUpdate(expr string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (UpdateBuilder, error) {
+	p, err := newParser(expr, names, values)
+	if err != nil {
+		return UpdateBuilder{}, err
+	}
+
+	update := UpdateBuilder{}
+	seen := map[operationMode]bool{}
+	for {
+		tok := p.next()
+		var mode operationMode
+		switch {
+		case tok.isKeyword("SET"):
+			mode = setOperation
+		case tok.isKeyword("REMOVE"):
+			mode = removeOperation
+		case tok.isKeyword("ADD"):
+			mode = addOperation
+		case tok.isKeyword("DELETE"):
+			mode = deleteOperation
+		default:
+			return UpdateBuilder{}, p.unexpected(tok, "SET, REMOVE, ADD or DELETE")
+		}
+		if seen[mode] {
+			return UpdateBuilder{}, p.errorf(tok, "%s clause used more than once", mode)
+		}
+		seen[mode] = true
+
+		for {
+			if update, err = p.parseUpdateAction(update, mode); err != nil {
+				return UpdateBuilder{}, err
+			}
+			if !p.accept(",") {
+				break
+			}
+		}
+
+		if p.peek().kind == eofToken {
+			return update, nil
+		}
+	}
+}
+
+// tokenKind specifies the type of a token of an expression string.
+type tokenKind int
+
+const (
+	eofToken tokenKind = iota
+	// identToken is an unaliased name, keyword or function name
+	identToken
+	// nameToken is an ExpressionAttributeNames placeholder, e.g. #name
+	nameToken
+	// valueToken is an ExpressionAttributeValues placeholder, e.g. :value
+	valueToken
+	// numberToken is a list index
+	numberToken
+	// punctToken is an operator or punctuation, e.g. "<=" or ","
+	punctToken
+)
+
+// token is a token of an expression string, and its offset in the string.
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+// isKeyword returns true if the token is the keyword, which is case
+// insensitive.
+func (t token) isKeyword(keyword string) bool {
+	return t.kind == identToken && strings.EqualFold(t.text, keyword)
+}
+
+// isPunct returns true if the token is the operator or punctuation.
+func (t token) isPunct(punct string) bool {
+	return t.kind == punctToken && t.text == punct
+}
+
+func (t token) String() string {
+	if t.kind == eofToken {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// keywords are the words which cannot be used as unaliased names in the
+// position of an operand.
+var keywords = []string{"AND", "OR", "NOT", "BETWEEN", "IN"}
+
+// parser parses the tokens of an expression string into builders.
+type parser struct {
+	expr   string
+	tokens []token
+	pos    int
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+}
+
+// newParser splits the expression string into tokens, returning a parser of
+// the tokens.
+func newParser(expr string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (*parser, error) {
+	p := &parser{
+		expr:   expr,
+		names:  names,
+		values: values,
+	}
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(expr) && isIdentChar(expr[j]) {
+				j++
+			}
+			p.tokens = append(p.tokens, token{kind: identToken, text: expr[i:j], offset: i})
+			i = j
+		case c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(expr) && expr[j] >= '0' && expr[j] <= '9' {
+				j++
+			}
+			p.tokens = append(p.tokens, token{kind: numberToken, text: expr[i:j], offset: i})
+			i = j
+		case c == '#' || c == ':':
+			j := i + 1
+			for j < len(expr) && isIdentChar(expr[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, ParseError{Expression: expr, Offset: i, Message: fmt.Sprintf("empty placeholder %q", c)}
+			}
+			kind := nameToken
+			if c == ':' {
+				kind = valueToken
+			}
+			p.tokens = append(p.tokens, token{kind: kind, text: expr[i:j], offset: i})
+			i = j
+		case c == '<' && i+1 < len(expr) && (expr[i+1] == '=' || expr[i+1] == '>'),
+			c == '>' && i+1 < len(expr) && expr[i+1] == '=':
+			p.tokens = append(p.tokens, token{kind: punctToken, text: expr[i : i+2], offset: i})
+			i += 2
+		case strings.IndexByte("=<>()[],.+-", c) >= 0:
+			p.tokens = append(p.tokens, token{kind: punctToken, text: expr[i : i+1], offset: i})
+			i++
+		default:
+			return nil, ParseError{Expression: expr, Offset: i, Message: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	p.tokens = append(p.tokens, token{kind: eofToken, offset: len(expr)})
+
+	return p, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+// peek returns the next token without consuming it.
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// peekAt returns the token n tokens after the next token without consuming
+// it, or the end of the expression.
+func (p *parser) peekAt(n int) token {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+// next consumes and returns the next token.
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != eofToken {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is the punctuation.
+func (p *parser) accept(punct string) bool {
+	if p.peek().isPunct(punct) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect consumes the next token, returning an error if it is not the
+// punctuation.
+func (p *parser) expect(punct string) error {
+	if tok := p.next(); !tok.isPunct(punct) {
+		return p.unexpected(tok, strconv.Quote(punct))
+	}
+	return nil
+}
+
+// expectEnd returns an error if there are tokens left.
+func (p *parser) expectEnd() error {
+	if tok := p.peek(); tok.kind != eofToken {
+		return p.unexpected(tok, "end of expression")
+	}
+	return nil
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return ParseError{
+		Expression: p.expr,
+		Offset:     tok.offset,
+		Message:    fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) unexpected(tok token, expected string) error {
+	return p.errorf(tok, "expected %s, got %s", expected, tok)
+}
+
+// parseOr parses conditions separated by OR.
+func (p *parser) parseOr() (ConditionBuilder, error) {
+	return p.parseCompound(orCond, "OR", p.parseAnd)
+}
+
+// parseAnd parses conditions separated by AND.
+func (p *parser) parseAnd() (ConditionBuilder, error) {
+	return p.parseCompound(andCond, "AND", p.parseNot)
+}
+
+// parseCompound parses conditions separated by the keyword, returning a
+// single ConditionBuilder of the mode for all of them.
+func (p *parser) parseCompound(mode conditionMode, keyword string, parse func() (ConditionBuilder, error)) (ConditionBuilder, error) {
+	condition, err := parse()
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+
+	conditions := []ConditionBuilder{condition}
+	for p.peek().isKeyword(keyword) {
+		p.next()
+		condition, err := parse()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) == 1 {
+		return conditions[0], nil
+	}
+	return ConditionBuilder{
+		conditionList: conditions,
+		mode:          mode,
+	}, nil
+}
+
+// parseNot parses a condition, optionally negated by NOT.
+func (p *parser) parseNot() (ConditionBuilder, error) {
+	if !p.peek().isKeyword("NOT") {
+		return p.parseConditionTerm()
+	}
+	p.next()
+
+	condition, err := p.parseNot()
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+	return Not(condition), nil
+}
+
+// conditionFunctions are the modes of the functions of condition
+// expressions, by name.
+var conditionFunctions = map[string]conditionMode{
+	"attribute_exists":     attrExistsCond,
+	"attribute_not_exists": attrNotExistsCond,
+	"attribute_type":       attrTypeCond,
+	"begins_with":          beginsWithCond,
+	"contains":             containsCond,
+}
+
+// parseConditionTerm parses a parenthesized condition, a function, or a
+// comparison, BETWEEN or IN condition of an operand.
+func (p *parser) parseConditionTerm() (ConditionBuilder, error) {
+	if p.accept("(") {
+		condition, err := p.parseOr()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		if err := p.expect(")"); err != nil {
+			return ConditionBuilder{}, err
+		}
+		return condition, nil
+	}
+
+	if tok := p.peek(); tok.kind == identToken && p.peekAt(1).isPunct("(") {
+		if mode, ok := conditionFunctions[strings.ToLower(tok.text)]; ok {
+			return p.parseConditionFunction(mode)
+		}
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+
+	tok := p.next()
+	switch {
+	case tok.isKeyword("BETWEEN"):
+		lower, err := p.parseOperand()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		if and := p.next(); !and.isKeyword("AND") {
+			return ConditionBuilder{}, p.unexpected(and, "AND")
+		}
+		upper, err := p.parseOperand()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		return Between(left, lower, upper), nil
+
+	case tok.isKeyword("IN"):
+		if err := p.expect("("); err != nil {
+			return ConditionBuilder{}, err
+		}
+		operands := []OperandBuilder{}
+		for {
+			operand, err := p.parseOperand()
+			if err != nil {
+				return ConditionBuilder{}, err
+			}
+			operands = append(operands, operand)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return ConditionBuilder{}, err
+		}
+		return In(left, operands[0], operands[1:]...), nil
+
+	case tok.kind == punctToken:
+		var mode conditionMode
+		switch tok.text {
+		case "=":
+			mode = equalCond
+		case "<>":
+			mode = notEqualCond
+		case "<":
+			mode = lessThanCond
+		case "<=":
+			mode = lessThanEqualCond
+		case ">":
+			mode = greaterThanCond
+		case ">=":
+			mode = greaterThanEqualCond
+		default:
+			return ConditionBuilder{}, p.unexpected(tok, "comparator, BETWEEN or IN")
+		}
+		right, err := p.parseOperand()
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		return ConditionBuilder{
+			operandList: []OperandBuilder{left, right},
+			mode:        mode,
+		}, nil
+	}
+
+	return ConditionBuilder{}, p.unexpected(tok, "comparator, BETWEEN or IN")
+}
+
+// parseConditionFunction parses the arguments of a function of a condition
+// expression.
+func (p *parser) parseConditionFunction(mode conditionMode) (ConditionBuilder, error) {
+	// The function name and "(" were checked by the caller.
+	p.next()
+	p.next()
+
+	name, err := p.parsePath()
+	if err != nil {
+		return ConditionBuilder{}, err
+	}
+	operands := []OperandBuilder{name}
+
+	switch mode {
+	case attrTypeCond, beginsWithCond, containsCond:
+		if err := p.expect(","); err != nil {
+			return ConditionBuilder{}, err
+		}
+		var operand OperandBuilder
+		if mode == attrTypeCond {
+			operand, err = p.parseValue()
+		} else {
+			operand, err = p.parseOperand()
+		}
+		if err != nil {
+			return ConditionBuilder{}, err
+		}
+		operands = append(operands, operand)
+	}
+
+	if err := p.expect(")"); err != nil {
+		return ConditionBuilder{}, err
+	}
+	return ConditionBuilder{
+		operandList: operands,
+		mode:        mode,
+	}, nil
+}
+
+// parseOperand parses a document path, value placeholder, or size function
+// of a condition expression.
+func (p *parser) parseOperand() (OperandBuilder, error) {
+	tok := p.peek()
+	switch {
+	case tok.kind == valueToken:
+		return p.parseValue()
+	case tok.isKeyword("size") && p.peekAt(1).isPunct("("):
+		p.next()
+		p.next()
+		name, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return name.Size(), nil
+	}
+	return p.parsePath()
+}
+
+// parseValue parses a value placeholder, returning a ValueBuilder of its
+// value in the ExpressionAttributeValues.
+func (p *parser) parseValue() (ValueBuilder, error) {
+	tok := p.next()
+	if tok.kind != valueToken {
+		return ValueBuilder{}, p.unexpected(tok, "value placeholder")
+	}
+	av, ok := p.values[tok.text]
+	if !ok || av == nil {
+		return ValueBuilder{}, p.errorf(tok, "%s is not defined in ExpressionAttributeValues", tok.text)
+	}
+	return ValueBuilder{value: parsedValue{av: av}}, nil
+}
+
+// parseName parses an unaliased name or name placeholder, returning the
+// name.
+func (p *parser) parseName() (string, error) {
+	tok := p.next()
+	switch tok.kind {
+	case identToken:
+		for _, keyword := range keywords {
+			if tok.isKeyword(keyword) {
+				return "", p.unexpected(tok, "attribute name")
+			}
+		}
+		return tok.text, nil
+	case nameToken:
+		name, ok := p.names[tok.text]
+		if !ok || name == nil {
+			return "", p.errorf(tok, "%s is not defined in ExpressionAttributeNames", tok.text)
+		}
+		return *name, nil
+	}
+	return "", p.unexpected(tok, "attribute name")
+}
+
+// parsePath parses a document path of names, map keys and list indexes.
+func (p *parser) parsePath() (NameBuilder, error) {
+	name, err := p.parseName()
+	if err != nil {
+		return NameBuilder{}, err
+	}
+	nameBuilder := NameNoDotSplit(name)
+
+	for {
+		switch {
+		case p.accept("."):
+			key, err := p.parseName()
+			if err != nil {
+				return NameBuilder{}, err
+			}
+			nameBuilder = nameBuilder.Key(key)
+		case p.accept("["):
+			tok := p.next()
+			if tok.kind != numberToken {
+				return NameBuilder{}, p.unexpected(tok, "list index")
+			}
+			index, err := strconv.Atoi(tok.text)
+			if err != nil {
+				return NameBuilder{}, p.errorf(tok, "invalid list index %s", tok)
+			}
+			if err := p.expect("]"); err != nil {
+				return NameBuilder{}, err
+			}
+			nameBuilder = nameBuilder.Index(index)
+		default:
+			return nameBuilder, nil
+		}
+	}
+}
+
+// parseKeyCondition parses a key condition, or two key conditions separated
+// by AND, one of which is an equality condition.
+func (p *parser) parseKeyCondition() (KeyConditionBuilder, error) {
+	left, err := p.parseKeyConditionTerm()
+	if err != nil {
+		return KeyConditionBuilder{}, err
+	}
+	if !p.peek().isKeyword("AND") {
+		return left, nil
+	}
+
+	and := p.next()
+	right, err := p.parseKeyConditionTerm()
+	if err != nil {
+		return KeyConditionBuilder{}, err
+	}
+
+	if left.mode != equalKeyCond && right.mode == equalKeyCond {
+		left, right = right, left
+	}
+	if left.mode != equalKeyCond || right.mode == andKeyCond {
+		return KeyConditionBuilder{}, p.errorf(and,
+			"key condition must be an equality condition on the partition key AND a condition on the sort key")
+	}
+	return KeyAnd(left, right), nil
+}
+
+// parseKeyConditionTerm parses a parenthesized key condition, the
+// begins_with function, or a comparison or BETWEEN condition of a key.
+func (p *parser) parseKeyConditionTerm() (KeyConditionBuilder, error) {
+	if p.accept("(") {
+		keyCondition, err := p.parseKeyCondition()
+		if err != nil {
+			return KeyConditionBuilder{}, err
+		}
+		if err := p.expect(")"); err != nil {
+			return KeyConditionBuilder{}, err
+		}
+		return keyCondition, nil
+	}
+
+	if tok := p.peek(); tok.isKeyword("begins_with") && p.peekAt(1).isPunct("(") {
+		p.next()
+		p.next()
+		key, err := p.parseKey()
+		if err != nil {
+			return KeyConditionBuilder{}, err
+		}
+		if err := p.expect(","); err != nil {
+			return KeyConditionBuilder{}, err
+		}
+		prefix, err := p.parseValue()
+		if err != nil {
+			return KeyConditionBuilder{}, err
+		}
+		if err := p.expect(")"); err != nil {
+			return KeyConditionBuilder{}, err
+		}
+		return KeyConditionBuilder{
+			operandList: []OperandBuilder{key, prefix},
+			mode:        beginsWithKeyCond,
+		}, nil
+	}
+
+	key, err := p.parseKey()
+	if err != nil {
+		return KeyConditionBuilder{}, err
+	}
+
+	tok := p.next()
+	var mode keyConditionMode
+	switch {
+	case tok.isKeyword("BETWEEN"):
+		lower, err := p.parseValue()
+		if err != nil {
+			return KeyConditionBuilder{}, err
+		}
+		if and := p.next(); !and.isKeyword("AND") {
+			return KeyConditionBuilder{}, p.unexpected(and, "AND")
+		}
+		upper, err := p.parseValue()
+		if err != nil {
+			return KeyConditionBuilder{}, err
+		}
+		return KeyBetween(key, lower, upper), nil
+	case tok.isPunct("="):
+		mode = equalKeyCond
+	case tok.isPunct("<"):
+		mode = lessThanKeyCond
+	case tok.isPunct("<="):
+		mode = lessThanEqualKeyCond
+	case tok.isPunct(">"):
+		mode = greaterThanKeyCond
+	case tok.isPunct(">="):
+		mode = greaterThanEqualKeyCond
+	default:
+		return KeyConditionBuilder{}, p.unexpected(tok, "comparator or BETWEEN")
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return KeyConditionBuilder{}, err
+	}
+	return KeyConditionBuilder{
+		operandList: []OperandBuilder{key, value},
+		mode:        mode,
+	}, nil
+}
+
+// parseKey parses the name of a key attribute, which must be a top level
+// attribute.
+func (p *parser) parseKey() (KeyBuilder, error) {
+	tok := p.peek()
+	name, err := p.parseName()
+	if err != nil {
+		return KeyBuilder{}, err
+	}
+	if next := p.peek(); next.isPunct(".") || next.isPunct("[") {
+		return KeyBuilder{}, p.errorf(tok, "key condition must refer to a top level key attribute")
+	}
+	return Key(name), nil
+}
+
+// parseUpdateAction parses an action of the update clause of the mode,
+// adding it to the UpdateBuilder.
+func (p *parser) parseUpdateAction(update UpdateBuilder, mode operationMode) (UpdateBuilder, error) {
+	name, err := p.parsePath()
+	if err != nil {
+		return UpdateBuilder{}, err
+	}
+
+	switch mode {
+	case setOperation:
+		if err := p.expect("="); err != nil {
+			return UpdateBuilder{}, err
+		}
+		value, err := p.parseSetValue()
+		if err != nil {
+			return UpdateBuilder{}, err
+		}
+		return update.Set(name, value), nil
+	case removeOperation:
+		return update.Remove(name), nil
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return UpdateBuilder{}, err
+	}
+	if mode == addOperation {
+		return update.Add(name, value), nil
+	}
+	return update.Delete(name, value), nil
+}
+
+// parseSetValue parses the value of a SET action, an operand optionally
+// followed by "+" or "-" and another operand.
+func (p *parser) parseSetValue() (OperandBuilder, error) {
+	left, err := p.parseSetOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.accept("+"):
+		right, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		return Plus(left, right), nil
+	case p.accept("-"):
+		right, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		return Minus(left, right), nil
+	}
+	return left, nil
+}
+
+// parseSetOperand parses a document path, value placeholder, or the
+// if_not_exists or list_append function of a SET action.
+func (p *parser) parseSetOperand() (OperandBuilder, error) {
+	tok := p.peek()
+	switch {
+	case tok.kind == valueToken:
+		return p.parseValue()
+	case tok.isKeyword("if_not_exists") && p.peekAt(1).isPunct("("):
+		p.next()
+		p.next()
+		name, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		value, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return IfNotExists(name, value), nil
+	case tok.isKeyword("list_append") && p.peekAt(1).isPunct("("):
+		p.next()
+		p.next()
+		left, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		right, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return ListAppend(left, right), nil
+	}
+	return p.parsePath()
+}
+
+// parsedValue is a value of the ExpressionAttributeValues of a parsed
+// expression, which is marshaled as is.
+type parsedValue struct {
+	av *dynamodb.AttributeValue
+}
+
+func (v parsedValue) MarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	*av = *v.av
+	return nil
+}
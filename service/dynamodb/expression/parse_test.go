@@ -0,0 +1,386 @@
+// +build go1.7
+
+package expression
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var parseTestValues = map[string]*dynamodb.AttributeValue{
+	":s": {S: aws.String("str")},
+	":n": {N: aws.String("5")},
+	":m": {N: aws.String("10")},
+	":l": {L: []*dynamodb.AttributeValue{{S: aws.String("a")}}},
+	":t": {S: aws.String("SS")},
+}
+
+var parseTestNames = map[string]*string{
+	"#n":   aws.String("name"),
+	"#dot": aws.String("a.b"),
+	"#and": aws.String("AND"),
+}
+
+func TestParseCondition(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+		names    []string
+		err      string
+		offset   int
+	}{
+		{
+			name:     "comparison",
+			input:    "foo = :n",
+			expected: "#0 = :0",
+			names:    []string{"foo"},
+		},
+		{
+			name:     "compound",
+			input:    "a < :n AND b >= :m OR NOT c <> :s AND d <= :n",
+			expected: "((#0 < :0) AND (#1 >= :1)) OR ((NOT (#2 <> :2)) AND (#3 <= :3))",
+			names:    []string{"a", "b", "c", "d"},
+		},
+		{
+			name:     "parentheses",
+			input:    "(a > :n OR b = :n) AND c = :n AND d = :n",
+			expected: "((#0 > :0) OR (#1 = :1)) AND (#2 = :2) AND (#3 = :3)",
+			names:    []string{"a", "b", "c", "d"},
+		},
+		{
+			name:     "between and in",
+			input:    "a BETWEEN :n AND :m and b in (:s, c.d, :n)",
+			expected: "(#0 BETWEEN :0 AND :1) AND (#1 IN (:2, #2.#3, :3))",
+			names:    []string{"a", "b", "c", "d"},
+		},
+		{
+			name:     "functions",
+			input:    "attribute_exists(#n) AND attribute_not_exists(#dot[1]) AND attribute_type(a, :t) AND begins_with(a, :s) AND contains(b, c)",
+			expected: "(attribute_exists (#0)) AND (attribute_not_exists (#1[1])) AND (attribute_type (#2, :0)) AND (begins_with (#2, :1)) AND (contains (#3, #4))",
+			names:    []string{"name", "a.b", "a", "b", "c"},
+		},
+		{
+			name:     "size",
+			input:    "size(#and.b[2]) > size (c)",
+			expected: "size (#0.#1[2]) > size (#2)",
+			names:    []string{"AND", "b", "c"},
+		},
+		{
+			name:   "unknown name",
+			input:  "a = :n AND #x = :n",
+			err:    "#x is not defined in ExpressionAttributeNames",
+			offset: 11,
+		},
+		{
+			name:   "unknown value",
+			input:  "a = :x",
+			err:    ":x is not defined in ExpressionAttributeValues",
+			offset: 4,
+		},
+		{
+			name:   "missing operand",
+			input:  "a = AND",
+			err:    `expected attribute name, got "AND"`,
+			offset: 4,
+		},
+		{
+			name:   "missing comparator",
+			input:  "a :n",
+			err:    `expected comparator, BETWEEN or IN, got ":n"`,
+			offset: 2,
+		},
+		{
+			name:   "unclosed parenthesis",
+			input:  "(a = :n",
+			err:    `expected ")", got end of expression`,
+			offset: 7,
+		},
+		{
+			name:   "trailing tokens",
+			input:  "a = :n b",
+			err:    `expected end of expression, got "b"`,
+			offset: 7,
+		},
+		{
+			name:   "invalid character",
+			input:  "a = :n AND b ! :n",
+			err:    "unexpected character '!'",
+			offset: 13,
+		},
+		{
+			name:   "invalid index",
+			input:  "a[b] = :n",
+			err:    `expected list index, got "b"`,
+			offset: 2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			condition, err := ParseCondition(c.input, parseTestNames, parseTestValues)
+			if c.err != "" {
+				assertParseError(t, err, c.err, c.offset)
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			expr, err := NewBuilder().WithCondition(condition).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := c.expected, aws.StringValue(expr.Condition()); e != a {
+				t.Errorf("expect %q condition, got %q", e, a)
+			}
+			assertNames(t, c.names, expr.Names())
+
+			// Parsing the built expression gives back the same expression.
+			reparsed, err := ParseCondition(*expr.Condition(), expr.Names(), expr.Values())
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			rebuilt, err := NewBuilder().WithCondition(reparsed).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := expr, rebuilt; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestParseKeyCondition(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+		err      string
+		offset   int
+	}{
+		{
+			name:     "partition key",
+			input:    "#n = :s",
+			expected: "#0 = :0",
+		},
+		{
+			name:     "sort key",
+			input:    "#n = :s AND sk BETWEEN :n AND :m",
+			expected: "(#0 = :0) AND (#1 BETWEEN :1 AND :2)",
+		},
+		{
+			name:     "sort key first",
+			input:    "begins_with(sk, :s) and (#n = :s)",
+			expected: "(#0 = :0) AND (begins_with (#1, :1))",
+		},
+		{
+			name:     "comparison",
+			input:    "#n = :s AND sk <= :n",
+			expected: "(#0 = :0) AND (#1 <= :1)",
+		},
+		{
+			name:   "no equality",
+			input:  "a < :n AND b > :n",
+			err:    "key condition must be an equality condition",
+			offset: 7,
+		},
+		{
+			name:   "nested key",
+			input:  "a.b = :n",
+			err:    "top level key attribute",
+			offset: 0,
+		},
+		{
+			name:   "or",
+			input:  "a = :n OR b = :n",
+			err:    `expected end of expression, got "OR"`,
+			offset: 7,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			keyCondition, err := ParseKeyCondition(c.input, parseTestNames, parseTestValues)
+			if c.err != "" {
+				assertParseError(t, err, c.err, c.offset)
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			expr, err := NewBuilder().WithKeyCondition(keyCondition).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := c.expected, aws.StringValue(expr.KeyCondition()); e != a {
+				t.Errorf("expect %q key condition, got %q", e, a)
+			}
+
+			reparsed, err := ParseKeyCondition(*expr.KeyCondition(), expr.Names(), expr.Values())
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			rebuilt, err := NewBuilder().WithKeyCondition(reparsed).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := expr, rebuilt; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestParseProjection(t *testing.T) {
+	projection, err := ParseProjection("a, #dot[0][1].c,#n", parseTestNames)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expr, err := NewBuilder().WithProjection(projection).Build()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "#0, #1[0][1].#2, #3", aws.StringValue(expr.Projection()); e != a {
+		t.Errorf("expect %q projection, got %q", e, a)
+	}
+	assertNames(t, []string{"a", "a.b", "c", "name"}, expr.Names())
+
+	_, err = ParseProjection("a,", parseTestNames)
+	assertParseError(t, err, "expected attribute name, got end of expression", 2)
+}
+
+func TestParseUpdate(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+		names    []string
+		err      string
+		offset   int
+	}{
+		{
+			name:     "set",
+			input:    "SET a = :n, b = c + :n, #n = if_not_exists(#n, :m) - :n, l = list_append(:l, if_not_exists(l, :l))",
+			expected: "SET #0 = :0, #1 = #2 + :1, #3 = if_not_exists(#3, :2) - :3, #4 = list_append(:4, if_not_exists(#4, :5))\n",
+			names:    []string{"a", "b", "c", "name", "l"},
+		},
+		{
+			name:     "all clauses",
+			input:    "remove a[1], b.c add d :n delete e :l set f = :s",
+			expected: "ADD #0 :0\nDELETE #1 :1\nREMOVE #2[1], #3.#4\nSET #5 = :2\n",
+			names:    []string{"d", "e", "a", "b", "c", "f"},
+		},
+		{
+			name:   "repeated clause",
+			input:  "SET a = :n REMOVE b SET c = :n",
+			err:    "SET clause used more than once",
+			offset: 20,
+		},
+		{
+			name:   "add without value",
+			input:  "ADD a b",
+			err:    `expected value placeholder, got "b"`,
+			offset: 6,
+		},
+		{
+			name:   "missing clause",
+			input:  "a = :n",
+			err:    `expected SET, REMOVE, ADD or DELETE, got "a"`,
+			offset: 0,
+		},
+		{
+			name:   "set without value",
+			input:  "SET a = ",
+			err:    "expected attribute name, got end of expression",
+			offset: 8,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			update, err := ParseUpdate(c.input, parseTestNames, parseTestValues)
+			if c.err != "" {
+				assertParseError(t, err, c.err, c.offset)
+				return
+			}
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+
+			expr, err := NewBuilder().WithUpdate(update).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := c.expected, aws.StringValue(expr.Update()); e != a {
+				t.Errorf("expect %q update, got %q", e, a)
+			}
+			assertNames(t, c.names, expr.Names())
+
+			reparsed, err := ParseUpdate(*expr.Update(), expr.Names(), expr.Values())
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			rebuilt, err := NewBuilder().WithUpdate(reparsed).Build()
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := expr, rebuilt; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	condition, err := ParseCondition("a IN (:s, :n, :l)", nil, parseTestValues)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	expr, err := NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expected := map[string]*dynamodb.AttributeValue{
+		":0": parseTestValues[":s"],
+		":1": parseTestValues[":n"],
+		":2": parseTestValues[":l"],
+	}
+	if e, a := expected, expr.Values(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+}
+
+func assertParseError(t *testing.T, err error, msg string, offset int) {
+	parseErr, ok := err.(ParseError)
+	if !ok {
+		t.Fatalf("expect ParseError, got %v", err)
+	}
+	if e, a := msg, parseErr.Message; !strings.Contains(a, e) {
+		t.Errorf("expect %q error message to be in %q", e, a)
+	}
+	if e, a := offset, parseErr.Offset; e != a {
+		t.Errorf("expect error at offset %d, got %d", e, a)
+	}
+}
+
+func assertNames(t *testing.T, names []string, actual map[string]*string) {
+	if e, a := len(names), len(actual); e != a {
+		t.Errorf("expect %d names, got %d", e, a)
+	}
+	for i, name := range names {
+		alias := fmt.Sprintf("#%d", i)
+		if e, a := name, aws.StringValue(actual[alias]); e != a {
+			t.Errorf("expect %v name for %v, got %v", e, alias, a)
+		}
+	}
+}
@@ -368,6 +368,69 @@ func (ub UpdateBuilder) buildTree() (exprNode, error) {
 	return ret, nil
 }
 
+// checkOverlaps returns an error if the document paths of two update actions
+// of the UpdateBuilder overlap, i.e. if they are equal or one is nested in the
+// other, since DynamoDB rejects such Update Expressions.
+func (ub UpdateBuilder) checkOverlaps() error {
+	type updatePath struct {
+		mode operationMode
+		path []pathElement
+	}
+
+	modes := modeList{}
+	for mode := range ub.operationList {
+		modes = append(modes, mode)
+	}
+	sort.Sort(modes)
+
+	paths := []updatePath{}
+	for _, mode := range modes {
+		for _, op := range ub.operationList[mode] {
+			path, err := op.name.documentPath()
+			if err != nil {
+				return err
+			}
+			for _, other := range paths {
+				if pathsOverlap(path, other.path) {
+					return newOverlappingPathsError("Build",
+						fmt.Sprintf("%s %s", other.mode, formatPath(other.path)),
+						fmt.Sprintf("%s %s", mode, formatPath(path)))
+				}
+			}
+			paths = append(paths, updatePath{mode: mode, path: path})
+		}
+	}
+
+	return nil
+}
+
+// pathsOverlap returns true if the document paths are equal, or one is a
+// prefix of the other.
+func pathsOverlap(a, b []pathElement) bool {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// formatPath returns the document path as it would be written in an
+// expression without aliasing.
+func formatPath(path []pathElement) string {
+	var str string
+	for i, element := range path {
+		if i > 0 && element.mode == namePathElement {
+			str += "."
+		}
+		str += element.String()
+	}
+	return str
+}
+
 // buildChildNodes creates the list of the child exprNodes.
 func buildChildNodes(operationBuilderList []operationBuilder) (exprNode, error) {
 	if len(operationBuilderList) == 0 {
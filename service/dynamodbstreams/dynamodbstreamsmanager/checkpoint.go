@@ -0,0 +1,195 @@
+package dynamodbstreamsmanager
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// ShardEnd is the checkpoint of a shard whose records have all been
+// processed, after the shard was closed.
+const ShardEnd = "SHARD_END"
+
+// CheckpointStore stores the progress of a Consumer through the shards of a
+// stream, as the sequence number of the last record of each shard processed.
+//
+// A CheckpointStore must be safe to use concurrently across goroutines.
+type CheckpointStore interface {
+	// GetCheckpoint returns the checkpoint of the shard, or an empty string
+	// if the shard has no checkpoint.
+	GetCheckpoint(ctx aws.Context, streamArn, shardID string) (string, error)
+
+	// SetCheckpoint stores the checkpoint of the shard. The checkpoint is
+	// either a sequence number, or ShardEnd.
+	SetCheckpoint(ctx aws.Context, streamArn, shardID, checkpoint string) error
+}
+
+// MemoryCheckpoints stores checkpoints in memory. Its checkpoints are lost
+// when the process exits, so it is mostly useful for consumers which are
+// started from the LATEST records of a stream, and for testing.
+type MemoryCheckpoints struct {
+	m           sync.Mutex
+	checkpoints map[string]map[string]string
+}
+
+// NewMemoryCheckpoints returns a new MemoryCheckpoints without checkpoints.
+func NewMemoryCheckpoints() *MemoryCheckpoints {
+	return &MemoryCheckpoints{checkpoints: map[string]map[string]string{}}
+}
+
+// GetCheckpoint returns the checkpoint of the shard, or an empty string if
+// the shard has no checkpoint.
+func (c *MemoryCheckpoints) GetCheckpoint(ctx aws.Context, streamArn, shardID string) (string, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.checkpoints[streamArn][shardID], nil
+}
+
+// SetCheckpoint stores the checkpoint of the shard.
+func (c *MemoryCheckpoints) SetCheckpoint(ctx aws.Context, streamArn, shardID, checkpoint string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.checkpoints == nil {
+		c.checkpoints = map[string]map[string]string{}
+	}
+	if c.checkpoints[streamArn] == nil {
+		c.checkpoints[streamArn] = map[string]string{}
+	}
+	c.checkpoints[streamArn][shardID] = checkpoint
+	return nil
+}
+
+// The attributes of the items of a TableCheckpoints table.
+const (
+	// The partition key of the table, the stream's ARN, prefixed by the
+	// Application name and a slash if one is set.
+	CheckpointStreamAttribute = "stream"
+
+	// The sort key of the table, the shard's ID.
+	CheckpointShardAttribute = "shard"
+
+	// The checkpoint of the shard.
+	CheckpointAttribute = "checkpoint"
+
+	// The time the checkpoint was stored, in Unix seconds.
+	CheckpointUpdatedAttribute = "updated"
+)
+
+// WithTableCheckpointsRequestOptions appends to the TableCheckpoints's API
+// request options.
+func WithTableCheckpointsRequestOptions(opts ...request.Option) func(*TableCheckpoints) {
+	return func(c *TableCheckpoints) {
+		c.RequestOptions = append(c.RequestOptions, opts...)
+	}
+}
+
+// TableCheckpoints stores checkpoints in a DynamoDB table, with an item for
+// each shard. The table's partition key is the string attribute
+// CheckpointStreamAttribute, and its sort key is the string attribute
+// CheckpointShardAttribute.
+//
+// The table can be shared by the consumers of different applications, or
+// of different streams, each with its own checkpoints.
+type TableCheckpoints struct {
+	// The name of the table.
+	TableName string
+
+	// The name of the application consuming the stream. Applications with
+	// different names have separate checkpoints for the same stream.
+	Application string
+
+	// The client to use when reading and writing checkpoints.
+	Client dynamodbiface.DynamoDBAPI
+
+	// List of request options that will be passed down to individual API
+	// operation requests made by the TableCheckpoints.
+	RequestOptions []request.Option
+}
+
+// NewTableCheckpoints returns a new TableCheckpoints for the table using a
+// DynamoDB client created from the session. Options can be passed in to
+// modify it.
+//
+// Example:
+//     sess := session.Must(session.NewSession())
+//
+//     checkpoints := dynamodbstreamsmanager.NewTableCheckpoints(sess, "checkpoints",
+//         func(c *dynamodbstreamsmanager.TableCheckpoints) {
+//             c.Application = "indexer"
+//         })
+func NewTableCheckpoints(c client.ConfigProvider, tableName string, options ...func(*TableCheckpoints)) *TableCheckpoints {
+	return NewTableCheckpointsWithClient(dynamodb.New(c), tableName, options...)
+}
+
+// NewTableCheckpointsWithClient returns a new TableCheckpoints for the table
+// using the DynamoDB client. Options can be passed in to modify it.
+func NewTableCheckpointsWithClient(svc dynamodbiface.DynamoDBAPI, tableName string, options ...func(*TableCheckpoints)) *TableCheckpoints {
+	c := &TableCheckpoints{
+		TableName: tableName,
+		Client:    svc,
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// key returns the key of the shard's item.
+func (c *TableCheckpoints) key(streamArn, shardID string) map[string]*dynamodb.AttributeValue {
+	stream := streamArn
+	if len(c.Application) != 0 {
+		stream = c.Application + "/" + streamArn
+	}
+	return map[string]*dynamodb.AttributeValue{
+		CheckpointStreamAttribute: {S: aws.String(stream)},
+		CheckpointShardAttribute:  {S: aws.String(shardID)},
+	}
+}
+
+func (c *TableCheckpoints) requestOptions() []request.Option {
+	return append(c.RequestOptions[:len(c.RequestOptions):len(c.RequestOptions)],
+		request.WithAppendUserAgent("DynamoDBStreamsManager"))
+}
+
+// GetCheckpoint returns the checkpoint of the shard, or an empty string if
+// the shard has no checkpoint. The checkpoint is read with a strongly
+// consistent read.
+func (c *TableCheckpoints) GetCheckpoint(ctx aws.Context, streamArn, shardID string) (string, error) {
+	out, err := c.Client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(c.TableName),
+		Key:            c.key(streamArn, shardID),
+		ConsistentRead: aws.Bool(true),
+	}, c.requestOptions()...)
+	if err != nil {
+		return "", err
+	}
+	if av, ok := out.Item[CheckpointAttribute]; ok {
+		return aws.StringValue(av.S), nil
+	}
+	return "", nil
+}
+
+// SetCheckpoint stores the checkpoint of the shard.
+func (c *TableCheckpoints) SetCheckpoint(ctx aws.Context, streamArn, shardID, checkpoint string) error {
+	item := c.key(streamArn, shardID)
+	item[CheckpointAttribute] = &dynamodb.AttributeValue{S: aws.String(checkpoint)}
+	item[CheckpointUpdatedAttribute] = &dynamodb.AttributeValue{
+		N: aws.String(strconv.FormatInt(time.Now().Unix(), 10)),
+	}
+
+	_, err := c.Client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.TableName),
+		Item:      item,
+	}, c.requestOptions()...)
+	return err
+}
@@ -0,0 +1,114 @@
+package dynamodbstreamsmanager_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbtest"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsmanager"
+)
+
+func TestTableCheckpoints(t *testing.T) {
+	db := dynamodbtest.NewDB()
+	_, err := db.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("checkpoints"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("stream"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("shard"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("stream"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("shard"), KeyType: aws.String("RANGE")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	ctx := aws.BackgroundContext()
+	indexer := dynamodbstreamsmanager.NewTableCheckpointsWithClient(db, "checkpoints",
+		func(c *dynamodbstreamsmanager.TableCheckpoints) {
+			c.Application = "indexer"
+		})
+	other := dynamodbstreamsmanager.NewTableCheckpointsWithClient(db, "checkpoints")
+
+	cp, err := indexer.GetCheckpoint(ctx, testStreamArn, "shard")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if len(cp) != 0 {
+		t.Errorf("expect no checkpoint, got %v", cp)
+	}
+
+	if err := indexer.SetCheckpoint(ctx, testStreamArn, "shard", "000000000000000000001"); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if err := other.SetCheckpoint(ctx, testStreamArn, "shard", dynamodbstreamsmanager.ShardEnd); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	cp, err = indexer.GetCheckpoint(ctx, testStreamArn, "shard")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "000000000000000000001", cp; e != a {
+		t.Errorf("expect %v checkpoint, got %v", e, a)
+	}
+
+	out, err := db.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("checkpoints"),
+		Key: map[string]*dynamodb.AttributeValue{
+			"stream": {S: aws.String("indexer/" + testStreamArn)},
+			"shard":  {S: aws.String("shard")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if out.Item["updated"] == nil {
+		t.Errorf("expect updated attribute, got %v", out.Item)
+	}
+}
+
+func TestConsumer_TableCheckpoints(t *testing.T) {
+	db := dynamodbtest.NewDB()
+	_, err := db.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("checkpoints"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("stream"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("shard"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("stream"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("shard"), KeyType: aws.String("RANGE")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	svc := newStreamSvc()
+	svc.addShard("root", "", true, 1, 2)
+	svc.addShard("child", "root", true, 10)
+
+	consumer := newTestConsumer(svc, func(c *dynamodbstreamsmanager.Consumer) {
+		c.Checkpoints = dynamodbstreamsmanager.NewTableCheckpointsWithClient(db, "checkpoints")
+	})
+	r := &recorder{}
+	if err := consumer.Consume(testStreamArn, r.handle); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 3, len(r.values); e != a {
+		t.Errorf("expect %d records, got %d", e, a)
+	}
+
+	// Consuming the stream again processes no records.
+	r = &recorder{}
+	if err := consumer.Consume(testStreamArn, r.handle); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 0, len(r.values); e != a {
+		t.Errorf("expect %d records, got %d", e, a)
+	}
+}
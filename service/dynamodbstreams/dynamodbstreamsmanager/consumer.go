@@ -0,0 +1,408 @@
+package dynamodbstreamsmanager
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+)
+
+const (
+	// DefaultPollInterval is the default time a Consumer waits before
+	// reading more records from an open shard which had no new records.
+	DefaultPollInterval = time.Second
+
+	// DefaultRefreshInterval is the default time between the Consumer's
+	// descriptions of a stream, to find its new shards.
+	DefaultRefreshInterval = 10 * time.Second
+)
+
+// ErrCodeImageNotFound is the code of the error returned when decoding an
+// image which is not in a record, because of the stream's view type or the
+// record's event.
+const ErrCodeImageNotFound = "ImageNotFound"
+
+// Record is a record of a stream, read from a shard by a Consumer.
+type Record struct {
+	*dynamodbstreams.Record
+
+	// The ID of the shard the record was read from.
+	ShardID string
+
+	decoder *dynamodbattribute.Decoder
+}
+
+// DecodeNewImage unmarshals the item after it was modified into out, with
+// dynamodbattribute.UnmarshalMap, or the Consumer's Decoder if set.
+func (r *Record) DecodeNewImage(out interface{}) error {
+	if r.Dynamodb == nil || r.Dynamodb.NewImage == nil {
+		return r.imageNotFound("NewImage")
+	}
+	return r.decode(r.Dynamodb.NewImage, out)
+}
+
+// DecodeOldImage unmarshals the item before it was modified into out, with
+// dynamodbattribute.UnmarshalMap, or the Consumer's Decoder if set.
+func (r *Record) DecodeOldImage(out interface{}) error {
+	if r.Dynamodb == nil || r.Dynamodb.OldImage == nil {
+		return r.imageNotFound("OldImage")
+	}
+	return r.decode(r.Dynamodb.OldImage, out)
+}
+
+// DecodeKeys unmarshals the key attributes of the modified item into out,
+// with dynamodbattribute.UnmarshalMap, or the Consumer's Decoder if set.
+func (r *Record) DecodeKeys(out interface{}) error {
+	if r.Dynamodb == nil || r.Dynamodb.Keys == nil {
+		return r.imageNotFound("Keys")
+	}
+	return r.decode(r.Dynamodb.Keys, out)
+}
+
+func (r *Record) decode(item map[string]*dynamodb.AttributeValue, out interface{}) error {
+	if r.decoder == nil {
+		return dynamodbattribute.UnmarshalMap(item, out)
+	}
+	return r.decoder.Decode(&dynamodb.AttributeValue{M: item}, out)
+}
+
+func (r *Record) imageNotFound(image string) error {
+	var viewType string
+	if r.Dynamodb != nil {
+		viewType = aws.StringValue(r.Dynamodb.StreamViewType)
+	}
+	return awserr.New(ErrCodeImageNotFound, fmt.Sprintf(
+		"%s record %s has no %s, stream view type %s",
+		aws.StringValue(r.EventName), aws.StringValue(r.EventID), image, viewType), nil)
+}
+
+// WithConsumerRequestOptions appends to the Consumer's API request options.
+func WithConsumerRequestOptions(opts ...request.Option) func(*Consumer) {
+	return func(c *Consumer) {
+		c.RequestOptions = append(c.RequestOptions, opts...)
+	}
+}
+
+// Consumer processes the records of a DynamoDB stream, following the
+// lineage of its shards.
+//
+// The records of a shard are processed in order, and only once the records
+// of its parent shard have all been processed, so the modifications of an
+// item are processed in the order they were made, across shard splits.
+// Shards which do not depend on each other are processed concurrently.
+//
+// The sequence number of the last record processed in each shard is stored
+// in the Checkpoints after each batch of records, and consuming the stream
+// again resumes after it. Records are processed at least once: the records
+// of a batch whose processing failed, or whose checkpoint was not stored,
+// are processed again.
+//
+// It is safe to call Consume concurrently across goroutines, for different
+// streams, or with different Checkpoints.
+type Consumer struct {
+	// The checkpoints the Consumer resumes processing shards from, and
+	// stores its progress in. If nil, the checkpoints are stored in memory
+	// for the duration of each call to Consume.
+	Checkpoints CheckpointStore
+
+	// The position a shard without a checkpoint is read from, either
+	// TRIM_HORIZON or LATEST, unless its parent shard was processed, in
+	// which case it is read from its oldest record. If empty, TRIM_HORIZON
+	// is used.
+	//
+	// With LATEST, shards which were already closed when first read are
+	// skipped.
+	IteratorType string
+
+	// The maximum number of records read from a shard with each GetRecords
+	// request, and passed to the handler at once. If zero, the service's
+	// default limit is used.
+	BatchSize int64
+
+	// The maximum number of shards processed at once. If zero, all shards
+	// which can be processed are processed at once.
+	Concurrency int
+
+	// The time waited before reading more records from an open shard which
+	// had no new records. If zero, the DefaultPollInterval is used.
+	PollInterval time.Duration
+
+	// The time between descriptions of the stream, to find its new shards.
+	// If zero, the DefaultRefreshInterval is used.
+	RefreshInterval time.Duration
+
+	// The Decoder used to unmarshal records by the Record's Decode methods.
+	// If nil, dynamodbattribute.UnmarshalMap is used.
+	Decoder *dynamodbattribute.Decoder
+
+	// The client to use when reading the stream.
+	Client dynamodbstreamsiface.DynamoDBStreamsAPI
+
+	// List of request options that will be passed down to individual API
+	// operation requests made by the Consumer.
+	RequestOptions []request.Option
+}
+
+// NewConsumer returns a new Consumer using a DynamoDB Streams client created
+// from the session, with the default configuration. Options can be passed in
+// to modify it.
+//
+// Example:
+//     sess := session.Must(session.NewSession())
+//
+//     consumer := dynamodbstreamsmanager.NewConsumer(sess, func(c *dynamodbstreamsmanager.Consumer) {
+//         c.Checkpoints = dynamodbstreamsmanager.NewTableCheckpoints(sess, "checkpoints")
+//         c.BatchSize = 100
+//     })
+func NewConsumer(c client.ConfigProvider, options ...func(*Consumer)) *Consumer {
+	return NewConsumerWithClient(dynamodbstreams.New(c), options...)
+}
+
+// NewConsumerWithClient returns a new Consumer using the DynamoDB Streams
+// client, with the default configuration. Options can be passed in to modify
+// it.
+func NewConsumerWithClient(svc dynamodbstreamsiface.DynamoDBStreamsAPI, options ...func(*Consumer)) *Consumer {
+	c := &Consumer{
+		PollInterval:    DefaultPollInterval,
+		RefreshInterval: DefaultRefreshInterval,
+		Client:          svc,
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// Handler processes a batch of records from a shard. The records are in the
+// order of their sequence numbers. If the Handler returns an error, the
+// Consumer stops, and the batch's checkpoint is not stored.
+//
+// The Handler is called concurrently for different shards.
+type Handler func(ctx aws.Context, records []*Record) error
+
+// Consume processes the records of the stream with the handler, until the
+// stream is disabled and all of its records were processed, or an error
+// occurs.
+//
+// Example:
+//     err := consumer.Consume(streamArn, func(ctx aws.Context, records []*dynamodbstreamsmanager.Record) error {
+//         for _, r := range records {
+//             var item Item
+//             if err := r.DecodeNewImage(&item); err != nil {
+//                 return err
+//             }
+//             // ...
+//         }
+//         return nil
+//     })
+func (c Consumer) Consume(streamArn string, fn Handler, options ...func(*Consumer)) error {
+	return c.ConsumeWithContext(aws.BackgroundContext(), streamArn, fn, options...)
+}
+
+// ConsumeWithContext is the same as Consume with the additional support for
+// Context input parameters. The Context must not be nil. A nil Context will
+// cause a panic. Use the Context to add deadlining, timeouts, etc.
+//
+// Consuming the stream is stopped when the Context is done, returning the
+// Context's error once the shards being processed have stopped.
+func (c Consumer) ConsumeWithContext(ctx aws.Context, streamArn string, fn Handler, options ...func(*Consumer)) error {
+	for _, option := range options {
+		option(&c)
+	}
+	if c.Checkpoints == nil {
+		c.Checkpoints = NewMemoryCheckpoints()
+	}
+	if len(c.IteratorType) == 0 {
+		c.IteratorType = dynamodbstreams.ShardIteratorTypeTrimHorizon
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultPollInterval
+	}
+	if c.RefreshInterval <= 0 {
+		c.RefreshInterval = DefaultRefreshInterval
+	}
+	c.RequestOptions = append(c.RequestOptions[:len(c.RequestOptions):len(c.RequestOptions)],
+		request.WithAppendUserAgent("DynamoDBStreamsManager"))
+
+	s := &stream{
+		Consumer: c,
+		ctx:      ctx,
+		arn:      streamArn,
+		fn:       fn,
+		shards:   map[string]*dynamodbstreams.Shard{},
+		running:  map[string]bool{},
+		done:     map[string]bool{},
+		skipped:  map[string]bool{},
+		results:  make(chan shardResult),
+		stop:     make(chan struct{}),
+	}
+	return s.consume()
+}
+
+// stream is the state of the consumption of a stream.
+type stream struct {
+	Consumer
+
+	ctx aws.Context
+	arn string
+	fn  Handler
+
+	// The shards of the stream, by ID, in the order they were described.
+	shards map[string]*dynamodbstreams.Shard
+	order  []string
+	status string
+
+	// The shards being processed, and which were processed. Skipped shards
+	// are closed shards read from LATEST.
+	running map[string]bool
+	done    map[string]bool
+	skipped map[string]bool
+
+	results chan shardResult
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// shardResult is the result of processing a shard.
+type shardResult struct {
+	shardID string
+	skipped bool
+	err     error
+}
+
+// consume processes the shards of the stream until they are all done, or
+// processing a shard fails.
+func (s *stream) consume() error {
+	refresh := time.NewTimer(0)
+	defer refresh.Stop()
+
+	for {
+		if s.status == dynamodbstreams.StreamStatusDisabled && len(s.running) == 0 && s.allDone() {
+			return nil
+		}
+
+		select {
+		case <-refresh.C:
+			if err := s.describe(); err != nil {
+				return s.shutdown(err)
+			}
+			refresh.Reset(s.RefreshInterval)
+		case r := <-s.results:
+			delete(s.running, r.shardID)
+			if err := s.ctx.Err(); err != nil {
+				return s.shutdown(err)
+			}
+			if r.err != nil {
+				return s.shutdown(r.err)
+			}
+			s.done[r.shardID] = true
+			s.skipped[r.shardID] = r.skipped
+		case <-s.ctx.Done():
+			return s.shutdown(s.ctx.Err())
+		}
+
+		s.start()
+	}
+}
+
+// allDone returns true if all of the shards of the stream were processed.
+func (s *stream) allDone() bool {
+	for _, id := range s.order {
+		if !s.done[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// describe updates the shards and status of the stream.
+func (s *stream) describe() error {
+	in := &dynamodbstreams.DescribeStreamInput{StreamArn: aws.String(s.arn)}
+	for {
+		out, err := s.Client.DescribeStreamWithContext(s.ctx, in, s.RequestOptions...)
+		if err != nil {
+			return err
+		}
+
+		desc := out.StreamDescription
+		s.status = aws.StringValue(desc.StreamStatus)
+		for _, shard := range desc.Shards {
+			id := aws.StringValue(shard.ShardId)
+			if _, ok := s.shards[id]; !ok {
+				s.order = append(s.order, id)
+			}
+			s.shards[id] = shard
+		}
+
+		if desc.LastEvaluatedShardId == nil {
+			return nil
+		}
+		in.ExclusiveStartShardId = desc.LastEvaluatedShardId
+	}
+}
+
+// start starts processing the shards whose parent shard is done, or is no
+// longer in the stream, in the order they were described.
+func (s *stream) start() {
+	for _, id := range s.order {
+		if s.Concurrency > 0 && len(s.running) >= s.Concurrency {
+			return
+		}
+		if s.running[id] || s.done[id] {
+			continue
+		}
+
+		shard := s.shards[id]
+		parent := aws.StringValue(shard.ParentShardId)
+		_, hasParent := s.shards[parent]
+		if hasParent && !s.done[parent] {
+			continue
+		}
+
+		// A child of a processed shard is read from its oldest record, and
+		// a child of a skipped shard from the position of its parent.
+		iteratorType := s.IteratorType
+		if hasParent && !s.skipped[parent] {
+			iteratorType = dynamodbstreams.ShardIteratorTypeTrimHorizon
+		}
+
+		s.running[id] = true
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			skipped, err := s.process(shard, iteratorType)
+			select {
+			case s.results <- shardResult{shardID: aws.StringValue(shard.ShardId), skipped: skipped, err: err}:
+			case <-s.stop:
+			}
+		}()
+	}
+}
+
+// shutdown stops processing the shards, returning the error once all of the
+// shards being processed have stopped.
+func (s *stream) shutdown(err error) error {
+	close(s.stop)
+	s.wg.Wait()
+	return err
+}
+
+// stopped returns true if the consumer was stopped.
+func (s *stream) stopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,461 @@
+package dynamodbstreamsmanager_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsmanager"
+)
+
+const testStreamArn = "arn:aws:dynamodb:us-west-2:000000000000:table/items/stream/2019-01-01T00:00:00.000"
+
+type item struct {
+	ID    string `dynamodbav:"id"`
+	Value int    `dynamodbav:"value"`
+}
+
+type fakeShard struct {
+	id, parent string
+	records    []*dynamodbstreams.Record
+	closed     bool
+}
+
+// streamSvc serves the shards of a stream, with shard iterators of the form
+// "shardID|position".
+type streamSvc struct {
+	dynamodbstreamsiface.DynamoDBStreamsAPI
+
+	m       sync.Mutex
+	shards  []*fakeShard
+	status  string
+	seq     int
+	expired bool
+}
+
+func newStreamSvc() *streamSvc {
+	return &streamSvc{status: dynamodbstreams.StreamStatusDisabled}
+}
+
+// addShard adds a shard with records whose new images have the values.
+func (s *streamSvc) addShard(id, parent string, closed bool, values ...int) {
+	shard := &fakeShard{id: id, parent: parent, closed: closed}
+	for _, v := range values {
+		s.seq++
+		shard.records = append(shard.records, &dynamodbstreams.Record{
+			EventID:   aws.String(strconv.Itoa(s.seq)),
+			EventName: aws.String(dynamodbstreams.OperationTypeModify),
+			Dynamodb: &dynamodbstreams.StreamRecord{
+				SequenceNumber: aws.String(fmt.Sprintf("%021d", s.seq)),
+				NewImage: map[string]*dynamodb.AttributeValue{
+					"id":    {S: aws.String(id)},
+					"value": {N: aws.String(strconv.Itoa(v))},
+				},
+			},
+		})
+	}
+	s.shards = append(s.shards, shard)
+}
+
+func (s *streamSvc) shard(id string) *fakeShard {
+	for _, shard := range s.shards {
+		if shard.id == id {
+			return shard
+		}
+	}
+	return nil
+}
+
+func (s *streamSvc) DescribeStreamWithContext(ctx aws.Context, in *dynamodbstreams.DescribeStreamInput, opts ...request.Option) (*dynamodbstreams.DescribeStreamOutput, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// Shards are described two at a time.
+	start := 0
+	if in.ExclusiveStartShardId != nil {
+		for i, shard := range s.shards {
+			if shard.id == *in.ExclusiveStartShardId {
+				start = i + 1
+			}
+		}
+	}
+	desc := &dynamodbstreams.StreamDescription{
+		StreamArn:    in.StreamArn,
+		StreamStatus: aws.String(s.status),
+	}
+	for i := start; i < len(s.shards) && i < start+2; i++ {
+		shard := s.shards[i]
+		out := &dynamodbstreams.Shard{
+			ShardId:             aws.String(shard.id),
+			SequenceNumberRange: &dynamodbstreams.SequenceNumberRange{},
+		}
+		if len(shard.parent) != 0 {
+			out.ParentShardId = aws.String(shard.parent)
+		}
+		if shard.closed {
+			out.SequenceNumberRange.EndingSequenceNumber = aws.String("999999999999999999999")
+		}
+		desc.Shards = append(desc.Shards, out)
+	}
+	if start+2 < len(s.shards) {
+		desc.LastEvaluatedShardId = aws.String(s.shards[start+1].id)
+	}
+	return &dynamodbstreams.DescribeStreamOutput{StreamDescription: desc}, nil
+}
+
+func (s *streamSvc) GetShardIteratorWithContext(ctx aws.Context, in *dynamodbstreams.GetShardIteratorInput, opts ...request.Option) (*dynamodbstreams.GetShardIteratorOutput, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	shard := s.shard(*in.ShardId)
+	if shard == nil {
+		return nil, awserr.New(dynamodbstreams.ErrCodeResourceNotFoundException, "shard not found", nil)
+	}
+
+	var pos int
+	switch *in.ShardIteratorType {
+	case dynamodbstreams.ShardIteratorTypeLatest:
+		pos = len(shard.records)
+	case dynamodbstreams.ShardIteratorTypeAfterSequenceNumber:
+		for i, r := range shard.records {
+			if *r.Dynamodb.SequenceNumber == *in.SequenceNumber {
+				pos = i + 1
+			}
+		}
+	}
+	return &dynamodbstreams.GetShardIteratorOutput{
+		ShardIterator: aws.String(fmt.Sprintf("%s|%d", shard.id, pos)),
+	}, nil
+}
+
+func (s *streamSvc) GetRecordsWithContext(ctx aws.Context, in *dynamodbstreams.GetRecordsInput, opts ...request.Option) (*dynamodbstreams.GetRecordsOutput, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.expired {
+		s.expired = false
+		return nil, awserr.New(dynamodbstreams.ErrCodeExpiredIteratorException, "iterator expired", nil)
+	}
+
+	parts := strings.Split(*in.ShardIterator, "|")
+	shard := s.shard(parts[0])
+	pos, _ := strconv.Atoi(parts[1])
+
+	end := len(shard.records)
+	if in.Limit != nil && pos+int(*in.Limit) < end {
+		end = pos + int(*in.Limit)
+	}
+	out := &dynamodbstreams.GetRecordsOutput{Records: shard.records[pos:end]}
+	if !shard.closed || end < len(shard.records) {
+		out.NextShardIterator = aws.String(fmt.Sprintf("%s|%d", shard.id, end))
+	}
+	return out, nil
+}
+
+// recorder records the values of the records processed by a Consumer.
+type recorder struct {
+	m      sync.Mutex
+	values []int
+	shards []string
+}
+
+func (r *recorder) handle(ctx aws.Context, records []*dynamodbstreamsmanager.Record) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	for _, record := range records {
+		var v item
+		if err := record.DecodeNewImage(&v); err != nil {
+			return err
+		}
+		if e, a := record.ShardID, v.ID; e != a {
+			return fmt.Errorf("expect record of shard %v, got %v", e, a)
+		}
+		r.values = append(r.values, v.Value)
+		r.shards = append(r.shards, record.ShardID)
+	}
+	return nil
+}
+
+// indexes returns the indexes of the shard's records in the order the
+// records were processed.
+func (r *recorder) indexes(shardID string) []int {
+	var indexes []int
+	for i, id := range r.shards {
+		if id == shardID {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func (r *recorder) shardValues(shardID string) []int {
+	var values []int
+	for _, i := range r.indexes(shardID) {
+		values = append(values, r.values[i])
+	}
+	return values
+}
+
+func newTestConsumer(svc dynamodbstreamsiface.DynamoDBStreamsAPI, options ...func(*dynamodbstreamsmanager.Consumer)) *dynamodbstreamsmanager.Consumer {
+	return dynamodbstreamsmanager.NewConsumerWithClient(svc, append([]func(*dynamodbstreamsmanager.Consumer){
+		func(c *dynamodbstreamsmanager.Consumer) {
+			c.BatchSize = 2
+			c.PollInterval = time.Millisecond
+			c.RefreshInterval = 5 * time.Millisecond
+		},
+	}, options...)...)
+}
+
+func TestConsumer_Lineage(t *testing.T) {
+	svc := newStreamSvc()
+	// Children are described before their parents.
+	svc.addShard("grandchild", "left", true, 30)
+	svc.addShard("left", "root", true, 10, 11, 12)
+	svc.addShard("right", "root", true, 20, 21)
+	svc.addShard("root", "trimmed", true, 1, 2, 3)
+	svc.addShard("empty", "", true)
+
+	checkpoints := dynamodbstreamsmanager.NewMemoryCheckpoints()
+	r := &recorder{}
+	err := newTestConsumer(svc, func(c *dynamodbstreamsmanager.Consumer) {
+		c.Checkpoints = checkpoints
+	}).Consume(testStreamArn, r.handle)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expected := map[string][]int{
+		"root":       {1, 2, 3},
+		"left":       {10, 11, 12},
+		"right":      {20, 21},
+		"grandchild": {30},
+	}
+	for shard, values := range expected {
+		if e, a := values, r.shardValues(shard); !reflect.DeepEqual(e, a) {
+			t.Errorf("expect %v values of shard %v, got %v", e, shard, a)
+		}
+	}
+	if e, a := 9, len(r.values); e != a {
+		t.Errorf("expect %d records, got %d", e, a)
+	}
+
+	parents := map[string]string{"left": "root", "right": "root", "grandchild": "left"}
+	for child, parent := range parents {
+		p, c := r.indexes(parent), r.indexes(child)
+		if p[len(p)-1] > c[0] {
+			t.Errorf("expect records of %v before records of %v, got %v", parent, child, r.shards)
+		}
+	}
+
+	for _, shard := range []string{"root", "left", "right", "grandchild", "empty"} {
+		cp, err := checkpoints.GetCheckpoint(aws.BackgroundContext(), testStreamArn, shard)
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		if e, a := dynamodbstreamsmanager.ShardEnd, cp; e != a {
+			t.Errorf("expect %v checkpoint of %v, got %v", e, shard, a)
+		}
+	}
+}
+
+func TestConsumer_Resume(t *testing.T) {
+	svc := newStreamSvc()
+	svc.addShard("root", "", true, 1, 2, 3)
+	svc.addShard("left", "root", true, 10, 11)
+	svc.addShard("right", "root", true, 20, 21)
+	svc.expired = true
+
+	checkpoints := dynamodbstreamsmanager.NewMemoryCheckpoints()
+	checkpoints.SetCheckpoint(aws.BackgroundContext(), testStreamArn, "root",
+		*svc.shards[0].records[1].Dynamodb.SequenceNumber)
+	checkpoints.SetCheckpoint(aws.BackgroundContext(), testStreamArn, "left", dynamodbstreamsmanager.ShardEnd)
+
+	r := &recorder{}
+	err := newTestConsumer(svc, func(c *dynamodbstreamsmanager.Consumer) {
+		c.Checkpoints = checkpoints
+		c.Concurrency = 1
+	}).Consume(testStreamArn, r.handle)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := []int{3, 20, 21}, r.values; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v values, got %v", e, a)
+	}
+}
+
+func TestConsumer_Latest(t *testing.T) {
+	svc := newStreamSvc()
+	svc.addShard("root", "", true, 1, 2)
+	svc.addShard("child", "root", true, 10)
+	svc.addShard("open", "child", false, 20)
+
+	ctx := newCancelContext()
+	r := &recorder{}
+	err := newTestConsumer(svc, func(c *dynamodbstreamsmanager.Consumer) {
+		c.IteratorType = dynamodbstreams.ShardIteratorTypeLatest
+	}).ConsumeWithContext(ctx, testStreamArn, r.handle, func(c *dynamodbstreamsmanager.Consumer) {
+		c.Client = &addRecordSvc{streamSvc: svc, ctx: ctx}
+	})
+	if e, a := errCanceled, err; e != a {
+		t.Fatalf("expect %v error, got %v", e, a)
+	}
+
+	if e, a := []int{21}, r.values; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v values, got %v", e, a)
+	}
+}
+
+// addRecordSvc adds a record to the open shard after it is first read, and
+// cancels the context once the record has been read.
+type addRecordSvc struct {
+	*streamSvc
+	ctx   *cancelContext
+	reads int
+}
+
+func (s *addRecordSvc) GetRecordsWithContext(ctx aws.Context, in *dynamodbstreams.GetRecordsInput, opts ...request.Option) (*dynamodbstreams.GetRecordsOutput, error) {
+	out, err := s.streamSvc.GetRecordsWithContext(ctx, in, opts...)
+	if err != nil || !strings.HasPrefix(*in.ShardIterator, "open|") {
+		return out, err
+	}
+
+	s.reads++
+	switch {
+	case s.reads == 1:
+		s.m.Lock()
+		open := s.shard("open")
+		s.seq++
+		open.records = append(open.records, &dynamodbstreams.Record{
+			Dynamodb: &dynamodbstreams.StreamRecord{
+				SequenceNumber: aws.String(fmt.Sprintf("%021d", s.seq)),
+				NewImage: map[string]*dynamodb.AttributeValue{
+					"id":    {S: aws.String("open")},
+					"value": {N: aws.String("21")},
+				},
+			},
+		})
+		s.m.Unlock()
+	case len(out.Records) == 0:
+		s.ctx.cancel()
+	}
+	return out, err
+}
+
+func TestConsumer_HandlerError(t *testing.T) {
+	svc := newStreamSvc()
+	svc.addShard("root", "", true, 1, 2, 3)
+	svc.addShard("child", "root", true, 10)
+
+	checkpoints := dynamodbstreamsmanager.NewMemoryCheckpoints()
+	handlerErr := errors.New("handler error")
+	var calls int
+	err := newTestConsumer(svc, func(c *dynamodbstreamsmanager.Consumer) {
+		c.Checkpoints = checkpoints
+	}).Consume(testStreamArn, func(ctx aws.Context, records []*dynamodbstreamsmanager.Record) error {
+		calls++
+		if calls == 2 {
+			return handlerErr
+		}
+		return nil
+	})
+	if e, a := handlerErr, err; e != a {
+		t.Fatalf("expect %v error, got %v", e, a)
+	}
+	if e, a := 2, calls; e != a {
+		t.Errorf("expect %d calls, got %d", e, a)
+	}
+
+	cp, _ := checkpoints.GetCheckpoint(aws.BackgroundContext(), testStreamArn, "root")
+	if e, a := *svc.shards[0].records[1].Dynamodb.SequenceNumber, cp; e != a {
+		t.Errorf("expect %v checkpoint, got %v", e, a)
+	}
+	cp, _ = checkpoints.GetCheckpoint(aws.BackgroundContext(), testStreamArn, "child")
+	if len(cp) != 0 {
+		t.Errorf("expect no checkpoint of child, got %v", cp)
+	}
+}
+
+func TestRecord_Decode(t *testing.T) {
+	r := &dynamodbstreamsmanager.Record{
+		Record: &dynamodbstreams.Record{
+			EventID:   aws.String("1"),
+			EventName: aws.String(dynamodbstreams.OperationTypeInsert),
+			Dynamodb: &dynamodbstreams.StreamRecord{
+				Keys: map[string]*dynamodb.AttributeValue{
+					"id": {S: aws.String("a")},
+				},
+				NewImage: map[string]*dynamodb.AttributeValue{
+					"id":    {S: aws.String("a")},
+					"value": {N: aws.String("1")},
+				},
+				StreamViewType: aws.String(dynamodbstreams.StreamViewTypeNewImage),
+			},
+		},
+	}
+
+	var v item
+	if err := r.DecodeNewImage(&v); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := (item{ID: "a", Value: 1}), v; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	var key item
+	if err := r.DecodeKeys(&key); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "a", key.ID; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	err := r.DecodeOldImage(&v)
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		t.Fatalf("expect awserr.Error, got %v", err)
+	}
+	if e, a := dynamodbstreamsmanager.ErrCodeImageNotFound, aerr.Code(); e != a {
+		t.Errorf("expect %v code, got %v", e, a)
+	}
+}
+
+var errCanceled = errors.New("context canceled")
+
+// cancelContext is an aws.Context which can be canceled.
+type cancelContext struct {
+	once sync.Once
+	done chan struct{}
+}
+
+func newCancelContext() *cancelContext {
+	return &cancelContext{done: make(chan struct{})}
+}
+
+func (c *cancelContext) cancel() {
+	c.once.Do(func() { close(c.done) })
+}
+
+func (c *cancelContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c *cancelContext) Done() <-chan struct{}             { return c.done }
+func (c *cancelContext) Value(key interface{}) interface{} { return nil }
+func (c *cancelContext) Err() error {
+	select {
+	case <-c.done:
+		return errCanceled
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+// Package dynamodbstreamsmanager provides utilities for consuming Amazon
+// DynamoDB Streams, built on the DescribeStream, GetShardIterator and
+// GetRecords API operations.
+//
+// Consumer
+//
+// The Consumer processes the records of a stream with a Handler, following
+// the lineage of the stream's shards: a shard is processed only after its
+// parent shard was closed and all of its records were processed, so the
+// modifications of each item are processed in order across shard splits.
+// The stream is described periodically to find new shards.
+//
+// The progress of each shard is stored in a CheckpointStore, as the sequence
+// number of its last processed record, or ShardEnd once the shard is closed
+// and processed. MemoryCheckpoints stores the checkpoints in memory, and
+// TableCheckpoints in a DynamoDB table, so that a restarted consumer
+// resumes where it stopped.
+//
+//     type Item struct {
+//         ID    string `dynamodbav:"id"`
+//         Value int    `dynamodbav:"value"`
+//     }
+//
+//     consumer := dynamodbstreamsmanager.NewConsumer(sess, func(c *dynamodbstreamsmanager.Consumer) {
+//         c.Checkpoints = dynamodbstreamsmanager.NewTableCheckpoints(sess, "checkpoints")
+//     })
+//
+//     err := consumer.ConsumeWithContext(ctx, streamArn,
+//         func(ctx aws.Context, records []*dynamodbstreamsmanager.Record) error {
+//             for _, r := range records {
+//                 if aws.StringValue(r.EventName) == dynamodbstreams.OperationTypeRemove {
+//                     continue
+//                 }
+//                 var item Item
+//                 if err := r.DecodeNewImage(&item); err != nil {
+//                     return err
+//                 }
+//                 fmt.Println(r.ShardID, item.ID, item.Value)
+//             }
+//             return nil
+//         })
+package dynamodbstreamsmanager
@@ -0,0 +1,131 @@
+package dynamodbstreamsmanager
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+)
+
+// process processes the records of the shard from its checkpoint, or from
+// the iterator type if it has none, until the shard is closed and all of its
+// records were processed. Returns true if the shard was skipped, because it
+// was closed and read from LATEST.
+func (s *stream) process(shard *dynamodbstreams.Shard, iteratorType string) (bool, error) {
+	id := aws.StringValue(shard.ShardId)
+	checkpoint, err := s.Checkpoints.GetCheckpoint(s.ctx, s.arn, id)
+	if err != nil || checkpoint == ShardEnd {
+		return false, err
+	}
+
+	closed := shard.SequenceNumberRange != nil && shard.SequenceNumberRange.EndingSequenceNumber != nil
+	if len(checkpoint) == 0 && iteratorType == dynamodbstreams.ShardIteratorTypeLatest && closed {
+		return true, s.Checkpoints.SetCheckpoint(s.ctx, s.arn, id, ShardEnd)
+	}
+
+	iterator, err := s.iterator(id, checkpoint, iteratorType)
+	if isErrCode(err, dynamodbstreams.ErrCodeResourceNotFoundException) {
+		// The shard is past the stream's retention period, and has been
+		// removed.
+		return false, s.Checkpoints.SetCheckpoint(s.ctx, s.arn, id, ShardEnd)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for !s.stopped() {
+		in := &dynamodbstreams.GetRecordsInput{ShardIterator: iterator}
+		if s.BatchSize > 0 {
+			in.Limit = aws.Int64(s.BatchSize)
+		}
+		out, err := s.Client.GetRecordsWithContext(s.ctx, in, s.RequestOptions...)
+		switch {
+		case isErrCode(err, dynamodbstreams.ErrCodeExpiredIteratorException):
+			if iterator, err = s.iterator(id, checkpoint, iteratorType); err != nil {
+				return false, err
+			}
+			continue
+		case isErrCode(err, dynamodbstreams.ErrCodeTrimmedDataAccessException):
+			// The records after the checkpoint are past the stream's
+			// retention period, so the shard is read from its oldest
+			// record which was not removed.
+			checkpoint, iteratorType = "", dynamodbstreams.ShardIteratorTypeTrimHorizon
+			if iterator, err = s.iterator(id, checkpoint, iteratorType); err != nil {
+				return false, err
+			}
+			continue
+		case err != nil:
+			return false, err
+		}
+
+		if len(out.Records) != 0 {
+			records := make([]*Record, len(out.Records))
+			for i, r := range out.Records {
+				records[i] = &Record{Record: r, ShardID: id, decoder: s.Decoder}
+			}
+			if err := s.fn(s.ctx, records); err != nil {
+				return false, err
+			}
+
+			last := out.Records[len(out.Records)-1]
+			if last.Dynamodb != nil && last.Dynamodb.SequenceNumber != nil {
+				checkpoint = *last.Dynamodb.SequenceNumber
+				if err := s.Checkpoints.SetCheckpoint(s.ctx, s.arn, id, checkpoint); err != nil {
+					return false, err
+				}
+			}
+		}
+
+		if out.NextShardIterator == nil {
+			return false, s.Checkpoints.SetCheckpoint(s.ctx, s.arn, id, ShardEnd)
+		}
+		iterator = out.NextShardIterator
+
+		if len(out.Records) == 0 && !s.wait(s.PollInterval) {
+			break
+		}
+	}
+	return false, s.ctx.Err()
+}
+
+// iterator returns a shard iterator after the checkpoint, or of the iterator
+// type if there is no checkpoint.
+func (s *stream) iterator(shardID, checkpoint, iteratorType string) (*string, error) {
+	in := &dynamodbstreams.GetShardIteratorInput{
+		StreamArn:         aws.String(s.arn),
+		ShardId:           aws.String(shardID),
+		ShardIteratorType: aws.String(iteratorType),
+	}
+	if len(checkpoint) != 0 {
+		in.ShardIteratorType = aws.String(dynamodbstreams.ShardIteratorTypeAfterSequenceNumber)
+		in.SequenceNumber = aws.String(checkpoint)
+	}
+
+	out, err := s.Client.GetShardIteratorWithContext(s.ctx, in, s.RequestOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return out.ShardIterator, nil
+}
+
+// wait waits for the duration, returning false if the consumer was stopped
+// first.
+func (s *stream) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-s.stop:
+		return false
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+func isErrCode(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
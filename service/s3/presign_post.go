@@ -0,0 +1,274 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+const (
+	postPolicyAlgorithm      = "AWS4-HMAC-SHA256"
+	postPolicyTimeFormat     = "20060102T150405Z"
+	postPolicyExpirationTime = "2006-01-02T15:04:05.000Z"
+
+	// ErrCodeInvalidPresignPost is the error code returned when a POST
+	// policy cannot be presigned because the input is not valid.
+	ErrCodeInvalidPresignPost = "InvalidPresignPostError"
+)
+
+// presignPostNow returns the time the POST policy is signed at.
+var presignPostNow = time.Now
+
+// PostPolicyCondition is a single condition of a POST policy. The
+// condition is serialized as the JSON array of its values.
+//
+// Use the PostPolicyConditionEquals, PostPolicyConditionStartsWith, and
+// PostPolicyConditionContentLengthRange functions to create conditions.
+type PostPolicyCondition []interface{}
+
+// PostPolicyConditionEquals returns a condition requiring the form field to
+// exactly match the value.
+func PostPolicyConditionEquals(field, value string) PostPolicyCondition {
+	return PostPolicyCondition{"eq", "$" + field, value}
+}
+
+// PostPolicyConditionStartsWith returns a condition requiring the form field
+// to start with the prefix. An empty prefix allows any value for the field.
+func PostPolicyConditionStartsWith(field, prefix string) PostPolicyCondition {
+	return PostPolicyCondition{"starts-with", "$" + field, prefix}
+}
+
+// PostPolicyConditionContentLengthRange returns a condition requiring the
+// size of the uploaded object, in bytes, to be within the range.
+func PostPolicyConditionContentLengthRange(min, max int64) PostPolicyCondition {
+	return PostPolicyCondition{"content-length-range", min, max}
+}
+
+// PresignPostInput provides the parameters for presigning an Amazon S3
+// browser based upload POST policy.
+type PresignPostInput struct {
+	// The bucket the object will be uploaded to.
+	//
+	// Bucket is a required field
+	Bucket *string
+
+	// The exact key the object will be uploaded to. The key may contain the
+	// ${filename} variable, which is replaced by the name of the file
+	// uploaded by the user.
+	//
+	// Either Key or KeyPrefix is required.
+	Key *string
+
+	// The prefix the key of the uploaded object must start with. The form's
+	// key field is set to the prefix followed by ${filename}, but the form
+	// may change the key to any value starting with the prefix.
+	//
+	// Either Key or KeyPrefix is required.
+	KeyPrefix *string
+
+	// The duration the POST policy is valid for after it is signed.
+	//
+	// Expires is a required field
+	Expires time.Duration
+
+	// The canned ACL to apply to the uploaded object.
+	ACL *string
+
+	// The metadata to store with the uploaded object. The metadata keys are
+	// added as x-amz-meta- prefixed form fields.
+	Metadata map[string]*string
+
+	// Additional form fields, and their values, the upload must exactly
+	// match. e.g. Content-Type, or success_action_status.
+	Fields map[string]string
+
+	// Additional conditions the upload must satisfy, such as the range of
+	// the uploaded object's size.
+	Conditions []PostPolicyCondition
+}
+
+// Validate inspects the fields of the type to determine if they are valid.
+func (s *PresignPostInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "PresignPostInput"}
+	if s.Bucket == nil {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if s.Bucket != nil && len(*s.Bucket) < 1 {
+		invalidParams.Add(request.NewErrParamMinLen("Bucket", 1))
+	}
+	if s.Key == nil && s.KeyPrefix == nil {
+		invalidParams.Add(request.NewErrParamRequired("Key"))
+	}
+	if s.Key != nil && s.KeyPrefix != nil {
+		invalidParams.Add(request.NewErrParamFormat("KeyPrefix", "only one of Key or KeyPrefix", *s.KeyPrefix))
+	}
+	if s.Expires <= 0 {
+		invalidParams.Add(request.NewErrParamRequired("Expires"))
+	}
+
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// PresignedPost is a presigned POST policy for uploading an object to Amazon
+// S3 with a browser based HTML form.
+type PresignedPost struct {
+	// The URL the form must be submitted to.
+	URL string
+
+	// The form fields which must be included in the form. The file to upload
+	// must be the last field of the form.
+	Fields map[string]string
+
+	// The time the POST policy expires at.
+	Expires time.Time
+}
+
+// PresignPost returns a presigned POST policy, and the form fields, for
+// uploading an object to Amazon S3 directly from a browser using an HTML form.
+//
+// The policy is signed with the client's credentials using AWS Signature
+// Version 4, with the same signing key derivation as the v4 request signer.
+//
+//     post, err := svc.PresignPost(&s3.PresignPostInput{
+//         Bucket:    aws.String("myBucket"),
+//         KeyPrefix: aws.String("uploads/"),
+//         Expires:   15 * time.Minute,
+//         Conditions: []s3.PostPolicyCondition{
+//             s3.PostPolicyConditionContentLengthRange(1, 10*1024*1024),
+//             s3.PostPolicyConditionStartsWith("Content-Type", "image/"),
+//         },
+//     })
+//
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-HTTPPOSTConstructPolicy.html
+// for more information on POST policies.
+func (c *S3) PresignPost(input *PresignPostInput) (*PresignedPost, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	if c.Config.Credentials == nil || c.Config.Credentials == credentials.AnonymousCredentials {
+		return nil, awserr.New(ErrCodeInvalidPresignPost,
+			"credentials are required to presign a POST policy", nil)
+	}
+	creds, err := c.Config.Credentials.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := c.presignPostURL(aws.StringValue(input.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	region := c.SigningRegion
+	if len(region) == 0 {
+		region = aws.StringValue(c.Config.Region)
+	}
+	signingName := c.SigningName
+	if len(signingName) == 0 {
+		signingName = ServiceName
+	}
+
+	signTime := presignPostNow().UTC()
+	expires := signTime.Add(input.Expires)
+
+	fields := map[string]string{}
+	conditions := []interface{}{
+		map[string]string{"bucket": aws.StringValue(input.Bucket)},
+	}
+	addField := func(k, v string) {
+		fields[k] = v
+		conditions = append(conditions, map[string]string{k: v})
+	}
+
+	if input.KeyPrefix != nil {
+		prefix := aws.StringValue(input.KeyPrefix)
+		fields["key"] = prefix + "${filename}"
+		conditions = append(conditions, PostPolicyConditionStartsWith("key", prefix))
+	} else {
+		addField("key", aws.StringValue(input.Key))
+	}
+	if input.ACL != nil {
+		addField("acl", aws.StringValue(input.ACL))
+	}
+	metaKeys := make([]string, 0, len(input.Metadata))
+	for k := range input.Metadata {
+		metaKeys = append(metaKeys, k)
+	}
+	sort.Strings(metaKeys)
+	for _, k := range metaKeys {
+		addField("x-amz-meta-"+strings.ToLower(k), aws.StringValue(input.Metadata[k]))
+	}
+	fieldKeys := make([]string, 0, len(input.Fields))
+	for k := range input.Fields {
+		fieldKeys = append(fieldKeys, k)
+	}
+	sort.Strings(fieldKeys)
+	for _, k := range fieldKeys {
+		addField(k, input.Fields[k])
+	}
+	for _, cond := range input.Conditions {
+		conditions = append(conditions, cond)
+	}
+
+	addField("x-amz-algorithm", postPolicyAlgorithm)
+	addField("x-amz-credential", creds.AccessKeyID+"/"+v4.BuildCredentialScope(signTime, region, signingName))
+	addField("x-amz-date", signTime.Format(postPolicyTimeFormat))
+	if len(creds.SessionToken) != 0 {
+		addField("x-amz-security-token", creds.SessionToken)
+	}
+
+	policy, err := json.Marshal(struct {
+		Expiration string        `json:"expiration"`
+		Conditions []interface{} `json:"conditions"`
+	}{
+		Expiration: expires.Format(postPolicyExpirationTime),
+		Conditions: conditions,
+	})
+	if err != nil {
+		return nil, awserr.New(request.ErrCodeSerialization, "failed to encode POST policy", err)
+	}
+	encodedPolicy := base64.StdEncoding.EncodeToString(policy)
+
+	key := v4.DeriveSigningKey(creds.SecretAccessKey, signTime, region, signingName)
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(encodedPolicy))
+
+	fields["policy"] = encodedPolicy
+	fields["x-amz-signature"] = hex.EncodeToString(h.Sum(nil))
+
+	return &PresignedPost{
+		URL:     u,
+		Fields:  fields,
+		Expires: expires,
+	}, nil
+}
+
+// presignPostURL returns the URL of the bucket the form must be submitted
+// to. The URL is built by the client's request handlers so that it uses the
+// same bucket addressing style as the client's API requests.
+func (c *S3) presignPostURL(bucket string) (string, error) {
+	req, _ := c.HeadBucketRequest(&HeadBucketInput{Bucket: aws.String(bucket)})
+	if err := req.Build(); err != nil {
+		return "", err
+	}
+
+	u := *req.HTTPRequest.URL
+	u.RawQuery = ""
+
+	return u.String(), nil
+}
@@ -0,0 +1,204 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+)
+
+func TestPresignPost(t *testing.T) {
+	origNow := presignPostNow
+	defer func() { presignPostNow = origNow }()
+	signTime := time.Date(2019, 9, 1, 12, 30, 0, 0, time.UTC)
+	presignPostNow = func() time.Time { return signTime }
+
+	cases := map[string]struct {
+		Config       *aws.Config
+		Input        *PresignPostInput
+		ExpectURL    string
+		ExpectFields map[string]string
+		ExpectConds  []interface{}
+	}{
+		"key prefix": {
+			Input: &PresignPostInput{
+				Bucket:    aws.String("bucket"),
+				KeyPrefix: aws.String("uploads/"),
+				Expires:   15 * time.Minute,
+				ACL:       aws.String("public-read"),
+				Metadata:  map[string]*string{"Owner": aws.String("user")},
+				Conditions: []PostPolicyCondition{
+					PostPolicyConditionContentLengthRange(1, 1024),
+					PostPolicyConditionStartsWith("Content-Type", "image/"),
+				},
+			},
+			ExpectURL: "https://bucket.s3.mock-region.amazonaws.com/",
+			ExpectFields: map[string]string{
+				"key":                  "uploads/${filename}",
+				"acl":                  "public-read",
+				"x-amz-meta-owner":     "user",
+				"x-amz-algorithm":      "AWS4-HMAC-SHA256",
+				"x-amz-credential":     "AKID/20190901/mock-region/s3/aws4_request",
+				"x-amz-date":           "20190901T123000Z",
+				"x-amz-security-token": "SESSION",
+			},
+			ExpectConds: []interface{}{
+				map[string]interface{}{"bucket": "bucket"},
+				[]interface{}{"starts-with", "$key", "uploads/"},
+				map[string]interface{}{"acl": "public-read"},
+				map[string]interface{}{"x-amz-meta-owner": "user"},
+				[]interface{}{"content-length-range", float64(1), float64(1024)},
+				[]interface{}{"starts-with", "$Content-Type", "image/"},
+				map[string]interface{}{"x-amz-algorithm": "AWS4-HMAC-SHA256"},
+				map[string]interface{}{"x-amz-credential": "AKID/20190901/mock-region/s3/aws4_request"},
+				map[string]interface{}{"x-amz-date": "20190901T123000Z"},
+				map[string]interface{}{"x-amz-security-token": "SESSION"},
+			},
+		},
+		"exact key path style with session token": {
+			Config: &aws.Config{
+				S3ForcePathStyle: aws.Bool(true),
+				Credentials:      credentials.NewStaticCredentials("AKID", "SECRET", "TOKEN"),
+			},
+			Input: &PresignPostInput{
+				Bucket:  aws.String("bucket"),
+				Key:     aws.String("path/to/key"),
+				Expires: time.Hour,
+				Fields:  map[string]string{"success_action_status": "201"},
+			},
+			ExpectURL: "https://s3.mock-region.amazonaws.com/bucket",
+			ExpectFields: map[string]string{
+				"key":                   "path/to/key",
+				"success_action_status": "201",
+				"x-amz-algorithm":       "AWS4-HMAC-SHA256",
+				"x-amz-credential":      "AKID/20190901/mock-region/s3/aws4_request",
+				"x-amz-date":            "20190901T123000Z",
+				"x-amz-security-token":  "TOKEN",
+			},
+			ExpectConds: []interface{}{
+				map[string]interface{}{"bucket": "bucket"},
+				map[string]interface{}{"key": "path/to/key"},
+				map[string]interface{}{"success_action_status": "201"},
+				map[string]interface{}{"x-amz-algorithm": "AWS4-HMAC-SHA256"},
+				map[string]interface{}{"x-amz-credential": "AKID/20190901/mock-region/s3/aws4_request"},
+				map[string]interface{}{"x-amz-date": "20190901T123000Z"},
+				map[string]interface{}{"x-amz-security-token": "TOKEN"},
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := New(unit.Session, c.Config)
+
+			post, err := svc.PresignPost(c.Input)
+			if err != nil {
+				t.Fatalf("expect no error, got %v", err)
+			}
+			if e, a := c.ExpectURL, post.URL; e != a {
+				t.Errorf("expect %v URL, got %v", e, a)
+			}
+			if e, a := signTime.Add(c.Input.Expires), post.Expires; !e.Equal(a) {
+				t.Errorf("expect %v expires, got %v", e, a)
+			}
+
+			encodedPolicy := post.Fields["policy"]
+			signature := post.Fields["x-amz-signature"]
+			fields := map[string]string{}
+			for k, v := range post.Fields {
+				fields[k] = v
+			}
+			delete(fields, "policy")
+			delete(fields, "x-amz-signature")
+			if e, a := c.ExpectFields, fields; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v fields, got %v", e, a)
+			}
+
+			b, err := base64.StdEncoding.DecodeString(encodedPolicy)
+			if err != nil {
+				t.Fatalf("expect no error decoding policy, got %v", err)
+			}
+			var policy struct {
+				Expiration string        `json:"expiration"`
+				Conditions []interface{} `json:"conditions"`
+			}
+			if err := json.Unmarshal(b, &policy); err != nil {
+				t.Fatalf("expect no error unmarshaling policy, got %v", err)
+			}
+			if e, a := signTime.Add(c.Input.Expires).Format("2006-01-02T15:04:05.000Z"), policy.Expiration; e != a {
+				t.Errorf("expect %v expiration, got %v", e, a)
+			}
+			if e, a := c.ExpectConds, policy.Conditions; !reflect.DeepEqual(e, a) {
+				t.Errorf("expect %v conditions, got %v", e, a)
+			}
+
+			h := hmac.New(sha256.New, v4.DeriveSigningKey("SECRET", signTime, "mock-region", "s3"))
+			h.Write([]byte(encodedPolicy))
+			if e, a := hex.EncodeToString(h.Sum(nil)), signature; e != a {
+				t.Errorf("expect %v signature, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestPresignPost_Errors(t *testing.T) {
+	cases := map[string]struct {
+		Config *aws.Config
+		Input  *PresignPostInput
+		Code   string
+	}{
+		"missing bucket": {
+			Input: &PresignPostInput{Key: aws.String("key"), Expires: time.Minute},
+			Code:  request.InvalidParameterErrCode,
+		},
+		"missing key": {
+			Input: &PresignPostInput{Bucket: aws.String("bucket"), Expires: time.Minute},
+			Code:  request.InvalidParameterErrCode,
+		},
+		"key and prefix": {
+			Input: &PresignPostInput{
+				Bucket: aws.String("bucket"), Key: aws.String("key"), KeyPrefix: aws.String("prefix"),
+				Expires: time.Minute,
+			},
+			Code: request.InvalidParameterErrCode,
+		},
+		"missing expires": {
+			Input: &PresignPostInput{Bucket: aws.String("bucket"), Key: aws.String("key")},
+			Code:  request.InvalidParameterErrCode,
+		},
+		"anonymous credentials": {
+			Config: &aws.Config{Credentials: credentials.AnonymousCredentials},
+			Input:  &PresignPostInput{Bucket: aws.String("bucket"), Key: aws.String("key"), Expires: time.Minute},
+			Code:   ErrCodeInvalidPresignPost,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc := New(unit.Session, c.Config)
+
+			_, err := svc.PresignPost(c.Input)
+			if err == nil {
+				t.Fatalf("expect error, got none")
+			}
+			aerr, ok := err.(awserr.Error)
+			if !ok {
+				t.Fatalf("expect awserr.Error, got %T", err)
+			}
+			if e, a := c.Code, aerr.Code(); e != a {
+				t.Errorf("expect %v error code, got %v", e, a)
+			}
+		})
+	}
+}
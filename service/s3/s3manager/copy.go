@@ -0,0 +1,598 @@
+package s3manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// MaxCopyObjectSize is the maximum size, in bytes, of an object which can be
+// copied with a single CopyObject request.
+const MaxCopyObjectSize int64 = 1024 * 1024 * 1024 * 5
+
+// DefaultCopyPartSize is the default size, in bytes, of the parts of a
+// multipart copy.
+const DefaultCopyPartSize int64 = 1024 * 1024 * 64
+
+// DefaultCopyConcurrency is the default number of goroutines to spin up when
+// using Copy().
+const DefaultCopyConcurrency = 5
+
+// A MultiCopyFailure wraps a failed S3 multipart copy. Multipart copies cannot
+// be resumed with the Uploader, so in place of a checkpoint the error provides
+// the parts which were copied before the failure. The UploadID and parts are
+// only of use if the Copier's LeavePartsOnError is set, as the multipart
+// upload is otherwise aborted.
+//
+// Example:
+//
+//     _, err := copier.Copy(input)
+//     if err != nil {
+//         if copyErr, ok := err.(s3manager.MultiCopyFailure); ok {
+//             fmt.Println("Error:", copyErr.UploadID(), len(copyErr.CompletedParts()))
+//         }
+//     }
+//
+type MultiCopyFailure interface {
+	MultiUploadFailure
+
+	// Returns the parts of the S3 multipart copy which were copied before
+	// it failed, sorted by part number.
+	CompletedParts() []*s3.CompletedPart
+}
+
+// internal structure to implement the MultiCopyFailure interface.
+type multiCopyError struct {
+	awsError
+
+	// ID for multipart upload of the copy which failed.
+	uploadID string
+
+	// Parts copied before the copy failed.
+	parts completedParts
+}
+
+// Error returns the string representation of the error.
+//
+// Satisfies the error interface.
+func (m multiCopyError) Error() string {
+	extra := fmt.Sprintf("upload id: %s", m.uploadID)
+	return awserr.SprintError(m.Code(), m.Message(), extra, m.OrigErr())
+}
+
+// String returns the string representation of the error.
+// Alias for Error to satisfy the stringer interface.
+func (m multiCopyError) String() string {
+	return m.Error()
+}
+
+// UploadID returns the id of the S3 upload of the copy which failed.
+func (m multiCopyError) UploadID() string {
+	return m.uploadID
+}
+
+// CompletedParts returns the parts copied before the copy failed.
+func (m multiCopyError) CompletedParts() []*s3.CompletedPart {
+	return m.parts
+}
+
+// CopyOutput represents a response from the Copy() call.
+type CopyOutput struct {
+	// The ETag of the copied object.
+	ETag *string
+
+	// The version of the copied object. Will only be populated if the S3
+	// Bucket is versioned.
+	VersionID *string
+
+	// The ID for the multipart upload used to copy the object. Empty if the
+	// object was copied with a single CopyObject request.
+	UploadID string
+}
+
+// WithCopierRequestOptions appends to the Copier's API request options.
+func WithCopierRequestOptions(opts ...request.Option) func(*Copier) {
+	return func(c *Copier) {
+		c.RequestOptions = append(c.RequestOptions, opts...)
+	}
+}
+
+// The Copier structure that calls Copy(). It is safe to call Copy() on this
+// structure for multiple objects and across concurrent goroutines. Mutating
+// the Copier's properties is not safe to be done concurrently.
+type Copier struct {
+	// The size, in bytes, of the parts of a multipart copy. The minimum
+	// allowed part size is 5MB, and if this value is set to zero, the
+	// DefaultCopyPartSize value will be used.
+	PartSize int64
+
+	// Objects larger than this size, in bytes, are copied with a multipart
+	// copy. Objects larger than MaxCopyObjectSize are always copied with a
+	// multipart copy. If this value is set to zero, the PartSize will be used.
+	MultipartCopyThreshold int64
+
+	// The number of goroutines to spin up in parallel per call to Copy when
+	// copying parts. If this is set to zero, the DefaultCopyConcurrency value
+	// will be used.
+	Concurrency int
+
+	// Setting this value to true will cause the SDK to avoid calling
+	// AbortMultipartUpload on a failure, leaving all successfully copied
+	// parts on S3 for manual recovery.
+	LeavePartsOnError bool
+
+	// The client to use when copying objects. Requests are made to the
+	// destination bucket's region.
+	S3 s3iface.S3API
+
+	// SourceClient returns a client for the region, to use for requests to
+	// the source bucket. If set, the region of the source bucket is determined
+	// with GetBucketRegionWithClient. If nil, the S3 client is used for all
+	// requests.
+	//
+	// NewCopier sets SourceClient to create clients from its
+	// client.ConfigProvider.
+	SourceClient func(region string) s3iface.S3API
+
+	// List of request options that will be passed down to individual API
+	// operation requests made by the copier.
+	RequestOptions []request.Option
+}
+
+// NewCopier creates a new Copier instance to copy objects in S3. Pass in
+// additional functional options to customize the copier's behavior. Requires
+// a client.ConfigProvider in order to create a S3 service client. The
+// session.Session satisfies the client.ConfigProvider interface.
+//
+// Example:
+//     // The session the S3 Copier will use
+//     sess := session.Must(session.NewSession())
+//
+//     // Create a copier with the session and custom options
+//     copier := s3manager.NewCopier(sess, func(c *s3manager.Copier) {
+//          c.PartSize = 128 * 1024 * 1024 // 128MB per part
+//     })
+func NewCopier(c client.ConfigProvider, options ...func(*Copier)) *Copier {
+	cp := newCopier(s3.New(c))
+	cp.SourceClient = func(region string) s3iface.S3API {
+		return s3.New(c, &aws.Config{Region: aws.String(region)})
+	}
+
+	for _, option := range options {
+		option(cp)
+	}
+
+	return cp
+}
+
+// NewCopierWithClient creates a new Copier instance to copy objects in S3.
+// Pass in additional functional options to customize the copier's behavior.
+// Requires a S3 service client to make S3 API calls.
+//
+// The S3 client is used for requests to the source bucket unless the
+// SourceClient option is set.
+func NewCopierWithClient(svc s3iface.S3API, options ...func(*Copier)) *Copier {
+	cp := newCopier(svc)
+
+	for _, option := range options {
+		option(cp)
+	}
+
+	return cp
+}
+
+func newCopier(svc s3iface.S3API) *Copier {
+	return &Copier{
+		S3:          svc,
+		PartSize:    DefaultCopyPartSize,
+		Concurrency: DefaultCopyConcurrency,
+	}
+}
+
+// Copy copies an object in S3 to a new bucket and key. Objects larger than
+// the MultipartCopyThreshold are copied with concurrent UploadPartCopy
+// requests, and smaller objects with a single CopyObject request.
+//
+// The input's CopySource is the source bucket and URL encoded key, with an
+// optional versionId query parameter, e.g. "bucket/key?versionId=id".
+//
+// Objects copied with a multipart copy keep the semantics of CopyObject. The
+// source's metadata is copied unless the MetadataDirective is REPLACE, and the
+// source's tags are copied unless the TaggingDirective is REPLACE. The
+// destination's SSE settings are taken from the input, and the
+// CopySourceSSECustomer fields provide the key of a source encrypted with
+// SSE-C. The parts are copied only if the source's ETag does not change
+// during the copy.
+//
+// If a multipart copy fails, the multipart upload is aborted unless
+// LeavePartsOnError is set, and the error satisfies MultiCopyFailure.
+//
+// Example:
+//     result, err := copier.Copy(&s3.CopyObjectInput{
+//         Bucket:     aws.String("destBucket"),
+//         Key:        aws.String("destKey"),
+//         CopySource: aws.String("srcBucket/srcKey"),
+//     })
+func (c Copier) Copy(input *s3.CopyObjectInput, options ...func(*Copier)) (*CopyOutput, error) {
+	return c.CopyWithContext(aws.BackgroundContext(), input, options...)
+}
+
+// CopyWithContext is the same as Copy with the additional support for
+// Context input parameters. The Context must not be nil. A nil Context will
+// cause a panic. Use the context to add deadlining, timeouts, etc.
+func (c Copier) CopyWithContext(ctx aws.Context, input *s3.CopyObjectInput, options ...func(*Copier)) (*CopyOutput, error) {
+	i := copier{in: input, cfg: c, ctx: ctx}
+
+	for _, option := range options {
+		option(&i.cfg)
+	}
+	i.cfg.RequestOptions = append(i.cfg.RequestOptions, request.WithAppendUserAgent("S3Manager"))
+
+	return i.copy()
+}
+
+// internal structure to manage a copy of an object in S3.
+type copier struct {
+	ctx aws.Context
+	cfg Copier
+
+	in *s3.CopyObjectInput
+
+	srcBucket, srcKey, srcVersion string
+	src                           s3iface.S3API
+	head                          *s3.HeadObjectOutput
+
+	wg       sync.WaitGroup
+	m        sync.Mutex
+	err      error
+	uploadID string
+	parts    completedParts
+}
+
+// copy decides whether to copy the object with a single CopyObject request,
+// or a multipart copy.
+func (c *copier) copy() (*CopyOutput, error) {
+	if c.cfg.Concurrency == 0 {
+		c.cfg.Concurrency = DefaultCopyConcurrency
+	}
+	if c.cfg.PartSize == 0 {
+		c.cfg.PartSize = DefaultCopyPartSize
+	}
+	if c.cfg.MultipartCopyThreshold == 0 {
+		c.cfg.MultipartCopyThreshold = c.cfg.PartSize
+	}
+	if c.cfg.PartSize < MinUploadPartSize {
+		msg := fmt.Sprintf("part size must be at least %d bytes", MinUploadPartSize)
+		return nil, awserr.New("ConfigError", msg, nil)
+	}
+
+	if err := c.parseCopySource(); err != nil {
+		return nil, err
+	}
+	if err := c.initSourceClient(); err != nil {
+		return nil, err
+	}
+
+	head, err := c.src.HeadObjectWithContext(c.ctx, &s3.HeadObjectInput{
+		Bucket:               aws.String(c.srcBucket),
+		Key:                  aws.String(c.srcKey),
+		VersionId:            nonEmptyString(c.srcVersion),
+		IfMatch:              c.in.CopySourceIfMatch,
+		IfNoneMatch:          c.in.CopySourceIfNoneMatch,
+		IfModifiedSince:      c.in.CopySourceIfModifiedSince,
+		IfUnmodifiedSince:    c.in.CopySourceIfUnmodifiedSince,
+		SSECustomerAlgorithm: c.in.CopySourceSSECustomerAlgorithm,
+		SSECustomerKey:       c.in.CopySourceSSECustomerKey,
+		RequestPayer:         c.in.RequestPayer,
+	}, c.cfg.RequestOptions...)
+	if err != nil {
+		return nil, err
+	}
+	c.head = head
+
+	size := aws.Int64Value(head.ContentLength)
+	if size <= c.cfg.MultipartCopyThreshold && size <= MaxCopyObjectSize {
+		return c.singlePart()
+	}
+
+	return c.multipart(size)
+}
+
+// parseCopySource splits the input's CopySource into the source bucket, key,
+// and version.
+func (c *copier) parseCopySource() error {
+	src := strings.TrimPrefix(aws.StringValue(c.in.CopySource), "/")
+
+	u, err := url.Parse("/" + src)
+	if err != nil {
+		return awserr.New("ConfigError", "invalid copy source", err)
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return awserr.New("ConfigError",
+			fmt.Sprintf("copy source must be bucket/key, got %q", src), nil)
+	}
+
+	c.srcBucket, c.srcKey = parts[0], parts[1]
+	c.srcVersion = u.Query().Get("versionId")
+
+	return nil
+}
+
+// initSourceClient sets the client for requests to the source bucket, in the
+// source bucket's region.
+func (c *copier) initSourceClient() error {
+	c.src = c.cfg.S3
+	if c.cfg.SourceClient == nil {
+		return nil
+	}
+
+	region, err := GetBucketRegionWithClient(c.ctx, c.cfg.S3, c.srcBucket, c.cfg.RequestOptions...)
+	if err != nil {
+		return err
+	}
+
+	if svc, ok := c.cfg.S3.(*s3.S3); ok && aws.StringValue(svc.Config.Region) == region {
+		return nil
+	}
+	c.src = c.cfg.SourceClient(region)
+
+	return nil
+}
+
+// singlePart copies the object with a single CopyObject request.
+func (c *copier) singlePart() (*CopyOutput, error) {
+	resp, err := c.cfg.S3.CopyObjectWithContext(c.ctx, c.in, c.cfg.RequestOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &CopyOutput{VersionID: resp.VersionId}
+	if resp.CopyObjectResult != nil {
+		out.ETag = resp.CopyObjectResult.ETag
+	}
+	return out, nil
+}
+
+// multipart copies the object of the size with concurrent UploadPartCopy
+// requests.
+func (c *copier) multipart(size int64) (*CopyOutput, error) {
+	params, err := c.createMultipartUploadInput()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.cfg.S3.CreateMultipartUploadWithContext(c.ctx, params, c.cfg.RequestOptions...)
+	if err != nil {
+		return nil, err
+	}
+	c.uploadID = aws.StringValue(resp.UploadId)
+
+	partSize := c.cfg.PartSize
+	if size/partSize >= int64(MaxUploadParts) {
+		partSize = size/int64(MaxUploadParts) + 1
+	}
+
+	ch := make(chan copyChunk, c.cfg.Concurrency)
+	for i := 0; i < c.cfg.Concurrency; i++ {
+		c.wg.Add(1)
+		go c.copyChunks(ch)
+	}
+
+	var num int64 = 1
+	for start := int64(0); start < size && c.geterr() == nil; start += partSize {
+		end := start + partSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		ch <- copyChunk{num: num, start: start, end: end}
+		num++
+	}
+	close(ch)
+	c.wg.Wait()
+
+	complete := c.complete()
+	if err := c.geterr(); err != nil {
+		parts := append(completedParts{}, c.parts...)
+		sort.Sort(parts)
+
+		return nil, &multiCopyError{
+			awsError: awserr.New("MultipartCopy", "copy multipart failed", err),
+			uploadID: c.uploadID,
+			parts:    parts,
+		}
+	}
+
+	return &CopyOutput{
+		ETag:      complete.ETag,
+		VersionID: complete.VersionId,
+		UploadID:  c.uploadID,
+	}, nil
+}
+
+// createMultipartUploadInput returns the input to create the multipart
+// upload with, copying the source's metadata and tags unless they are
+// replaced.
+func (c *copier) createMultipartUploadInput() (*s3.CreateMultipartUploadInput, error) {
+	params := &s3.CreateMultipartUploadInput{}
+	awsutil.Copy(params, c.in)
+
+	if !strings.EqualFold(aws.StringValue(c.in.MetadataDirective), s3.MetadataDirectiveReplace) {
+		h := c.head
+		params.Metadata = h.Metadata
+		params.CacheControl = h.CacheControl
+		params.ContentDisposition = h.ContentDisposition
+		params.ContentEncoding = h.ContentEncoding
+		params.ContentLanguage = h.ContentLanguage
+		params.ContentType = h.ContentType
+		params.WebsiteRedirectLocation = h.WebsiteRedirectLocation
+		params.Expires = nil
+		if t, err := http.ParseTime(aws.StringValue(h.Expires)); err == nil {
+			params.Expires = aws.Time(t)
+		}
+	}
+
+	if !strings.EqualFold(aws.StringValue(c.in.TaggingDirective), s3.TaggingDirectiveReplace) {
+		resp, err := c.src.GetObjectTaggingWithContext(c.ctx, &s3.GetObjectTaggingInput{
+			Bucket:    aws.String(c.srcBucket),
+			Key:       aws.String(c.srcKey),
+			VersionId: nonEmptyString(c.srcVersion),
+		}, c.cfg.RequestOptions...)
+		if err != nil {
+			return nil, err
+		}
+		params.Tagging = encodeTagging(resp.TagSet)
+	}
+
+	return params, nil
+}
+
+// encodeTagging returns the tags encoded as URL query parameters, or nil if
+// there are no tags.
+func encodeTagging(tags []*s3.Tag) *string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	v := url.Values{}
+	for _, t := range tags {
+		v.Add(aws.StringValue(t.Key), aws.StringValue(t.Value))
+	}
+	return aws.String(v.Encode())
+}
+
+// keeps track of a single part being copied.
+type copyChunk struct {
+	num        int64
+	start, end int64
+}
+
+// copyChunks runs in worker goroutines to pull chunks off of the ch channel
+// and copy them with UploadPartCopy requests.
+func (c *copier) copyChunks(ch chan copyChunk) {
+	defer c.wg.Done()
+	for chunk := range ch {
+		if c.geterr() != nil {
+			continue
+		}
+		if err := c.copyChunk(chunk); err != nil {
+			c.seterr(err)
+		}
+	}
+}
+
+// copyChunk performs an UploadPartCopy request and keeps track of the
+// completed part information. The part is only copied if the source's ETag
+// has not changed since the copy started.
+func (c *copier) copyChunk(chunk copyChunk) error {
+	ifMatch := c.in.CopySourceIfMatch
+	if ifMatch == nil {
+		ifMatch = c.head.ETag
+	}
+
+	resp, err := c.cfg.S3.UploadPartCopyWithContext(c.ctx, &s3.UploadPartCopyInput{
+		Bucket:                         c.in.Bucket,
+		Key:                            c.in.Key,
+		UploadId:                       &c.uploadID,
+		PartNumber:                     aws.Int64(chunk.num),
+		CopySource:                     c.in.CopySource,
+		CopySourceRange:                aws.String(fmt.Sprintf("bytes=%d-%d", chunk.start, chunk.end)),
+		CopySourceIfMatch:              ifMatch,
+		CopySourceSSECustomerAlgorithm: c.in.CopySourceSSECustomerAlgorithm,
+		CopySourceSSECustomerKey:       c.in.CopySourceSSECustomerKey,
+		SSECustomerAlgorithm:           c.in.SSECustomerAlgorithm,
+		SSECustomerKey:                 c.in.SSECustomerKey,
+		RequestPayer:                   c.in.RequestPayer,
+	}, c.cfg.RequestOptions...)
+	if err != nil {
+		return err
+	}
+
+	var etag *string
+	if resp.CopyPartResult != nil {
+		etag = resp.CopyPartResult.ETag
+	}
+
+	c.m.Lock()
+	c.parts = append(c.parts, &s3.CompletedPart{ETag: etag, PartNumber: aws.Int64(chunk.num)})
+	c.m.Unlock()
+
+	return nil
+}
+
+// geterr is a thread-safe getter for the error object
+func (c *copier) geterr() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.err
+}
+
+// seterr is a thread-safe setter for the error object
+func (c *copier) seterr(e error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.err = e
+}
+
+// fail will abort the multipart unless LeavePartsOnError is set to true.
+func (c *copier) fail() {
+	if c.cfg.LeavePartsOnError {
+		return
+	}
+
+	_, err := c.cfg.S3.AbortMultipartUploadWithContext(c.ctx, &s3.AbortMultipartUploadInput{
+		Bucket:       c.in.Bucket,
+		Key:          c.in.Key,
+		UploadId:     &c.uploadID,
+		RequestPayer: c.in.RequestPayer,
+	}, c.cfg.RequestOptions...)
+	if err != nil {
+		logMessage(c.cfg.S3, aws.LogDebug, fmt.Sprintf("failed to abort multipart copy, %v", err))
+	}
+}
+
+// complete successfully completes a multipart copy and returns the response.
+func (c *copier) complete() *s3.CompleteMultipartUploadOutput {
+	if c.geterr() != nil {
+		c.fail()
+		return nil
+	}
+
+	// Parts must be sorted in PartNumber order.
+	sort.Sort(c.parts)
+
+	resp, err := c.cfg.S3.CompleteMultipartUploadWithContext(c.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          c.in.Bucket,
+		Key:             c.in.Key,
+		UploadId:        &c.uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: c.parts},
+		RequestPayer:    c.in.RequestPayer,
+	}, c.cfg.RequestOptions...)
+	if err != nil {
+		c.seterr(err)
+		c.fail()
+	}
+
+	return resp
+}
+
+func nonEmptyString(v string) *string {
+	if len(v) == 0 {
+		return nil
+	}
+	return aws.String(v)
+}
@@ -0,0 +1,336 @@
+package s3manager_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// copyTestSvc is a client serving a source object of the size, recording the
+// names and parameters of the operations made.
+type copyTestSvc struct {
+	*s3.S3
+
+	m      sync.Mutex
+	ops    []string
+	params []interface{}
+}
+
+func newCopyTestSvc(size int64, region string, failOp string) *copyTestSvc {
+	svc := &copyTestSvc{S3: s3.New(unit.Session, &aws.Config{Region: aws.String(region)})}
+	svc.Handlers.Unmarshal.Clear()
+	svc.Handlers.UnmarshalMeta.Clear()
+	svc.Handlers.UnmarshalError.Clear()
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		svc.m.Lock()
+		defer svc.m.Unlock()
+
+		svc.ops = append(svc.ops, r.Operation.Name)
+		svc.params = append(svc.params, r.Params)
+
+		r.HTTPResponse = &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		}
+		if r.Operation.Name == failOp {
+			r.Error = awserr.New("InternalError", "failed", nil)
+			return
+		}
+
+		switch data := r.Data.(type) {
+		case *s3.HeadBucketOutput:
+			r.HTTPResponse.Header.Set("X-Amz-Bucket-Region", "us-west-2")
+		case *s3.HeadObjectOutput:
+			data.ContentLength = aws.Int64(size)
+			data.ETag = aws.String(`"etag"`)
+			data.ContentType = aws.String("text/plain")
+			data.Metadata = map[string]*string{"Foo": aws.String("bar")}
+		case *s3.GetObjectTaggingOutput:
+			data.TagSet = []*s3.Tag{{Key: aws.String("k"), Value: aws.String("v")}}
+		case *s3.CopyObjectOutput:
+			data.CopyObjectResult = &s3.CopyObjectResult{ETag: aws.String(`"copied"`)}
+		case *s3.CreateMultipartUploadOutput:
+			data.UploadId = aws.String("UPLOAD-ID")
+		case *s3.UploadPartCopyOutput:
+			num := aws.Int64Value(r.Params.(*s3.UploadPartCopyInput).PartNumber)
+			data.CopyPartResult = &s3.CopyPartResult{ETag: aws.String(fmt.Sprintf("ETAG%d", num))}
+		case *s3.CompleteMultipartUploadOutput:
+			data.ETag = aws.String(`"complete"`)
+			data.VersionId = aws.String("VERSION-ID")
+		}
+	})
+
+	return svc
+}
+
+// opParams returns the parameters of the operations with the name.
+func (s *copyTestSvc) opParams(name string) []interface{} {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	var params []interface{}
+	for i, op := range s.ops {
+		if op == name {
+			params = append(params, s.params[i])
+		}
+	}
+	return params
+}
+
+func (s *copyTestSvc) opNames() []string {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return append([]string{}, s.ops...)
+}
+
+func TestCopySinglePart(t *testing.T) {
+	svc := newCopyTestSvc(1024, "us-west-2", "")
+	c := s3manager.NewCopierWithClient(svc)
+
+	resp, err := c.Copy(&s3.CopyObjectInput{
+		Bucket:     aws.String("dest"),
+		Key:        aws.String("key"),
+		CopySource: aws.String("src/path/to%20key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := []string{"HeadObject", "CopyObject"}, svc.opNames(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v ops, got %v", e, a)
+	}
+	head := svc.opParams("HeadObject")[0].(*s3.HeadObjectInput)
+	if e, a := "src", aws.StringValue(head.Bucket); e != a {
+		t.Errorf("expect %q bucket, got %q", e, a)
+	}
+	if e, a := "path/to key", aws.StringValue(head.Key); e != a {
+		t.Errorf("expect %q key, got %q", e, a)
+	}
+	if e, a := `"copied"`, aws.StringValue(resp.ETag); e != a {
+		t.Errorf("expect %q etag, got %q", e, a)
+	}
+	if e, a := "", resp.UploadID; e != a {
+		t.Errorf("expect %q upload id, got %q", e, a)
+	}
+}
+
+func TestCopyMultipart(t *testing.T) {
+	svc := newCopyTestSvc(12*1024*1024, "us-west-2", "")
+	c := s3manager.NewCopierWithClient(svc, func(c *s3manager.Copier) {
+		c.PartSize = 5 * 1024 * 1024
+		c.Concurrency = 2
+	})
+
+	resp, err := c.Copy(&s3.CopyObjectInput{
+		Bucket:                         aws.String("dest"),
+		Key:                            aws.String("key"),
+		CopySource:                     aws.String("src/key?versionId=v1"),
+		CopySourceSSECustomerAlgorithm: aws.String("AES256"),
+		CopySourceSSECustomerKey:       aws.String("source-key"),
+		ServerSideEncryption:           aws.String("aws:kms"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	ops := svc.opNames()
+	sort.Strings(ops[3:6])
+	expectOps := []string{"HeadObject", "GetObjectTagging", "CreateMultipartUpload",
+		"UploadPartCopy", "UploadPartCopy", "UploadPartCopy", "CompleteMultipartUpload"}
+	if e, a := expectOps, ops; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v ops, got %v", e, a)
+	}
+
+	head := svc.opParams("HeadObject")[0].(*s3.HeadObjectInput)
+	if e, a := "v1", aws.StringValue(head.VersionId); e != a {
+		t.Errorf("expect %q version, got %q", e, a)
+	}
+	if e, a := "source-key", aws.StringValue(head.SSECustomerKey); e != a {
+		t.Errorf("expect %q source SSE-C key, got %q", e, a)
+	}
+
+	create := svc.opParams("CreateMultipartUpload")[0].(*s3.CreateMultipartUploadInput)
+	if e, a := "bar", aws.StringValue(create.Metadata["Foo"]); e != a {
+		t.Errorf("expect %q metadata, got %q", e, a)
+	}
+	if e, a := "text/plain", aws.StringValue(create.ContentType); e != a {
+		t.Errorf("expect %q content type, got %q", e, a)
+	}
+	if e, a := "k=v", aws.StringValue(create.Tagging); e != a {
+		t.Errorf("expect %q tagging, got %q", e, a)
+	}
+	if e, a := "aws:kms", aws.StringValue(create.ServerSideEncryption); e != a {
+		t.Errorf("expect %q SSE, got %q", e, a)
+	}
+
+	var ranges []string
+	for _, p := range svc.opParams("UploadPartCopy") {
+		in := p.(*s3.UploadPartCopyInput)
+		ranges = append(ranges, aws.StringValue(in.CopySourceRange))
+		if e, a := `"etag"`, aws.StringValue(in.CopySourceIfMatch); e != a {
+			t.Errorf("expect %q if match, got %q", e, a)
+		}
+		if e, a := "source-key", aws.StringValue(in.CopySourceSSECustomerKey); e != a {
+			t.Errorf("expect %q source SSE-C key, got %q", e, a)
+		}
+	}
+	sort.Strings(ranges)
+	expectRanges := []string{"bytes=0-5242879", "bytes=10485760-12582911", "bytes=5242880-10485759"}
+	if e, a := expectRanges, ranges; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v ranges, got %v", e, a)
+	}
+
+	complete := svc.opParams("CompleteMultipartUpload")[0].(*s3.CompleteMultipartUploadInput)
+	for i, p := range complete.MultipartUpload.Parts {
+		if e, a := int64(i+1), aws.Int64Value(p.PartNumber); e != a {
+			t.Errorf("expect %d part number, got %d", e, a)
+		}
+		if e, a := fmt.Sprintf("ETAG%d", i+1), aws.StringValue(p.ETag); e != a {
+			t.Errorf("expect %q etag, got %q", e, a)
+		}
+	}
+
+	if e, a := "UPLOAD-ID", resp.UploadID; e != a {
+		t.Errorf("expect %q upload id, got %q", e, a)
+	}
+	if e, a := "VERSION-ID", aws.StringValue(resp.VersionID); e != a {
+		t.Errorf("expect %q version id, got %q", e, a)
+	}
+}
+
+func TestCopyMultipart_ReplaceDirectives(t *testing.T) {
+	svc := newCopyTestSvc(12*1024*1024, "us-west-2", "")
+	c := s3manager.NewCopierWithClient(svc, func(c *s3manager.Copier) {
+		c.PartSize = 5 * 1024 * 1024
+	})
+
+	_, err := c.Copy(&s3.CopyObjectInput{
+		Bucket:            aws.String("dest"),
+		Key:               aws.String("key"),
+		CopySource:        aws.String("src/key"),
+		MetadataDirective: aws.String(s3.MetadataDirectiveReplace),
+		Metadata:          map[string]*string{"New": aws.String("value")},
+		TaggingDirective:  aws.String(s3.TaggingDirectiveReplace),
+		Tagging:           aws.String("a=b"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if a := svc.opParams("GetObjectTagging"); len(a) != 0 {
+		t.Errorf("expect no GetObjectTagging calls, got %d", len(a))
+	}
+	create := svc.opParams("CreateMultipartUpload")[0].(*s3.CreateMultipartUploadInput)
+	if e, a := map[string]*string{"New": aws.String("value")}, create.Metadata; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v metadata, got %v", e, a)
+	}
+	if e, a := "", aws.StringValue(create.ContentType); e != a {
+		t.Errorf("expect %q content type, got %q", e, a)
+	}
+	if e, a := "a=b", aws.StringValue(create.Tagging); e != a {
+		t.Errorf("expect %q tagging, got %q", e, a)
+	}
+}
+
+func TestCopyMultipart_Failure(t *testing.T) {
+	svc := newCopyTestSvc(12*1024*1024, "us-west-2", "UploadPartCopy")
+	c := s3manager.NewCopierWithClient(svc, func(c *s3manager.Copier) {
+		c.PartSize = 5 * 1024 * 1024
+		c.Concurrency = 1
+	})
+
+	_, err := c.Copy(&s3.CopyObjectInput{
+		Bucket:     aws.String("dest"),
+		Key:        aws.String("key"),
+		CopySource: aws.String("src/key"),
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	aerr, ok := err.(s3manager.MultiCopyFailure)
+	if !ok {
+		t.Fatalf("expect MultiCopyFailure, got %T", err)
+	}
+	if e, a := "UPLOAD-ID", aerr.UploadID(); e != a {
+		t.Errorf("expect %q upload id, got %q", e, a)
+	}
+	if _, ok := err.(s3manager.UploadCheckpointer); ok {
+		t.Errorf("expect copy error not to be resumable by the Uploader")
+	}
+
+	ops := svc.opNames()
+	if e, a := "AbortMultipartUpload", ops[len(ops)-1]; e != a {
+		t.Errorf("expect %q last op, got %q", e, a)
+	}
+	if a := svc.opParams("CompleteMultipartUpload"); len(a) != 0 {
+		t.Errorf("expect no CompleteMultipartUpload calls, got %d", len(a))
+	}
+}
+
+func TestCopy_CrossRegion(t *testing.T) {
+	dest := newCopyTestSvc(1024, "us-east-1", "")
+	src := newCopyTestSvc(1024, "us-west-2", "")
+
+	var regions []string
+	c := s3manager.NewCopierWithClient(dest, func(c *s3manager.Copier) {
+		c.SourceClient = func(region string) s3iface.S3API {
+			regions = append(regions, region)
+			return src
+		}
+	})
+
+	_, err := c.Copy(&s3.CopyObjectInput{
+		Bucket:     aws.String("dest"),
+		Key:        aws.String("key"),
+		CopySource: aws.String("src/key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := []string{"us-west-2"}, regions; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v regions, got %v", e, a)
+	}
+	if e, a := []string{"HeadBucket", "CopyObject"}, dest.opNames(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v destination ops, got %v", e, a)
+	}
+	if e, a := []string{"HeadObject"}, src.opNames(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v source ops, got %v", e, a)
+	}
+}
+
+func TestCopy_InvalidCopySource(t *testing.T) {
+	svc := newCopyTestSvc(1024, "us-west-2", "")
+	c := s3manager.NewCopierWithClient(svc)
+
+	for _, src := range []string{"", "bucket", "bucket/", "/key"} {
+		_, err := c.Copy(&s3.CopyObjectInput{
+			Bucket:     aws.String("dest"),
+			Key:        aws.String("key"),
+			CopySource: aws.String(src),
+		})
+		if err == nil {
+			t.Errorf("%q, expect error, got none", src)
+		}
+	}
+	if a := svc.opNames(); len(a) != 0 {
+		t.Errorf("expect no ops, got %v", a)
+	}
+}
@@ -50,6 +50,30 @@ type Downloader struct {
 	// List of request options that will be passed down to individual API
 	// operation requests made by the downloader.
 	RequestOptions []request.Option
+
+	// The listener the progress of each download is reported to. The bytes
+	// received by a part which is retried are discarded from the progress.
+	ProgressListener ProgressListener
+
+	// Setting this value to true will cause the Download to verify the
+	// content downloaded against the object's ETag. The ETag of a single part
+	// object is the MD5 of its content, and the ETag of a multipart uploaded
+	// object is the MD5 of the MD5s of its parts. A HeadObject request is made
+	// to get the object's ETag, and its part sizes for multipart uploaded
+	// objects. If the content does not match, the download fails with a
+	// ChecksumMismatchError.
+	//
+	// Content downloaded ahead of the content being verified is buffered in
+	// memory until the content before it in the same upload part of the
+	// object is downloaded. The memory used is not bounded by PartSize. A
+	// slow download of the start of an upload part can cause up to the
+	// object's upload part size to be buffered, for each of the Concurrency
+	// parts being downloaded.
+	//
+	// The checksum is not verified if the Range input parameter is provided,
+	// or if the object is encrypted with SSE-KMS or SSE-C, as its ETag is not
+	// an MD5 checksum.
+	VerifyChecksum bool
 }
 
 // WithDownloaderRequestOptions appends to the Downloader's API request options.
@@ -196,7 +220,12 @@ func (d Downloader) DownloadWithContext(ctx aws.Context, w io.WriterAt, input *s
 		impl.cfg.PartSize = DefaultDownloadPartSize
 	}
 
-	return impl.download()
+	impl.progress = newProgressTracker(impl.cfg.ProgressListener)
+
+	n, err = impl.download()
+	impl.progress.completed(err)
+
+	return n, err
 }
 
 // DownloadWithIterator will download a batched amount of objects in S3 and writes them
@@ -276,6 +305,9 @@ type downloader struct {
 	err        error
 
 	partBodyMaxRetries int
+
+	progress *progressTracker
+	checksum *checksumVerifier
 }
 
 // download performs the implementation of the object download across ranged
@@ -289,6 +321,12 @@ func (d *downloader) download() (n int64, err error) {
 		return d.written, d.err
 	}
 
+	if d.cfg.VerifyChecksum {
+		if err := d.initChecksum(); err != nil {
+			return 0, err
+		}
+	}
+
 	// Spin off first worker to check additional header information
 	d.getChunk()
 
@@ -332,6 +370,10 @@ func (d *downloader) download() (n int64, err error) {
 		}
 	}
 
+	if d.err == nil && d.checksum != nil {
+		d.err = d.verifyChecksum()
+	}
+
 	// Return error
 	return d.written, d.err
 }
@@ -402,11 +444,15 @@ func (d *downloader) downloadChunk(chunk dlchunk) error {
 	// Get the next byte range of data
 	in.Range = aws.String(chunk.ByteRange())
 
+	chunk.progress = d.chunkProgress(chunk)
+	chunk.progress.started()
+	opts := chunk.progress.requestOptions(d.cfg.RequestOptions, false)
+
 	var n int64
 	var err error
 	for retry := 0; retry <= d.partBodyMaxRetries; retry++ {
 		var resp *s3.GetObjectOutput
-		resp, err = d.cfg.S3.GetObjectWithContext(d.ctx, in, d.cfg.RequestOptions...)
+		resp, err = d.cfg.S3.GetObjectWithContext(d.ctx, in, opts...)
 		if err != nil {
 			return err
 		}
@@ -415,9 +461,11 @@ func (d *downloader) downloadChunk(chunk dlchunk) error {
 		n, err = io.Copy(&chunk, resp.Body)
 		resp.Body.Close()
 		if err == nil {
+			chunk.progress.completed()
 			break
 		}
 
+		chunk.progress.retried(err)
 		chunk.cur = 0
 		logMessage(d.cfg.S3, aws.LogDebugWithRequestRetries,
 			fmt.Sprintf("DEBUG: object part body download interrupted %s, err, %v, retrying attempt %d",
@@ -429,6 +477,14 @@ func (d *downloader) downloadChunk(chunk dlchunk) error {
 	return err
 }
 
+// chunkProgress returns the progress of the chunk's part.
+func (d *downloader) chunkProgress(chunk dlchunk) *partProgress {
+	if len(chunk.withRange) != 0 {
+		return d.progress.part(1, -1)
+	}
+	return d.progress.part(chunk.start/d.cfg.PartSize+1, chunk.size)
+}
+
 func logMessage(svc s3iface.S3API, level aws.LogLevelType, msg string) {
 	s, ok := svc.(*s3.S3)
 	if !ok {
@@ -464,6 +520,9 @@ func (d *downloader) setTotalBytes(resp *s3.GetObjectOutput) {
 	if d.totalBytes >= 0 {
 		return
 	}
+	defer func() {
+		d.progress.setTotal(d.totalBytes)
+	}()
 
 	if resp.ContentRange == nil {
 		// ContentRange is nil when the full file contents is provided, and
@@ -528,6 +587,9 @@ type dlchunk struct {
 
 	// specifies the byte range the chunk should be downloaded with.
 	withRange string
+
+	// progress of the chunk's part.
+	progress *partProgress
 }
 
 // Write wraps io.WriterAt for the dlchunk, writing from the dlchunk's start
@@ -542,6 +604,7 @@ func (c *dlchunk) Write(p []byte) (n int, err error) {
 
 	n, err = c.w.WriteAt(p, c.start+c.cur)
 	c.cur += int64(n)
+	c.progress.transferred(nil, int64(n))
 
 	return
 }
@@ -0,0 +1,308 @@
+package s3manager
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ErrCodeChecksumMismatch is the error code of a ChecksumMismatchError.
+const ErrCodeChecksumMismatch = "ChecksumMismatch"
+
+// ChecksumMismatchError is returned by the Downloader when the content
+// downloaded does not match the object's checksum, with VerifyChecksum
+// enabled. The whole object should be downloaded again.
+//
+// Only the whole object can be verified. S3 does not provide the checksums of
+// the parts of a multipart uploaded object, only the checksum of their
+// checksums, so the part which failed verification cannot be identified.
+type ChecksumMismatchError struct {
+	// The bucket and key of the object downloaded.
+	Bucket string
+	Key    string
+
+	// The checksum of the object, and the checksum of the content
+	// downloaded. The checksum of a multipart uploaded object is the MD5 of
+	// the MD5s of its parts, followed by the number of parts, e.g. "<md5>-3".
+	Expected string
+	Actual   string
+}
+
+// Code returns ErrCodeChecksumMismatch.
+func (e *ChecksumMismatchError) Code() string {
+	return ErrCodeChecksumMismatch
+}
+
+// Message returns the error's description.
+func (e *ChecksumMismatchError) Message() string {
+	return fmt.Sprintf("checksum of %s/%s does not match, expected %s, got %s",
+		e.Bucket, e.Key, e.Expected, e.Actual)
+}
+
+// OrigErr always returns nil.
+func (e *ChecksumMismatchError) OrigErr() error {
+	return nil
+}
+
+// Error satisfies the error interface.
+func (e *ChecksumMismatchError) Error() string {
+	return awserr.SprintError(e.Code(), e.Message(), "", nil)
+}
+
+// WithDownloaderChecksumVerification enables the Downloader's verification of
+// the content downloaded against the object's checksum.
+func WithDownloaderChecksumVerification(d *Downloader) {
+	d.VerifyChecksum = true
+}
+
+var etagChecksumRegex = regexp.MustCompile(`^([0-9a-f]{32})(?:-(\d+))?$`)
+
+// initChecksum gets the object's ETag and part layout, and wraps the
+// downloader's writer to compute the checksum of the content written. The
+// object is not verified if its ETag is not an MD5 checksum.
+//
+// The object's parts are downloaded only if its ETag has not changed, so the
+// content downloaded is the content the ETag was read from.
+func (d *downloader) initChecksum() error {
+	head, err := d.cfg.S3.HeadObjectWithContext(d.ctx, d.headObjectInput(0), d.cfg.RequestOptions...)
+	if err != nil {
+		return err
+	}
+
+	if aws.StringValue(head.ServerSideEncryption) == s3.ServerSideEncryptionAwsKms ||
+		head.SSECustomerAlgorithm != nil {
+		// The ETag of an object encrypted with SSE-KMS or SSE-C is not the MD5
+		// of its content.
+		logMessage(d.cfg.S3, aws.LogDebug, fmt.Sprintf(
+			"DEBUG: checksum of encrypted object %s not verified", aws.StringValue(d.in.Key)))
+		return nil
+	}
+
+	etag := strings.Trim(aws.StringValue(head.ETag), `"`)
+	match := etagChecksumRegex.FindStringSubmatch(etag)
+	if match == nil {
+		logMessage(d.cfg.S3, aws.LogDebug, fmt.Sprintf(
+			"DEBUG: object %s ETag %s is not a checksum, not verified", aws.StringValue(d.in.Key), etag))
+		return nil
+	}
+
+	total := aws.Int64Value(head.ContentLength)
+	sizes := []int64{total}
+	if len(match[2]) != 0 {
+		count, err := strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			return awserr.New("ReadChecksum", "invalid object ETag part count", err)
+		}
+		if sizes, err = d.partSizes(total, count); err != nil {
+			return err
+		}
+	}
+
+	in := &s3.GetObjectInput{}
+	awsutil.Copy(in, d.in)
+	if in.IfMatch == nil {
+		in.IfMatch = head.ETag
+	}
+	d.in = in
+
+	d.checksum = newChecksumVerifier(etag, sizes)
+	d.w = &checksumWriterAt{w: d.w, v: d.checksum}
+
+	return nil
+}
+
+// headObjectInput returns the input of a HeadObject request for the part
+// number of the downloaded object, or the whole object if the part number is
+// zero.
+func (d *downloader) headObjectInput(partNumber int64) *s3.HeadObjectInput {
+	in := &s3.HeadObjectInput{
+		Bucket:               d.in.Bucket,
+		Key:                  d.in.Key,
+		VersionId:            d.in.VersionId,
+		IfMatch:              d.in.IfMatch,
+		RequestPayer:         d.in.RequestPayer,
+		SSECustomerAlgorithm: d.in.SSECustomerAlgorithm,
+		SSECustomerKey:       d.in.SSECustomerKey,
+		SSECustomerKeyMD5:    d.in.SSECustomerKeyMD5,
+	}
+	if partNumber != 0 {
+		in.PartNumber = aws.Int64(partNumber)
+	}
+	return in
+}
+
+// partSizes returns the sizes of the count parts the object of the total
+// size was uploaded with, requesting the size of each part. Parts may have
+// any size, so their sizes cannot be inferred from the total size.
+func (d *downloader) partSizes(total, count int64) ([]int64, error) {
+	sizes := make([]int64, count)
+	var sum int64
+	for i := int64(1); i <= count; i++ {
+		head, err := d.cfg.S3.HeadObjectWithContext(d.ctx, d.headObjectInput(i), d.cfg.RequestOptions...)
+		if err != nil {
+			return nil, err
+		}
+		if n := aws.Int64Value(head.PartsCount); n != 0 && n != count {
+			return nil, awserr.New("ReadChecksum",
+				fmt.Sprintf("object has %d parts, ETag has %d", n, count), nil)
+		}
+		sizes[i-1] = aws.Int64Value(head.ContentLength)
+		sum += sizes[i-1]
+	}
+
+	if sum != total {
+		return nil, awserr.New("ReadChecksum",
+			fmt.Sprintf("object parts have %d bytes, object has %d", sum, total), nil)
+	}
+	return sizes, nil
+}
+
+// verifyChecksum returns a ChecksumMismatchError if the content downloaded
+// does not match the object's checksum.
+func (d *downloader) verifyChecksum() error {
+	actual := d.checksum.sum()
+	if actual == d.checksum.etag {
+		return nil
+	}
+
+	return &ChecksumMismatchError{
+		Bucket:   aws.StringValue(d.in.Bucket),
+		Key:      aws.StringValue(d.in.Key),
+		Expected: d.checksum.etag,
+		Actual:   actual,
+	}
+}
+
+// checksumWriterAt computes the checksum of the content written to the
+// io.WriterAt.
+type checksumWriterAt struct {
+	w io.WriterAt
+	v *checksumVerifier
+}
+
+func (w *checksumWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := w.w.WriteAt(p, off)
+	w.v.write(p[:n], off)
+	return n, err
+}
+
+// checksumVerifier computes the MD5 checksum of each part of an object, from
+// content written at any offset. Content written ahead of the content hashed
+// is buffered until the content before it is written, so up to the size of
+// the part may be buffered for each part.
+type checksumVerifier struct {
+	m     sync.Mutex
+	etag  string
+	total int64
+	parts []*partHasher
+}
+
+func newChecksumVerifier(etag string, sizes []int64) *checksumVerifier {
+	v := &checksumVerifier{etag: etag}
+	for _, size := range sizes {
+		v.parts = append(v.parts, &partHasher{
+			next:    v.total,
+			end:     v.total + size,
+			hash:    md5.New(),
+			pending: map[int64][]byte{},
+		})
+		v.total += size
+	}
+	return v
+}
+
+// write hashes the content written at the offset.
+func (v *checksumVerifier) write(p []byte, off int64) {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	i := sort.Search(len(v.parts), func(i int) bool { return v.parts[i].end > off })
+	for ; i < len(v.parts) && len(p) > 0; i++ {
+		part := v.parts[i]
+		n := int64(len(p))
+		if off+n > part.end {
+			n = part.end - off
+		}
+		part.write(p[:n], off)
+		p, off = p[n:], off+n
+	}
+}
+
+// sum returns the checksum of the content written, in the format of the
+// object's ETag.
+func (v *checksumVerifier) sum() string {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	if len(v.parts) == 1 && !strings.Contains(v.etag, "-") {
+		return hex.EncodeToString(v.parts[0].sum())
+	}
+
+	h := md5.New()
+	for _, part := range v.parts {
+		h.Write(part.sum())
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(h.Sum(nil)), len(v.parts))
+}
+
+// partHasher computes the MD5 checksum of a single part of an object, in
+// order.
+type partHasher struct {
+	next, end int64
+
+	hash    hash.Hash
+	pending map[int64][]byte
+}
+
+// write hashes the content if it is next, or buffers it until the content
+// before it is written. Content which was already hashed is ignored.
+func (h *partHasher) write(p []byte, off int64) {
+	if off > h.next {
+		if b, ok := h.pending[off]; !ok || len(b) < len(p) {
+			h.pending[off] = append([]byte{}, p...)
+		}
+		return
+	}
+
+	h.hashFrom(p, off)
+	for progressed := true; progressed; {
+		progressed = false
+		for o, b := range h.pending {
+			if o > h.next {
+				continue
+			}
+			delete(h.pending, o)
+			progressed = h.hashFrom(b, o) || progressed
+		}
+	}
+}
+
+// hashFrom hashes the content at the offset, which is not after the next
+// offset, from the next offset. Returns if any content was hashed.
+func (h *partHasher) hashFrom(p []byte, off int64) bool {
+	if off+int64(len(p)) <= h.next {
+		return false
+	}
+	p = p[h.next-off:]
+	h.hash.Write(p)
+	h.next += int64(len(p))
+	return true
+}
+
+// sum returns the MD5 checksum of the part. An incomplete part's checksum
+// is of the content hashed.
+func (h *partHasher) sum() []byte {
+	return h.hash.Sum(nil)
+}
@@ -0,0 +1,218 @@
+package s3manager_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// dlChecksumSvc returns a client serving the data as an object uploaded with
+// parts of the sizes, and the ETag. The names of the operations made are
+// recorded.
+func dlChecksumSvc(data []byte, etag string, partSizes []int, headers map[string]string) (*s3.S3, func() []string) {
+	var m sync.Mutex
+	names := []string{}
+
+	svc := s3.New(unit.Session)
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		m.Lock()
+		defer m.Unlock()
+
+		names = append(names, r.Operation.Name)
+
+		r.HTTPResponse = &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+			Header:     http.Header{},
+		}
+		r.HTTPResponse.Header.Set("ETag", `"`+etag+`"`)
+		for k, v := range headers {
+			r.HTTPResponse.Header.Set(k, v)
+		}
+
+		switch in := r.Params.(type) {
+		case *s3.HeadObjectInput:
+			size := len(data)
+			if in.PartNumber != nil {
+				size = partSizes[*in.PartNumber-1]
+				r.HTTPResponse.Header.Set("x-amz-mp-parts-count", strconv.Itoa(len(partSizes)))
+			}
+			r.HTTPResponse.Header.Set("Content-Length", strconv.Itoa(size))
+		case *s3.GetObjectInput:
+			if in.IfMatch != nil && `"`+etag+`"` != *in.IfMatch {
+				r.HTTPResponse.StatusCode = 412
+				return
+			}
+			rng := regexp.MustCompile(`bytes=(\d+)-(\d+)`).FindStringSubmatch(aws.StringValue(in.Range))
+			start, _ := strconv.Atoi(rng[1])
+			fin, _ := strconv.Atoi(rng[2])
+			fin++
+			if fin > len(data) {
+				fin = len(data)
+			}
+			r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader(data[start:fin]))
+			r.HTTPResponse.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d",
+				start, fin-1, len(data)))
+			r.HTTPResponse.Header.Set("Content-Length", strconv.Itoa(fin-start))
+		}
+	})
+
+	return svc, func() []string {
+		m.Lock()
+		defer m.Unlock()
+		return append([]string{}, names...)
+	}
+}
+
+// multipartETag returns the ETag of the data uploaded with parts of the sizes.
+func multipartETag(data []byte, partSizes []int) string {
+	h := md5.New()
+	for _, size := range partSizes {
+		sum := md5.Sum(data[:size])
+		h.Write(sum[:])
+		data = data[size:]
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(h.Sum(nil)), len(partSizes))
+}
+
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func downloadChecksum(svc *s3.S3) ([]byte, error) {
+	d := s3manager.NewDownloaderWithClient(svc, func(d *s3manager.Downloader) {
+		d.PartSize = 7
+		d.Concurrency = 3
+	}, s3manager.WithDownloaderChecksumVerification)
+
+	w := &aws.WriteAtBuffer{}
+	_, err := d.Download(w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	return w.Bytes(), err
+}
+
+func TestDownloadChecksum_SinglePart(t *testing.T) {
+	data := newStreamTestData(100)
+	svc, names := dlChecksumSvc(data, md5Hex(data), nil, nil)
+
+	b, err := downloadChecksum(svc)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if !bytes.Equal(data, b) {
+		t.Errorf("expect downloaded content to match")
+	}
+	if e, a := "HeadObject", names()[0]; e != a {
+		t.Errorf("expect %q first op, got %q", e, a)
+	}
+}
+
+func TestDownloadChecksum_Mismatch(t *testing.T) {
+	data := newStreamTestData(100)
+	svc, _ := dlChecksumSvc(data, md5Hex(data[1:]), nil, nil)
+
+	_, err := downloadChecksum(svc)
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	aerr, ok := err.(*s3manager.ChecksumMismatchError)
+	if !ok {
+		t.Fatalf("expect ChecksumMismatchError, got %T", err)
+	}
+	if e, a := s3manager.ErrCodeChecksumMismatch, aerr.Code(); e != a {
+		t.Errorf("expect %q code, got %q", e, a)
+	}
+	if e, a := md5Hex(data[1:]), aerr.Expected; e != a {
+		t.Errorf("expect %q expected checksum, got %q", e, a)
+	}
+	if e, a := md5Hex(data), aerr.Actual; e != a {
+		t.Errorf("expect %q actual checksum, got %q", e, a)
+	}
+}
+
+func TestDownloadChecksum_Multipart(t *testing.T) {
+	cases := map[string]struct {
+		partSizes []int
+		expectOps []string
+	}{
+		"uniform parts": {
+			partSizes: []int{30, 30, 30, 10},
+			expectOps: []string{"HeadObject", "HeadObject", "HeadObject", "HeadObject", "HeadObject", "GetObject"},
+		},
+		"irregular parts": {
+			partSizes: []int{40, 30, 30},
+			expectOps: []string{"HeadObject", "HeadObject", "HeadObject", "HeadObject", "GetObject"},
+		},
+		"non-uniform middle parts": {
+			partSizes: []int{25, 15, 35, 25},
+			expectOps: []string{"HeadObject", "HeadObject", "HeadObject", "HeadObject", "HeadObject", "GetObject"},
+		},
+	}
+
+	for name, c := range cases {
+		data := newStreamTestData(100)
+		svc, names := dlChecksumSvc(data, multipartETag(data, c.partSizes), c.partSizes, nil)
+
+		b, err := downloadChecksum(svc)
+		if err != nil {
+			t.Fatalf("%s, expect no error, got %v", name, err)
+		}
+		if !bytes.Equal(data, b) {
+			t.Errorf("%s, expect downloaded content to match", name)
+		}
+		if e, a := c.expectOps, names()[:len(c.expectOps)]; !reflect.DeepEqual(e, a) {
+			t.Errorf("%s, expect %v ops, got %v", name, e, a)
+		}
+	}
+}
+
+func TestDownloadChecksum_MultipartMismatch(t *testing.T) {
+	data := newStreamTestData(100)
+	partSizes := []int{30, 30, 30, 10}
+	corrupt := append([]byte{}, data...)
+	corrupt[50]++
+	svc, _ := dlChecksumSvc(data, multipartETag(corrupt, partSizes), partSizes, nil)
+
+	_, err := downloadChecksum(svc)
+	aerr, ok := err.(*s3manager.ChecksumMismatchError)
+	if !ok {
+		t.Fatalf("expect ChecksumMismatchError, got %T", err)
+	}
+	if e, a := multipartETag(data, partSizes), aerr.Actual; e != a {
+		t.Errorf("expect %q actual checksum, got %q", e, a)
+	}
+}
+
+func TestDownloadChecksum_SkipEncrypted(t *testing.T) {
+	data := newStreamTestData(100)
+	svc, _ := dlChecksumSvc(data, md5Hex(data[1:]), nil, map[string]string{
+		"x-amz-server-side-encryption": s3.ServerSideEncryptionAwsKms,
+	})
+
+	b, err := downloadChecksum(svc)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if !bytes.Equal(data, b) {
+		t.Errorf("expect downloaded content to match")
+	}
+}
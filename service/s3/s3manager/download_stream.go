@@ -0,0 +1,404 @@
+package s3manager
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// DownloadStream downloads an object in S3 using concurrent ranged GET
+// requests, returning an io.ReadCloser which reads the object's content in
+// order. The returned reader must be closed to release its resources, and
+// stop any requests in flight.
+//
+// Up to the Downloader's Concurrency parts of PartSize bytes are downloaded
+// ahead of the reader, and buffered in memory. Once the buffer is full, no
+// more parts are downloaded until the reader consumes the next part.
+//
+// If the body of a part fails to download, only the remaining bytes of the
+// part are downloaded again, without restarting the stream.
+//
+// If the GetObjectInput's Range value is provided that will cause the
+// downloader to perform a single GetObject request for that object's range,
+// returning the request's body.
+//
+// Example:
+//     body, err := downloader.DownloadStream(&s3.GetObjectInput{
+//         Bucket: aws.String("bucket"),
+//         Key:    aws.String("key.gz"),
+//     })
+//     if err != nil {
+//         return err
+//     }
+//     defer body.Close()
+//
+//     gz, err := gzip.NewReader(body)
+func (d Downloader) DownloadStream(input *s3.GetObjectInput, options ...func(*Downloader)) (io.ReadCloser, error) {
+	return d.DownloadStreamWithContext(aws.BackgroundContext(), input, options...)
+}
+
+// DownloadStreamWithContext is the same as DownloadStream with the additional
+// support for Context input parameters. The Context must not be nil. A nil
+// Context will cause a panic. Canceling the Context will cause the returned
+// reader to fail.
+func (d Downloader) DownloadStreamWithContext(ctx aws.Context, input *s3.GetObjectInput, options ...func(*Downloader)) (io.ReadCloser, error) {
+	impl := downloader{in: input, cfg: d}
+
+	for _, option := range options {
+		option(&impl.cfg)
+	}
+	impl.cfg.RequestOptions = append(impl.cfg.RequestOptions, request.WithAppendUserAgent("S3Manager"))
+
+	if s, ok := d.S3.(maxRetrier); ok {
+		impl.partBodyMaxRetries = s.MaxRetries()
+	}
+
+	impl.totalBytes = -1
+	if impl.cfg.Concurrency == 0 {
+		impl.cfg.Concurrency = DefaultDownloadConcurrency
+	}
+
+	if impl.cfg.PartSize == 0 {
+		impl.cfg.PartSize = DefaultDownloadPartSize
+	}
+
+	impl.progress = newProgressTracker(impl.cfg.ProgressListener)
+
+	if rng := aws.StringValue(input.Range); len(rng) > 0 {
+		return impl.streamRange(ctx, rng)
+	}
+
+	return newDownloadStream(ctx, &impl)
+}
+
+// streamRange returns the body of a single GetObject request for the range.
+func (d *downloader) streamRange(ctx aws.Context, rng string) (io.ReadCloser, error) {
+	in := &s3.GetObjectInput{}
+	awsutil.Copy(in, d.in)
+	in.Range = aws.String(rng)
+
+	progress := d.progress.part(1, -1)
+	progress.started()
+
+	resp, err := d.cfg.S3.GetObjectWithContext(ctx, in,
+		progress.requestOptions(d.cfg.RequestOptions, false)...)
+	if err != nil {
+		d.progress.completed(err)
+		return nil, err
+	}
+
+	if d.progress == nil {
+		return resp.Body, nil
+	}
+	d.setTotalBytes(resp)
+
+	return &rangeStreamReader{body: resp.Body, progress: progress, tracker: d.progress}, nil
+}
+
+// rangeStreamReader reports the progress of reading a ranged GetObject body.
+type rangeStreamReader struct {
+	body     io.ReadCloser
+	progress *partProgress
+	tracker  *progressTracker
+	done     bool
+}
+
+func (r *rangeStreamReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.progress.transferred(nil, int64(n))
+	if err != nil && !r.done {
+		r.done = true
+		if err == io.EOF {
+			r.progress.completed()
+			r.tracker.completed(nil)
+		} else {
+			r.tracker.completed(err)
+		}
+	}
+	return n, err
+}
+
+func (r *rangeStreamReader) Close() error {
+	return r.body.Close()
+}
+
+// streamPart is the result of downloading a single part of a stream.
+type streamPart struct {
+	buf      []byte
+	n        int
+	err      error
+	progress *partProgress
+}
+
+// downloadStream is an io.ReadCloser reading the parts of an object
+// downloaded concurrently, in order.
+type downloadStream struct {
+	d      *downloader
+	ctx    aws.Context
+	cancel func()
+
+	// parts is the ordered window of parts being downloaded. Each part's
+	// result is delivered on its own channel once downloaded.
+	parts chan chan streamPart
+
+	bufferPool sync.Pool
+
+	cur    streamPart
+	off    int
+	err    error
+	closed bool
+}
+
+// newDownloadStream downloads the first part of the object to determine its
+// size, and starts downloading the remaining parts.
+func newDownloadStream(ctx aws.Context, d *downloader) (*downloadStream, error) {
+	sctx, cancel := newStreamContext(ctx)
+
+	s := &downloadStream{
+		d:      d,
+		ctx:    sctx,
+		cancel: cancel,
+		parts:  make(chan chan streamPart, d.cfg.Concurrency),
+	}
+	s.bufferPool = sync.Pool{
+		New: func() interface{} { return make([]byte, d.cfg.PartSize) },
+	}
+
+	first := s.downloadPart(0)
+	if first.err != nil {
+		if isErrRangeNotSatisfiable(first.err) {
+			// The object is empty.
+			first = streamPart{}
+		} else {
+			cancel()
+			d.progress.completed(first.err)
+			return nil, first.err
+		}
+	}
+
+	ch := make(chan streamPart, 1)
+	ch <- first
+	s.parts <- ch
+
+	go s.queueParts()
+
+	return s, nil
+}
+
+// queueParts queues the download of the parts after the first part, in
+// order, until all parts are queued, or the stream is closed. Queuing blocks
+// while the window of parts is full.
+func (s *downloadStream) queueParts() {
+	defer close(s.parts)
+
+	total := s.d.getTotalBytes()
+	if total >= 0 && total <= s.d.cfg.PartSize {
+		return
+	}
+
+	var sem = make(chan struct{}, s.d.cfg.Concurrency)
+	for pos := s.d.cfg.PartSize; total < 0 || pos < total; pos += s.d.cfg.PartSize {
+		ch := make(chan streamPart, 1)
+		select {
+		case s.parts <- ch:
+		case <-s.ctx.Done():
+			return
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-s.ctx.Done():
+			ch <- streamPart{err: s.ctx.Err()}
+			return
+		}
+
+		go func(pos int64) {
+			defer func() { <-sem }()
+			ch <- s.downloadPart(pos)
+		}(pos)
+	}
+}
+
+// downloadPart downloads the part starting at pos into a buffer. If the body
+// of the part fails to download, only the remaining bytes of the part are
+// requested again.
+func (s *downloadStream) downloadPart(pos int64) streamPart {
+	d := s.d
+	size := d.cfg.PartSize
+	if total := d.getTotalBytes(); total >= 0 && total-pos < size {
+		size = total - pos
+	}
+
+	part := streamPart{
+		buf:      s.bufferPool.Get().([]byte),
+		progress: d.progress.part(pos/d.cfg.PartSize+1, size),
+	}
+	part.progress.started()
+	opts := part.progress.requestOptions(d.cfg.RequestOptions, false)
+
+	in := &s3.GetObjectInput{}
+	awsutil.Copy(in, d.in)
+
+	for retry := 0; ; retry++ {
+		in.Range = aws.String(fmt.Sprintf("bytes=%d-%d", pos+int64(part.n), pos+size-1))
+
+		resp, err := d.cfg.S3.GetObjectWithContext(s.ctx, in, opts...)
+		if err != nil {
+			part.err = err
+			return part
+		}
+		d.setTotalBytes(resp)
+		if total := d.getTotalBytes(); total >= 0 && total-pos < size {
+			size = total - pos
+		}
+
+		n, err := readFillBuf(resp.Body, part.buf[part.n:size])
+		resp.Body.Close()
+		part.n += n
+		part.progress.transferred(nil, int64(n))
+
+		if err == nil || err == io.EOF {
+			if int64(part.n) == size || d.getTotalBytes() < 0 {
+				part.progress.completed()
+				return part
+			}
+			err = io.ErrUnexpectedEOF
+		}
+
+		if retry >= d.partBodyMaxRetries {
+			part.err = err
+			return part
+		}
+		logMessage(d.cfg.S3, aws.LogDebugWithRequestRetries,
+			fmt.Sprintf("DEBUG: object part body download interrupted %s, err, %v, resuming at byte %d",
+				aws.StringValue(in.Key), err, pos+int64(part.n)))
+	}
+}
+
+// Read reads the content of the object, in order. Blocks until the next part
+// of the object is downloaded.
+func (s *downloadStream) Read(p []byte) (int, error) {
+	for s.off >= s.cur.n {
+		if s.err != nil {
+			return 0, s.err
+		}
+		s.nextPart()
+	}
+
+	n := copy(p, s.cur.buf[s.off:s.cur.n])
+	s.off += n
+
+	return n, nil
+}
+
+// nextPart waits for the next part of the object to be downloaded.
+func (s *downloadStream) nextPart() {
+	if s.cur.buf != nil {
+		s.bufferPool.Put(s.cur.buf)
+	}
+	s.cur, s.off = streamPart{}, 0
+
+	ch, ok := <-s.parts
+	if !ok {
+		s.finish(io.EOF)
+		return
+	}
+
+	part := <-ch
+	switch {
+	case part.err == nil:
+		s.cur = part
+	case s.d.getTotalBytes() < 0 && isErrRangeNotSatisfiable(part.err):
+		// The size of the object is not known, the object ends at the first
+		// part out of range.
+		s.finish(io.EOF)
+	default:
+		s.finish(part.err)
+	}
+}
+
+// finish ends the stream with the error, and stops any downloads in flight.
+func (s *downloadStream) finish(err error) {
+	s.err = err
+	s.cancel()
+
+	if err == io.EOF {
+		s.d.progress.completed(nil)
+	} else if !s.closed {
+		s.d.progress.completed(err)
+	}
+}
+
+// Close stops any downloads in flight. Reading from the stream after it is
+// closed returns an error.
+func (s *downloadStream) Close() error {
+	if s.err == nil {
+		s.closed = true
+		s.finish(awserr.New(request.CanceledErrorCode, "download stream closed", nil))
+	}
+	s.cur, s.off = streamPart{}, 0
+
+	return nil
+}
+
+func isErrRangeNotSatisfiable(err error) bool {
+	e, ok := err.(awserr.RequestFailure)
+	return ok && e.StatusCode() == http.StatusRequestedRangeNotSatisfiable
+}
+
+// streamContext is a Context which is canceled when the stream finishes, or
+// when its parent Context is canceled.
+type streamContext struct {
+	aws.Context
+
+	done chan struct{}
+	once sync.Once
+	m    sync.Mutex
+	err  error
+}
+
+func newStreamContext(parent aws.Context) (*streamContext, func()) {
+	ctx := &streamContext{Context: parent, done: make(chan struct{})}
+
+	go func() {
+		select {
+		case <-parent.Done():
+			ctx.cancel(parent.Err())
+		case <-ctx.done:
+		}
+	}()
+
+	return ctx, func() {
+		ctx.cancel(errStreamContextCanceled)
+	}
+}
+
+func (c *streamContext) cancel(err error) {
+	c.once.Do(func() {
+		c.m.Lock()
+		c.err = err
+		c.m.Unlock()
+		close(c.done)
+	})
+}
+
+// Done returns a channel which is closed when the Context is canceled.
+func (c *streamContext) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the parent Context's error if it was canceled, Canceled if the
+// stream finished, or nil if the Context was not canceled.
+func (c *streamContext) Err() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.err
+}
@@ -0,0 +1,9 @@
+// +build !go1.7
+
+package s3manager
+
+import "errors"
+
+// errStreamContextCanceled is the error returned by a stream's Context once
+// the stream finishes, matching the stdlib's context.Canceled.
+var errStreamContextCanceled = errors.New("context canceled")
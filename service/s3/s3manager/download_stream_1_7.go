@@ -0,0 +1,9 @@
+// +build go1.7
+
+package s3manager
+
+import "context"
+
+// errStreamContextCanceled is the error returned by a stream's Context once
+// the stream finishes.
+var errStreamContextCanceled = context.Canceled
@@ -0,0 +1,30 @@
+// +build go1.7
+
+package s3manager
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStreamContextErr(t *testing.T) {
+	ctx, finish := newStreamContext(context.Background())
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	finish()
+	<-ctx.Done()
+	if e, a := context.Canceled, ctx.Err(); e != a {
+		t.Errorf("expect %v error, got %v", e, a)
+	}
+
+	parent, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	ctx, finish = newStreamContext(parent)
+	defer finish()
+	<-ctx.Done()
+	if e, a := context.DeadlineExceeded, ctx.Err(); e != a {
+		t.Errorf("expect %v error, got %v", e, a)
+	}
+}
@@ -0,0 +1,196 @@
+package s3manager_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+func newStreamTestData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+// dlStreamSvc returns a client serving ranges of the data. The body of the
+// first response for each of the failStarts range starts is cut short.
+func dlStreamSvc(data []byte, failStarts ...int64) (*s3.S3, func() []string) {
+	var m sync.Mutex
+	ranges := []string{}
+	failed := map[int64]bool{}
+
+	svc := s3.New(unit.Session)
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		m.Lock()
+		defer m.Unlock()
+
+		rng := aws.StringValue(r.Params.(*s3.GetObjectInput).Range)
+		ranges = append(ranges, rng)
+
+		match := regexp.MustCompile(`bytes=(\d+)-(\d+)`).FindStringSubmatch(rng)
+		start, _ := strconv.ParseInt(match[1], 10, 64)
+		fin, _ := strconv.ParseInt(match[2], 10, 64)
+		fin++
+		if fin > int64(len(data)) {
+			fin = int64(len(data))
+		}
+
+		var body io.Reader = bytes.NewReader(data[start:fin])
+		for _, s := range failStarts {
+			if s == start && !failed[s] {
+				failed[s] = true
+				body = &testErrReader{Buf: data[start : start+(fin-start)/2], Err: io.ErrUnexpectedEOF}
+			}
+		}
+
+		r.HTTPResponse = &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(body),
+			Header:     http.Header{},
+		}
+		r.HTTPResponse.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d",
+			start, fin-1, len(data)))
+		r.HTTPResponse.Header.Set("Content-Length", fmt.Sprintf("%d", fin-start))
+	})
+
+	return svc, func() []string {
+		m.Lock()
+		defer m.Unlock()
+		return append([]string{}, ranges...)
+	}
+}
+
+func TestDownloadStream(t *testing.T) {
+	data := newStreamTestData(10500)
+	s, ranges := dlStreamSvc(data)
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.PartSize = 1000
+		d.Concurrency = 3
+	})
+	body, err := d.DownloadStream(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer body.Close()
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if !bytes.Equal(data, b) {
+		t.Errorf("expect downloaded content to match")
+	}
+	if e, a := 11, len(ranges()); e != a {
+		t.Errorf("expect %d requests, got %d", e, a)
+	}
+}
+
+func TestDownloadStream_ResumePart(t *testing.T) {
+	data := newStreamTestData(3000)
+	s, ranges := dlStreamSvc(data, 1000)
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.PartSize = 1000
+		d.Concurrency = 1
+	})
+	body, err := d.DownloadStream(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer body.Close()
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if !bytes.Equal(data, b) {
+		t.Errorf("expect downloaded content to match")
+	}
+
+	expectRngs := []string{"bytes=0-999", "bytes=1000-1999", "bytes=1500-1999", "bytes=2000-2999"}
+	if e, a := expectRngs, ranges(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v ranges, got %v", e, a)
+	}
+}
+
+func TestDownloadStream_Empty(t *testing.T) {
+	s, _ := dlStreamSvc([]byte{})
+
+	d := s3manager.NewDownloaderWithClient(s)
+	body, err := d.DownloadStream(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer body.Close()
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 0, len(b); e != a {
+		t.Errorf("expect %d bytes, got %d", e, a)
+	}
+}
+
+func TestDownloadStream_Close(t *testing.T) {
+	data := newStreamTestData(100000)
+	s, ranges := dlStreamSvc(data)
+
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.PartSize = 1000
+		d.Concurrency = 2
+	})
+	body, err := d.DownloadStream(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	b := make([]byte, 1500)
+	if _, err := io.ReadFull(body, b); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if !bytes.Equal(data[:1500], b) {
+		t.Errorf("expect downloaded content to match")
+	}
+
+	if err := body.Close(); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if _, err := body.Read(b); err == nil {
+		t.Errorf("expect error reading closed stream, got none")
+	}
+
+	// Backpressure limits the parts downloaded ahead of the reader.
+	if a := len(ranges()); a > 6 {
+		t.Errorf("expect no more than 6 requests, got %d", a)
+	}
+}
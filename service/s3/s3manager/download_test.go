@@ -642,6 +642,109 @@ func TestDownload_WithFailure(t *testing.T) {
 	}
 }
 
+type progressRecorder struct {
+	events []s3manager.ProgressEvent
+}
+
+func (r *progressRecorder) OnProgress(e s3manager.ProgressEvent) {
+	r.events = append(r.events, e)
+}
+
+func (r *progressRecorder) count(typ s3manager.ProgressEventType) int {
+	var n int
+	for _, e := range r.events {
+		if e.Type == typ {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *progressRecorder) last() s3manager.ProgressEvent {
+	if len(r.events) == 0 {
+		return s3manager.ProgressEvent{}
+	}
+	return r.events[len(r.events)-1]
+}
+
+func TestDownloadProgress(t *testing.T) {
+	s, _, _ := dlLoggingSvc(buf12MB)
+
+	progress := &progressRecorder{}
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.Concurrency = 2
+	}, s3manager.WithDownloaderProgressListener(progress))
+
+	w := &aws.WriteAtBuffer{}
+	_, err := d.Download(w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	if e, a := 3, progress.count(s3manager.ProgressPartStarted); e != a {
+		t.Errorf("expect %d parts started, got %d", e, a)
+	}
+	if e, a := 3, progress.count(s3manager.ProgressPartCompleted); e != a {
+		t.Errorf("expect %d parts completed, got %d", e, a)
+	}
+
+	last := progress.last()
+	if e, a := s3manager.ProgressTransferCompleted, last.Type; e != a {
+		t.Errorf("expect %v last event, got %v", e, a)
+	}
+	if e, a := int64(len(buf12MB)), last.TransferredBytes; e != a {
+		t.Errorf("expect %d bytes transferred, got %d", e, a)
+	}
+	if e, a := int64(len(buf12MB)), last.TotalBytes; e != a {
+		t.Errorf("expect %d total bytes, got %d", e, a)
+	}
+}
+
+func TestDownloadProgress_PartBodyRetry(t *testing.T) {
+	s, _ := dlLoggingSvcWithErrReader([]testErrReader{
+		{Buf: []byte("ab"), Len: 3, Err: io.ErrUnexpectedEOF},
+		{Buf: []byte("123"), Len: 3, Err: io.EOF},
+	})
+
+	progress := &progressRecorder{}
+	d := s3manager.NewDownloaderWithClient(s, func(d *s3manager.Downloader) {
+		d.Concurrency = 1
+		d.ProgressListener = progress
+	})
+
+	w := &aws.WriteAtBuffer{}
+	_, err := d.Download(w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	var retried []s3manager.ProgressEvent
+	for _, e := range progress.events {
+		if e.Type == s3manager.ProgressPartRetried {
+			retried = append(retried, e)
+		}
+	}
+	if e, a := 1, len(retried); e != a {
+		t.Fatalf("expect %d retried events, got %d", e, a)
+	}
+	if e, a := int64(-2), retried[0].Bytes; e != a {
+		t.Errorf("expect %d bytes discarded, got %d", e, a)
+	}
+	if retried[0].Err == nil {
+		t.Errorf("expect retried event error, got none")
+	}
+
+	if e, a := int64(3), progress.last().TransferredBytes; e != a {
+		t.Errorf("expect %d bytes transferred, got %d", e, a)
+	}
+}
+
 type testErrReader struct {
 	Buf []byte
 	Err error
@@ -15,9 +15,19 @@ import (
 	"github.com/aws/aws-sdk-go/awstesting/integration/s3integ"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3test"
 )
 
 func init() {
+	if len(os.Getenv("AWS_S3_INTEG_OFFLINE")) != 0 {
+		// Run the integration tests against an in-memory S3 server, without
+		// making requests to S3.
+		srv := s3test.NewServer()
+		srv.Handler.Region = "us-west-2"
+		integSess = session.Must(session.NewSession(srv.Config()))
+		return
+	}
+
 	integSess = integration.SessionWithDefaultRegion("us-west-2")
 }
 
@@ -0,0 +1,331 @@
+package s3manager
+
+import (
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// ProgressEventType is the type of a progress event emitted by the Uploader
+// and Downloader.
+type ProgressEventType int
+
+const (
+	// ProgressPartStarted is emitted when the transfer of a part starts.
+	ProgressPartStarted ProgressEventType = iota + 1
+
+	// ProgressBytesTransferred is emitted when bytes of a part are sent or
+	// received. The event's Bytes is the number of bytes transferred.
+	ProgressBytesTransferred
+
+	// ProgressPartCompleted is emitted when the transfer of a part completes.
+	ProgressPartCompleted
+
+	// ProgressPartRetried is emitted when the transfer of a part is retried.
+	// The event's Bytes is the negative number of bytes transferred by the
+	// failed attempt, which are discarded from the transferred bytes.
+	ProgressPartRetried
+
+	// ProgressTransferCompleted is emitted when the whole transfer completes.
+	ProgressTransferCompleted
+
+	// ProgressTransferFailed is emitted when the whole transfer fails. The
+	// event's Err is the error the transfer failed with.
+	ProgressTransferFailed
+)
+
+// String returns the string representation of the progress event type.
+func (t ProgressEventType) String() string {
+	switch t {
+	case ProgressPartStarted:
+		return "PartStarted"
+	case ProgressBytesTransferred:
+		return "BytesTransferred"
+	case ProgressPartCompleted:
+		return "PartCompleted"
+	case ProgressPartRetried:
+		return "PartRetried"
+	case ProgressTransferCompleted:
+		return "TransferCompleted"
+	case ProgressTransferFailed:
+		return "TransferFailed"
+	default:
+		return "Unknown"
+	}
+}
+
+// ProgressEvent is the progress of an upload or download.
+type ProgressEvent struct {
+	// The type of the event.
+	Type ProgressEventType
+
+	// The number of the part the event is for, starting at 1. Zero for
+	// ProgressTransferCompleted and ProgressTransferFailed events.
+	PartNumber int64
+
+	// The size, in bytes, of the part the event is for. -1 if the size of
+	// the part is not known.
+	PartSize int64
+
+	// The number of bytes the event changed TransferredBytes by.
+	Bytes int64
+
+	// The total number of bytes transferred by all parts.
+	TransferredBytes int64
+
+	// The total size, in bytes, of the object being transferred. -1 if the
+	// total size is not known yet.
+	TotalBytes int64
+
+	// The error a part was retried for, or the transfer failed with.
+	Err error
+}
+
+// A ProgressListener receives the progress events of an upload or download.
+//
+// Events are delivered one at a time, in the order they occurred, from the
+// goroutines transferring the parts. The listener must not block, as it
+// delays the transfer.
+type ProgressListener interface {
+	OnProgress(ProgressEvent)
+}
+
+// ProgressListenerFunc is a function which satisfies the ProgressListener
+// interface.
+type ProgressListenerFunc func(ProgressEvent)
+
+// OnProgress calls the function with the progress event.
+func (fn ProgressListenerFunc) OnProgress(e ProgressEvent) {
+	fn(e)
+}
+
+// WithUploaderProgressListener sets the Uploader's progress listener.
+func WithUploaderProgressListener(l ProgressListener) func(*Uploader) {
+	return func(u *Uploader) {
+		u.ProgressListener = l
+	}
+}
+
+// WithDownloaderProgressListener sets the Downloader's progress listener.
+func WithDownloaderProgressListener(l ProgressListener) func(*Downloader) {
+	return func(d *Downloader) {
+		d.ProgressListener = l
+	}
+}
+
+// progressTracker tracks the progress of a single upload or download,
+// emitting events to the listener. A nil progressTracker emits no events.
+type progressTracker struct {
+	m           sync.Mutex
+	listener    ProgressListener
+	transferred int64
+	total       int64
+}
+
+func newProgressTracker(l ProgressListener) *progressTracker {
+	if l == nil {
+		return nil
+	}
+	return &progressTracker{listener: l, total: -1}
+}
+
+// setTotal sets the total size of the object being transferred.
+func (p *progressTracker) setTotal(n int64) {
+	if p == nil {
+		return
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.total = n
+}
+
+// completed emits the completion, or failure, of the transfer.
+func (p *progressTracker) completed(err error) {
+	if p == nil {
+		return
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	e := ProgressEvent{Type: ProgressTransferCompleted, Err: err}
+	if err != nil {
+		e.Type = ProgressTransferFailed
+	} else if p.total < 0 {
+		p.total = p.transferred
+	}
+	p.notify(e)
+}
+
+// part returns the progress of the part number of the transfer.
+func (p *progressTracker) part(num, size int64) *partProgress {
+	if p == nil {
+		return nil
+	}
+	return &partProgress{tracker: p, num: num, size: size}
+}
+
+// notify emits the event to the listener. Must be called with the lock held.
+func (p *progressTracker) notify(e ProgressEvent) {
+	p.transferred += e.Bytes
+	e.TransferredBytes = p.transferred
+	e.TotalBytes = p.total
+
+	p.listener.OnProgress(e)
+}
+
+// partProgress tracks the progress of a single part of a transfer. The bytes
+// transferred by an attempt of the part are discarded when it is retried. A
+// nil partProgress emits no events.
+type partProgress struct {
+	tracker *progressTracker
+	num     int64
+	size    int64
+
+	attempt *progressAttempt
+	lastErr error
+}
+
+// progressAttempt is the bytes transferred by a single attempt of a part.
+// Once done, the attempt no longer counts bytes transferred.
+type progressAttempt struct {
+	bytes int64
+	done  bool
+}
+
+func (p *partProgress) emit(typ ProgressEventType) {
+	if p == nil {
+		return
+	}
+
+	p.tracker.m.Lock()
+	defer p.tracker.m.Unlock()
+
+	p.tracker.notify(ProgressEvent{Type: typ, PartNumber: p.num, PartSize: p.size})
+}
+
+// started emits the start of the part.
+func (p *partProgress) started() { p.emit(ProgressPartStarted) }
+
+// completed emits the completion of the part.
+func (p *partProgress) completed() { p.emit(ProgressPartCompleted) }
+
+// currentAttempt returns the attempt bytes are being transferred by. Must be
+// called with the tracker's lock held.
+func (p *partProgress) currentAttempt() *progressAttempt {
+	if p.attempt == nil {
+		p.attempt = &progressAttempt{}
+	}
+	return p.attempt
+}
+
+// transferred emits the bytes transferred by the attempt. Bytes transferred
+// by an attempt which was retried are ignored.
+func (p *partProgress) transferred(a *progressAttempt, n int64) {
+	if p == nil || n == 0 {
+		return
+	}
+
+	p.tracker.m.Lock()
+	defer p.tracker.m.Unlock()
+
+	if a == nil {
+		a = p.currentAttempt()
+	}
+	if a.done {
+		return
+	}
+	a.bytes += n
+
+	p.tracker.notify(ProgressEvent{
+		Type:       ProgressBytesTransferred,
+		PartNumber: p.num,
+		PartSize:   p.size,
+		Bytes:      n,
+	})
+}
+
+// retried emits the retry of the part, discarding the bytes transferred by
+// the current attempt.
+func (p *partProgress) retried(err error) {
+	if p == nil {
+		return
+	}
+
+	p.tracker.m.Lock()
+	defer p.tracker.m.Unlock()
+
+	a := p.currentAttempt()
+	a.done = true
+	p.attempt = nil
+
+	p.tracker.notify(ProgressEvent{
+		Type:       ProgressPartRetried,
+		PartNumber: p.num,
+		PartSize:   p.size,
+		Bytes:      -a.bytes,
+		Err:        err,
+	})
+}
+
+// requestOptions returns the request options with the part's progress
+// handlers added. If countBody is set the bytes of the request's body sent are
+// counted as transferred.
+func (p *partProgress) requestOptions(opts []request.Option, countBody bool) []request.Option {
+	if p == nil {
+		return opts
+	}
+
+	return append(opts[:len(opts):len(opts)], func(r *request.Request) {
+		if countBody {
+			r.Handlers.Send.PushFrontNamed(request.NamedHandler{
+				Name: "s3manager.ProgressBodyHandler",
+				Fn:   p.wrapBody,
+			})
+		}
+		r.Handlers.CompleteAttempt.PushBackNamed(request.NamedHandler{
+			Name: "s3manager.ProgressAttemptHandler",
+			Fn: func(r *request.Request) {
+				p.lastErr = r.Error
+			},
+		})
+		r.Handlers.AfterRetry.PushBackNamed(request.NamedHandler{
+			Name: "s3manager.ProgressRetryHandler",
+			Fn: func(r *request.Request) {
+				if r.Error == nil && aws.BoolValue(r.Retryable) {
+					p.retried(p.lastErr)
+				}
+			},
+		})
+	})
+}
+
+// wrapBody wraps the body of the request's attempt to count the bytes sent.
+func (p *partProgress) wrapBody(r *request.Request) {
+	if r.HTTPRequest.Body == nil || r.HTTPRequest.ContentLength <= 0 {
+		return
+	}
+
+	p.tracker.m.Lock()
+	a := p.currentAttempt()
+	p.tracker.m.Unlock()
+
+	r.HTTPRequest.Body = &progressReader{ReadCloser: r.HTTPRequest.Body, part: p, attempt: a}
+}
+
+// progressReader counts the bytes read from the body of a part's attempt.
+type progressReader struct {
+	io.ReadCloser
+	part    *partProgress
+	attempt *progressAttempt
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.part.transferred(r.attempt, int64(n))
+	return n, err
+}
@@ -22,6 +22,15 @@ type DownloadWithIterator interface {
 	DownloadWithIterator(aws.Context, s3manager.BatchDownloadIterator, ...func(*s3manager.Downloader)) error
 }
 
+var _ DownloadStream = (*s3manager.Downloader)(nil)
+
+// DownloadStream is the interface for downloading an object from S3 as an
+// in order stream using the S3 download manager.
+type DownloadStream interface {
+	DownloadStream(*s3.GetObjectInput, ...func(*s3manager.Downloader)) (io.ReadCloser, error)
+	DownloadStreamWithContext(aws.Context, *s3.GetObjectInput, ...func(*s3manager.Downloader)) (io.ReadCloser, error)
+}
+
 var _ UploaderAPI = (*s3manager.Uploader)(nil)
 var _ UploadWithIterator = (*s3manager.Uploader)(nil)
 
@@ -37,6 +46,22 @@ type UploadWithIterator interface {
 	UploadWithIterator(aws.Context, s3manager.BatchUploadIterator, ...func(*s3manager.Uploader)) error
 }
 
+var _ ResumeUpload = (*s3manager.Uploader)(nil)
+
+// ResumeUpload is the interface for resuming a failed multipart upload using
+// the S3 upload manager.
+type ResumeUpload interface {
+	Resume(aws.Context, string, *s3manager.UploadInput, ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)
+}
+
+var _ SyncerAPI = (*s3manager.Syncer)(nil)
+
+// SyncerAPI is the interface type for s3manager.Syncer.
+type SyncerAPI interface {
+	SyncDirectory(*s3manager.SyncDirectoryInput, ...func(*s3manager.Syncer)) (*s3manager.SyncDirectoryOutput, error)
+	SyncDirectoryWithContext(aws.Context, *s3manager.SyncDirectoryInput, ...func(*s3manager.Syncer)) (*s3manager.SyncDirectoryOutput, error)
+}
+
 var _ BatchDelete = (*s3manager.BatchDelete)(nil)
 
 // BatchDelete is the interface type for batch deleting objects from S3 using
@@ -44,3 +69,11 @@ var _ BatchDelete = (*s3manager.BatchDelete)(nil)
 type BatchDelete interface {
 	Delete(aws.Context, s3manager.BatchDeleteIterator) error
 }
+
+var _ CopierAPI = (*s3manager.Copier)(nil)
+
+// CopierAPI is the interface type for s3manager.Copier.
+type CopierAPI interface {
+	Copy(*s3.CopyObjectInput, ...func(*s3manager.Copier)) (*s3manager.CopyOutput, error)
+	CopyWithContext(aws.Context, *s3.CopyObjectInput, ...func(*s3manager.Copier)) (*s3manager.CopyOutput, error)
+}
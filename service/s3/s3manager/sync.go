@@ -0,0 +1,744 @@
+package s3manager
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// SyncDirection is the direction objects are transferred in when syncing a
+// local directory with an S3 prefix.
+type SyncDirection string
+
+const (
+	// SyncUpload uploads files from the local directory to the S3 prefix.
+	SyncUpload SyncDirection = "upload"
+
+	// SyncDownload downloads objects from the S3 prefix to the local directory.
+	SyncDownload SyncDirection = "download"
+)
+
+// SyncCompareMode is how a local file and an S3 object are compared to
+// determine if the file or object needs to be transferred.
+type SyncCompareMode string
+
+const (
+	// SyncCompareSizeAndModTime transfers a file or object if the sizes
+	// differ, or if the source was modified after the destination.
+	SyncCompareSizeAndModTime SyncCompareMode = "size-and-modtime"
+
+	// SyncCompareChecksum transfers a file or object if the sizes differ, or
+	// if the MD5 checksum of the local file does not match the ETag of the S3
+	// object. Objects uploaded with multipart upload do not have an MD5 ETag,
+	// and are compared by size and modification time instead.
+	SyncCompareChecksum SyncCompareMode = "checksum"
+)
+
+// SyncActionType is the type of a sync action.
+type SyncActionType string
+
+const (
+	// SyncActionUpload is the action of uploading a local file to S3.
+	SyncActionUpload SyncActionType = "upload"
+
+	// SyncActionDownload is the action of downloading an S3 object to a local
+	// file.
+	SyncActionDownload SyncActionType = "download"
+
+	// SyncActionDelete is the action of deleting an extraneous local file, or
+	// S3 object, from the destination.
+	SyncActionDelete SyncActionType = "delete"
+)
+
+// SyncDirectoryInput provides the parameters for syncing a local directory
+// with an S3 prefix.
+type SyncDirectoryInput struct {
+	// The local directory to sync.
+	//
+	// LocalDir is a required field
+	LocalDir string
+
+	// The bucket to sync.
+	//
+	// Bucket is a required field
+	Bucket *string
+
+	// The prefix of the keys to sync. The key of an object is the prefix
+	// followed by the file's slash separated path relative to LocalDir. The
+	// prefix should end with "/" unless the files should share a key prefix.
+	Prefix *string
+
+	// The direction files and objects are transferred in.
+	//
+	// Direction is a required field
+	Direction SyncDirection
+
+	// How files and objects are compared. Defaults to
+	// SyncCompareSizeAndModTime.
+	CompareMode SyncCompareMode
+
+	// Delete files, or objects, in the destination which do not exist in the
+	// source. Setting DeleteExtraneous mirrors the source to the destination.
+	//
+	// Extraneous S3 objects are deleted with the Syncer's BatchDelete.
+	DeleteExtraneous bool
+
+	// Glob patterns, as supported by path.Match, of the relative slash
+	// separated paths to sync. If set, only paths matching at least one
+	// pattern are synced. A pattern without a "/" is also matched against
+	// the last element of the path, so "*.txt" matches "a/b.txt".
+	Include []string
+
+	// Glob patterns, as supported by path.Match, of the relative slash
+	// separated paths not to sync, matched as for Include. Exclude takes
+	// precedence over Include.
+	Exclude []string
+
+	// Only report the actions which would be taken, without transferring or
+	// deleting any files or objects.
+	DryRun bool
+}
+
+// SyncAction is an action taken, or which would be taken in a dry run, to
+// sync a single file or object.
+type SyncAction struct {
+	// The type of the action.
+	Type SyncActionType
+
+	// The key of the S3 object.
+	Key string
+
+	// The path of the local file.
+	Path string
+
+	// The size, in bytes, of the file or object transferred. Zero for deletes.
+	Size int64
+
+	// The reason the action is taken. e.g. "missing", "size differs",
+	// "modified", "checksum differs", or "extraneous".
+	Reason string
+}
+
+// SyncDirectoryOutput is the report of a sync. If the sync failed for some
+// files or objects, the report still contains all actions attempted.
+type SyncDirectoryOutput struct {
+	// The actions taken, or which would be taken in a dry run, sorted by key.
+	Actions []SyncAction
+}
+
+// The Syncer structure that calls SyncDirectory(). It is safe to call
+// SyncDirectory() on this structure across concurrent goroutines. Mutating
+// the Syncer's properties is not safe to be done concurrently.
+type Syncer struct {
+	// The client to use for listing and deleting objects.
+	S3 s3iface.S3API
+
+	// The Uploader used to upload files. The Uploader's Concurrency bounds
+	// the total number of parts uploaded in parallel, across all files.
+	Uploader *Uploader
+
+	// The Downloader used to download objects. The Downloader's Concurrency
+	// bounds the total number of parts downloaded in parallel, across all
+	// objects.
+	Downloader *Downloader
+
+	// The BatchDelete used to delete extraneous objects.
+	BatchDelete *BatchDelete
+}
+
+// NewSyncer creates a new Syncer instance to sync local directories with
+// S3. Pass in additional functional options to customize the syncer's
+// behavior. Requires a client.ConfigProvider in order to create a S3 service
+// client. The session.Session satisfies the client.ConfigProvider interface.
+//
+// Example:
+//     // The session the S3 Syncer will use
+//     sess := session.Must(session.NewSession())
+//
+//     // Create a syncer with the session and default options
+//     syncer := s3manager.NewSyncer(sess)
+func NewSyncer(c client.ConfigProvider, options ...func(*Syncer)) *Syncer {
+	return NewSyncerWithClient(s3.New(c), options...)
+}
+
+// NewSyncerWithClient creates a new Syncer instance to sync local directories
+// with S3. Pass in additional functional options to customize the syncer's
+// behavior. Requires a S3 service client to make S3 API calls.
+//
+// Example:
+//     // Create a syncer with the s3 client and custom options
+//     syncer := s3manager.NewSyncerWithClient(s3Svc, func(s *s3manager.Syncer) {
+//          s.Uploader.Concurrency = 10
+//     })
+func NewSyncerWithClient(svc s3iface.S3API, options ...func(*Syncer)) *Syncer {
+	s := &Syncer{
+		S3:          svc,
+		Uploader:    NewUploaderWithClient(svc),
+		Downloader:  NewDownloaderWithClient(svc),
+		BatchDelete: NewBatchDeleteWithClient(svc),
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// SyncDirectory syncs a local directory with an S3 prefix, transferring only
+// the files or objects which differ between the source and destination.
+//
+// If some files or objects fail to sync, the returned error is a BatchError
+// with an Error for each of them. Objects whose key would resolve to a path
+// outside of LocalDir are never synced, and are reported as failed.
+//
+// Example:
+//     report, err := syncer.SyncDirectory(&s3manager.SyncDirectoryInput{
+//         LocalDir:  "/backups",
+//         Bucket:    aws.String("myBucket"),
+//         Prefix:    aws.String("backups/"),
+//         Direction: s3manager.SyncUpload,
+//         Exclude:   []string{"*.tmp"},
+//         DryRun:    true,
+//     })
+func (s Syncer) SyncDirectory(input *SyncDirectoryInput, options ...func(*Syncer)) (*SyncDirectoryOutput, error) {
+	return s.SyncDirectoryWithContext(aws.BackgroundContext(), input, options...)
+}
+
+// SyncDirectoryWithContext is the same as SyncDirectory with the additional
+// support for Context input parameters. The Context must not be nil. A nil
+// Context will cause a panic.
+func (s Syncer) SyncDirectoryWithContext(ctx aws.Context, input *SyncDirectoryInput, options ...func(*Syncer)) (*SyncDirectoryOutput, error) {
+	for _, option := range options {
+		option(&s)
+	}
+
+	if err := validateSyncInput(input); err != nil {
+		return nil, err
+	}
+
+	locals, err := s.listLocalFiles(input)
+	if err != nil {
+		return nil, awserr.New("ReadLocalDir", "unable to list local directory", err)
+	}
+	remotes, err := s.listRemoteObjects(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := removeUnsafeObjects(input, remotes)
+
+	actions := planSync(input, locals, remotes)
+	out := &SyncDirectoryOutput{Actions: actions}
+	if !input.DryRun {
+		switch input.Direction {
+		case SyncUpload:
+			errs = append(errs, s.upload(ctx, input, actions)...)
+		case SyncDownload:
+			errs = append(errs, s.download(ctx, input, actions, remotes)...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return out, NewBatchError("BatchedSyncIncomplete", "some objects have failed to sync.", errs)
+	}
+	return out, nil
+}
+
+func validateSyncInput(input *SyncDirectoryInput) error {
+	if len(input.LocalDir) == 0 {
+		return awserr.New("ConfigError", "local directory is required to sync", nil)
+	}
+	if len(aws.StringValue(input.Bucket)) == 0 {
+		return awserr.New("ConfigError", "bucket is required to sync", nil)
+	}
+	switch input.Direction {
+	case SyncUpload, SyncDownload:
+	default:
+		return awserr.New("ConfigError",
+			fmt.Sprintf("invalid sync direction %q", input.Direction), nil)
+	}
+	switch input.CompareMode {
+	case "", SyncCompareSizeAndModTime, SyncCompareChecksum:
+	default:
+		return awserr.New("ConfigError",
+			fmt.Sprintf("invalid sync compare mode %q", input.CompareMode), nil)
+	}
+
+	for _, patterns := range [][]string{input.Include, input.Exclude} {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return awserr.New("ConfigError",
+					fmt.Sprintf("invalid sync pattern %q", p), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// syncIncluded returns if the relative path should be synced based on the
+// input's include and exclude patterns.
+func syncIncluded(input *SyncDirectoryInput, rel string) bool {
+	if len(input.Include) > 0 && !syncMatchAny(input.Include, rel) {
+		return false
+	}
+	return !syncMatchAny(input.Exclude, rel)
+}
+
+// syncMatchAny returns if the path matches any of the patterns. Patterns
+// without a "/" are also matched against the path's last element.
+func syncMatchAny(patterns []string, rel string) bool {
+	base := path.Base(rel)
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, rel); ok {
+			return true
+		}
+		if strings.Contains(p, "/") {
+			continue
+		}
+		if ok, _ := path.Match(p, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// syncFile is a local file, or S3 object, being synced keyed by its slash
+// separated path relative to the local directory or S3 prefix.
+type syncFile struct {
+	rel     string
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+// listLocalFiles walks the local directory returning the regular files
+// which should be synced.
+func (s *Syncer) listLocalFiles(input *SyncDirectoryInput) (map[string]syncFile, error) {
+	files := map[string]syncFile{}
+
+	if _, err := os.Stat(input.LocalDir); os.IsNotExist(err) && input.Direction == SyncDownload {
+		return files, nil
+	}
+
+	err := filepath.Walk(input.LocalDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(input.LocalDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !syncIncluded(input, rel) {
+			return nil
+		}
+
+		files[rel] = syncFile{rel: rel, size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+
+	return files, err
+}
+
+// listRemoteObjects lists the objects under the S3 prefix which should be
+// synced.
+func (s *Syncer) listRemoteObjects(ctx aws.Context, input *SyncDirectoryInput) (map[string]syncFile, error) {
+	files := map[string]syncFile{}
+	prefix := aws.StringValue(input.Prefix)
+
+	params := &s3.ListObjectsV2Input{
+		Bucket: input.Bucket,
+		Prefix: input.Prefix,
+	}
+	err := s.S3.ListObjectsV2PagesWithContext(ctx, params,
+		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, o := range page.Contents {
+				key := aws.StringValue(o.Key)
+				rel := strings.TrimPrefix(key, prefix)
+				// Skip the prefix itself, and directory placeholder objects.
+				if len(rel) == 0 || strings.HasSuffix(rel, "/") {
+					continue
+				}
+				if !syncIncluded(input, rel) {
+					continue
+				}
+
+				files[rel] = syncFile{
+					rel:     rel,
+					size:    aws.Int64Value(o.Size),
+					modTime: aws.TimeValue(o.LastModified),
+					etag:    strings.Trim(aws.StringValue(o.ETag), `"`),
+				}
+			}
+			return true
+		})
+
+	return files, err
+}
+
+// syncLocalPath returns the local path of the file relative to LocalDir, and
+// false if the path would resolve to outside of LocalDir.
+func syncLocalPath(input *SyncDirectoryInput, rel string) (string, bool) {
+	name := filepath.Join(input.LocalDir, filepath.FromSlash(rel))
+	r, err := filepath.Rel(filepath.Clean(input.LocalDir), name)
+	if err != nil || r == "." || r == ".." ||
+		strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return name, true
+}
+
+// removeUnsafeObjects removes the S3 objects whose key would resolve to a
+// path outside of LocalDir, such as "prefix/../../file", returning an error
+// for each of them.
+func removeUnsafeObjects(input *SyncDirectoryInput, remotes map[string]syncFile) []Error {
+	var rels []string
+	for rel := range remotes {
+		if _, ok := syncLocalPath(input, rel); !ok {
+			rels = append(rels, rel)
+		}
+	}
+	sort.Strings(rels)
+
+	var errs []Error
+	for _, rel := range rels {
+		delete(remotes, rel)
+		key := aws.StringValue(input.Prefix) + rel
+		errs = append(errs, newError(
+			awserr.New("InvalidSyncKey", fmt.Sprintf("key %s resolves to outside of the local directory", key), nil),
+			input.Bucket, aws.String(key)))
+	}
+	return errs
+}
+
+// planSync compares the source and destination files, returning the actions
+// needed to sync the destination with the source.
+func planSync(input *SyncDirectoryInput, locals, remotes map[string]syncFile) []SyncAction {
+	src, dst := locals, remotes
+	transfer := SyncActionUpload
+	if input.Direction == SyncDownload {
+		src, dst = remotes, locals
+		transfer = SyncActionDownload
+	}
+
+	var actions []SyncAction
+	for rel, sf := range src {
+		var reason string
+		if df, ok := dst[rel]; !ok {
+			reason = "missing"
+		} else {
+			reason = syncCompare(input, sf, df)
+		}
+		if len(reason) == 0 {
+			continue
+		}
+
+		// Objects which resolve to outside of LocalDir have been removed by
+		// removeUnsafeObjects.
+		name, _ := syncLocalPath(input, rel)
+		actions = append(actions, SyncAction{
+			Type:   transfer,
+			Key:    aws.StringValue(input.Prefix) + rel,
+			Path:   name,
+			Size:   sf.size,
+			Reason: reason,
+		})
+	}
+
+	if input.DeleteExtraneous {
+		for rel := range dst {
+			if _, ok := src[rel]; ok {
+				continue
+			}
+			name, _ := syncLocalPath(input, rel)
+			actions = append(actions, SyncAction{
+				Type:   SyncActionDelete,
+				Key:    aws.StringValue(input.Prefix) + rel,
+				Path:   name,
+				Reason: "extraneous",
+			})
+		}
+	}
+
+	sort.Sort(syncActions(actions))
+
+	return actions
+}
+
+// syncActions is a wrapper to make sync actions sortable by their key.
+type syncActions []SyncAction
+
+func (a syncActions) Len() int      { return len(a) }
+func (a syncActions) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a syncActions) Less(i, j int) bool {
+	if a[i].Key != a[j].Key {
+		return a[i].Key < a[j].Key
+	}
+	return a[i].Type < a[j].Type
+}
+
+// syncCompare returns the reason the source file needs to be transferred to
+// the destination, or an empty string if the files match.
+func syncCompare(input *SyncDirectoryInput, src, dst syncFile) string {
+	if src.size != dst.size {
+		return "size differs"
+	}
+
+	local, remote := src, dst
+	if input.Direction == SyncDownload {
+		local, remote = dst, src
+	}
+
+	// Multipart uploaded objects have an ETag which is not the MD5 of the
+	// object, and are compared by modification time.
+	if input.CompareMode == SyncCompareChecksum && len(remote.etag) > 0 && !strings.Contains(remote.etag, "-") {
+		sum, err := fileMD5(filepath.Join(input.LocalDir, filepath.FromSlash(local.rel)))
+		if err != nil || !strings.EqualFold(sum, remote.etag) {
+			return "checksum differs"
+		}
+		return ""
+	}
+
+	// S3 modification times have a precision of seconds.
+	if src.modTime.Truncate(time.Second).After(dst.modTime.Truncate(time.Second)) {
+		return "modified"
+	}
+	return ""
+}
+
+func fileMD5(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// syncLimit bounds the total number of parts transferred in parallel across
+// all of the files or objects being synced.
+type syncLimit struct {
+	cond  *sync.Cond
+	avail int
+}
+
+func newSyncLimit(n int) *syncLimit {
+	return &syncLimit{cond: sync.NewCond(&sync.Mutex{}), avail: n}
+}
+
+// acquire blocks until n parts are available.
+func (l *syncLimit) acquire(n int) {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+	for l.avail < n {
+		l.cond.Wait()
+	}
+	l.avail -= n
+}
+
+// release returns n parts to the limit.
+func (l *syncLimit) release(n int) {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+	l.avail += n
+	l.cond.Broadcast()
+}
+
+// syncParts returns the number of parts of the given size a transfer of size
+// bytes is split into, up to concurrency.
+func syncParts(size, partSize int64, concurrency int) int {
+	parts := (size + partSize - 1) / partSize
+	if parts < 1 {
+		return 1
+	}
+	if parts > int64(concurrency) {
+		return concurrency
+	}
+	return int(parts)
+}
+
+// syncEach calls fn for each action of the type, and returns the errors of
+// the failed actions. Actions are run in parallel, with fn given the number
+// of parts the action may transfer in parallel, so that no more than
+// concurrency parts are transferred in total.
+func syncEach(actions []SyncAction, typ SyncActionType, concurrency int, partSize int64, fn func(a SyncAction, parts int) error) []Error {
+	var (
+		wg   sync.WaitGroup
+		m    sync.Mutex
+		errs []Error
+	)
+
+	limit := newSyncLimit(concurrency)
+	for _, a := range actions {
+		if a.Type != typ {
+			continue
+		}
+
+		parts := syncParts(a.Size, partSize, concurrency)
+		limit.acquire(parts)
+
+		wg.Add(1)
+		go func(a SyncAction, parts int) {
+			defer wg.Done()
+			defer limit.release(parts)
+
+			if err := fn(a, parts); err != nil {
+				m.Lock()
+				errs = append(errs, newError(err, nil, aws.String(a.Key)))
+				m.Unlock()
+			}
+		}(a, parts)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// upload uploads the local files, and deletes the extraneous S3 objects.
+func (s *Syncer) upload(ctx aws.Context, input *SyncDirectoryInput, actions []SyncAction) []Error {
+	concurrency, partSize := s.Uploader.Concurrency, s.Uploader.PartSize
+	if concurrency <= 0 {
+		concurrency = DefaultUploadConcurrency
+	}
+	if partSize <= 0 {
+		partSize = DefaultUploadPartSize
+	}
+
+	errs := syncEach(actions, SyncActionUpload, concurrency, partSize, func(a SyncAction, parts int) error {
+		f, err := os.Open(a.Path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = s.Uploader.UploadWithContext(ctx, &UploadInput{
+			Bucket: input.Bucket,
+			Key:    aws.String(a.Key),
+			Body:   f,
+		}, func(u *Uploader) {
+			u.Concurrency = parts
+		})
+		return err
+	})
+	for i := range errs {
+		errs[i].Bucket = input.Bucket
+	}
+
+	var objects []BatchDeleteObject
+	for _, a := range actions {
+		if a.Type != SyncActionDelete {
+			continue
+		}
+		objects = append(objects, BatchDeleteObject{
+			Object: &s3.DeleteObjectInput{
+				Bucket: input.Bucket,
+				Key:    aws.String(a.Key),
+			},
+		})
+	}
+	if len(objects) > 0 {
+		err := s.BatchDelete.Delete(ctx, &DeleteObjectsIterator{Objects: objects})
+		if batchErr, ok := err.(*BatchError); ok {
+			errs = append(errs, batchErr.Errors...)
+		} else if err != nil {
+			errs = append(errs, newError(err, input.Bucket, nil))
+		}
+	}
+
+	return errs
+}
+
+// download downloads the S3 objects, and deletes the extraneous local files.
+// Downloaded files have their modification time set to the object's.
+func (s *Syncer) download(ctx aws.Context, input *SyncDirectoryInput, actions []SyncAction, remotes map[string]syncFile) []Error {
+	prefix := aws.StringValue(input.Prefix)
+
+	concurrency, partSize := s.Downloader.Concurrency, s.Downloader.PartSize
+	if concurrency <= 0 {
+		concurrency = DefaultDownloadConcurrency
+	}
+	if partSize <= 0 {
+		partSize = DefaultDownloadPartSize
+	}
+
+	errs := syncEach(actions, SyncActionDownload, concurrency, partSize, func(a SyncAction, parts int) error {
+		if err := s.downloadFile(ctx, input.Bucket, a, parts); err != nil {
+			return err
+		}
+		modTime := remotes[strings.TrimPrefix(a.Key, prefix)].modTime
+		return os.Chtimes(a.Path, modTime, modTime)
+	})
+	for i := range errs {
+		errs[i].Bucket = input.Bucket
+	}
+
+	for _, a := range actions {
+		if a.Type != SyncActionDelete {
+			continue
+		}
+		if err := os.Remove(a.Path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, newError(err, input.Bucket, aws.String(a.Key)))
+		}
+	}
+
+	return errs
+}
+
+// downloadFile downloads the object to a temporary file which replaces the
+// local file once the download completes. Up to parts parts of the object are
+// downloaded in parallel.
+func (s *Syncer) downloadFile(ctx aws.Context, bucket *string, a SyncAction, parts int) error {
+	dir := filepath.Dir(a.Path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(a.Path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = s.Downloader.DownloadWithContext(ctx, f, &s3.GetObjectInput{
+		Bucket: bucket,
+		Key:    aws.String(a.Key),
+	}, func(d *Downloader) {
+		d.Concurrency = parts
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), a.Path)
+}
@@ -0,0 +1,384 @@
+package s3manager
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type syncTestObject struct {
+	body    string
+	modTime time.Time
+	etag    string
+}
+
+// newSyncTestClient returns a client serving the objects, and recording the
+// names of the operations made, and the keys of the objects uploaded and
+// deleted.
+func newSyncTestClient(objects map[string]syncTestObject) (*s3.S3, *[]string, *[]string) {
+	var m sync.Mutex
+	var ops, keys []string
+
+	svc := s3.New(unit.Session)
+	svc.Handlers.Unmarshal.Clear()
+	svc.Handlers.UnmarshalMeta.Clear()
+	svc.Handlers.UnmarshalError.Clear()
+	svc.Handlers.Send.Clear()
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		m.Lock()
+		defer m.Unlock()
+
+		ops = append(ops, r.Operation.Name)
+		r.HTTPResponse = &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		}
+
+		switch data := r.Data.(type) {
+		case *s3.ListObjectsV2Output:
+			for k, o := range objects {
+				etag := o.etag
+				if len(etag) == 0 {
+					sum := md5.Sum([]byte(o.body))
+					etag = hex.EncodeToString(sum[:])
+				}
+				data.Contents = append(data.Contents, &s3.Object{
+					Key:          aws.String(k),
+					Size:         aws.Int64(int64(len(o.body))),
+					LastModified: aws.Time(o.modTime),
+					ETag:         aws.String(`"` + etag + `"`),
+				})
+			}
+		case *s3.GetObjectOutput:
+			key := aws.StringValue(r.Params.(*s3.GetObjectInput).Key)
+			body := objects[key].body
+			data.Body = ioutil.NopCloser(bytes.NewReader([]byte(body)))
+			data.ContentLength = aws.Int64(int64(len(body)))
+		case *s3.CreateMultipartUploadOutput:
+			data.UploadId = aws.String("UPLOAD-ID")
+		case *s3.PutObjectOutput:
+			keys = append(keys, aws.StringValue(r.Params.(*s3.PutObjectInput).Key))
+		case *s3.DeleteObjectsOutput:
+			for _, o := range r.Params.(*s3.DeleteObjectsInput).Delete.Objects {
+				keys = append(keys, "delete:"+aws.StringValue(o.Key))
+			}
+		}
+	})
+
+	return svc, &ops, &keys
+}
+
+func writeSyncTestFile(t *testing.T, dir, rel, body string, modTime time.Time) {
+	name := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if err := ioutil.WriteFile(name, []byte(body), 0644); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+}
+
+func TestSyncDirectory_Upload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "s3manager-sync")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	old := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeSyncTestFile(t, dir, "a.txt", "new file", old)
+	writeSyncTestFile(t, dir, "b.txt", "unchanged", old)
+	writeSyncTestFile(t, dir, "c.tmp", "excluded", old)
+	writeSyncTestFile(t, dir, "sub/f.tmp", "excluded", old)
+	writeSyncTestFile(t, dir, "sub/d.txt", "changed size", old)
+	writeSyncTestFile(t, dir, "sub/e.txt", "modified", old.Add(time.Hour))
+
+	svc, ops, keys := newSyncTestClient(map[string]syncTestObject{
+		"prefix/b.txt":     {body: "unchanged", modTime: old},
+		"prefix/sub/d.txt": {body: "changed", modTime: old},
+		"prefix/sub/e.txt": {body: "modified", modTime: old},
+		"prefix/extra.txt": {body: "extraneous", modTime: old},
+		"prefix/x.tmp":     {body: "excluded", modTime: old},
+	})
+	syncer := NewSyncerWithClient(svc)
+
+	input := &SyncDirectoryInput{
+		LocalDir:         dir,
+		Bucket:           aws.String("bucket"),
+		Prefix:           aws.String("prefix/"),
+		Direction:        SyncUpload,
+		DeleteExtraneous: true,
+		Exclude:          []string{"*.tmp"},
+		DryRun:           true,
+	}
+
+	expect := []SyncAction{
+		{Type: SyncActionUpload, Key: "prefix/a.txt", Path: filepath.Join(dir, "a.txt"), Size: 8, Reason: "missing"},
+		{Type: SyncActionDelete, Key: "prefix/extra.txt", Path: filepath.Join(dir, "extra.txt"), Reason: "extraneous"},
+		{Type: SyncActionUpload, Key: "prefix/sub/d.txt", Path: filepath.Join(dir, "sub", "d.txt"), Size: 12, Reason: "size differs"},
+		{Type: SyncActionUpload, Key: "prefix/sub/e.txt", Path: filepath.Join(dir, "sub", "e.txt"), Size: 8, Reason: "modified"},
+	}
+
+	out, err := syncer.SyncDirectory(input)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := expect, out.Actions; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v actions, got %v", e, a)
+	}
+	if e, a := []string{"ListObjectsV2"}, *ops; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v ops for dry run, got %v", e, a)
+	}
+
+	input.DryRun = false
+	out, err = syncer.SyncDirectory(input)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := expect, out.Actions; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v actions, got %v", e, a)
+	}
+
+	sort.Strings(*keys)
+	expectKeys := []string{"delete:prefix/extra.txt", "prefix/a.txt", "prefix/sub/d.txt", "prefix/sub/e.txt"}
+	if e, a := expectKeys, *keys; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v keys, got %v", e, a)
+	}
+}
+
+func TestSyncDirectory_Download(t *testing.T) {
+	dir, err := ioutil.TempDir("", "s3manager-sync")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	modTime := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeSyncTestFile(t, dir, "b.txt", "unchanged", modTime)
+	writeSyncTestFile(t, dir, "extra.txt", "extraneous", modTime)
+
+	svc, _, _ := newSyncTestClient(map[string]syncTestObject{
+		"a.txt":     {body: "new object", modTime: modTime},
+		"b.txt":     {body: "unchanged", modTime: modTime},
+		"sub/c.txt": {body: "nested", modTime: modTime},
+		"sub/":      {modTime: modTime},
+	})
+	syncer := NewSyncerWithClient(svc)
+
+	out, err := syncer.SyncDirectory(&SyncDirectoryInput{
+		LocalDir:         dir,
+		Bucket:           aws.String("bucket"),
+		Direction:        SyncDownload,
+		DeleteExtraneous: true,
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expect := []SyncAction{
+		{Type: SyncActionDownload, Key: "a.txt", Path: filepath.Join(dir, "a.txt"), Size: 10, Reason: "missing"},
+		{Type: SyncActionDelete, Key: "extra.txt", Path: filepath.Join(dir, "extra.txt"), Reason: "extraneous"},
+		{Type: SyncActionDownload, Key: "sub/c.txt", Path: filepath.Join(dir, "sub", "c.txt"), Size: 6, Reason: "missing"},
+	}
+	if e, a := expect, out.Actions; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v actions, got %v", e, a)
+	}
+
+	for rel, body := range map[string]string{"a.txt": "new object", "sub/c.txt": "nested"} {
+		name := filepath.Join(dir, filepath.FromSlash(rel))
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		if e, a := body, string(b); e != a {
+			t.Errorf("expect %q body, got %q", e, a)
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		if e, a := modTime, info.ModTime().UTC(); !e.Equal(a) {
+			t.Errorf("expect %v mod time, got %v", e, a)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "extra.txt")); !os.IsNotExist(err) {
+		t.Errorf("expect extraneous file to be deleted, got %v", err)
+	}
+}
+
+func TestSyncDirectory_Checksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "s3manager-sync")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	modTime := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeSyncTestFile(t, dir, "same.txt", "same", modTime.Add(time.Hour))
+	writeSyncTestFile(t, dir, "diff.txt", "abcd", modTime)
+	writeSyncTestFile(t, dir, "multi.txt", "abcd", modTime)
+
+	svc, _, _ := newSyncTestClient(map[string]syncTestObject{
+		"same.txt":  {body: "same", modTime: modTime},
+		"diff.txt":  {body: "efgh", modTime: modTime},
+		"multi.txt": {body: "efgh", modTime: modTime, etag: "0123-2"},
+	})
+	syncer := NewSyncerWithClient(svc)
+
+	out, err := syncer.SyncDirectory(&SyncDirectoryInput{
+		LocalDir:    dir,
+		Bucket:      aws.String("bucket"),
+		Direction:   SyncUpload,
+		CompareMode: SyncCompareChecksum,
+		DryRun:      true,
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expect := []SyncAction{
+		{Type: SyncActionUpload, Key: "diff.txt", Path: filepath.Join(dir, "diff.txt"), Size: 4, Reason: "checksum differs"},
+	}
+	if e, a := expect, out.Actions; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v actions, got %v", e, a)
+	}
+}
+
+func TestSyncDirectory_UnsafeKey(t *testing.T) {
+	root, err := ioutil.TempDir("", "s3manager-sync")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "a", "b")
+
+	modTime := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc, _, _ := newSyncTestClient(map[string]syncTestObject{
+		"prefix/ok.txt":            {body: "ok", modTime: modTime},
+		"prefix/../../escaped.txt": {body: "escaped", modTime: modTime},
+	})
+	syncer := NewSyncerWithClient(svc)
+
+	out, err := syncer.SyncDirectory(&SyncDirectoryInput{
+		LocalDir:         dir,
+		Bucket:           aws.String("bucket"),
+		Prefix:           aws.String("prefix/"),
+		Direction:        SyncDownload,
+		DeleteExtraneous: true,
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	batchErr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("expect BatchError, got %T", err)
+	}
+	if e, a := 1, len(batchErr.Errors); e != a {
+		t.Fatalf("expect %d errors, got %d", e, a)
+	}
+	if e, a := "prefix/../../escaped.txt", aws.StringValue(batchErr.Errors[0].Key); e != a {
+		t.Errorf("expect %q key, got %q", e, a)
+	}
+
+	expect := []SyncAction{
+		{Type: SyncActionDownload, Key: "prefix/ok.txt", Path: filepath.Join(dir, "ok.txt"), Size: 2, Reason: "missing"},
+	}
+	if e, a := expect, out.Actions; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v actions, got %v", e, a)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escaped.txt")); !os.IsNotExist(err) {
+		t.Errorf("expect no file outside of local dir, got %v", err)
+	}
+}
+
+func TestSyncDirectory_Concurrency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "s3manager-sync")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	modTime := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	body := string(make([]byte, 2*MinUploadPartSize))
+	for _, rel := range []string{"a", "b", "c", "d"} {
+		writeSyncTestFile(t, dir, rel, body, modTime)
+	}
+
+	svc, _, _ := newSyncTestClient(nil)
+
+	var m sync.Mutex
+	var inFlight, maxInFlight int
+	svc.Handlers.Send.PushFront(func(r *request.Request) {
+		if r.Operation.Name != "UploadPart" {
+			return
+		}
+		m.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		m.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		m.Lock()
+		inFlight--
+		m.Unlock()
+	})
+
+	syncer := NewSyncerWithClient(svc, func(s *Syncer) {
+		s.Uploader.Concurrency = 2
+		s.Uploader.PartSize = MinUploadPartSize
+	})
+
+	_, err = syncer.SyncDirectory(&SyncDirectoryInput{
+		LocalDir:  dir,
+		Bucket:    aws.String("bucket"),
+		Direction: SyncUpload,
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 2, maxInFlight; a > e {
+		t.Errorf("expect at most %d parts in flight, got %d", e, a)
+	}
+}
+
+func TestSyncDirectory_InvalidInput(t *testing.T) {
+	svc, ops, _ := newSyncTestClient(nil)
+	syncer := NewSyncerWithClient(svc)
+
+	cases := map[string]*SyncDirectoryInput{
+		"no local dir": {Bucket: aws.String("bucket"), Direction: SyncUpload},
+		"no bucket":    {LocalDir: "dir", Direction: SyncUpload},
+		"no direction": {LocalDir: "dir", Bucket: aws.String("bucket")},
+		"bad pattern":  {LocalDir: "dir", Bucket: aws.String("bucket"), Direction: SyncUpload, Include: []string{"["}},
+	}
+
+	for name, input := range cases {
+		if _, err := syncer.SyncDirectory(input); err == nil {
+			t.Errorf("%s, expect error, got none", name)
+		}
+	}
+	if e, a := 0, len(*ops); e != a {
+		t.Errorf("expect %d ops, got %d", e, a)
+	}
+}
@@ -2,9 +2,12 @@ package s3manager
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -13,6 +16,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/internal/sdkio"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 )
@@ -59,6 +63,63 @@ type MultiUploadFailure interface {
 	UploadID() string
 }
 
+// An UploadCheckpointer is a failed S3 multipart upload whose checkpoint can
+// be used to resume the upload with Uploader.Resume, if the parts of the
+// upload were not aborted. Errors returned by the Uploader which satisfy
+// MultiUploadFailure also satisfy this interface.
+//
+// Example:
+//
+//     u := s3manager.NewUploader(opts, func(u *s3manager.Uploader) {
+//         u.LeavePartsOnError = true
+//     })
+//     output, err := u.Upload(input)
+//     if cperr, ok := err.(s3manager.UploadCheckpointer); ok {
+//         // Save the checkpoint to resume the upload later with Resume.
+//         b, _ := json.Marshal(cperr.Checkpoint())
+//     }
+//
+type UploadCheckpointer interface {
+	MultiUploadFailure
+
+	// Returns the checkpoint of the S3 multipart upload that failed.
+	Checkpoint() UploadCheckpoint
+}
+
+// UploadCheckpoint is the serializable state of a multipart upload which
+// failed. The checkpoint can be saved, e.g. encoded as JSON, so that the
+// upload can be resumed by a restarted process with Uploader.Resume.
+//
+// The PartSize of the checkpoint must be used as the Uploader's PartSize when
+// resuming the upload so that the chunks of the body match the parts that
+// were already uploaded.
+type UploadCheckpoint struct {
+	// The bucket the object was being uploaded to.
+	Bucket string `json:"bucket"`
+
+	// The key the object was being uploaded to.
+	Key string `json:"key"`
+
+	// The ID of the multipart upload.
+	UploadID string `json:"uploadId"`
+
+	// The part size, in bytes, the body was split into.
+	PartSize int64 `json:"partSize"`
+
+	// The parts which were successfully uploaded, sorted by part number.
+	Parts []UploadCheckpointPart `json:"parts"`
+}
+
+// UploadCheckpointPart is a part of a multipart upload which was successfully
+// uploaded.
+type UploadCheckpointPart struct {
+	// The part number of the part.
+	PartNumber int64 `json:"partNumber"`
+
+	// The ETag returned when the part was uploaded.
+	ETag string `json:"etag"`
+}
+
 // So that the Error interface type can be included as an anonymous field
 // in the multiUploadError struct and not conflict with the error.Error() method.
 type awsError awserr.Error
@@ -73,6 +134,9 @@ type multiUploadError struct {
 
 	// ID for multipart upload which failed.
 	uploadID string
+
+	// Checkpoint of the multipart upload which failed.
+	checkpoint UploadCheckpoint
 }
 
 // Error returns the string representation of the error.
@@ -96,6 +160,11 @@ func (m multiUploadError) UploadID() string {
 	return m.uploadID
 }
 
+// Checkpoint returns the checkpoint of the S3 upload which failed.
+func (m multiUploadError) Checkpoint() UploadCheckpoint {
+	return m.checkpoint
+}
+
 // UploadOutput represents a response from the Upload() call.
 type UploadOutput struct {
 	// The URL where the object was uploaded to.
@@ -162,6 +231,10 @@ type Uploader struct {
 	// List of request options that will be passed down to individual API
 	// operation requests made by the uploader.
 	RequestOptions []request.Option
+
+	// The listener the progress of each upload is reported to. The bytes
+	// sent by a part which is retried are discarded from the progress.
+	ProgressListener ProgressListener
 }
 
 // NewUploader creates a new Uploader instance to upload objects to S3. Pass In
@@ -288,8 +361,52 @@ func (u Uploader) UploadWithContext(ctx aws.Context, input *UploadInput, opts ..
 		opt(&i.cfg)
 	}
 	i.cfg.RequestOptions = append(i.cfg.RequestOptions, request.WithAppendUserAgent("S3Manager"))
+	i.progress = newProgressTracker(i.cfg.ProgressListener)
 
-	return i.upload()
+	out, err := i.upload()
+	i.progress.completed(err)
+
+	return out, err
+}
+
+// Resume resumes a multipart upload which previously failed, uploading only
+// the parts which are missing from the upload, and completes the upload.
+//
+// The parts already uploaded are listed with ListParts. Each part's ETag is
+// compared to the MD5 checksum of the matching chunk of the input's Body, and
+// parts which do not match are uploaded again. Parts encrypted with SSE-KMS
+// or SSE-C do not have an MD5 ETag, and will always be uploaded again.
+//
+// The input's Body must implement io.ReadSeeker, be positioned at the same
+// offset, and contain the same content as the upload which failed. The
+// Uploader's PartSize and MaxUploadParts must be the same as the ones used
+// by the upload which failed, otherwise the chunks of the Body will not match
+// the parts which were uploaded. An UploadCheckpoint records the part size.
+//
+// Example:
+//     var cp s3manager.UploadCheckpoint
+//     json.Unmarshal(b, &cp)
+//
+//     result, err := uploader.Resume(ctx, cp.UploadID, &s3manager.UploadInput{
+//         Bucket: aws.String(cp.Bucket),
+//         Key:    aws.String(cp.Key),
+//         Body:   file,
+//     }, func(u *s3manager.Uploader) {
+//          u.PartSize = cp.PartSize
+//     })
+func (u Uploader) Resume(ctx aws.Context, uploadID string, input *UploadInput, opts ...func(*Uploader)) (*UploadOutput, error) {
+	i := uploader{in: input, cfg: u, ctx: ctx}
+
+	for _, opt := range opts {
+		opt(&i.cfg)
+	}
+	i.cfg.RequestOptions = append(i.cfg.RequestOptions, request.WithAppendUserAgent("S3Manager"))
+	i.progress = newProgressTracker(i.cfg.ProgressListener)
+
+	out, err := i.resume(uploadID)
+	i.progress.completed(err)
+
+	return out, err
 }
 
 // UploadWithIterator will upload a batched amount of objects to S3. This operation uses
@@ -358,6 +475,8 @@ type uploader struct {
 	totalSize int64 // set to -1 if the size is not known
 
 	bufferPool sync.Pool
+
+	progress *progressTracker
 }
 
 // internal logic for deciding whether to upload a single part or use a
@@ -384,6 +503,38 @@ func (u *uploader) upload() (*UploadOutput, error) {
 	return mu.upload(reader, part)
 }
 
+// resume continues the multipart upload uploadID, uploading the parts which
+// are missing or do not match the input's body.
+func (u *uploader) resume(uploadID string) (*UploadOutput, error) {
+	if len(uploadID) == 0 {
+		return nil, awserr.New("ConfigError", "upload id is required to resume an upload", nil)
+	}
+	if _, ok := u.in.Body.(io.ReadSeeker); !ok {
+		return nil, awserr.New("ConfigError", "body must implement io.ReadSeeker to resume an upload", nil)
+	}
+
+	if err := u.init(); err != nil {
+		return nil, awserr.New("ReadRequestBody", "unable to initialize upload", err)
+	}
+
+	if u.cfg.PartSize < MinUploadPartSize {
+		msg := fmt.Sprintf("part size must be at least %d bytes", MinUploadPartSize)
+		return nil, awserr.New("ConfigError", msg, nil)
+	}
+
+	mu := multiuploader{uploader: u, uploadID: uploadID}
+	if err := mu.listParts(); err != nil {
+		return nil, err
+	}
+
+	reader, _, part, err := u.nextReader()
+	if err != nil && err != io.EOF {
+		return nil, awserr.New("ReadRequestBody", "read upload data failed", err)
+	}
+
+	return mu.uploadParts(reader, part)
+}
+
 // init will initialize all default options.
 func (u *uploader) init() error {
 	if u.cfg.Concurrency == 0 {
@@ -401,7 +552,12 @@ func (u *uploader) init() error {
 	}
 
 	// Try to get the total size for some optimizations
-	return u.initSize()
+	if err := u.initSize(); err != nil {
+		return err
+	}
+	u.progress.setTotal(u.totalSize)
+
+	return nil
 }
 
 // initSize tries to detect the total stream size, setting u.totalSize. If
@@ -484,14 +640,19 @@ func (u *uploader) singlePart(buf io.ReadSeeker) (*UploadOutput, error) {
 	awsutil.Copy(params, u.in)
 	params.Body = buf
 
+	size, _ := aws.SeekerLen(buf)
+	progress := u.progress.part(1, size)
+	progress.started()
+
 	// Need to use request form because URL generated in request is
 	// used in return.
 	req, out := u.cfg.S3.PutObjectRequest(params)
 	req.SetContext(u.ctx)
-	req.ApplyOptions(u.cfg.RequestOptions...)
+	req.ApplyOptions(progress.requestOptions(u.cfg.RequestOptions, true)...)
 	if err := req.Send(); err != nil {
 		return nil, err
 	}
+	progress.completed()
 
 	url := req.HTTPRequest.URL.String()
 	return &UploadOutput{
@@ -508,6 +669,9 @@ type multiuploader struct {
 	err      error
 	uploadID string
 	parts    completedParts
+
+	// parts previously uploaded to the multipart upload being resumed.
+	existingParts map[int64]*s3.Part
 }
 
 // keeps track of a single chunk of data being sent to S3.
@@ -538,6 +702,37 @@ func (u *multiuploader) upload(firstBuf io.ReadSeeker, firstPart []byte) (*Uploa
 	}
 	u.uploadID = *resp.UploadId
 
+	return u.uploadParts(firstBuf, firstPart)
+}
+
+// listParts lists the parts previously uploaded to the multipart upload
+// being resumed.
+func (u *multiuploader) listParts() error {
+	u.existingParts = map[int64]*s3.Part{}
+
+	params := &s3.ListPartsInput{
+		Bucket:       u.in.Bucket,
+		Key:          u.in.Key,
+		UploadId:     &u.uploadID,
+		RequestPayer: u.in.RequestPayer,
+	}
+	return u.cfg.S3.ListPartsPagesWithContext(u.ctx, params,
+		func(page *s3.ListPartsOutput, lastPage bool) bool {
+			for _, p := range page.Parts {
+				if p.PartNumber != nil {
+					u.existingParts[*p.PartNumber] = p
+				}
+			}
+			return true
+		}, u.cfg.RequestOptions...)
+}
+
+// uploadParts uploads the parts of the multipart upload, starting with the
+// firstBuf buffer containing the first chunk of data, and completes the
+// upload.
+func (u *multiuploader) uploadParts(firstBuf io.ReadSeeker, firstPart []byte) (*UploadOutput, error) {
+	var err error
+
 	// Create the workers
 	ch := make(chan chunk, u.cfg.Concurrency)
 	for i := 0; i < u.cfg.Concurrency; i++ {
@@ -600,7 +795,8 @@ func (u *multiuploader) upload(firstBuf io.ReadSeeker, firstPart []byte) (*Uploa
 				"MultipartUpload",
 				"upload multipart failed",
 				err),
-			uploadID: u.uploadID,
+			uploadID:   u.uploadID,
+			checkpoint: u.checkpoint(),
 		}
 	}
 
@@ -640,8 +836,35 @@ func (u *multiuploader) readChunk(ch chan chunk) {
 }
 
 // send performs an UploadPart request and keeps track of the completed
-// part information.
+// part information. If the part was previously uploaded to the multipart
+// upload being resumed, and matches the chunk, the part is not uploaded again.
 func (u *multiuploader) send(c chunk) error {
+	size, _ := aws.SeekerLen(c.buf)
+	progress := u.progress.part(c.num, size)
+	progress.started()
+
+	if existing, ok := u.existingParts[c.num]; ok {
+		match, err := partMatches(c.buf, existing)
+		if err != nil {
+			u.bufferPool.Put(c.part)
+			return awserr.New("ReadRequestBody", "read multipart upload data failed", err)
+		}
+		if match {
+			u.bufferPool.Put(c.part)
+			progress.transferred(nil, size)
+			progress.completed()
+
+			n := c.num
+			completed := &s3.CompletedPart{ETag: existing.ETag, PartNumber: &n}
+
+			u.m.Lock()
+			u.parts = append(u.parts, completed)
+			u.m.Unlock()
+
+			return nil
+		}
+	}
+
 	params := &s3.UploadPartInput{
 		Bucket:               u.in.Bucket,
 		Key:                  u.in.Key,
@@ -651,12 +874,14 @@ func (u *multiuploader) send(c chunk) error {
 		SSECustomerKey:       u.in.SSECustomerKey,
 		PartNumber:           &c.num,
 	}
-	resp, err := u.cfg.S3.UploadPartWithContext(u.ctx, params, u.cfg.RequestOptions...)
+	resp, err := u.cfg.S3.UploadPartWithContext(u.ctx, params,
+		progress.requestOptions(u.cfg.RequestOptions, true)...)
 	// put the byte array back into the pool to conserve memory
 	u.bufferPool.Put(c.part)
 	if err != nil {
 		return err
 	}
+	progress.completed()
 
 	n := c.num
 	completed := &s3.CompletedPart{ETag: resp.ETag, PartNumber: &n}
@@ -668,6 +893,50 @@ func (u *multiuploader) send(c chunk) error {
 	return nil
 }
 
+// partMatches returns if the MD5 checksum of the chunk buf matches the ETag
+// of the part previously uploaded. The buf is rewound to its start.
+func partMatches(buf io.ReadSeeker, part *s3.Part) (bool, error) {
+	etag := strings.Trim(aws.StringValue(part.ETag), `"`)
+	if len(etag) == 0 {
+		return false, nil
+	}
+
+	h := md5.New()
+	if _, err := io.Copy(h, buf); err != nil {
+		return false, err
+	}
+	if _, err := buf.Seek(0, sdkio.SeekStart); err != nil {
+		return false, err
+	}
+
+	return strings.EqualFold(etag, hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// checkpoint returns the checkpoint of the multipart upload with the parts
+// which were successfully uploaded.
+func (u *multiuploader) checkpoint() UploadCheckpoint {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	sort.Sort(u.parts)
+
+	parts := make([]UploadCheckpointPart, 0, len(u.parts))
+	for _, p := range u.parts {
+		parts = append(parts, UploadCheckpointPart{
+			PartNumber: aws.Int64Value(p.PartNumber),
+			ETag:       aws.StringValue(p.ETag),
+		})
+	}
+
+	return UploadCheckpoint{
+		Bucket:   aws.StringValue(u.in.Bucket),
+		Key:      aws.StringValue(u.in.Key),
+		UploadID: u.uploadID,
+		PartSize: u.cfg.PartSize,
+		Parts:    parts,
+	}
+}
+
 // geterr is a thread-safe getter for the error object
 func (u *multiuploader) geterr() error {
 	u.m.Lock()
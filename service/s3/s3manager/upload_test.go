@@ -4,6 +4,9 @@ package s3manager_test
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -1036,6 +1039,148 @@ func TestUploadMaxPartsEOF(t *testing.T) {
 	}
 }
 
+func TestUploadFailureCheckpoint(t *testing.T) {
+	s, _, _ := loggingSvc(emptyList)
+	s.Handlers.Send.PushBack(func(r *request.Request) {
+		switch data := r.Data.(type) {
+		case *s3.UploadPartOutput:
+			if *data.ETag == "ETAG2" {
+				r.HTTPResponse.StatusCode = 400
+			}
+		}
+	})
+
+	mgr := s3manager.NewUploaderWithClient(s, func(u *s3manager.Uploader) {
+		u.Concurrency = 1
+		u.LeavePartsOnError = true
+	})
+	_, err := mgr.Upload(&s3manager.UploadInput{
+		Bucket: aws.String("Bucket"),
+		Key:    aws.String("Key"),
+		Body:   bytes.NewReader(buf12MB),
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+
+	merr, ok := err.(s3manager.UploadCheckpointer)
+	if !ok {
+		t.Fatalf("expect UploadCheckpointer, got %T", err)
+	}
+
+	b, err := json.Marshal(merr.Checkpoint())
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	var cp s3manager.UploadCheckpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expect := s3manager.UploadCheckpoint{
+		Bucket:   "Bucket",
+		Key:      "Key",
+		UploadID: "UPLOAD-ID",
+		PartSize: s3manager.DefaultUploadPartSize,
+		Parts: []s3manager.UploadCheckpointPart{
+			{PartNumber: 1, ETag: "ETAG1"},
+		},
+	}
+	if e, a := expect, cp; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v checkpoint, got %v", e, a)
+	}
+}
+
+func TestUploadResume(t *testing.T) {
+	sum := md5.Sum(buf12MB[:s3manager.DefaultUploadPartSize])
+
+	s, ops, args := loggingSvc(emptyList)
+	s.Handlers.Send.PushBack(func(r *request.Request) {
+		switch data := r.Data.(type) {
+		case *s3.ListPartsOutput:
+			data.Parts = []*s3.Part{
+				{PartNumber: aws.Int64(1), ETag: aws.String(`"` + hex.EncodeToString(sum[:]) + `"`)},
+				{PartNumber: aws.Int64(2), ETag: aws.String(`"mismatch"`)},
+			}
+		}
+	})
+
+	mgr := s3manager.NewUploaderWithClient(s, func(u *s3manager.Uploader) {
+		u.Concurrency = 1
+	})
+	resp, err := mgr.Resume(aws.BackgroundContext(), "UPLOAD-ID", &s3manager.UploadInput{
+		Bucket: aws.String("Bucket"),
+		Key:    aws.String("Key"),
+		Body:   bytes.NewReader(buf12MB),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	expectOps := []string{"ListParts", "UploadPart", "UploadPart", "CompleteMultipartUpload"}
+	if e, a := expectOps, *ops; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v ops, got %v", e, a)
+	}
+	if e, a := "UPLOAD-ID", resp.UploadID; e != a {
+		t.Errorf("expect %q upload ID, got %q", e, a)
+	}
+
+	if e, a := "UPLOAD-ID", val((*args)[0], "UploadId"); e != a {
+		t.Errorf("expect %q list parts upload ID, got %q", e, a)
+	}
+	for i, num := range []int64{2, 3} {
+		if e, a := num, val((*args)[i+1], "PartNumber"); e != a {
+			t.Errorf("expect %v part number, got %v", e, a)
+		}
+	}
+
+	parts := (*args)[3].(*s3.CompleteMultipartUploadInput).MultipartUpload.Parts
+	if e, a := 3, len(parts); e != a {
+		t.Fatalf("expect %d completed parts, got %d", e, a)
+	}
+	expectETags := []string{`"` + hex.EncodeToString(sum[:]) + `"`, "ETAG1", "ETAG2"}
+	for i, p := range parts {
+		if e, a := int64(i+1), *p.PartNumber; e != a {
+			t.Errorf("expect %v part number, got %v", e, a)
+		}
+		if e, a := expectETags[i], *p.ETag; e != a {
+			t.Errorf("expect %q ETag, got %q", e, a)
+		}
+	}
+}
+
+func TestUploadResumeFailure(t *testing.T) {
+	s, ops, _ := loggingSvc(emptyList)
+	s.Handlers.Send.PushBack(func(r *request.Request) {
+		switch r.Data.(type) {
+		case *s3.ListPartsOutput:
+			r.HTTPResponse.StatusCode = 404
+		}
+	})
+
+	mgr := s3manager.NewUploaderWithClient(s)
+	_, err := mgr.Resume(aws.BackgroundContext(), "UPLOAD-ID", &s3manager.UploadInput{
+		Bucket: aws.String("Bucket"),
+		Key:    aws.String("Key"),
+		Body:   bytes.NewReader(buf12MB),
+	})
+	if err == nil {
+		t.Fatalf("expect error, got none")
+	}
+	if e, a := []string{"ListParts"}, *ops; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v ops, got %v", e, a)
+	}
+
+	_, err = mgr.Resume(aws.BackgroundContext(), "", &s3manager.UploadInput{
+		Bucket: aws.String("Bucket"),
+		Key:    aws.String("Key"),
+		Body:   bytes.NewReader(buf12MB),
+	})
+	if err == nil {
+		t.Fatalf("expect error for missing upload ID, got none")
+	}
+}
+
 func createTempFile(t *testing.T, size int64) (*os.File, func(*testing.T), error) {
 	file, err := ioutil.TempFile(os.TempDir(), aws.SDKName+t.Name())
 	if err != nil {
@@ -1140,6 +1285,75 @@ func TestUploadRetry(t *testing.T) {
 	}
 }
 
+func TestUploadProgress_Retry(t *testing.T) {
+	const numParts, retries = 3, 2
+
+	mux := newMockS3UploadServer(t, buildFailHandlers(t, numParts, retries))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	sess := unit.Session.Copy(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		S3ForcePathStyle: aws.Bool(true),
+		DisableSSL:       aws.Bool(true),
+		MaxRetries:       aws.Int(retries + 1),
+		SleepDelay:       func(time.Duration) {},
+	})
+
+	var m sync.Mutex
+	var events []s3manager.ProgressEvent
+	var maxTransferred int64
+	listener := s3manager.ProgressListenerFunc(func(e s3manager.ProgressEvent) {
+		m.Lock()
+		defer m.Unlock()
+		events = append(events, e)
+		if e.TransferredBytes > maxTransferred {
+			maxTransferred = e.TransferredBytes
+		}
+	})
+
+	size := s3manager.DefaultUploadPartSize * numParts
+	uploader := s3manager.NewUploader(sess, s3manager.WithUploaderProgressListener(listener))
+	_, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   bytes.NewReader(make([]byte, size)),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	counts := map[s3manager.ProgressEventType]int{}
+	for _, e := range events {
+		counts[e.Type]++
+		if e.TotalBytes != size {
+			t.Errorf("expect %d total bytes, got %d", size, e.TotalBytes)
+		}
+	}
+	if e, a := numParts, counts[s3manager.ProgressPartStarted]; e != a {
+		t.Errorf("expect %d parts started, got %d", e, a)
+	}
+	if e, a := numParts, counts[s3manager.ProgressPartCompleted]; e != a {
+		t.Errorf("expect %d parts completed, got %d", e, a)
+	}
+	if e, a := numParts*retries, counts[s3manager.ProgressPartRetried]; e != a {
+		t.Errorf("expect %d parts retried, got %d", e, a)
+	}
+
+	last := events[len(events)-1]
+	if e, a := s3manager.ProgressTransferCompleted, last.Type; e != a {
+		t.Errorf("expect %v last event, got %v", e, a)
+	}
+	if e, a := size, last.TransferredBytes; e != a {
+		t.Errorf("expect %d bytes transferred, got %d", e, a)
+	}
+	// Bytes of retried parts are discarded, so progress never exceeds the
+	// total, even with concurrent parts.
+	if maxTransferred > size {
+		t.Errorf("expect transferred bytes no more than %d, got %d", size, maxTransferred)
+	}
+}
+
 type mockS3UploadServer struct {
 	*http.ServeMux
 
@@ -0,0 +1,383 @@
+package s3test
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type xmlOwner struct {
+	ID          string
+	DisplayName string
+}
+
+var owner = &xmlOwner{ID: "s3test", DisplayName: "s3test"}
+
+type xmlBucket struct {
+	Name         string
+	CreationDate string
+}
+
+type xmlListAllMyBucketsResult struct {
+	XMLName xml.Name    `xml:"ListAllMyBucketsResult"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Owner   *xmlOwner   `xml:"Owner"`
+	Buckets []xmlBucket `xml:"Buckets>Bucket"`
+}
+
+func (h *Handler) listBuckets(req *request) error {
+	names := make([]string, 0, len(h.buckets))
+	for name := range h.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := xmlListAllMyBucketsResult{Xmlns: xmlns, Owner: owner, Buckets: []xmlBucket{}}
+	for _, name := range names {
+		result.Buckets = append(result.Buckets, xmlBucket{
+			Name:         name,
+			CreationDate: iso8601(h.buckets[name].created),
+		})
+	}
+
+	req.writeXML(http.StatusOK, result)
+	return nil
+}
+
+func (h *Handler) createBucket(req *request) error {
+	if n := len(req.bucket); n < 3 || n > 63 || strings.ContainsAny(req.bucket, " /\\") {
+		return newError(http.StatusBadRequest, "InvalidBucketName",
+			"the specified bucket %s is not valid", req.bucket)
+	}
+	if _, ok := h.buckets[req.bucket]; ok {
+		return newError(http.StatusConflict, "BucketAlreadyOwnedByYou",
+			"your previous request to create the named bucket succeeded and you already own it")
+	}
+
+	h.buckets[req.bucket] = &bucket{
+		name:    req.bucket,
+		created: now(),
+		objects: map[string]*object{},
+		uploads: map[string]*upload{},
+	}
+
+	req.w.Header().Set("Location", "/"+req.bucket)
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (h *Handler) deleteBucket(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+	if len(b.objects) != 0 || len(b.uploads) != 0 {
+		return newError(http.StatusConflict, "BucketNotEmpty",
+			"the bucket you tried to delete is not empty")
+	}
+
+	delete(h.buckets, req.bucket)
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h *Handler) headBucket(req *request) error {
+	if _, err := h.getBucket(req); err != nil {
+		return err
+	}
+
+	req.w.Header().Set("x-amz-bucket-region", h.region())
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+type xmlLocationConstraint struct {
+	XMLName  xml.Name `xml:"LocationConstraint"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:",chardata"`
+}
+
+func (h *Handler) getBucketLocation(req *request) error {
+	if _, err := h.getBucket(req); err != nil {
+		return err
+	}
+
+	// The location of buckets in us-east-1 is empty.
+	loc := h.region()
+	if loc == "us-east-1" {
+		loc = ""
+	}
+
+	req.writeXML(http.StatusOK, xmlLocationConstraint{Xmlns: xmlns, Location: loc})
+	return nil
+}
+
+// listResult is a page of the objects and common prefixes of a bucket.
+type listResult struct {
+	contents  []*object
+	prefixes  []string
+	truncated bool
+
+	// The last key or common prefix of the page.
+	last string
+}
+
+// list returns up to max objects and common prefixes of the bucket with the
+// prefix, after the key or common prefix. Keys containing the delimiter after
+// the prefix are rolled up into a common prefix.
+func (b *bucket) list(prefix, delimiter, after string, max int) listResult {
+	var res listResult
+	for _, key := range b.sortedKeys() {
+		if key <= after || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		if len(delimiter) != 0 {
+			rest := key[len(prefix):]
+			if i := strings.Index(rest, delimiter); i >= 0 {
+				cp := prefix + rest[:i+len(delimiter)]
+				if cp <= after || cp == res.last {
+					continue
+				}
+				if len(res.contents)+len(res.prefixes) == max {
+					res.truncated = true
+					break
+				}
+				res.prefixes = append(res.prefixes, cp)
+				res.last = cp
+				continue
+			}
+		}
+
+		if len(res.contents)+len(res.prefixes) == max {
+			res.truncated = true
+			break
+		}
+		res.contents = append(res.contents, b.objects[key])
+		res.last = key
+	}
+	return res
+}
+
+type xmlObject struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int64
+	StorageClass string
+	Owner        *xmlOwner `xml:"Owner,omitempty"`
+}
+
+type xmlCommonPrefix struct {
+	Prefix string
+}
+
+func (res listResult) xmlContents(withOwner bool) ([]xmlObject, []xmlCommonPrefix) {
+	contents := make([]xmlObject, 0, len(res.contents))
+	for _, o := range res.contents {
+		x := xmlObject{
+			Key:          o.key,
+			LastModified: iso8601(o.lastModified),
+			ETag:         o.etag,
+			Size:         int64(len(o.data)),
+			StorageClass: o.storageClass(),
+		}
+		if withOwner {
+			x.Owner = owner
+		}
+		contents = append(contents, x)
+	}
+
+	prefixes := make([]xmlCommonPrefix, 0, len(res.prefixes))
+	for _, p := range res.prefixes {
+		prefixes = append(prefixes, xmlCommonPrefix{Prefix: p})
+	}
+
+	return contents, prefixes
+}
+
+// maxKeys returns the request's max-keys parameter, or 1000 if not set.
+func (req *request) maxKeys() (int, error) {
+	v := req.q.Get("max-keys")
+	if len(v) == 0 {
+		return 1000, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, newError(http.StatusBadRequest, "InvalidArgument",
+			"provided max-keys not an integer or within integer range")
+	}
+	if n > 1000 {
+		n = 1000
+	}
+	return n, nil
+}
+
+type xmlListBucketResult struct {
+	XMLName        xml.Name `xml:"ListBucketResult"`
+	Xmlns          string   `xml:"xmlns,attr"`
+	Name           string
+	Prefix         string
+	Marker         string
+	NextMarker     string `xml:",omitempty"`
+	MaxKeys        int
+	Delimiter      string `xml:",omitempty"`
+	IsTruncated    bool
+	Contents       []xmlObject
+	CommonPrefixes []xmlCommonPrefix
+}
+
+func (h *Handler) listObjects(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+	max, err := req.maxKeys()
+	if err != nil {
+		return err
+	}
+
+	q := req.q
+	res := b.list(q.Get("prefix"), q.Get("delimiter"), q.Get("marker"), max)
+
+	result := xmlListBucketResult{
+		Xmlns:       xmlns,
+		Name:        b.name,
+		Prefix:      q.Get("prefix"),
+		Marker:      q.Get("marker"),
+		MaxKeys:     max,
+		Delimiter:   q.Get("delimiter"),
+		IsTruncated: res.truncated,
+	}
+	if res.truncated && len(result.Delimiter) != 0 {
+		// S3 only returns the NextMarker of a truncated listing with a
+		// delimiter.
+		result.NextMarker = res.last
+	}
+	result.Contents, result.CommonPrefixes = res.xmlContents(true)
+
+	req.writeXML(http.StatusOK, result)
+	return nil
+}
+
+type xmlListBucketV2Result struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Xmlns                 string   `xml:"xmlns,attr"`
+	Name                  string
+	Prefix                string
+	MaxKeys               int
+	KeyCount              int
+	Delimiter             string `xml:",omitempty"`
+	IsTruncated           bool
+	ContinuationToken     string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+	StartAfter            string `xml:",omitempty"`
+	Contents              []xmlObject
+	CommonPrefixes        []xmlCommonPrefix
+}
+
+func (h *Handler) listObjectsV2(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+	max, err := req.maxKeys()
+	if err != nil {
+		return err
+	}
+
+	q := req.q
+	after := q.Get("start-after")
+	if token := q.Get("continuation-token"); len(token) != 0 {
+		v, err := base64.URLEncoding.DecodeString(token)
+		if err != nil {
+			return newError(http.StatusBadRequest, "InvalidArgument",
+				"the continuation token provided is incorrect")
+		}
+		after = string(v)
+	}
+
+	res := b.list(q.Get("prefix"), q.Get("delimiter"), after, max)
+
+	result := xmlListBucketV2Result{
+		Xmlns:             xmlns,
+		Name:              b.name,
+		Prefix:            q.Get("prefix"),
+		MaxKeys:           max,
+		KeyCount:          len(res.contents) + len(res.prefixes),
+		Delimiter:         q.Get("delimiter"),
+		IsTruncated:       res.truncated,
+		ContinuationToken: q.Get("continuation-token"),
+		StartAfter:        q.Get("start-after"),
+	}
+	if res.truncated {
+		result.NextContinuationToken = base64.URLEncoding.EncodeToString([]byte(res.last))
+	}
+	result.Contents, result.CommonPrefixes = res.xmlContents(q.Get("fetch-owner") == "true")
+
+	req.writeXML(http.StatusOK, result)
+	return nil
+}
+
+type xmlDelete struct {
+	Quiet   bool
+	Objects []struct {
+		Key       string
+		VersionID string `xml:"VersionId"`
+	} `xml:"Object"`
+}
+
+type xmlDeleted struct {
+	Key string
+}
+
+type xmlDeleteError struct {
+	Key     string
+	Code    string
+	Message string
+}
+
+type xmlDeleteResult struct {
+	XMLName xml.Name `xml:"DeleteResult"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Deleted []xmlDeleted
+	Errors  []xmlDeleteError `xml:"Error"`
+}
+
+func (h *Handler) deleteObjects(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+
+	var in xmlDelete
+	if err := req.readXML(&in); err != nil {
+		return err
+	}
+	if len(in.Objects) > 1000 {
+		return newError(http.StatusBadRequest, "MalformedXML",
+			"the request must not delete more than 1000 objects")
+	}
+
+	result := xmlDeleteResult{Xmlns: xmlns}
+	for _, o := range in.Objects {
+		if len(o.Key) == 0 {
+			result.Errors = append(result.Errors, xmlDeleteError{
+				Code: "InvalidArgument", Message: "the key must not be empty",
+			})
+			continue
+		}
+
+		delete(b.objects, o.Key)
+		if !in.Quiet {
+			result.Deleted = append(result.Deleted, xmlDeleted{Key: o.Key})
+		}
+	}
+
+	req.writeXML(http.StatusOK, result)
+	return nil
+}
@@ -0,0 +1,414 @@
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type upload struct {
+	id        string
+	key       string
+	initiated time.Time
+
+	header http.Header
+	tags   url.Values
+	parts  map[int64]*part
+}
+
+type part struct {
+	num          int64
+	data         []byte
+	etag         string
+	lastModified time.Time
+}
+
+type xmlInitiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string
+	Key      string
+	UploadID string `xml:"UploadId"`
+}
+
+func (h *Handler) createMultipartUpload(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+	tags, err := parseTags(req.r.Header)
+	if err != nil {
+		return err
+	}
+
+	h.uploadID++
+	u := &upload{
+		id:        fmt.Sprintf("%s-%d", strconv.FormatInt(time.Now().UnixNano(), 36), h.uploadID),
+		key:       req.key,
+		initiated: now(),
+		header:    objectHeader(req.r.Header, true),
+		tags:      tags,
+		parts:     map[int64]*part{},
+	}
+	b.uploads[u.id] = u
+
+	copyHeaders(req.w.Header(), u.header, encryptionHeaders)
+	req.writeXML(http.StatusOK, xmlInitiateMultipartUploadResult{
+		Xmlns:    xmlns,
+		Bucket:   b.name,
+		Key:      u.key,
+		UploadID: u.id,
+	})
+	return nil
+}
+
+// getUpload returns the bucket and multipart upload of the request's
+// uploadId, or a NoSuchUpload error if it does not exist.
+func (h *Handler) getUpload(req *request) (*bucket, *upload, error) {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u, ok := b.uploads[req.q.Get("uploadId")]
+	if !ok || u.key != req.key {
+		return nil, nil, newError(http.StatusNotFound, "NoSuchUpload",
+			"the specified multipart upload does not exist")
+	}
+	return b, u, nil
+}
+
+// partNumber returns the request's partNumber parameter.
+func (req *request) partNumber() (int64, error) {
+	num, err := strconv.ParseInt(req.q.Get("partNumber"), 10, 64)
+	if err != nil || num < 1 || num > 10000 {
+		return 0, newError(http.StatusBadRequest, "InvalidArgument",
+			"part number must be an integer between 1 and 10000, inclusive")
+	}
+	return num, nil
+}
+
+func (h *Handler) uploadPart(req *request) error {
+	_, u, err := h.getUpload(req)
+	if err != nil {
+		return err
+	}
+	num, err := req.partNumber()
+	if err != nil {
+		return err
+	}
+
+	body, err := req.readBody()
+	if err != nil {
+		return err
+	}
+
+	p := &part{num: num, data: body, etag: md5ETag(body), lastModified: now()}
+	u.parts[num] = p
+
+	copyHeaders(req.w.Header(), u.header, encryptionHeaders)
+	req.w.Header().Set("ETag", p.etag)
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (h *Handler) uploadPartCopy(req *request) error {
+	_, u, err := h.getUpload(req)
+	if err != nil {
+		return err
+	}
+	num, err := req.partNumber()
+	if err != nil {
+		return err
+	}
+	src, err := h.copySource(req)
+	if err != nil {
+		return err
+	}
+
+	data := src.data
+	if v := req.r.Header.Get("X-Amz-Copy-Source-Range"); len(v) != 0 {
+		start, end, ok, err := parseRange(v, int64(len(src.data)))
+		if err != nil || !ok || !strings.HasSuffix(v, strconv.FormatInt(end, 10)) {
+			return newError(http.StatusBadRequest, "InvalidArgument",
+				"the x-amz-copy-source-range value %s is not valid", v)
+		}
+		data = src.data[start : end+1]
+	}
+
+	p := &part{num: num, data: data, etag: md5ETag(data), lastModified: now()}
+	u.parts[num] = p
+
+	req.writeXML(http.StatusOK, xmlCopyResult{
+		XMLName:      xml.Name{Local: "CopyPartResult"},
+		Xmlns:        xmlns,
+		ETag:         p.etag,
+		LastModified: iso8601(p.lastModified),
+	})
+	return nil
+}
+
+type xmlCompleteMultipartUpload struct {
+	Parts []struct {
+		PartNumber int64
+		ETag       string
+	} `xml:"Part"`
+}
+
+type xmlCompleteMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
+func (h *Handler) completeMultipartUpload(req *request) error {
+	b, u, err := h.getUpload(req)
+	if err != nil {
+		return err
+	}
+
+	var in xmlCompleteMultipartUpload
+	if err := req.readXML(&in); err != nil {
+		return err
+	}
+	if len(in.Parts) == 0 {
+		return newError(http.StatusBadRequest, "MalformedXML",
+			"the XML you provided did not specify any parts")
+	}
+
+	var data []byte
+	var sizes []int64
+	sums := md5.New()
+	for i, p := range in.Parts {
+		if i > 0 && p.PartNumber <= in.Parts[i-1].PartNumber {
+			return newError(http.StatusBadRequest, "InvalidPartOrder",
+				"the list of parts was not in ascending order")
+		}
+
+		up, ok := u.parts[p.PartNumber]
+		if !ok || strings.Trim(p.ETag, `"`) != strings.Trim(up.etag, `"`) {
+			return newError(http.StatusBadRequest, "InvalidPart",
+				"one or more of the specified parts could not be found, part %d", p.PartNumber)
+		}
+		if i < len(in.Parts)-1 && int64(len(up.data)) < h.MinPartSize {
+			return newError(http.StatusBadRequest, "EntityTooSmall",
+				"your proposed upload is smaller than the minimum allowed size, part %d", p.PartNumber)
+		}
+
+		data = append(data, up.data...)
+		sizes = append(sizes, int64(len(up.data)))
+		sum, _ := hex.DecodeString(strings.Trim(up.etag, `"`))
+		sums.Write(sum)
+	}
+
+	o := &object{
+		key:          u.key,
+		data:         data,
+		etag:         fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sums.Sum(nil)), len(in.Parts)),
+		lastModified: now(),
+		header:       u.header,
+		tags:         u.tags,
+		partSizes:    sizes,
+	}
+	b.objects[u.key] = o
+	delete(b.uploads, u.id)
+
+	copyHeaders(req.w.Header(), o.header, encryptionHeaders)
+	req.writeXML(http.StatusOK, xmlCompleteMultipartUploadResult{
+		Xmlns:    xmlns,
+		Location: "http://" + req.r.Host + "/" + b.name + "/" + o.key,
+		Bucket:   b.name,
+		Key:      o.key,
+		ETag:     o.etag,
+	})
+	return nil
+}
+
+func (h *Handler) abortMultipartUpload(req *request) error {
+	b, u, err := h.getUpload(req)
+	if err != nil {
+		return err
+	}
+
+	delete(b.uploads, u.id)
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type xmlPart struct {
+	PartNumber   int64
+	LastModified string
+	ETag         string
+	Size         int64
+}
+
+type xmlListPartsResult struct {
+	XMLName              xml.Name `xml:"ListPartsResult"`
+	Xmlns                string   `xml:"xmlns,attr"`
+	Bucket               string
+	Key                  string
+	UploadID             string `xml:"UploadId"`
+	PartNumberMarker     int64
+	NextPartNumberMarker int64
+	MaxParts             int
+	IsTruncated          bool
+	Parts                []xmlPart `xml:"Part"`
+	StorageClass         string
+}
+
+type partNumbers []int64
+
+func (p partNumbers) Len() int           { return len(p) }
+func (p partNumbers) Less(i, j int) bool { return p[i] < p[j] }
+func (p partNumbers) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+func (h *Handler) listParts(req *request) error {
+	b, u, err := h.getUpload(req)
+	if err != nil {
+		return err
+	}
+
+	marker, _ := strconv.ParseInt(req.q.Get("part-number-marker"), 10, 64)
+	max := 1000
+	if v := req.q.Get("max-parts"); len(v) != 0 {
+		if max, err = strconv.Atoi(v); err != nil || max < 0 {
+			return newError(http.StatusBadRequest, "InvalidArgument",
+				"provided max-parts not an integer or within integer range")
+		}
+	}
+
+	nums := make(partNumbers, 0, len(u.parts))
+	for num := range u.parts {
+		if num > marker {
+			nums = append(nums, num)
+		}
+	}
+	sort.Sort(nums)
+
+	result := xmlListPartsResult{
+		Xmlns:            xmlns,
+		Bucket:           b.name,
+		Key:              u.key,
+		UploadID:         u.id,
+		PartNumberMarker: marker,
+		MaxParts:         max,
+		StorageClass:     (&object{header: u.header}).storageClass(),
+	}
+	for _, num := range nums {
+		if len(result.Parts) == max {
+			result.IsTruncated = true
+			break
+		}
+		p := u.parts[num]
+		result.Parts = append(result.Parts, xmlPart{
+			PartNumber:   p.num,
+			LastModified: iso8601(p.lastModified),
+			ETag:         p.etag,
+			Size:         int64(len(p.data)),
+		})
+		result.NextPartNumberMarker = p.num
+	}
+
+	req.writeXML(http.StatusOK, result)
+	return nil
+}
+
+type xmlUpload struct {
+	Key          string
+	UploadID     string `xml:"UploadId"`
+	Initiated    string
+	StorageClass string
+	Owner        *xmlOwner
+	Initiator    *xmlOwner
+}
+
+type xmlListMultipartUploadsResult struct {
+	XMLName            xml.Name `xml:"ListMultipartUploadsResult"`
+	Xmlns              string   `xml:"xmlns,attr"`
+	Bucket             string
+	KeyMarker          string
+	UploadIDMarker     string `xml:"UploadIdMarker"`
+	NextKeyMarker      string
+	NextUploadIDMarker string `xml:"NextUploadIdMarker"`
+	Prefix             string
+	MaxUploads         int
+	IsTruncated        bool
+	Uploads            []xmlUpload `xml:"Upload"`
+}
+
+type sortedUploads []*upload
+
+func (u sortedUploads) Len() int { return len(u) }
+func (u sortedUploads) Less(i, j int) bool {
+	if u[i].key != u[j].key {
+		return u[i].key < u[j].key
+	}
+	return u[i].id < u[j].id
+}
+func (u sortedUploads) Swap(i, j int) { u[i], u[j] = u[j], u[i] }
+
+func (h *Handler) listMultipartUploads(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+
+	q := req.q
+	max := 1000
+	if v := q.Get("max-uploads"); len(v) != 0 {
+		if max, err = strconv.Atoi(v); err != nil || max < 0 {
+			return newError(http.StatusBadRequest, "InvalidArgument",
+				"provided max-uploads not an integer or within integer range")
+		}
+	}
+	keyMarker, idMarker := q.Get("key-marker"), q.Get("upload-id-marker")
+
+	uploads := make(sortedUploads, 0, len(b.uploads))
+	for _, u := range b.uploads {
+		if !strings.HasPrefix(u.key, q.Get("prefix")) {
+			continue
+		}
+		if u.key < keyMarker || u.key == keyMarker && (len(idMarker) == 0 || u.id <= idMarker) {
+			continue
+		}
+		uploads = append(uploads, u)
+	}
+	sort.Sort(uploads)
+
+	result := xmlListMultipartUploadsResult{
+		Xmlns:          xmlns,
+		Bucket:         b.name,
+		KeyMarker:      keyMarker,
+		UploadIDMarker: idMarker,
+		Prefix:         q.Get("prefix"),
+		MaxUploads:     max,
+	}
+	for _, u := range uploads {
+		if len(result.Uploads) == max {
+			result.IsTruncated = true
+			break
+		}
+		result.Uploads = append(result.Uploads, xmlUpload{
+			Key:          u.key,
+			UploadID:     u.id,
+			Initiated:    iso8601(u.initiated),
+			StorageClass: (&object{header: u.header}).storageClass(),
+			Owner:        owner,
+			Initiator:    owner,
+		})
+		result.NextKeyMarker, result.NextUploadIDMarker = u.key, u.id
+	}
+
+	req.writeXML(http.StatusOK, result)
+	return nil
+}
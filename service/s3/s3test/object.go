@@ -0,0 +1,500 @@
+package s3test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// storedHeaders are the headers of a request stored with an object, and
+// returned when the object is read. Metadata headers are also stored.
+var storedHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Type",
+	"Expires",
+	"X-Amz-Website-Redirect-Location",
+}
+
+// encryptionHeaders are the headers of a request setting the encryption and
+// storage class of an object, which are not copied from a copy's source.
+var encryptionHeaders = []string{
+	"X-Amz-Server-Side-Encryption",
+	"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id",
+	"X-Amz-Server-Side-Encryption-Customer-Algorithm",
+	"X-Amz-Server-Side-Encryption-Customer-Key-Md5",
+	"X-Amz-Storage-Class",
+}
+
+const metadataPrefix = "X-Amz-Meta-"
+
+// objectHeader returns the headers of the request stored with an object. If
+// metadata is false, the content and metadata headers are not included.
+func objectHeader(r http.Header, metadata bool) http.Header {
+	header := http.Header{}
+	copyHeaders(header, r, encryptionHeaders)
+	if metadata {
+		copyMetadata(header, r)
+	}
+	return header
+}
+
+func copyHeaders(dst, src http.Header, names []string) {
+	for _, name := range names {
+		if v := src.Get(name); len(v) != 0 {
+			dst.Set(name, v)
+		}
+	}
+}
+
+// copyMetadata copies the content and metadata headers.
+func copyMetadata(dst, src http.Header) {
+	copyHeaders(dst, src, storedHeaders)
+	for k, v := range src {
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), metadataPrefix) {
+			dst[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+}
+
+func (o *object) storageClass() string {
+	if v := o.header.Get("X-Amz-Storage-Class"); len(v) != 0 {
+		return v
+	}
+	return "STANDARD"
+}
+
+// parseTags parses the tags of the x-amz-tagging header.
+func parseTags(r http.Header) (url.Values, error) {
+	v := r.Get("X-Amz-Tagging")
+	if len(v) == 0 {
+		return url.Values{}, nil
+	}
+
+	tags, err := url.ParseQuery(v)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "InvalidArgument",
+			"the header 'x-amz-tagging' shall be encoded as UTF-8 then URLEncoded URL query parameters")
+	}
+	return tags, nil
+}
+
+func (h *Handler) putObject(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+
+	body, err := req.readBody()
+	if err != nil {
+		return err
+	}
+	tags, err := parseTags(req.r.Header)
+	if err != nil {
+		return err
+	}
+
+	o := &object{
+		key:          req.key,
+		data:         body,
+		etag:         md5ETag(body),
+		lastModified: now(),
+		header:       objectHeader(req.r.Header, true),
+		tags:         tags,
+	}
+	b.objects[req.key] = o
+
+	copyHeaders(req.w.Header(), o.header, encryptionHeaders)
+	req.w.Header().Set("ETag", o.etag)
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// getObject serves the GetObject and HeadObject API operations.
+func (h *Handler) getObject(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+	o, err := b.getObject(req.key)
+	if err != nil {
+		return err
+	}
+
+	if err := checkConditions(o, req.r.Header, "", false); err != nil {
+		return err
+	}
+
+	start, end := int64(0), int64(len(o.data))-1
+	partial := false
+	if v := req.q.Get("partNumber"); len(v) != 0 {
+		if start, end, err = o.partRange(v); err != nil {
+			return err
+		}
+		partial = o.partSizes != nil
+		if partial {
+			req.w.Header().Set("X-Amz-Mp-Parts-Count", strconv.Itoa(len(o.partSizes)))
+		}
+	} else if v := req.r.Header.Get("Range"); len(v) != 0 {
+		var ok bool
+		if start, end, ok, err = parseRange(v, int64(len(o.data))); err != nil {
+			req.w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", len(o.data)))
+			return err
+		}
+		partial = ok
+	}
+
+	header := req.w.Header()
+	copyHeaders(header, o.header, encryptionHeaders)
+	copyMetadata(header, o.header)
+	if len(header.Get("Content-Type")) == 0 {
+		header.Set("Content-Type", "binary/octet-stream")
+	}
+	for param, name := range responseHeaderOverrides {
+		if v := req.q.Get(param); len(v) != 0 {
+			header.Set(name, v)
+		}
+	}
+	header.Set("ETag", o.etag)
+	header.Set("Last-Modified", o.lastModified.Format(http.TimeFormat))
+	header.Set("Accept-Ranges", "bytes")
+	header.Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	if len(o.tags) != 0 {
+		header.Set("X-Amz-Tagging-Count", strconv.Itoa(len(o.tags)))
+	}
+
+	status := http.StatusOK
+	if partial {
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(o.data)))
+		status = http.StatusPartialContent
+	}
+
+	req.w.WriteHeader(status)
+	if !req.head {
+		req.w.Write(o.data[start : end+1])
+	}
+	return nil
+}
+
+// responseHeaderOverrides are the query parameters of a GetObject request
+// overriding the response's headers.
+var responseHeaderOverrides = map[string]string{
+	"response-cache-control":       "Cache-Control",
+	"response-content-disposition": "Content-Disposition",
+	"response-content-encoding":    "Content-Encoding",
+	"response-content-language":    "Content-Language",
+	"response-content-type":        "Content-Type",
+	"response-expires":             "Expires",
+}
+
+// partRange returns the byte range of the object's part number.
+func (o *object) partRange(v string) (int64, int64, error) {
+	num, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || num < 1 {
+		return 0, 0, newError(http.StatusBadRequest, "InvalidArgument",
+			"part number must be an integer between 1 and 10000")
+	}
+
+	if o.partSizes == nil {
+		if num != 1 {
+			return 0, 0, newError(http.StatusRequestedRangeNotSatisfiable, "InvalidPartNumber",
+				"the requested partnumber is not satisfiable")
+		}
+		return 0, int64(len(o.data)) - 1, nil
+	}
+
+	if num > int64(len(o.partSizes)) {
+		return 0, 0, newError(http.StatusRequestedRangeNotSatisfiable, "InvalidPartNumber",
+			"the requested partnumber is not satisfiable")
+	}
+
+	var start int64
+	for _, size := range o.partSizes[:num-1] {
+		start += size
+	}
+	return start, start + o.partSizes[num-1] - 1, nil
+}
+
+var rangeRegex = regexp.MustCompile(`^bytes=(\d*)-(\d*)$`)
+
+// parseRange parses the Range header value for content of the size. Returns
+// false if the range is not valid, and should be ignored, or an InvalidRange
+// error if the range is not satisfiable.
+func parseRange(v string, size int64) (start, end int64, ok bool, err error) {
+	match := rangeRegex.FindStringSubmatch(v)
+	if match == nil || len(match[1]) == 0 && len(match[2]) == 0 {
+		return 0, size - 1, false, nil
+	}
+
+	errInvalidRange := newError(http.StatusRequestedRangeNotSatisfiable, "InvalidRange",
+		"the requested range is not satisfiable")
+
+	if len(match[1]) == 0 {
+		// Suffix range of the last bytes.
+		n, _ := strconv.ParseInt(match[2], 10, 64)
+		if n == 0 || size == 0 {
+			return 0, 0, false, errInvalidRange
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true, nil
+	}
+
+	start, _ = strconv.ParseInt(match[1], 10, 64)
+	end = size - 1
+	if len(match[2]) != 0 {
+		end, _ = strconv.ParseInt(match[2], 10, 64)
+		if end < start {
+			return 0, size - 1, false, nil
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return 0, 0, false, errInvalidRange
+	}
+
+	return start, end, true, nil
+}
+
+// checkConditions checks the conditional headers with the prefix against
+// the object. A failed If-None-Match or If-Modified-Since condition is a Not
+// Modified response, unless copy is true.
+func checkConditions(o *object, r http.Header, prefix string, copy bool) error {
+	errPrecondition := newError(http.StatusPreconditionFailed, "PreconditionFailed",
+		"at least one of the pre-conditions you specified did not hold")
+	errNotModified := newError(http.StatusNotModified, "NotModified", "not modified")
+	if copy {
+		errNotModified = errPrecondition
+	}
+
+	ifMatch := r.Get(prefix + "If-Match")
+	if len(ifMatch) != 0 {
+		if !etagMatches(ifMatch, o.etag) {
+			return errPrecondition
+		}
+	} else if t, ok := parseTime(r.Get(prefix + "If-Unmodified-Since")); ok && o.lastModified.After(t) {
+		return errPrecondition
+	}
+
+	ifNoneMatch := r.Get(prefix + "If-None-Match")
+	if len(ifNoneMatch) != 0 {
+		if etagMatches(ifNoneMatch, o.etag) {
+			return errNotModified
+		}
+	} else if t, ok := parseTime(r.Get(prefix + "If-Modified-Since")); ok && !o.lastModified.After(t) {
+		return errNotModified
+	}
+
+	return nil
+}
+
+// etagMatches returns if the list of ETags of a conditional header matches
+// the ETag.
+func etagMatches(list, etag string) bool {
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.Trim(v, `"`) == strings.Trim(etag, `"`) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTime(v string) (time.Time, bool) {
+	if len(v) == 0 {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(v)
+	return t, err == nil
+}
+
+func (h *Handler) deleteObject(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+
+	delete(b.objects, req.key)
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// copySource returns the object of the request's x-amz-copy-source header,
+// checking the copy source conditions.
+func (h *Handler) copySource(req *request) (*object, error) {
+	v := strings.TrimPrefix(req.r.Header.Get("X-Amz-Copy-Source"), "/")
+	u, err := url.Parse("/" + v)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "InvalidArgument",
+			"copy source %s is not valid", v)
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return nil, newError(http.StatusBadRequest, "InvalidArgument",
+			"copy source %s must be of the form bucket/key", v)
+	}
+
+	b, err := h.getBucket(&request{bucket: parts[0]})
+	if err != nil {
+		return nil, err
+	}
+	o, err := b.getObject(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkConditions(o, req.r.Header, "X-Amz-Copy-Source-", true); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+type xmlCopyResult struct {
+	XMLName      xml.Name
+	Xmlns        string `xml:"xmlns,attr"`
+	ETag         string
+	LastModified string
+}
+
+func (h *Handler) copyObject(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+	src, err := h.copySource(req)
+	if err != nil {
+		return err
+	}
+
+	replaceMetadata := strings.EqualFold(req.r.Header.Get("X-Amz-Metadata-Directive"), "REPLACE")
+	if src == b.objects[req.key] && !replaceMetadata && len(objectHeader(req.r.Header, false)) == 0 {
+		return newError(http.StatusBadRequest, "InvalidRequest",
+			"this copy request is illegal because it is trying to copy an object to itself "+
+				"without changing the object's metadata, storage class, website redirect location "+
+				"or encryption attributes")
+	}
+
+	o := &object{
+		key:          req.key,
+		data:         src.data,
+		etag:         md5ETag(src.data),
+		lastModified: now(),
+		header:       objectHeader(req.r.Header, replaceMetadata),
+		tags:         src.tags,
+	}
+	if !replaceMetadata {
+		copyMetadata(o.header, src.header)
+	}
+	if strings.EqualFold(req.r.Header.Get("X-Amz-Tagging-Directive"), "REPLACE") {
+		if o.tags, err = parseTags(req.r.Header); err != nil {
+			return err
+		}
+	}
+	b.objects[req.key] = o
+
+	copyHeaders(req.w.Header(), o.header, encryptionHeaders)
+	req.writeXML(http.StatusOK, xmlCopyResult{
+		XMLName:      xml.Name{Local: "CopyObjectResult"},
+		Xmlns:        xmlns,
+		ETag:         o.etag,
+		LastModified: iso8601(o.lastModified),
+	})
+	return nil
+}
+
+type xmlTag struct {
+	Key   string
+	Value string
+}
+
+type xmlTagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	TagSet  []xmlTag `xml:"TagSet>Tag"`
+}
+
+func (h *Handler) putObjectTagging(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+	o, err := b.getObject(req.key)
+	if err != nil {
+		return err
+	}
+
+	var in xmlTagging
+	if err := req.readXML(&in); err != nil {
+		return err
+	}
+	if len(in.TagSet) > 10 {
+		return newError(http.StatusBadRequest, "BadRequest",
+			"object tags cannot be greater than 10")
+	}
+
+	tags := url.Values{}
+	for _, t := range in.TagSet {
+		if _, ok := tags[t.Key]; ok {
+			return newError(http.StatusBadRequest, "InvalidTag",
+				"cannot provide multiple tags with the same key")
+		}
+		tags.Set(t.Key, t.Value)
+	}
+	o.tags = tags
+
+	req.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (h *Handler) getObjectTagging(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+	o, err := b.getObject(req.key)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(o.tags))
+	for k := range o.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := xmlTagging{Xmlns: xmlns, TagSet: []xmlTag{}}
+	for _, k := range keys {
+		result.TagSet = append(result.TagSet, xmlTag{Key: k, Value: o.tags.Get(k)})
+	}
+
+	req.writeXML(http.StatusOK, result)
+	return nil
+}
+
+func (h *Handler) deleteObjectTagging(req *request) error {
+	b, err := h.getBucket(req)
+	if err != nil {
+		return err
+	}
+	o, err := b.getObject(req.key)
+	if err != nil {
+		return err
+	}
+
+	o.tags = url.Values{}
+	req.w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,459 @@
+// Package s3test provides an in-memory Amazon S3 server for testing code
+// which uses the S3 API client, and the S3 transfer managers, without making
+// network requests to S3.
+//
+// The Server speaks enough of the S3 REST XML protocol to serve the bucket,
+// object, list, multipart upload, copy, and tagging API operations of a real
+// s3.S3 client using path style addressing. Requests are not authenticated,
+// and buckets are not versioned.
+//
+// Example:
+//     srv := s3test.NewServer()
+//     defer srv.Close()
+//
+//     sess := session.Must(session.NewSession(srv.Config()))
+//     svc := s3.New(sess)
+//
+//     _, err := svc.CreateBucket(&s3.CreateBucketInput{
+//         Bucket: aws.String("bucket"),
+//     })
+package s3test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// DefaultRegion is the region the Handler's buckets are in if no region is
+// set.
+const DefaultRegion = "us-east-1"
+
+const xmlns = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// Server is an in-memory S3 server, serving the Handler from a local
+// httptest.Server.
+type Server struct {
+	*httptest.Server
+	Handler *Handler
+}
+
+// NewServer starts and returns a new Server, with an empty Handler. The
+// server should be closed when finished, to stop it.
+func NewServer() *Server {
+	h := NewHandler()
+	return &Server{
+		Server:  httptest.NewServer(h),
+		Handler: h,
+	}
+}
+
+// Config returns the configuration of an S3 client making requests to the
+// server, with path style addressing, and static credentials.
+func (s *Server) Config() *aws.Config {
+	return &aws.Config{
+		Endpoint:         aws.String(s.URL),
+		Region:           aws.String(s.Handler.region()),
+		DisableSSL:       aws.Bool(true),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("AKID", "SECRET", ""),
+	}
+}
+
+// Handler is an http.Handler serving the S3 API from memory. It is safe to
+// use the Handler concurrently. Its configuration must not be changed while
+// serving requests.
+type Handler struct {
+	// The region the buckets are in, returned by HeadBucket and
+	// GetBucketLocation. If empty, the DefaultRegion is used.
+	Region string
+
+	// The minimum size, in bytes, of the parts of a multipart upload, other
+	// than the last part. S3 requires parts of at least 5MB. If zero, the size
+	// of the parts is not checked.
+	MinPartSize int64
+
+	m         sync.Mutex
+	buckets   map[string]*bucket
+	requestID int64
+	uploadID  int64
+}
+
+// NewHandler returns a new Handler without buckets.
+func NewHandler() *Handler {
+	return &Handler{buckets: map[string]*bucket{}}
+}
+
+func (h *Handler) region() string {
+	if len(h.Region) == 0 {
+		return DefaultRegion
+	}
+	return h.Region
+}
+
+type bucket struct {
+	name    string
+	created time.Time
+	objects map[string]*object
+	uploads map[string]*upload
+}
+
+type object struct {
+	key          string
+	data         []byte
+	etag         string
+	lastModified time.Time
+
+	// The headers of the object stored with its content, e.g. Content-Type
+	// and metadata.
+	header http.Header
+	tags   url.Values
+
+	// The sizes of the parts of an object uploaded with a multipart upload.
+	partSizes []int64
+}
+
+// request is the state of a single request being served.
+type request struct {
+	w    http.ResponseWriter
+	r    *http.Request
+	q    url.Values
+	id   string
+	head bool
+
+	bucket, key string
+
+	body    []byte
+	bodyErr error
+}
+
+// s3Error is an S3 API error response.
+type s3Error struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *s3Error) Error() string {
+	return e.code + ": " + e.message
+}
+
+func newError(status int, code, format string, args ...interface{}) *s3Error {
+	return &s3Error{status: status, code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// ServeHTTP serves the S3 API request. The request's body is read, and the
+// response written, without holding the Handler's lock, so that only the
+// changes to the buckets and objects are serialized.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := httptest.NewRecorder()
+	req := &request{
+		w:    rec,
+		r:    r,
+		q:    r.URL.Query(),
+		head: r.Method == "HEAD",
+	}
+	req.body, req.bodyErr = req.receiveBody()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	req.bucket = parts[0]
+	if len(parts) == 2 {
+		req.key = parts[1]
+	}
+
+	h.serve(req)
+
+	header := w.Header()
+	for k, v := range rec.HeaderMap {
+		header[k] = v
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(rec.Body.Bytes())
+}
+
+// serve serves the request while holding the Handler's lock, recording the
+// response.
+func (h *Handler) serve(req *request) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.requestID++
+	req.id = fmt.Sprintf("%016X", h.requestID)
+	req.w.Header().Set("x-amz-request-id", req.id)
+	req.w.Header().Set("x-amz-id-2", req.id)
+
+	var err error
+	switch {
+	case len(req.bucket) == 0:
+		err = h.serveService(req)
+	case len(req.key) == 0:
+		err = h.serveBucket(req)
+	default:
+		err = h.serveObject(req)
+	}
+
+	if err != nil {
+		req.writeError(err)
+	}
+}
+
+func (h *Handler) serveService(req *request) error {
+	if req.r.Method != "GET" {
+		return errMethodNotAllowed(req)
+	}
+	return h.listBuckets(req)
+}
+
+func (h *Handler) serveBucket(req *request) error {
+	q := req.q
+	switch req.r.Method {
+	case "PUT":
+		if has(q, "tagging", "versioning", "policy", "acl", "cors", "lifecycle") {
+			return errNotImplemented(req)
+		}
+		return h.createBucket(req)
+	case "DELETE":
+		return h.deleteBucket(req)
+	case "HEAD":
+		return h.headBucket(req)
+	case "GET":
+		switch {
+		case has(q, "location"):
+			return h.getBucketLocation(req)
+		case has(q, "uploads"):
+			return h.listMultipartUploads(req)
+		case q.Get("list-type") == "2":
+			return h.listObjectsV2(req)
+		case len(q) == 0 || has(q, "prefix", "delimiter", "marker", "max-keys", "encoding-type"):
+			return h.listObjects(req)
+		}
+	case "POST":
+		if has(q, "delete") {
+			return h.deleteObjects(req)
+		}
+	}
+	return errNotImplemented(req)
+}
+
+func (h *Handler) serveObject(req *request) error {
+	q := req.q
+	copySource := req.r.Header.Get("x-amz-copy-source")
+	switch req.r.Method {
+	case "PUT":
+		switch {
+		case has(q, "tagging"):
+			return h.putObjectTagging(req)
+		case has(q, "uploadId") && len(copySource) != 0:
+			return h.uploadPartCopy(req)
+		case has(q, "uploadId"):
+			return h.uploadPart(req)
+		case len(copySource) != 0:
+			return h.copyObject(req)
+		case len(q) == 0:
+			return h.putObject(req)
+		}
+	case "GET", "HEAD":
+		switch {
+		case has(q, "tagging") && !req.head:
+			return h.getObjectTagging(req)
+		case has(q, "uploadId") && !req.head:
+			return h.listParts(req)
+		case !has(q, "acl", "torrent"):
+			return h.getObject(req)
+		}
+	case "DELETE":
+		switch {
+		case has(q, "tagging"):
+			return h.deleteObjectTagging(req)
+		case has(q, "uploadId"):
+			return h.abortMultipartUpload(req)
+		default:
+			return h.deleteObject(req)
+		}
+	case "POST":
+		switch {
+		case has(q, "uploads"):
+			return h.createMultipartUpload(req)
+		case has(q, "uploadId"):
+			return h.completeMultipartUpload(req)
+		}
+	}
+	return errNotImplemented(req)
+}
+
+// has returns if the query has any of the parameters.
+func has(q url.Values, names ...string) bool {
+	for _, name := range names {
+		if _, ok := q[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func errNotImplemented(req *request) error {
+	return newError(http.StatusNotImplemented, "NotImplemented",
+		"%s %s is not implemented", req.r.Method, req.r.URL.RequestURI())
+}
+
+func errMethodNotAllowed(req *request) error {
+	return newError(http.StatusMethodNotAllowed, "MethodNotAllowed",
+		"the method %s is not allowed", req.r.Method)
+}
+
+// getBucket returns the request's bucket, or a NoSuchBucket error if it does
+// not exist.
+func (h *Handler) getBucket(req *request) (*bucket, error) {
+	b, ok := h.buckets[req.bucket]
+	if !ok {
+		return nil, newError(http.StatusNotFound, "NoSuchBucket",
+			"the specified bucket %s does not exist", req.bucket)
+	}
+	return b, nil
+}
+
+// getObject returns the object of the bucket, or a NoSuchKey error if it does
+// not exist.
+func (b *bucket) getObject(key string) (*object, error) {
+	o, ok := b.objects[key]
+	if !ok {
+		return nil, newError(http.StatusNotFound, "NoSuchKey",
+			"the specified key %s does not exist", key)
+	}
+	return o, nil
+}
+
+// readBody returns the request's body, or the error reading it.
+func (req *request) readBody() ([]byte, error) {
+	return req.body, req.bodyErr
+}
+
+// receiveBody reads the request's body, checking its Content-MD5 and
+// X-Amz-Content-Sha256 if provided.
+func (req *request) receiveBody() ([]byte, error) {
+	body, err := ioutil.ReadAll(req.r.Body)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "IncompleteBody",
+			"failed to read request body, %v", err)
+	}
+
+	if v := req.r.Header.Get("Content-MD5"); len(v) != 0 {
+		sum := md5.Sum(body)
+		if v != base64.StdEncoding.EncodeToString(sum[:]) {
+			return nil, newError(http.StatusBadRequest, "BadDigest",
+				"the Content-MD5 you specified did not match what we received")
+		}
+	}
+
+	if v := req.r.Header.Get("X-Amz-Content-Sha256"); len(v) != 0 && v != "UNSIGNED-PAYLOAD" &&
+		!strings.HasPrefix(v, "STREAMING-") {
+		sum := sha256.Sum256(body)
+		if v != hex.EncodeToString(sum[:]) {
+			return nil, newError(http.StatusBadRequest, "XAmzContentSHA256Mismatch",
+				"the provided 'x-amz-content-sha256' header does not match what was computed")
+		}
+	}
+
+	return body, nil
+}
+
+// readXML reads the request's XML body into v.
+func (req *request) readXML(v interface{}) error {
+	body, err := req.readBody()
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(body, v); err != nil {
+		return newError(http.StatusBadRequest, "MalformedXML",
+			"the XML you provided was not well-formed, %v", err)
+	}
+	return nil
+}
+
+// writeXML writes the XML response with the status.
+func (req *request) writeXML(status int, v interface{}) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		req.writeError(newError(http.StatusInternalServerError, "InternalError",
+			"failed to marshal response, %v", err))
+		return
+	}
+
+	req.w.Header().Set("Content-Type", "application/xml")
+	req.w.Header().Set("Content-Length", strconv.Itoa(len(xml.Header)+len(b)))
+	req.w.WriteHeader(status)
+	req.w.Write([]byte(xml.Header))
+	req.w.Write(b)
+}
+
+type xmlError struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string
+	Message   string
+	Resource  string
+	RequestID string `xml:"RequestId"`
+}
+
+// writeError writes the error response. Responses to HEAD requests do not
+// have a body.
+func (req *request) writeError(err error) {
+	e, ok := err.(*s3Error)
+	if !ok {
+		e = newError(http.StatusInternalServerError, "InternalError", "%v", err)
+	}
+
+	if req.head || e.status == http.StatusNotModified {
+		req.w.WriteHeader(e.status)
+		return
+	}
+
+	req.writeXML(e.status, xmlError{
+		Code:      e.code,
+		Message:   e.message,
+		Resource:  req.r.URL.Path,
+		RequestID: req.id,
+	})
+}
+
+// now returns the time of a modification, in the precision of the
+// Last-Modified header.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// iso8601 formats the time in the format of timestamps in XML responses.
+func iso8601(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// md5ETag returns the quoted ETag of the content.
+func md5ETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// sortedKeys returns the keys of the bucket's objects in order.
+func (b *bucket) sortedKeys() []string {
+	keys := make([]string, 0, len(b.objects))
+	for k := range b.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
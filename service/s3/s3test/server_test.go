@@ -0,0 +1,640 @@
+package s3test_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3test"
+)
+
+func newTestClient(t *testing.T) (*s3.S3, func()) {
+	srv := s3test.NewServer()
+	svc := s3.New(unit.Session, srv.Config())
+
+	if _, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("bucket")}); err != nil {
+		srv.Close()
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	return svc, srv.Close
+}
+
+func putObjects(t *testing.T, svc *s3.S3, keys ...string) {
+	for _, key := range keys {
+		_, err := svc.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String(key),
+			Body:   strings.NewReader("content of " + key),
+		})
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+	}
+}
+
+func expectErrorCode(t *testing.T, err error, code string, status int) {
+	aerr, ok := err.(awserr.RequestFailure)
+	if !ok {
+		t.Fatalf("expect %s request failure, got %v", code, err)
+	}
+	if e, a := code, aerr.Code(); e != a {
+		t.Errorf("expect %q error code, got %q", e, a)
+	}
+	if e, a := status, aerr.StatusCode(); e != a {
+		t.Errorf("expect %d status code, got %d", e, a)
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	svc, cleanup := newTestClient(t)
+	defer cleanup()
+
+	_, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("bucket")})
+	expectErrorCode(t, err, "BucketAlreadyOwnedByYou", 409)
+
+	if _, err := svc.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String("bucket")}); err != nil {
+		t.Errorf("expect no error, got %v", err)
+	}
+	_, err = svc.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String("missing")})
+	expectErrorCode(t, err, "NotFound", 404)
+
+	region, err := s3manager.GetBucketRegionWithClient(aws.BackgroundContext(), svc, "bucket")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := s3test.DefaultRegion, region; e != a {
+		t.Errorf("expect %q region, got %q", e, a)
+	}
+
+	list, err := svc.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 1, len(list.Buckets); e != a {
+		t.Fatalf("expect %d buckets, got %d", e, a)
+	}
+	if e, a := "bucket", aws.StringValue(list.Buckets[0].Name); e != a {
+		t.Errorf("expect %q bucket, got %q", e, a)
+	}
+
+	putObjects(t, svc, "key")
+	_, err = svc.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String("bucket")})
+	expectErrorCode(t, err, "BucketNotEmpty", 409)
+
+	svc.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String("bucket"), Key: aws.String("key")})
+	if _, err := svc.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String("bucket")}); err != nil {
+		t.Errorf("expect no error, got %v", err)
+	}
+}
+
+func TestObjects(t *testing.T) {
+	svc, cleanup := newTestClient(t)
+	defer cleanup()
+
+	put, err := svc.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String("bucket"),
+		Key:         aws.String("dir/my key+1"),
+		Body:        strings.NewReader("0123456789"),
+		ContentType: aws.String("text/plain"),
+		Metadata:    map[string]*string{"Foo": aws.String("bar")},
+		Tagging:     aws.String("a=1&b=2"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := `"781e5e245d69b566979b86e28d23f2c7"`, aws.StringValue(put.ETag); e != a {
+		t.Errorf("expect %q etag, got %q", e, a)
+	}
+
+	get, err := svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/my key+1"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	b, _ := ioutil.ReadAll(get.Body)
+	get.Body.Close()
+	if e, a := "0123456789", string(b); e != a {
+		t.Errorf("expect %q body, got %q", e, a)
+	}
+	if e, a := "text/plain", aws.StringValue(get.ContentType); e != a {
+		t.Errorf("expect %q content type, got %q", e, a)
+	}
+	if e, a := "bar", aws.StringValue(get.Metadata["Foo"]); e != a {
+		t.Errorf("expect %q metadata, got %q", e, a)
+	}
+	if e, a := int64(2), aws.Int64Value(get.TagCount); e != a {
+		t.Errorf("expect %d tags, got %d", e, a)
+	}
+
+	head, err := svc.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/my key+1"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := int64(10), aws.Int64Value(head.ContentLength); e != a {
+		t.Errorf("expect %d content length, got %d", e, a)
+	}
+
+	if _, err := svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/my key+1"),
+	}); err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	_, err = svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dir/my key+1"),
+	})
+	expectErrorCode(t, err, "NoSuchKey", 404)
+}
+
+func TestGetObject_RangeAndConditions(t *testing.T) {
+	svc, cleanup := newTestClient(t)
+	defer cleanup()
+
+	put, err := svc.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   strings.NewReader("0123456789"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	cases := map[string]struct {
+		input      *s3.GetObjectInput
+		expectBody string
+		expectCode string
+		expectStat int
+	}{
+		"range": {
+			input:      &s3.GetObjectInput{Range: aws.String("bytes=2-4")},
+			expectBody: "234",
+		},
+		"open range": {
+			input:      &s3.GetObjectInput{Range: aws.String("bytes=7-")},
+			expectBody: "789",
+		},
+		"suffix range": {
+			input:      &s3.GetObjectInput{Range: aws.String("bytes=-2")},
+			expectBody: "89",
+		},
+		"range past end": {
+			input:      &s3.GetObjectInput{Range: aws.String("bytes=8-100")},
+			expectBody: "89",
+		},
+		"unsatisfiable range": {
+			input:      &s3.GetObjectInput{Range: aws.String("bytes=10-20")},
+			expectCode: "InvalidRange",
+			expectStat: 416,
+		},
+		"if match": {
+			input:      &s3.GetObjectInput{IfMatch: put.ETag},
+			expectBody: "0123456789",
+		},
+		"if match failed": {
+			input:      &s3.GetObjectInput{IfMatch: aws.String(`"other"`)},
+			expectCode: "PreconditionFailed",
+			expectStat: 412,
+		},
+		"if none match": {
+			input:      &s3.GetObjectInput{IfNoneMatch: put.ETag},
+			expectCode: "NotModified",
+			expectStat: 304,
+		},
+		"if modified since": {
+			input:      &s3.GetObjectInput{IfModifiedSince: aws.Time(time.Now().Add(time.Hour))},
+			expectCode: "NotModified",
+			expectStat: 304,
+		},
+		"if unmodified since": {
+			input:      &s3.GetObjectInput{IfUnmodifiedSince: aws.Time(time.Now().Add(-time.Hour))},
+			expectCode: "PreconditionFailed",
+			expectStat: 412,
+		},
+	}
+
+	for name, c := range cases {
+		c.input.Bucket = aws.String("bucket")
+		c.input.Key = aws.String("key")
+
+		resp, err := svc.GetObject(c.input)
+		if len(c.expectCode) != 0 {
+			if err == nil {
+				t.Errorf("%s, expect error, got none", name)
+				continue
+			}
+			if e, a := c.expectStat, err.(awserr.RequestFailure).StatusCode(); e != a {
+				t.Errorf("%s, expect %d status code, got %d", name, e, a)
+			}
+			if c.expectStat != 304 {
+				if e, a := c.expectCode, err.(awserr.Error).Code(); e != a {
+					t.Errorf("%s, expect %q error code, got %q", name, e, a)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s, expect no error, got %v", name, err)
+			continue
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if e, a := c.expectBody, string(b); e != a {
+			t.Errorf("%s, expect %q body, got %q", name, e, a)
+		}
+	}
+}
+
+func TestListObjects(t *testing.T) {
+	svc, cleanup := newTestClient(t)
+	defer cleanup()
+
+	putObjects(t, svc, "a", "b/1", "b/2", "c/1", "d")
+
+	var v1Keys, v1Prefixes []string
+	err := svc.ListObjectsPages(&s3.ListObjectsInput{
+		Bucket:    aws.String("bucket"),
+		Delimiter: aws.String("/"),
+		MaxKeys:   aws.Int64(2),
+	}, func(page *s3.ListObjectsOutput, last bool) bool {
+		for _, o := range page.Contents {
+			v1Keys = append(v1Keys, aws.StringValue(o.Key))
+		}
+		for _, p := range page.CommonPrefixes {
+			v1Prefixes = append(v1Prefixes, aws.StringValue(p.Prefix))
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := []string{"a", "d"}, v1Keys; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v keys, got %v", e, a)
+	}
+	if e, a := []string{"b/", "c/"}, v1Prefixes; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v prefixes, got %v", e, a)
+	}
+
+	var v2Keys []string
+	var pages int
+	err = svc.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket:  aws.String("bucket"),
+		Prefix:  aws.String("b/"),
+		MaxKeys: aws.Int64(1),
+	}, func(page *s3.ListObjectsV2Output, last bool) bool {
+		pages++
+		for _, o := range page.Contents {
+			v2Keys = append(v2Keys, aws.StringValue(o.Key))
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := []string{"b/1", "b/2"}, v2Keys; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v keys, got %v", e, a)
+	}
+	if e, a := 2, pages; e != a {
+		t.Errorf("expect %d pages, got %d", e, a)
+	}
+
+	resp, err := svc.ListObjectsV2(&s3.ListObjectsV2Input{
+		Bucket:     aws.String("bucket"),
+		StartAfter: aws.String("b/2"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := int64(2), aws.Int64Value(resp.KeyCount); e != a {
+		t.Errorf("expect %d keys, got %d", e, a)
+	}
+}
+
+func TestDeleteObjects(t *testing.T) {
+	svc, cleanup := newTestClient(t)
+	defer cleanup()
+
+	putObjects(t, svc, "a", "b", "c")
+
+	resp, err := svc.DeleteObjects(&s3.DeleteObjectsInput{
+		Bucket: aws.String("bucket"),
+		Delete: &s3.Delete{Objects: []*s3.ObjectIdentifier{
+			{Key: aws.String("a")}, {Key: aws.String("c")},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 2, len(resp.Deleted); e != a {
+		t.Errorf("expect %d deleted, got %d", e, a)
+	}
+
+	list, err := svc.ListObjects(&s3.ListObjectsInput{Bucket: aws.String("bucket")})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 1, len(list.Contents); e != a {
+		t.Fatalf("expect %d objects, got %d", e, a)
+	}
+	if e, a := "b", aws.StringValue(list.Contents[0].Key); e != a {
+		t.Errorf("expect %q key, got %q", e, a)
+	}
+}
+
+func TestMultipartUpload(t *testing.T) {
+	svc, cleanup := newTestClient(t)
+	defer cleanup()
+
+	create, err := svc.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("key"),
+		Metadata: map[string]*string{"Foo": aws.String("bar")},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	var parts []*s3.CompletedPart
+	for i, body := range []string{"hello ", "world"} {
+		resp, err := svc.UploadPart(&s3.UploadPartInput{
+			Bucket:     aws.String("bucket"),
+			Key:        aws.String("key"),
+			UploadId:   create.UploadId,
+			PartNumber: aws.Int64(int64(i + 1)),
+			Body:       strings.NewReader(body),
+		})
+		if err != nil {
+			t.Fatalf("expect no error, got %v", err)
+		}
+		parts = append(parts, &s3.CompletedPart{ETag: resp.ETag, PartNumber: aws.Int64(int64(i + 1))})
+	}
+
+	uploads, err := svc.ListMultipartUploads(&s3.ListMultipartUploadsInput{
+		Bucket: aws.String("bucket"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 1, len(uploads.Uploads); e != a {
+		t.Fatalf("expect %d uploads, got %d", e, a)
+	}
+	if e, a := aws.StringValue(create.UploadId), aws.StringValue(uploads.Uploads[0].UploadId); e != a {
+		t.Errorf("expect %q upload id, got %q", e, a)
+	}
+
+	list, err := svc.ListParts(&s3.ListPartsInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("key"),
+		UploadId: create.UploadId,
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := 2, len(list.Parts); e != a {
+		t.Errorf("expect %d parts, got %d", e, a)
+	}
+
+	_, err = svc.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String("bucket"),
+		Key:             aws.String("key"),
+		UploadId:        create.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: []*s3.CompletedPart{parts[1], parts[0]}},
+	})
+	expectErrorCode(t, err, "InvalidPartOrder", 400)
+
+	complete, err := svc.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String("bucket"),
+		Key:             aws.String("key"),
+		UploadId:        create.UploadId,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := `"e09e4fd6265b36115fe3db32df945d84-2"`, aws.StringValue(complete.ETag); e != a {
+		t.Errorf("expect %q etag, got %q", e, a)
+	}
+
+	head, err := svc.HeadObject(&s3.HeadObjectInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("key"),
+		PartNumber: aws.Int64(2),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := int64(5), aws.Int64Value(head.ContentLength); e != a {
+		t.Errorf("expect %d part size, got %d", e, a)
+	}
+	if e, a := int64(2), aws.Int64Value(head.PartsCount); e != a {
+		t.Errorf("expect %d parts, got %d", e, a)
+	}
+	if e, a := "bar", aws.StringValue(head.Metadata["Foo"]); e != a {
+		t.Errorf("expect %q metadata, got %q", e, a)
+	}
+
+	_, err = svc.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("key"),
+		UploadId: create.UploadId,
+	})
+	expectErrorCode(t, err, "NoSuchUpload", 404)
+}
+
+func TestCopyObjectAndTagging(t *testing.T) {
+	svc, cleanup := newTestClient(t)
+	defer cleanup()
+
+	_, err := svc.PutObject(&s3.PutObjectInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("src"),
+		Body:     strings.NewReader("content"),
+		Metadata: map[string]*string{"Foo": aws.String("bar")},
+		Tagging:  aws.String("a=1"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	_, err = svc.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("dst"),
+		CopySource: aws.String("bucket/src"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	head, err := svc.HeadObject(&s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("dst")})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if e, a := "bar", aws.StringValue(head.Metadata["Foo"]); e != a {
+		t.Errorf("expect %q metadata, got %q", e, a)
+	}
+
+	_, err = svc.CopyObject(&s3.CopyObjectInput{
+		Bucket:            aws.String("bucket"),
+		Key:               aws.String("dst"),
+		CopySource:        aws.String("bucket/src"),
+		CopySourceIfMatch: aws.String(`"other"`),
+	})
+	expectErrorCode(t, err, "PreconditionFailed", 412)
+
+	_, err = svc.PutObjectTagging(&s3.PutObjectTaggingInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("dst"),
+		Tagging: &s3.Tagging{TagSet: []*s3.Tag{
+			{Key: aws.String("b"), Value: aws.String("2")},
+			{Key: aws.String("a"), Value: aws.String("3")},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	tags, err := svc.GetObjectTagging(&s3.GetObjectTaggingInput{Bucket: aws.String("bucket"), Key: aws.String("dst")})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	expect := []*s3.Tag{
+		{Key: aws.String("a"), Value: aws.String("3")},
+		{Key: aws.String("b"), Value: aws.String("2")},
+	}
+	if e, a := expect, tags.TagSet; !reflect.DeepEqual(e, a) {
+		t.Errorf("expect %v tags, got %v", e, a)
+	}
+}
+
+func TestTransferManagers(t *testing.T) {
+	svc, cleanup := newTestClient(t)
+	defer cleanup()
+
+	data := make([]byte, 12*1024*1024)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	_, err := s3manager.NewUploaderWithClient(svc).Upload(&s3manager.UploadInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	_, err = s3manager.NewCopierWithClient(svc, func(c *s3manager.Copier) {
+		c.PartSize = 5 * 1024 * 1024
+	}).Copy(&s3.CopyObjectInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("copy"),
+		CopySource: aws.String("bucket/key"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+
+	w := &aws.WriteAtBuffer{}
+	_, err = s3manager.NewDownloaderWithClient(svc,
+		s3manager.WithDownloaderChecksumVerification).Download(w, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("copy"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	if !bytes.Equal(data, w.Bytes()) {
+		t.Errorf("expect downloaded content to match")
+	}
+}
+
+func TestNotImplemented(t *testing.T) {
+	srv := s3test.NewServer()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/bucket?acl")
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	resp.Body.Close()
+	if e, a := http.StatusNotImplemented, resp.StatusCode; e != a {
+		t.Errorf("expect %d status code, got %d", e, a)
+	}
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	srv := s3test.NewServer()
+	defer srv.Close()
+
+	put := func(path string, body io.Reader) (*http.Response, error) {
+		req, err := http.NewRequest("PUT", srv.URL+path, body)
+		if err != nil {
+			return nil, err
+		}
+		return http.DefaultClient.Do(req)
+	}
+
+	resp, err := put("/bucket", nil)
+	if err != nil {
+		t.Fatalf("expect no error, got %v", err)
+	}
+	resp.Body.Close()
+
+	pr, pw := io.Pipe()
+	putDone := make(chan int, 1)
+	go func() {
+		resp, err := put("/bucket/key", pr)
+		if err != nil {
+			putDone <- 0
+			return
+		}
+		resp.Body.Close()
+		putDone <- resp.StatusCode
+	}()
+	pw.Write([]byte("partial "))
+
+	getDone := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(srv.URL + "/bucket")
+		if err != nil {
+			getDone <- 0
+			return
+		}
+		resp.Body.Close()
+		getDone <- resp.StatusCode
+	}()
+
+	select {
+	case status := <-getDone:
+		if e, a := http.StatusOK, status; e != a {
+			t.Errorf("expect %d status code, got %d", e, a)
+		}
+	case <-time.After(5 * time.Second):
+		pw.CloseWithError(io.ErrUnexpectedEOF)
+		t.Fatalf("expect request not to wait for another request's body")
+	}
+
+	pw.Write([]byte("content"))
+	pw.Close()
+	if e, a := http.StatusOK, <-putDone; e != a {
+		t.Errorf("expect %d status code, got %d", e, a)
+	}
+}